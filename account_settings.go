@@ -0,0 +1,203 @@
+package gobookmarks
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/sessions"
+)
+
+// AccountSettings are choices kept for an account on the server so they
+// follow it across sessions and devices. Empty fields mean the defaults.
+type AccountSettings struct {
+	RepoName     string `json:"repo_name,omitempty"`
+	BookmarkFile string `json:"bookmark_file,omitempty"`
+}
+
+// AccountSettingsStore keeps AccountSettings by provider and user.
+// AccountSettings returns nil when nothing is stored and passing nil to
+// SetAccountSettings removes the stored settings.
+type AccountSettingsStore interface {
+	AccountSettings(ctx context.Context, provider, user string) (*AccountSettings, error)
+	SetAccountSettings(ctx context.Context, provider, user string, s *AccountSettings) error
+}
+
+// Settings is the store used by the handlers. A nil store keeps settings in
+// the session only.
+var Settings AccountSettingsStore
+
+// NewConfiguredSettingsStore returns the store selected by
+// Config.SettingsStore: "file" (the default) or "sql".
+func NewConfiguredSettingsStore() (AccountSettingsStore, error) {
+	switch strings.ToLower(Config.SettingsStore) {
+	case "", "file":
+		dir := Config.SettingsDir
+		if dir == "" {
+			dir = DefaultSettingsDir()
+		}
+		return NewFileSettingsStore(dir)
+	case "sql":
+		return &SQLSettingsStore{}, nil
+	default:
+		return nil, fmt.Errorf("unknown settings store %q, expected file or sql", Config.SettingsStore)
+	}
+}
+
+// Session keys caching the signed-in account's settings. settingsSessionFor
+// names the account they were loaded for.
+const (
+	settingsSessionFor = "SettingsFor"
+	repoSessionKey     = "RepoName"
+	fileSessionKey     = "BookmarkFile"
+)
+
+// accountSettings returns the settings of user, read from the session when
+// it already caches them and otherwise loaded from Settings and cached.
+func accountSettings(ctx context.Context, session *sessions.Session, provider, user string) AccountSettings {
+	account := provider + ":" + user
+	if user != "" && Settings != nil && session.Values[settingsSessionFor] != account {
+		s, err := Settings.AccountSettings(ctx, provider, user)
+		if err != nil {
+			slog.ErrorContext(ctx, "account settings load failed", "err", err)
+		} else {
+			cacheAccountSettings(session, account, s)
+		}
+	}
+	var s AccountSettings
+	s.RepoName, _ = session.Values[repoSessionKey].(string)
+	s.BookmarkFile, _ = session.Values[fileSessionKey].(string)
+	return s
+}
+
+// cacheAccountSettings copies s into the session for account.
+func cacheAccountSettings(session *sessions.Session, account string, s *AccountSettings) {
+	session.Values[settingsSessionFor] = account
+	delete(session.Values, repoSessionKey)
+	delete(session.Values, fileSessionKey)
+	if s == nil {
+		return
+	}
+	if s.RepoName != "" {
+		session.Values[repoSessionKey] = s.RepoName
+	}
+	if s.BookmarkFile != "" {
+		session.Values[fileSessionKey] = s.BookmarkFile
+	}
+}
+
+// clearAccountSettings drops the cached settings from the session.
+func clearAccountSettings(session *sessions.Session) {
+	delete(session.Values, settingsSessionFor)
+	delete(session.Values, repoSessionKey)
+	delete(session.Values, fileSessionKey)
+}
+
+// FileSettingsStore keeps each account's settings as a JSON file in Dir,
+// named by a hash of the provider and user.
+type FileSettingsStore struct {
+	Dir string
+	mu  sync.Mutex
+}
+
+// NewFileSettingsStore creates dir if needed and returns a store using it.
+func NewFileSettingsStore(dir string) (*FileSettingsStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("settings dir: %w", err)
+	}
+	return &FileSettingsStore{Dir: dir}, nil
+}
+
+func (s *FileSettingsStore) path(provider, user string) string {
+	sum := sha256.Sum256([]byte(provider + ":" + user))
+	return filepath.Join(s.Dir, hex.EncodeToString(sum[:])+".json")
+}
+
+func (s *FileSettingsStore) AccountSettings(ctx context.Context, provider, user string) (*AccountSettings, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, err := os.ReadFile(s.path(provider, user))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var out AccountSettings
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, fmt.Errorf("settings for %s:%s: %w", provider, user, err)
+	}
+	return &out, nil
+}
+
+func (s *FileSettingsStore) SetAccountSettings(ctx context.Context, provider, user string, settings *AccountSettings) error {
+	path := s.path(provider, user)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if settings == nil || *settings == (AccountSettings{}) {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// SQLSettingsStore keeps settings in the account_settings table so every
+// instance sharing the database sees them.
+type SQLSettingsStore struct{}
+
+func (s *SQLSettingsStore) AccountSettings(ctx context.Context, provider, user string) (*AccountSettings, error) {
+	db, err := sharedDB()
+	if err != nil {
+		return nil, err
+	}
+	var out AccountSettings
+	var repo, file sql.NullString
+	err = db.QueryRowContext(ctx, "SELECT repo_name, bookmark_file FROM account_settings WHERE name=?", provider+":"+user).Scan(&repo, &file)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	out.RepoName, out.BookmarkFile = repo.String, file.String
+	return &out, nil
+}
+
+func (s *SQLSettingsStore) SetAccountSettings(ctx context.Context, provider, user string, settings *AccountSettings) error {
+	db, err := sharedDB()
+	if err != nil {
+		return err
+	}
+	name := provider + ":" + user
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	if _, err := tx.ExecContext(ctx, "DELETE FROM account_settings WHERE name=?", name); err != nil {
+		return err
+	}
+	if settings != nil && *settings != (AccountSettings{}) {
+		if _, err := tx.ExecContext(ctx, "INSERT INTO account_settings(name, repo_name, bookmark_file) VALUES(?, ?, ?)", name, settings.RepoName, settings.BookmarkFile); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
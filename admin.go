@@ -0,0 +1,108 @@
+package gobookmarks
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/sessions"
+)
+
+// ErrAccountDisabled indicates an administrator disabled the account.
+var ErrAccountDisabled = errors.New("account disabled")
+
+// ErrImpersonationReadOnly indicates an administrator tried to change the
+// bookmarks of a user they are viewing.
+var ErrImpersonationReadOnly = errors.New("impersonation is read only")
+
+// IsAdmin reports whether user signed in through provider is listed in
+// Config.Admins as "provider:user".
+func IsAdmin(provider, user string) bool {
+	if provider == "" || user == "" {
+		return false
+	}
+	for _, a := range Config.Admins {
+		if a == provider+":"+user {
+			return true
+		}
+	}
+	return false
+}
+
+// accountDisabled reports whether an administrator disabled user's account
+// in p. Lookup errors are logged and do not block the user.
+func accountDisabled(ctx context.Context, p Provider, user string) bool {
+	am, ok := p.(AccountManager)
+	if !ok {
+		return false
+	}
+	disabled, err := am.AccountDisabled(ctx, user)
+	if err != nil {
+		slog.ErrorContext(ctx, "account status lookup failed", "user", user, "provider", p.Name(), "err", err)
+		return false
+	}
+	return disabled
+}
+
+// recordAccountLogin audits a successful login and notes it for the admin
+// user list.
+func recordAccountLogin(r *http.Request, p Provider, user string) {
+	Audit(r.Context(), AuditEntry{Event: "login", User: user, Provider: p.Name(), IP: clientIP(r)})
+	loginsTotal.Inc(p.Name(), "success")
+	am, ok := p.(AccountManager)
+	if !ok {
+		return
+	}
+	if err := am.RecordLogin(r.Context(), user, time.Now()); err != nil {
+		slog.ErrorContext(r.Context(), "record login failed", "user", user, "provider", p.Name(), "err", err)
+	}
+}
+
+// signOutDisabled ends the session of a user whose account has been
+// disabled since they signed in. It reports whether the session was changed.
+func signOutDisabled(w http.ResponseWriter, r *http.Request, session *sessions.Session) bool {
+	if session == nil {
+		return false
+	}
+	user, _ := session.Values["GithubUser"].(*User)
+	if user == nil {
+		return false
+	}
+	providerName, _ := session.Values["Provider"].(string)
+	if !accountDisabled(r.Context(), GetProvider(providerName), user.Login) {
+		return false
+	}
+	slog.InfoContext(r.Context(), "signing out disabled account", "user", user.Login, "provider", providerName)
+	renewSession(r, session)
+	for _, k := range []string{"GithubUser", "Token", "Provider", "ProxyAuth", "ImpersonateProvider", "ImpersonateUser"} {
+		delete(session.Values, k)
+	}
+	if err := session.Save(r, w); err != nil {
+		slog.ErrorContext(r.Context(), "session save failed", "err", err)
+	}
+	return true
+}
+
+// impersonation returns the provider and account an administrator is
+// viewing. It is empty when the session is not impersonating or the user is
+// no longer an administrator.
+func impersonation(session *sessions.Session, provider, login string) (string, string) {
+	targetProvider, _ := session.Values["ImpersonateProvider"].(string)
+	target, _ := session.Values["ImpersonateUser"].(string)
+	if target == "" || !IsAdmin(provider, login) {
+		return "", ""
+	}
+	if _, ok := GetProvider(targetProvider).(AccountManager); !ok {
+		return "", ""
+	}
+	return targetProvider, target
+}
+
+// impersonatedUser returns the account whose bookmarks an administrator is
+// viewing read-only, or "".
+func impersonatedUser(ctx context.Context) string {
+	user, _ := ctx.Value(ContextValues("impersonate")).(string)
+	return user
+}
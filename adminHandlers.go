@@ -0,0 +1,316 @@
+package gobookmarks
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+
+	"github.com/gorilla/sessions"
+)
+
+// AdminAccount is an account row on the admin page.
+type AdminAccount struct {
+	*Account
+	Provider string
+	// Self marks the administrator's own account.
+	Self bool
+}
+
+// AdminPageData is rendered by admin.gohtml.
+type AdminPageData struct {
+	*CoreData
+	Error    string
+	Accounts []AdminAccount
+	// TempPassword is shown once after a password reset for TempPasswordUser.
+	TempPassword     string
+	TempPasswordUser string
+	// ForceLogout is false when sessions live in cookies and cannot be
+	// revoked.
+	ForceLogout bool
+}
+
+// adminSession returns the provider and login of the signed-in
+// administrator. Other users get a 403 page and ErrHandled.
+func adminSession(w http.ResponseWriter, r *http.Request) (string, string, error) {
+	session := r.Context().Value(ContextValues("session")).(*sessions.Session)
+	githubUser, _ := session.Values["GithubUser"].(*User)
+	if githubUser == nil {
+		return "", "", ErrSignedOut
+	}
+	provider, _ := session.Values["Provider"].(string)
+	if !IsAdmin(provider, githubUser.Login) {
+		slog.WarnContext(r.Context(), "admin access refused", "user", githubUser.Login, "provider", provider)
+		renderErrorPage(w, r, http.StatusForbidden, "This page is only available to administrators.")
+		return "", "", ErrHandled
+	}
+	return provider, githubUser.Login, nil
+}
+
+// adminAccounts lists the accounts of every configured provider that keeps
+// its own accounts. Collection storage accounts are left out.
+func adminAccounts(r *http.Request, adminProvider, admin string) ([]AdminAccount, error) {
+	var res []AdminAccount
+	for _, name := range ConfiguredProviderNames() {
+		am, ok := GetProvider(name).(AccountManager)
+		if !ok {
+			continue
+		}
+		accounts, err := am.Accounts(r.Context())
+		if err != nil {
+			return nil, fmt.Errorf("%s accounts: %w", name, err)
+		}
+		for _, a := range accounts {
+			if isCollectionStorage(a.User) {
+				continue
+			}
+			res = append(res, AdminAccount{
+				Account:  a,
+				Provider: name,
+				Self:     name == adminProvider && a.User == admin,
+			})
+		}
+	}
+	sort.SliceStable(res, func(i, j int) bool {
+		if res[i].User != res[j].User {
+			return res[i].User < res[j].User
+		}
+		return res[i].Provider < res[j].Provider
+	})
+	return res, nil
+}
+
+func renderAdminPage(w http.ResponseWriter, r *http.Request, tempUser, tempPassword string) error {
+	provider, login, err := adminSession(w, r)
+	if err != nil {
+		return err
+	}
+	accounts, err := adminAccounts(r, provider, login)
+	if err != nil {
+		return err
+	}
+	data := AdminPageData{
+		CoreData:         r.Context().Value(ContextValues("coreData")).(*CoreData),
+		Error:            r.URL.Query().Get("error"),
+		Accounts:         accounts,
+		TempPassword:     tempPassword,
+		TempPasswordUser: tempUser,
+		ForceLogout:      serverSessionStore() != nil,
+	}
+	if err := GetCompiledTemplates(NewFuncs(r)).ExecuteTemplate(w, "admin.gohtml", data); err != nil {
+		return fmt.Errorf("template: %w", err)
+	}
+	return nil
+}
+
+// AdminPage lists the accounts of the sql and git providers.
+func AdminPage(w http.ResponseWriter, r *http.Request) error {
+	return renderAdminPage(w, r, "", "")
+}
+
+// adminAction is the administrator and target of an admin form post.
+type adminAction struct {
+	adminProvider string
+	admin         string
+	provider      Provider
+	accounts      AccountManager
+	user          string
+}
+
+func (a adminAction) self() bool {
+	return a.provider.Name() == a.adminProvider && a.user == a.admin
+}
+
+// audit records the action with the administrator who took it.
+func (a adminAction) audit(r *http.Request, event, detail string) {
+	if detail != "" {
+		detail = "; " + detail
+	}
+	Audit(r.Context(), AuditEntry{
+		Event:    "admin_" + event,
+		User:     a.user,
+		Provider: a.provider.Name(),
+		IP:       clientIP(r),
+		Detail:   "by " + a.adminProvider + ":" + a.admin + detail,
+	})
+}
+
+// revokeSessions signs the target out everywhere when sessions are kept on
+// the server. It returns the number of sessions removed.
+func (a adminAction) revokeSessions(r *http.Request) (int, error) {
+	store := serverSessionStore()
+	if store == nil {
+		return 0, nil
+	}
+	return store.RevokeAll(r.Context(), a.provider.Name(), a.user, "")
+}
+
+func loadAdminAction(w http.ResponseWriter, r *http.Request) (adminAction, error) {
+	adminProvider, admin, err := adminSession(w, r)
+	if err != nil {
+		return adminAction{}, err
+	}
+	name := r.PostFormValue("provider")
+	user := r.PostFormValue("user")
+	p := GetProvider(name)
+	am, ok := p.(AccountManager)
+	if !ok || providerCreds(name) == nil || user == "" {
+		return adminAction{}, NewUserError("Unknown account", ErrUserNotFound)
+	}
+	return adminAction{adminProvider: adminProvider, admin: admin, provider: p, accounts: am, user: user}, nil
+}
+
+func adminUserError(err error) error {
+	if errors.Is(err, ErrUserNotFound) {
+		return NewUserError("Unknown account", err)
+	}
+	return err
+}
+
+// AdminDisableAction stops an account from signing in and ends its sessions.
+func AdminDisableAction(w http.ResponseWriter, r *http.Request) error {
+	a, err := loadAdminAction(w, r)
+	if err != nil {
+		return err
+	}
+	if a.self() {
+		return NewUserError("You cannot disable your own account", nil)
+	}
+	if err := a.accounts.SetAccountDisabled(r.Context(), a.user, true); err != nil {
+		return adminUserError(err)
+	}
+	if _, err := a.revokeSessions(r); err != nil {
+		slog.ErrorContext(r.Context(), "revoke sessions failed", "action", "admin disable", "target", a.user, "err", err)
+	}
+	a.audit(r, "disable", "")
+	return nil
+}
+
+// AdminEnableAction lets a disabled account sign in again.
+func AdminEnableAction(w http.ResponseWriter, r *http.Request) error {
+	a, err := loadAdminAction(w, r)
+	if err != nil {
+		return err
+	}
+	if err := a.accounts.SetAccountDisabled(r.Context(), a.user, false); err != nil {
+		return adminUserError(err)
+	}
+	a.audit(r, "enable", "")
+	return nil
+}
+
+// AdminDeleteAction removes an account with its bookmarks and history.
+func AdminDeleteAction(w http.ResponseWriter, r *http.Request) error {
+	a, err := loadAdminAction(w, r)
+	if err != nil {
+		return err
+	}
+	if a.self() {
+		return NewUserError("You cannot delete your own account here", nil)
+	}
+	if err := a.accounts.DeleteAccount(r.Context(), a.user); err != nil {
+		return adminUserError(err)
+	}
+	invalidateBookmarkCache(a.user)
+	if _, err := a.revokeSessions(r); err != nil {
+		slog.ErrorContext(r.Context(), "revoke sessions failed", "action", "admin delete", "target", a.user, "err", err)
+	}
+	a.audit(r, "delete", "")
+	return nil
+}
+
+// AdminResetPasswordAction replaces the password with a random one that is
+// shown to the administrator once.
+func AdminResetPasswordAction(w http.ResponseWriter, r *http.Request) error {
+	a, err := loadAdminAction(w, r)
+	if err != nil {
+		return err
+	}
+	ph, ok := a.provider.(PasswordHandler)
+	if !ok {
+		return NewUserError("This provider has no passwords", nil)
+	}
+	password, err := newSessionID()
+	if err != nil {
+		return err
+	}
+	password = password[:16]
+	if err := ph.SetPassword(r.Context(), a.user, password); err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			return NewUserError("This account signs in without a password", err)
+		}
+		return err
+	}
+	a.audit(r, "reset_password", "")
+	return renderAdminPage(w, r, a.user, password)
+}
+
+// AdminForceLogoutAction ends every session of an account.
+func AdminForceLogoutAction(w http.ResponseWriter, r *http.Request) error {
+	a, err := loadAdminAction(w, r)
+	if err != nil {
+		return err
+	}
+	if serverSessionStore() == nil {
+		return NewUserError("Signing users out needs server-side sessions (session_store sql or file)", ErrSessionsUnsupported)
+	}
+	n, err := a.revokeSessions(r)
+	if err != nil {
+		return fmt.Errorf("revoke sessions: %w", err)
+	}
+	a.audit(r, "force_logout", fmt.Sprintf("%d sessions", n))
+	return nil
+}
+
+// AdminImpersonateAction shows the administrator another account's
+// bookmarks read only until AdminImpersonateStopAction.
+func AdminImpersonateAction(w http.ResponseWriter, r *http.Request) error {
+	a, err := loadAdminAction(w, r)
+	if err != nil {
+		return err
+	}
+	exists, err := a.provider.RepoExists(r.Context(), a.user, nil, Config.GetRepoName())
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return NewUserError("This account has no bookmarks", ErrUserNotFound)
+	}
+	session := r.Context().Value(ContextValues("session")).(*sessions.Session)
+	session.Values["ImpersonateProvider"] = a.provider.Name()
+	session.Values["ImpersonateUser"] = a.user
+	if err := session.Save(r, w); err != nil {
+		return fmt.Errorf("session save: %w", err)
+	}
+	a.audit(r, "impersonate", "read only")
+	return nil
+}
+
+// AdminImpersonateStopAction returns the administrator to their own
+// bookmarks.
+func AdminImpersonateStopAction(w http.ResponseWriter, r *http.Request) error {
+	session := r.Context().Value(ContextValues("session")).(*sessions.Session)
+	provider, _ := session.Values["ImpersonateProvider"].(string)
+	user, _ := session.Values["ImpersonateUser"].(string)
+	delete(session.Values, "ImpersonateProvider")
+	delete(session.Values, "ImpersonateUser")
+	if err := session.Save(r, w); err != nil {
+		return fmt.Errorf("session save: %w", err)
+	}
+	if user != "" {
+		login := ""
+		if u, ok := session.Values["GithubUser"].(*User); ok {
+			login = u.Login
+		}
+		adminProvider, _ := session.Values["Provider"].(string)
+		Audit(r.Context(), AuditEntry{
+			Event:    "admin_impersonate_stop",
+			User:     user,
+			Provider: provider,
+			IP:       clientIP(r),
+			Detail:   "by " + adminProvider + ":" + login,
+		})
+	}
+	return nil
+}
@@ -0,0 +1,239 @@
+package gobookmarks
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/sessions"
+)
+
+func accountManagers(t *testing.T) map[string]interface {
+	Provider
+	PasswordHandler
+	AccountManager
+} {
+	t.Helper()
+	old := Config
+	t.Cleanup(func() { Config = old })
+	Config.LocalGitPath = t.TempDir()
+	Config.DBConnectionProvider = "sqlite3"
+	Config.DBConnectionString = filepath.Join(t.TempDir(), "accounts.db")
+	sp := &SQLProvider{}
+	t.Cleanup(func() { _ = closeSharedDB() })
+	return map[string]interface {
+		Provider
+		PasswordHandler
+		AccountManager
+	}{"sql": sp, "git": GitProvider{}}
+}
+
+func TestAccountManagers(t *testing.T) {
+	for name, p := range accountManagers(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			if err := p.CreateUser(ctx, "alice", "password"); err != nil {
+				t.Fatalf("CreateUser: %v", err)
+			}
+			if err := ensureRepo(ctx, p, "alice", nil); err != nil {
+				t.Fatalf("ensureRepo: %v", err)
+			}
+			login := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+			if err := p.RecordLogin(ctx, "alice", login); err != nil {
+				t.Fatalf("RecordLogin: %v", err)
+			}
+			accounts, err := p.Accounts(ctx)
+			if err != nil || len(accounts) != 1 {
+				t.Fatalf("Accounts = %v, %v", accounts, err)
+			}
+			a := accounts[0]
+			if a.User != "alice" || !a.LastLogin.Equal(login) || !a.HasPassword || a.Disabled {
+				t.Fatalf("account = %+v", a)
+			}
+			if a.BookmarkBytes != len(defaultBookmarks) || a.Commits == 0 {
+				t.Fatalf("stats: %d bytes, %d commits", a.BookmarkBytes, a.Commits)
+			}
+
+			if err := p.SetAccountDisabled(ctx, "alice", true); err != nil {
+				t.Fatalf("SetAccountDisabled: %v", err)
+			}
+			if disabled, err := p.AccountDisabled(ctx, "alice"); err != nil || !disabled {
+				t.Fatalf("AccountDisabled = %v, %v", disabled, err)
+			}
+			if err := p.SetAccountDisabled(ctx, "nobody", true); !errors.Is(err, ErrUserNotFound) {
+				t.Fatalf("disable unknown user: %v", err)
+			}
+
+			if err := p.DeleteAccount(ctx, "alice"); err != nil {
+				t.Fatalf("DeleteAccount: %v", err)
+			}
+			if accounts, _ := p.Accounts(ctx); len(accounts) != 0 {
+				t.Fatalf("deleted account listed: %v", accounts)
+			}
+			if ok, _ := p.CheckPassword(ctx, "alice", "password"); ok {
+				t.Fatalf("password kept after delete")
+			}
+			if err := p.DeleteAccount(ctx, "alice"); !errors.Is(err, ErrUserNotFound) {
+				t.Fatalf("second delete: %v", err)
+			}
+		})
+	}
+}
+
+// setupAdminTest signs "root" in to the git provider as an administrator
+// and returns a router-like handler running the middleware.
+func setupAdminTest(t *testing.T) (http.Handler, *httptest.ResponseRecorder) {
+	t.Helper()
+	useLoginAttempts(t, nil)
+	old := Config
+	t.Cleanup(func() { Config = old })
+	Config.LocalGitPath = t.TempDir()
+	Config.SessionName = "gobookmarks"
+	Config.Admins = []string{"git:root"}
+	SessionStore = sessions.NewCookieStore([]byte("secret-key"))
+	ctx := context.Background()
+	for _, u := range []string{"root", "alice"} {
+		if err := (GitProvider{}).CreateUser(ctx, u, "password"); err != nil {
+			t.Fatalf("CreateUser: %v", err)
+		}
+		if err := ensureRepo(ctx, GitProvider{}, u, nil); err != nil {
+			t.Fatalf("ensureRepo: %v", err)
+		}
+	}
+	mux := http.NewServeMux()
+	handle := func(path string, h func(http.ResponseWriter, *http.Request) error) {
+		mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			if err := h(w, r); err != nil && !errors.Is(err, ErrHandled) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			}
+		})
+	}
+	handle("/admin", AdminPage)
+	handle("/admin/disable", AdminDisableAction)
+	handle("/admin/impersonate", AdminImpersonateAction)
+	handle("/admin/impersonate/stop", AdminImpersonateStopAction)
+	handle("/login/git", GitLoginAction)
+	mux.HandleFunc("/whoami", func(w http.ResponseWriter, r *http.Request) {
+		s := r.Context().Value(ContextValues("session")).(*sessions.Session)
+		if u, ok := s.Values["GithubUser"].(*User); ok {
+			w.Header().Set("X-Test-User", u.Login)
+		}
+		b, _, err := GetBookmarks(r.Context(), r.Header.Get("X-Test-As"), "", nil)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write([]byte(b))
+	})
+	mux.HandleFunc("/edit", func(w http.ResponseWriter, r *http.Request) {})
+	h := UserAdderMiddleware(CoreAdderMiddleware(mux))
+	return h, adminRequest(h, "POST", "/login/git", url.Values{"username": {"root"}, "password": {"password"}}, nil)
+}
+
+func adminRequest(h http.Handler, method, path string, form url.Values, prev *httptest.ResponseRecorder) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if prev != nil {
+		addLastCookies(req, prev)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+func TestIsAdmin(t *testing.T) {
+	old := Config
+	t.Cleanup(func() { Config = old })
+	Config.Admins = []string{"git:root", "root"}
+	if !IsAdmin("git", "root") {
+		t.Fatalf("git:root should be an admin")
+	}
+	for _, provider := range []string{"github", "gitlab", "sql", ""} {
+		if IsAdmin(provider, "root") {
+			t.Errorf("%s:root matched", provider)
+		}
+	}
+}
+
+func TestAdminPage(t *testing.T) {
+	h, root := setupAdminTest(t)
+	w := adminRequest(h, "GET", "/admin", nil, root)
+	if w.Code != http.StatusOK {
+		t.Fatalf("admin page: %d %s", w.Code, w.Body.String())
+	}
+	body := w.Body.String()
+	if !strings.Contains(body, "alice") || !strings.Contains(body, "root (you)") {
+		t.Fatalf("accounts missing: %s", body)
+	}
+
+	alice := adminRequest(h, "POST", "/login/git", url.Values{"username": {"alice"}, "password": {"password"}}, nil)
+	if w := adminRequest(h, "GET", "/admin", nil, alice); w.Code != http.StatusForbidden {
+		t.Fatalf("non-admin got %d", w.Code)
+	}
+}
+
+func TestAdminDisable(t *testing.T) {
+	h, root := setupAdminTest(t)
+	alice := adminRequest(h, "POST", "/login/git", url.Values{"username": {"alice"}, "password": {"password"}}, nil)
+	if got := adminRequest(h, "GET", "/whoami", nil, alice).Header().Get("X-Test-User"); got != "alice" {
+		t.Fatalf("alice not signed in: %q", got)
+	}
+
+	adminRequest(h, "POST", "/admin/disable", url.Values{"provider": {"git"}, "user": {"alice"}}, root)
+	if disabled, _ := (GitProvider{}).AccountDisabled(context.Background(), "alice"); !disabled {
+		t.Fatalf("account not disabled")
+	}
+	if got := adminRequest(h, "GET", "/whoami", nil, alice).Header().Get("X-Test-User"); got != "" {
+		t.Fatalf("disabled account still signed in as %q", got)
+	}
+	w := adminRequest(h, "POST", "/login/git", url.Values{"username": {"alice"}, "password": {"password"}}, nil)
+	if loc := w.Header().Get("Location"); loc != "/login/git?error=disabled" {
+		t.Fatalf("disabled login redirected to %q", loc)
+	}
+
+	if w := adminRequest(h, "POST", "/admin/disable", url.Values{"provider": {"git"}, "user": {"root"}}, root); w.Code == http.StatusOK {
+		t.Fatalf("admin disabled themselves")
+	}
+}
+
+func TestAdminImpersonate(t *testing.T) {
+	h, root := setupAdminTest(t)
+	ctx := context.Background()
+	if err := (GitProvider{}).UpdateBookmarks(ctx, "alice", nil, "", "main", "Category: Alice\n", ""); err != nil {
+		t.Fatalf("UpdateBookmarks: %v", err)
+	}
+
+	w := adminRequest(h, "POST", "/admin/impersonate", url.Values{"provider": {"git"}, "user": {"alice"}}, root)
+	req := httptest.NewRequest("GET", "/whoami", nil)
+	req.Header.Set("X-Test-As", "root")
+	addLastCookies(req, w)
+	view := httptest.NewRecorder()
+	h.ServeHTTP(view, req)
+	if view.Header().Get("X-Test-User") != "root" || !strings.Contains(view.Body.String(), "Category: Alice") {
+		t.Fatalf("impersonation view: %q %q", view.Header().Get("X-Test-User"), view.Body.String())
+	}
+
+	if edit := adminRequest(h, "POST", "/edit", url.Values{"text": {"x"}}, w); edit.Code != http.StatusForbidden {
+		t.Fatalf("edit while impersonating: %d", edit.Code)
+	}
+	ictx := context.WithValue(ctx, ContextValues("impersonate"), "alice")
+	if err := UpdateBookmarks(context.WithValue(ictx, ContextValues("provider"), "git"), "root", nil, "", "main", "x", ""); !errors.Is(err, ErrImpersonationReadOnly) {
+		t.Fatalf("write while impersonating: %v", err)
+	}
+
+	stop := adminRequest(h, "POST", "/admin/impersonate/stop", nil, w)
+	req = httptest.NewRequest("GET", "/whoami", nil)
+	req.Header.Set("X-Test-As", "root")
+	addLastCookies(req, stop)
+	view = httptest.NewRecorder()
+	h.ServeHTTP(view, req)
+	if strings.Contains(view.Body.String(), "Category: Alice") {
+		t.Fatalf("still viewing alice after stop")
+	}
+}
@@ -0,0 +1,48 @@
+package gobookmarks
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// AuditEntry records a security relevant event.
+type AuditEntry struct {
+	Time     time.Time
+	Event    string
+	User     string
+	Provider string
+	IP       string
+	Detail   string
+}
+
+var sqlAudit = &sqlAuditStore{}
+
+// Audit logs e and, when Config.AuditStore is "sql", stores it in the
+// audit_log table. Failures to store are logged rather than returned so
+// auditing never blocks the action being audited.
+func Audit(ctx context.Context, e AuditEntry) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	slog.InfoContext(ctx, "audit", "event", e.Event, "user", e.User, "provider", e.Provider, "ip", e.IP, "detail", e.Detail)
+	if strings.ToLower(Config.AuditStore) != "sql" {
+		return
+	}
+	if err := sqlAudit.record(ctx, e); err != nil {
+		slog.ErrorContext(ctx, "audit store failed", "err", err)
+	}
+}
+
+type sqlAuditStore struct{}
+
+func (s *sqlAuditStore) record(ctx context.Context, e AuditEntry) error {
+	db, err := sharedDB()
+	if err != nil {
+		return err
+	}
+	_, err = db.ExecContext(ctx, "INSERT INTO audit_log(at, event, user, provider, ip, detail) VALUES(?, ?, ?, ?, ?, ?)",
+		e.Time.UTC(), e.Event, e.User, e.Provider, e.IP, e.Detail)
+	return err
+}
@@ -7,9 +7,10 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/gorilla/sessions"
 	"golang.org/x/oauth2"
-	"log"
+	"log/slog"
 	"net/http"
 	"strings"
+	"time"
 )
 
 func UserLogoutAction(w http.ResponseWriter, r *http.Request) error {
@@ -22,9 +23,11 @@ func UserLogoutAction(w http.ResponseWriter, r *http.Request) error {
 	}
 
 	session := r.Context().Value(ContextValues("session")).(*sessions.Session)
+	renewSession(r, session)
 	delete(session.Values, "GithubUser")
 	delete(session.Values, "Token")
 	delete(session.Values, "Provider")
+	clearAccountSettings(session)
 
 	if err := session.Save(r, w); err != nil {
 		return fmt.Errorf("session.Save Error: %w", err)
@@ -42,31 +45,36 @@ var (
 // ensureRepo checks for the bookmarks repository and creates it with
 // some default content when missing.
 func ensureRepo(ctx context.Context, p Provider, user string, token *oauth2.Token) error {
-	log.Printf("checking repo for %s", user)
+	slog.DebugContext(ctx, "checking repository", "user", user, "provider", p.Name())
 
 	repoName := Config.GetRepoName()
+	start := time.Now()
 	exists, err := p.RepoExists(ctx, user, token, repoName)
+	observeProviderCall(ctx, p, "RepoExists", start, err)
 	if err != nil {
-		log.Printf("repo check error: %v", err)
+		slog.ErrorContext(ctx, "repository check failed", "user", user, "provider", p.Name(), "err", err)
 		return err
 	}
 	if !exists {
-		log.Printf("creating repo %s for %s", repoName, user)
-		if err := p.CreateRepo(ctx, user, token, repoName); err != nil {
-			log.Printf("create repo: %v", err)
+		slog.InfoContext(ctx, "creating repository", "user", user, "provider", p.Name(), "repo", repoName)
+		start := time.Now()
+		err := p.CreateRepo(ctx, user, token, repoName)
+		observeProviderCall(ctx, p, "CreateRepo", start, err)
+		if err != nil {
+			slog.ErrorContext(ctx, "create repository failed", "user", user, "provider", p.Name(), "err", err)
 			return err
 		}
 	}
 
 	b, _, err := p.GetBookmarks(ctx, user, "", token)
-	if err != nil {
-		log.Printf("get bookmarks: %v", err)
+	if err != nil && !errors.Is(err, ErrRepoNotFound) {
+		slog.ErrorContext(ctx, "get bookmarks failed", "user", user, "provider", p.Name(), "err", err)
 		return err
 	}
 	if b == "" {
-		log.Printf("creating initial bookmarks for %s", user)
+		slog.InfoContext(ctx, "creating initial bookmarks", "user", user, "provider", p.Name())
 		if err := p.CreateBookmarks(ctx, user, token, "main", defaultBookmarks); err != nil {
-			log.Printf("create bookmarks: %v", err)
+			slog.ErrorContext(ctx, "create bookmarks failed", "user", user, "provider", p.Name(), "err", err)
 			return err
 		}
 	}
@@ -160,10 +168,10 @@ func Oauth2CallbackPage(w http.ResponseWriter, r *http.Request) error {
 			}
 
 			if status == 0 || (status >= 400 && status < 500) {
-				log.Printf("oauth exchange failed for %s: %v", providerName, err)
+				slog.WarnContext(r.Context(), "oauth exchange failed", "provider", providerName, "err", err)
 				session.Options.MaxAge = -1
 				if saveErr := session.Save(r, w); saveErr != nil {
-					log.Printf("session save error after oauth failure: %v", saveErr)
+					slog.ErrorContext(r.Context(), "session save failed", "err", saveErr)
 				}
 				http.Redirect(w, r, fmt.Sprintf("/login/%s?error=oauth", providerName), http.StatusSeeOther)
 				return ErrHandled
@@ -184,13 +192,14 @@ func Oauth2CallbackPage(w http.ResponseWriter, r *http.Request) error {
 		return fmt.Errorf("repository setup failed: %w", err)
 	}
 
+	renewSession(r, session)
 	session.Values["Provider"] = providerName
 	session.Values["GithubUser"] = user
 	session.Values["Token"] = token
 	session.Values["version"] = version
 
 	if err := session.Save(r, w); err != nil {
-		log.Printf("Exchange error: %s", err)
+		slog.ErrorContext(r.Context(), "session save failed", "provider", providerName, "user", user.Login, "err", err)
 		return fmt.Errorf("exchange error: %w", err)
 	}
 
@@ -209,17 +218,34 @@ func GitLoginAction(w http.ResponseWriter, r *http.Request) error {
 	if !ok {
 		return fmt.Errorf("password handler not available")
 	}
+	if throttleLogin(w, r, "git", user) {
+		return nil
+	}
 	okPass, err := ph.CheckPassword(r.Context(), user, pass)
 	if err != nil {
-		log.Printf("git login check error for %s: %v", user, err)
+		slog.ErrorContext(r.Context(), "password check failed", "provider", "git", "user", user, "err", err)
 	}
 	if err != nil || !okPass {
 		if !okPass {
-			log.Printf("git login failed for %s: invalid password", user)
+			slog.InfoContext(r.Context(), "login failed", "provider", "git", "user", user, "reason", "invalid password")
+			recordLoginFailure(r, "git", user)
 		}
 		http.Redirect(w, r, "/login/git?error=invalid", http.StatusSeeOther)
 		return nil
 	}
+	if accountDisabled(r.Context(), p, user) {
+		slog.InfoContext(r.Context(), "login refused", "provider", "git", "user", user, "reason", "account disabled")
+		http.Redirect(w, r, "/login/git?error=disabled", http.StatusSeeOther)
+		return nil
+	}
+	if pending, err := beginTwoFactor(w, r, session, p, user); err != nil {
+		return err
+	} else if pending {
+		return ErrHandled
+	}
+	recordLoginSuccess(r, "git", user)
+	recordAccountLogin(r, p, user)
+	renewSession(r, session)
 	session.Values["Provider"] = "git"
 	session.Values["GithubUser"] = &User{Login: user}
 	session.Values["Token"] = nil
@@ -233,32 +259,54 @@ func GitLoginAction(w http.ResponseWriter, r *http.Request) error {
 func GitSignupAction(w http.ResponseWriter, r *http.Request) error {
 	user := r.FormValue("username")
 	pass := r.FormValue("password")
+	if IsReservedUsername(user) {
+		slog.InfoContext(r.Context(), "signup failed", "provider", "git", "user", user, "reason", "reserved username")
+		http.Redirect(w, r, "/login/git?error=reserved", http.StatusSeeOther)
+		return nil
+	}
+	email := r.FormValue("email")
+	if email != "" && !validEmail(email) {
+		slog.InfoContext(r.Context(), "signup failed", "provider", "git", "user", user, "reason", "invalid email")
+		http.Redirect(w, r, "/login/git?error=email", http.StatusSeeOther)
+		return nil
+	}
 	prov := GetProvider("git")
 	ph, ok := prov.(PasswordHandler)
 	if !ok {
 		return fmt.Errorf("password handler not available")
 	}
+	if ssoAccountExists(r.Context(), prov, user) {
+		slog.InfoContext(r.Context(), "signup failed", "provider", "git", "user", user, "reason", "account exists for single sign-on")
+		http.Redirect(w, r, "/login/git?error=exists", http.StatusSeeOther)
+		return nil
+	}
 	if err := ph.CreateUser(r.Context(), user, pass); err != nil {
 		if errors.Is(err, ErrUserExists) {
-			log.Printf("git signup for %s failed: user exists", user)
+			slog.InfoContext(r.Context(), "signup failed", "provider", "git", "user", user, "reason", "user exists")
 			http.Redirect(w, r, "/login/git?error=exists", http.StatusSeeOther)
 			return nil
 		}
-		log.Printf("git signup create user error for %s: %v", user, err)
+		slog.ErrorContext(r.Context(), "signup create user failed", "provider", "git", "user", user, "err", err)
 		return err
 	}
+	if eh, ok := prov.(EmailHandler); ok && email != "" {
+		if err := eh.SetEmail(r.Context(), user, email); err != nil {
+			slog.ErrorContext(r.Context(), "signup set email failed", "provider", "git", "user", user, "err", err)
+			return err
+		}
+	}
 	repoName := Config.GetRepoName()
 	if exists, err := prov.RepoExists(r.Context(), user, nil, repoName); err == nil && !exists {
 		if err := prov.CreateRepo(r.Context(), user, nil, repoName); err != nil {
-			log.Printf("git signup create repo error for %s: %v", user, err)
+			slog.ErrorContext(r.Context(), "signup create repository failed", "provider", "git", "user", user, "err", err)
 			return err
 		}
 	} else if err != nil {
-		log.Printf("git signup repo check error for %s: %v", user, err)
+		slog.ErrorContext(r.Context(), "signup repository check failed", "provider", "git", "user", user, "err", err)
 		return err
 	}
 	if err := prov.CreateBookmarks(r.Context(), user, nil, "main", defaultBookmarks); err != nil {
-		log.Printf("git signup create sample bookmarks error for %s: %v", user, err)
+		slog.ErrorContext(r.Context(), "signup create sample bookmarks failed", "provider", "git", "user", user, "err", err)
 		return fmt.Errorf("create sample bookmarks: %w", err)
 	}
 	return nil
@@ -276,17 +324,34 @@ func SqlLoginAction(w http.ResponseWriter, r *http.Request) error {
 	if !ok {
 		return fmt.Errorf("password handler not available")
 	}
+	if throttleLogin(w, r, "sql", user) {
+		return nil
+	}
 	okPass, err := ph.CheckPassword(r.Context(), user, pass)
 	if err != nil {
-		log.Printf("sql login check error for %s: %v", user, err)
+		slog.ErrorContext(r.Context(), "password check failed", "provider", "sql", "user", user, "err", err)
 	}
 	if err != nil || !okPass {
 		if !okPass {
-			log.Printf("sql login failed for %s: invalid password", user)
+			slog.InfoContext(r.Context(), "login failed", "provider", "sql", "user", user, "reason", "invalid password")
+			recordLoginFailure(r, "sql", user)
 		}
 		http.Redirect(w, r, "/login/sql?error=invalid", http.StatusSeeOther)
 		return nil
 	}
+	if accountDisabled(r.Context(), p, user) {
+		slog.InfoContext(r.Context(), "login refused", "provider", "sql", "user", user, "reason", "account disabled")
+		http.Redirect(w, r, "/login/sql?error=disabled", http.StatusSeeOther)
+		return nil
+	}
+	if pending, err := beginTwoFactor(w, r, session, p, user); err != nil {
+		return err
+	} else if pending {
+		return ErrHandled
+	}
+	recordLoginSuccess(r, "sql", user)
+	recordAccountLogin(r, p, user)
+	renewSession(r, session)
 	session.Values["Provider"] = "sql"
 	session.Values["GithubUser"] = &User{Login: user}
 	session.Values["Token"] = nil
@@ -300,32 +365,54 @@ func SqlLoginAction(w http.ResponseWriter, r *http.Request) error {
 func SqlSignupAction(w http.ResponseWriter, r *http.Request) error {
 	user := r.FormValue("username")
 	pass := r.FormValue("password")
+	if IsReservedUsername(user) {
+		slog.InfoContext(r.Context(), "signup failed", "provider", "sql", "user", user, "reason", "reserved username")
+		http.Redirect(w, r, "/login/sql?error=reserved", http.StatusSeeOther)
+		return nil
+	}
+	email := r.FormValue("email")
+	if email != "" && !validEmail(email) {
+		slog.InfoContext(r.Context(), "signup failed", "provider", "sql", "user", user, "reason", "invalid email")
+		http.Redirect(w, r, "/login/sql?error=email", http.StatusSeeOther)
+		return nil
+	}
 	prov := GetProvider("sql")
 	ph, ok := prov.(PasswordHandler)
 	if !ok {
 		return fmt.Errorf("password handler not available")
 	}
+	if ssoAccountExists(r.Context(), prov, user) {
+		slog.InfoContext(r.Context(), "signup failed", "provider", "sql", "user", user, "reason", "account exists for single sign-on")
+		http.Redirect(w, r, "/login/sql?error=exists", http.StatusSeeOther)
+		return nil
+	}
 	if err := ph.CreateUser(r.Context(), user, pass); err != nil {
 		if errors.Is(err, ErrUserExists) {
-			log.Printf("sql signup for %s failed: user exists", user)
+			slog.InfoContext(r.Context(), "signup failed", "provider", "sql", "user", user, "reason", "user exists")
 			http.Redirect(w, r, "/login/sql?error=exists", http.StatusSeeOther)
 			return nil
 		}
-		log.Printf("sql signup create user error for %s: %v", user, err)
+		slog.ErrorContext(r.Context(), "signup create user failed", "provider", "sql", "user", user, "err", err)
 		return err
 	}
+	if eh, ok := prov.(EmailHandler); ok && email != "" {
+		if err := eh.SetEmail(r.Context(), user, email); err != nil {
+			slog.ErrorContext(r.Context(), "signup set email failed", "provider", "sql", "user", user, "err", err)
+			return err
+		}
+	}
 	repoName := Config.GetRepoName()
 	if exists, err := prov.RepoExists(r.Context(), user, nil, repoName); err == nil && !exists {
 		if err := prov.CreateRepo(r.Context(), user, nil, repoName); err != nil {
-			log.Printf("sql signup create repo error for %s: %v", user, err)
+			slog.ErrorContext(r.Context(), "signup create repository failed", "provider", "sql", "user", user, "err", err)
 			return err
 		}
 	} else if err != nil {
-		log.Printf("sql signup repo check error for %s: %v", user, err)
+		slog.ErrorContext(r.Context(), "signup repository check failed", "provider", "sql", "user", user, "err", err)
 		return err
 	}
 	if err := prov.CreateBookmarks(r.Context(), user, nil, "main", defaultBookmarks); err != nil {
-		log.Printf("sql signup create sample bookmarks error for %s: %v", user, err)
+		slog.ErrorContext(r.Context(), "signup create sample bookmarks failed", "provider", "sql", "user", user, "err", err)
 		return fmt.Errorf("create sample bookmarks: %w", err)
 	}
 	return nil
@@ -336,8 +423,9 @@ func UserAdderMiddleware(next http.Handler) http.Handler {
 		// Get the session.
 		session, err := getSession(writer, request)
 		if session, err = sanitizeSession(writer, request, session, err); err != nil {
-			log.Printf("session error: %v", err)
+			slog.WarnContext(request.Context(), "session error", "err", err)
 		}
+		signOutDisabled(writer, request, session)
 
 		ctx := context.WithValue(request.Context(), ContextValues("session"), session)
 		next.ServeHTTP(writer, request.WithContext(ctx))
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/gorilla/sessions"
 	"golang.org/x/oauth2"
+	"log/slog"
 	"net/http"
 	"strconv"
 )
@@ -17,7 +18,7 @@ func BookmarksEditSaveAction(w http.ResponseWriter, r *http.Request) error {
 	branch := r.PostFormValue("branch")
 	ref := r.PostFormValue("ref")
 	sha := r.PostFormValue("sha")
-	repoName := Config.GetRepoName()
+	repoName := repoNameFromContext(r.Context())
 
 	login := ""
 	if githubUser != nil {
@@ -30,10 +31,14 @@ func BookmarksEditSaveAction(w http.ResponseWriter, r *http.Request) error {
 			return ErrSignedOut
 		}
 		if errors.Is(err, ErrRepoNotFound) {
+			owner, ownerErr := bookmarkOwner(r.Context(), login, true)
+			if ownerErr != nil {
+				return ownerErr
+			}
 			if p := providerFromContext(r.Context()); p != nil {
-				if err := p.CreateRepo(r.Context(), login, token, repoName); err == nil {
+				if err := p.CreateRepo(r.Context(), owner, token, repoName); err == nil {
 					if err := CreateBookmarks(r.Context(), login, token, branch, text); err == nil {
-						http.Redirect(w, r, "/edit?ref=refs/heads/"+branch, http.StatusSeeOther)
+						http.Redirect(w, r, collectionHref("/edit?ref=refs/heads/"+branch, requestCollection(r)), http.StatusSeeOther)
 						return ErrHandled
 					}
 				}
@@ -279,3 +284,144 @@ func CategoryMoveNewColumnAction(w http.ResponseWriter, r *http.Request) error {
 	}
 	return nil
 }
+
+func CategoryMoveIntoAction(w http.ResponseWriter, r *http.Request) error {
+	fromStr := r.PostFormValue("from")
+	intoStr := r.PostFormValue("into")
+	pageSha := r.PostFormValue("pageSha")
+	branch := r.PostFormValue("branch")
+	ref := r.PostFormValue("ref")
+
+	fromIdx, err := strconv.Atoi(fromStr)
+	if err != nil {
+		return fmt.Errorf("invalid from index: %w", err)
+	}
+	intoIdx, err := strconv.Atoi(intoStr)
+	if err != nil {
+		return fmt.Errorf("invalid into index: %w", err)
+	}
+
+	session := r.Context().Value(ContextValues("session")).(*sessions.Session)
+	githubUser, _ := session.Values["GithubUser"].(*User)
+	token, _ := session.Values["Token"].(*oauth2.Token)
+
+	login := ""
+	if githubUser != nil {
+		login = githubUser.Login
+	}
+
+	currentBookmarks, curSha, err := GetBookmarks(r.Context(), login, ref, token)
+	if err != nil {
+		return fmt.Errorf("GetBookmarks: %w", err)
+	}
+
+	tabs := ParseBookmarks(currentBookmarks)
+
+	page := PageForCategory(tabs, fromIdx)
+	if page == nil {
+		return fmt.Errorf("category index %d not found", fromIdx)
+	}
+	if pageSha != "" && page.Sha() != pageSha {
+		return fmt.Errorf("bookmark page modified concurrently")
+	}
+
+	if err := tabs.MoveCategoryInto(fromIdx, intoIdx); err != nil {
+		return fmt.Errorf("MoveCategory: %w", err)
+	}
+	updated := tabs.String()
+
+	if err := UpdateBookmarks(r.Context(), login, token, ref, branch, updated, curSha); err != nil {
+		return fmt.Errorf("updateBookmark error: %w", err)
+	}
+	return nil
+}
+
+// EntryMoveToAction moves an entry to another category, which may be on any
+// page or tab.
+func EntryMoveToAction(w http.ResponseWriter, r *http.Request) error {
+	return entryTransferResponse(w, r, entryTransferAction(r, false))
+}
+
+// EntryCopyToAction copies an entry to another category, which may be on any
+// page or tab.
+func EntryCopyToAction(w http.ResponseWriter, r *http.Request) error {
+	return entryTransferResponse(w, r, entryTransferAction(r, true))
+}
+
+// errEntryTransferStale rejects a transfer made from an outdated page.
+var errEntryTransferStale = errors.New("bookmark modified concurrently")
+
+// entryTransferResponse reports a failed transfer as an error status with a
+// plain text message, which the page's script shows instead of reloading.
+func entryTransferResponse(w http.ResponseWriter, r *http.Request, err error) error {
+	if err == nil || errors.Is(err, ErrSignedOut) {
+		return err
+	}
+	status := http.StatusInternalServerError
+	msg := "The link could not be moved"
+	var uerr UserError
+	if errors.As(err, &uerr) {
+		status = http.StatusBadRequest
+		msg = uerr.Msg
+	}
+	if errors.Is(err, errEntryTransferStale) {
+		status = http.StatusConflict
+	}
+	slog.WarnContext(r.Context(), "entry transfer failed", "err", err)
+	http.Error(w, msg, status)
+	return ErrHandled
+}
+
+func entryTransferAction(r *http.Request, copyEntry bool) error {
+	fromCat, err := strconv.Atoi(r.PostFormValue("fromCategory"))
+	if err != nil {
+		return fmt.Errorf("invalid from category: %w", err)
+	}
+	from, err := strconv.Atoi(r.PostFormValue("from"))
+	if err != nil {
+		return fmt.Errorf("invalid from index: %w", err)
+	}
+	toCat, err := strconv.Atoi(r.PostFormValue("toCategory"))
+	if err != nil {
+		return fmt.Errorf("invalid to category: %w", err)
+	}
+	to, err := strconv.Atoi(r.PostFormValue("to"))
+	if r.PostFormValue("to") == "" || err != nil {
+		to = -1
+	}
+	branch := r.PostFormValue("branch")
+	ref := r.PostFormValue("ref")
+	sha := r.PostFormValue("sha")
+
+	session := r.Context().Value(ContextValues("session")).(*sessions.Session)
+	githubUser, _ := session.Values["GithubUser"].(*User)
+	token, _ := session.Values["Token"].(*oauth2.Token)
+
+	login := ""
+	if githubUser != nil {
+		login = githubUser.Login
+	}
+
+	currentBookmarks, curSha, err := GetBookmarks(r.Context(), login, ref, token)
+	if err != nil {
+		return fmt.Errorf("GetBookmarks: %w", err)
+	}
+	if sha == "" || curSha != sha {
+		return NewUserError("The bookmarks changed since this page was loaded. Reload it and try again.", errEntryTransferStale)
+	}
+
+	tabs := ParseBookmarks(currentBookmarks)
+	if copyEntry {
+		err = tabs.CopyEntryTo(fromCat, from, toCat, to)
+	} else {
+		err = tabs.MoveEntryTo(fromCat, from, toCat, to)
+	}
+	if err != nil {
+		return NewUserError("The link or category no longer exists. Reload the page and try again.", fmt.Errorf("MoveEntry: %w", err))
+	}
+
+	if err := UpdateBookmarks(r.Context(), login, token, ref, branch, tabs.String(), curSha); err != nil {
+		return fmt.Errorf("updateBookmark error: %w", err)
+	}
+	return nil
+}
@@ -5,36 +5,50 @@ import (
 	"strings"
 )
 
-// ExtractCategoryByIndex returns the category text for the nth category (0 based)
-func ExtractCategoryByIndex(bookmarks string, index int) (string, error) {
-	lines := strings.Split(bookmarks, "\n")
+// categoryLineRange returns the lines [start, end) of the nth category (0
+// based). Categories and subcategories are counted in file order, matching
+// BookmarkCategory.Index, and a category's range includes its subcategories.
+// A subcategory ends at a line indented less than it, which belongs to its
+// parent.
+func categoryLineRange(lines []string, index int) (int, int, error) {
 	currentIndex := -1
-	start := -1
-	end := -1
 	for i, line := range lines {
 		trimmed := strings.TrimSpace(line)
-		if strings.HasPrefix(strings.ToLower(trimmed), "category:") {
-			currentIndex++
-			if currentIndex == index {
-				start = i
-				for j := i + 1; j <= len(lines); j++ {
-					if j == len(lines) {
-						end = j
-						break
-					}
-					t := strings.TrimSpace(lines[j])
-					lower := strings.ToLower(t)
-					if strings.HasPrefix(lower, "category:") || strings.EqualFold(lower, "column") || strings.HasPrefix(lower, "page") || strings.HasPrefix(lower, "tab:") || strings.EqualFold(lower, "tab") || t == "--" {
-						end = j
-						break
-					}
-				}
-				break
+		lower := strings.ToLower(trimmed)
+		sub := strings.HasPrefix(lower, "subcategory:")
+		if !sub && !strings.HasPrefix(lower, "category:") {
+			continue
+		}
+		currentIndex++
+		if currentIndex != index {
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " \t"))
+		for j := i + 1; j < len(lines); j++ {
+			t := strings.TrimSpace(lines[j])
+			lower := strings.ToLower(t)
+			lineIndent := len(lines[j]) - len(strings.TrimLeft(lines[j], " \t"))
+			if sub && t != "" && lineIndent < indent {
+				return i, j, nil
+			}
+			if strings.HasPrefix(lower, "category:") || strings.EqualFold(lower, "column") || strings.HasPrefix(lower, "page") || strings.HasPrefix(lower, "tab:") || strings.EqualFold(lower, "tab") || strings.HasPrefix(lower, "include:") || t == "--" {
+				return i, j, nil
+			}
+			if sub && strings.HasPrefix(lower, "subcategory:") && lineIndent <= indent {
+				return i, j, nil
 			}
 		}
+		return i, len(lines), nil
 	}
-	if start == -1 || end == -1 {
-		return "", fmt.Errorf("category index %d not found", index)
+	return -1, -1, fmt.Errorf("category index %d not found", index)
+}
+
+// ExtractCategoryByIndex returns the category text for the nth category (0 based)
+func ExtractCategoryByIndex(bookmarks string, index int) (string, error) {
+	lines := strings.Split(bookmarks, "\n")
+	start, end, err := categoryLineRange(lines, index)
+	if err != nil {
+		return "", err
 	}
 	return strings.Join(lines[start:end], "\n"), nil
 }
@@ -42,33 +56,9 @@ func ExtractCategoryByIndex(bookmarks string, index int) (string, error) {
 // ReplaceCategoryByIndex replaces the nth category with newText
 func ReplaceCategoryByIndex(bookmarks string, index int, newText string) (string, error) {
 	lines := strings.Split(bookmarks, "\n")
-	currentIndex := -1
-	start := -1
-	end := -1
-	for i, line := range lines {
-		trimmed := strings.TrimSpace(line)
-		if strings.HasPrefix(strings.ToLower(trimmed), "category:") {
-			currentIndex++
-			if currentIndex == index {
-				start = i
-				for j := i + 1; j <= len(lines); j++ {
-					if j == len(lines) {
-						end = j
-						break
-					}
-					t := strings.TrimSpace(lines[j])
-					lower := strings.ToLower(t)
-					if strings.HasPrefix(lower, "category:") || strings.EqualFold(lower, "column") || strings.HasPrefix(lower, "page") || strings.HasPrefix(lower, "tab:") || strings.EqualFold(lower, "tab") || t == "--" {
-						end = j
-						break
-					}
-				}
-				break
-			}
-		}
-	}
-	if start == -1 || end == -1 {
-		return "", fmt.Errorf("category index %d not found", index)
+	start, end, err := categoryLineRange(lines, index)
+	if err != nil {
+		return "", err
 	}
 	var result []string
 	result = append(result, lines[:start]...)
@@ -1,6 +1,9 @@
 package gobookmarks
 
-import "testing"
+import (
+	"strings"
+	"testing"
+)
 
 const testBookmarkText = `Category: A
 http://a.com a
@@ -20,3 +23,28 @@ func TestReplaceCategoryByIndexError(t *testing.T) {
 		t.Fatalf("expected error")
 	}
 }
+
+func TestCategoryRangeStopsAtInclude(t *testing.T) {
+	text := "Category: A\nhttp://a.com a\nInclude: shared.txt\nCategory: B\nhttp://b.com b\n"
+	got, err := ExtractCategoryByIndex(text, 0)
+	if err != nil {
+		t.Fatalf("ExtractCategoryByIndex: %v", err)
+	}
+	if got != "Category: A\nhttp://a.com a" {
+		t.Fatalf("extracted %q", got)
+	}
+	edited, err := ReplaceCategoryByIndex(text, 0, "Category: A2\nhttp://a.com a")
+	if err != nil {
+		t.Fatalf("ReplaceCategoryByIndex: %v", err)
+	}
+	if edited != strings.Replace(text, "Category: A\n", "Category: A2\n", 1) {
+		t.Fatalf("edit changed the include: %q", edited)
+	}
+	deleted, err := ReplaceCategoryByIndex(text, 0, "")
+	if err != nil {
+		t.Fatalf("ReplaceCategoryByIndex delete: %v", err)
+	}
+	if !strings.Contains(deleted, "Include: shared.txt\nCategory: B") || strings.Contains(deleted, "a.com") {
+		t.Fatalf("delete lost the include: %q", deleted)
+	}
+}
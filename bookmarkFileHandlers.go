@@ -0,0 +1,79 @@
+package gobookmarks
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/sessions"
+	"golang.org/x/oauth2"
+)
+
+// BookmarkFileSwitchAction selects the repository and file the account reads
+// and writes bookmarks from. The choice is kept in Settings and cached in the
+// session. Empty values restore the defaults. Collections and impersonated
+// accounts always use their default repository and file.
+func BookmarkFileSwitchAction(w http.ResponseWriter, r *http.Request) error {
+	if collectionFromContext(r.Context()) != nil || impersonatedUser(r.Context()) != "" {
+		return NewUserError("Only your own bookmarks can switch files", ErrBookmarkFileFixed)
+	}
+	session := r.Context().Value(ContextValues("session")).(*sessions.Session)
+	githubUser, _ := session.Values["GithubUser"].(*User)
+	token, _ := session.Values["Token"].(*oauth2.Token)
+	login := ""
+	if githubUser != nil {
+		login = githubUser.Login
+	}
+
+	file, err := CleanBookmarkPath(r.PostFormValue("file"))
+	if err != nil {
+		return NewUserError("Invalid bookmark file path", err)
+	}
+	repoName := strings.TrimSpace(r.PostFormValue("repo"))
+	if repoName == "" {
+		repoName = Config.GetRepoName()
+	}
+	if !ValidRepoName(repoName) {
+		return NewUserError("Invalid repository name", fmt.Errorf("invalid repository name %q", repoName))
+	}
+
+	p := providerFromContext(r.Context())
+	if p == nil {
+		return ErrNoProvider
+	}
+	if _, ok := p.(FileLister); !ok && file != DefaultBookmarkFile {
+		return NewUserError("This provider stores a single bookmark file", ErrSingleBookmarkFile)
+	}
+	if repoName != Config.GetRepoName() {
+		exists, err := p.RepoExists(r.Context(), login, token, repoName)
+		if err != nil {
+			if errors.Is(err, ErrSignedOut) {
+				return ErrSignedOut
+			}
+			return fmt.Errorf("RepoExists: %w", err)
+		}
+		if !exists {
+			return NewUserError("Repository not found", ErrRepoNotFound)
+		}
+	}
+
+	var settings AccountSettings
+	if repoName != Config.GetRepoName() {
+		settings.RepoName = repoName
+	}
+	if file != DefaultBookmarkFile {
+		settings.BookmarkFile = file
+	}
+	providerName, _ := session.Values["Provider"].(string)
+	if Settings != nil {
+		if err := Settings.SetAccountSettings(r.Context(), providerName, login, &settings); err != nil {
+			return fmt.Errorf("SetAccountSettings: %w", err)
+		}
+	}
+	cacheAccountSettings(session, providerName+":"+login, &settings)
+	if err := session.Save(r, w); err != nil {
+		return fmt.Errorf("session save: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package gobookmarks
+
+import (
+	"context"
+	"errors"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// DefaultBookmarkFile is the file in the repository holding the bookmarks
+// unless another file is selected.
+const DefaultBookmarkFile = "bookmarks.txt"
+
+// ErrInvalidBookmarkPath indicates a bookmark file path that escapes the
+// repository or names a reserved file.
+var ErrInvalidBookmarkPath = errors.New("invalid bookmark file path")
+
+// ErrSingleBookmarkFile is returned by providers that only store one bookmark
+// file per user when another file is requested.
+var ErrSingleBookmarkFile = errors.New("provider stores a single bookmark file")
+
+// ErrBookmarkFileFixed is returned when switching files while viewing a
+// collection or another account, which always use their default file.
+var ErrBookmarkFileFixed = errors.New("bookmark file cannot be switched here")
+
+// CleanBookmarkPath normalises a repository relative file path and rejects
+// paths outside the repository or inside git metadata.
+func CleanBookmarkPath(p string) (string, error) {
+	p = strings.TrimSpace(strings.ReplaceAll(p, "\\", "/"))
+	if p == "" {
+		return DefaultBookmarkFile, nil
+	}
+	if strings.HasPrefix(p, "/") {
+		return "", ErrInvalidBookmarkPath
+	}
+	p = path.Clean(p)
+	if p == "." || p == ".." || strings.HasPrefix(p, "../") {
+		return "", ErrInvalidBookmarkPath
+	}
+	first := strings.SplitN(p, "/", 2)[0]
+	if first == ".git" || p == ".password" || p == ".totp" || p == ".email" || p == ".reset" || p == ".gitignore" {
+		return "", ErrInvalidBookmarkPath
+	}
+	return p, nil
+}
+
+// withBookmarkFile selects the file provider calls read and write.
+func withBookmarkFile(ctx context.Context, file string) context.Context {
+	return context.WithValue(ctx, ContextValues("bookmarkFile"), file)
+}
+
+// bookmarkFileFromContext returns the file selected with withBookmarkFile or
+// DefaultBookmarkFile.
+func bookmarkFileFromContext(ctx context.Context) string {
+	if f, ok := ctx.Value(ContextValues("bookmarkFile")).(string); ok && f != "" {
+		return f
+	}
+	return DefaultBookmarkFile
+}
+
+var repoNamePattern = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// ValidRepoName reports whether name can be used as a repository name.
+func ValidRepoName(name string) bool {
+	return repoNamePattern.MatchString(name) && name != "." && name != ".."
+}
+
+// withRepoName selects the repository provider calls operate on.
+func withRepoName(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, ContextValues("repoName"), name)
+}
+
+// repoNameFromContext returns the repository selected with withRepoName or the
+// configured default.
+func repoNameFromContext(ctx context.Context) string {
+	if n, ok := ctx.Value(ContextValues("repoName")).(string); ok && n != "" {
+		return n
+	}
+	return Config.GetRepoName()
+}
+
+// isBookmarkFileName reports whether a repository file looks like a bookmark
+// file and should be offered in the file switcher.
+func isBookmarkFileName(name string) bool {
+	if !strings.HasSuffix(name, ".txt") {
+		return false
+	}
+	_, err := CleanBookmarkPath(name)
+	return err == nil
+}
@@ -0,0 +1,135 @@
+package gobookmarks
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestCleanBookmarkPath(t *testing.T) {
+	good := map[string]string{
+		"":                 DefaultBookmarkFile,
+		"work.txt":         "work.txt",
+		"dots/./links.txt": "dots/links.txt",
+	}
+	for in, want := range good {
+		got, err := CleanBookmarkPath(in)
+		if err != nil || got != want {
+			t.Errorf("CleanBookmarkPath(%q) = %q, %v want %q", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"/etc/passwd", "../x.txt", ".git/config", ".password"} {
+		if _, err := CleanBookmarkPath(in); !errors.Is(err, ErrInvalidBookmarkPath) {
+			t.Errorf("CleanBookmarkPath(%q) expected error, got %v", in, err)
+		}
+	}
+}
+
+func TestGitProviderBookmarkFiles(t *testing.T) {
+	Config.LocalGitPath = t.TempDir()
+	p := GitProvider{}
+	user := "alice"
+	ctx := context.WithValue(context.Background(), ContextValues("provider"), "git")
+	if err := p.CreateRepo(ctx, user, nil, Config.GetRepoName()); err != nil {
+		t.Fatalf("CreateRepo: %v", err)
+	}
+	if err := p.CreateBookmarks(ctx, user, nil, "main", "Category: Home\n"); err != nil {
+		t.Fatalf("CreateBookmarks: %v", err)
+	}
+	work := withBookmarkFile(ctx, "work/links.txt")
+	if err := p.UpdateBookmarks(work, user, nil, "refs/heads/main", "main", "Category: Work\n", ""); err != nil {
+		t.Fatalf("UpdateBookmarks work: %v", err)
+	}
+
+	got, _, err := GetBookmarks(work, user, "refs/heads/main", nil)
+	if err != nil || got != "Category: Work\n" {
+		t.Fatalf("GetBookmarks work = %q, %v", got, err)
+	}
+	if got, _, _ := GetBookmarks(ctx, user, "refs/heads/main", nil); got != "Category: Home\n" {
+		t.Fatalf("default file changed: %q", got)
+	}
+
+	files, err := ListBookmarkFiles(ctx, user, nil, "refs/heads/main")
+	if err != nil {
+		t.Fatalf("ListBookmarkFiles: %v", err)
+	}
+	sort.Strings(files)
+	if strings.Join(files, ",") != "bookmarks.txt,work/links.txt" {
+		t.Fatalf("unexpected files %v", files)
+	}
+
+	commits, err := p.GetCommits(work, user, nil, "refs/heads/main", 1, 10)
+	if err != nil {
+		t.Fatalf("GetCommits: %v", err)
+	}
+	if len(commits) != 1 {
+		t.Fatalf("expected history scoped to one commit, got %d", len(commits))
+	}
+}
+
+func TestBookmarkFileSwitchAction(t *testing.T) {
+	_, _, sess, ctx := setupCategoryEditTest(t)
+
+	form := url.Values{"file": {"work.txt"}}
+	req := httptest.NewRequest("POST", "/file", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if err := BookmarkFileSwitchAction(httptest.NewRecorder(), req.WithContext(ctx)); err != nil {
+		t.Fatalf("BookmarkFileSwitchAction: %v", err)
+	}
+	if sess.Values["BookmarkFile"] != "work.txt" {
+		t.Fatalf("session file not set: %v", sess.Values["BookmarkFile"])
+	}
+
+	form = url.Values{"file": {"../escape.txt"}}
+	req = httptest.NewRequest("POST", "/file", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	var uerr UserError
+	if err := BookmarkFileSwitchAction(httptest.NewRecorder(), req.WithContext(ctx)); !errors.As(err, &uerr) {
+		t.Fatalf("expected user error, got %v", err)
+	}
+}
+
+func TestBookmarkFileSwitchPersists(t *testing.T) {
+	_, user, sess, ctx := setupCategoryEditTest(t)
+	store, err := NewFileSettingsStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileSettingsStore: %v", err)
+	}
+	old := Settings
+	Settings = store
+	t.Cleanup(func() { Settings = old })
+	sess.Values["Provider"] = "git"
+
+	form := url.Values{"file": {"work.txt"}}
+	req := httptest.NewRequest("POST", "/file", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if err := BookmarkFileSwitchAction(httptest.NewRecorder(), req.WithContext(ctx)); err != nil {
+		t.Fatalf("BookmarkFileSwitchAction: %v", err)
+	}
+
+	// Another device starts with an empty session.
+	other, err := getSession(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	if err != nil {
+		t.Fatalf("getSession: %v", err)
+	}
+	if got := accountSettings(ctx, other, "git", user); got.BookmarkFile != "work.txt" {
+		t.Fatalf("file not restored from settings: %+v", got)
+	}
+	if got := accountSettings(ctx, other, "github", user); got.BookmarkFile != "" {
+		t.Fatalf("settings leaked to another provider's account: %+v", got)
+	}
+
+	form = url.Values{"file": {""}}
+	req = httptest.NewRequest("POST", "/file", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if err := BookmarkFileSwitchAction(httptest.NewRecorder(), req.WithContext(ctx)); err != nil {
+		t.Fatalf("BookmarkFileSwitchAction default: %v", err)
+	}
+	if s, err := store.AccountSettings(ctx, "git", user); err != nil || s != nil {
+		t.Fatalf("default selection should clear stored settings: %+v, %v", s, err)
+	}
+}
@@ -0,0 +1,138 @@
+package gobookmarks
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"golang.org/x/oauth2"
+)
+
+// ErrIncludeCycle indicates an include directive refers back to a file that
+// is already being included.
+var ErrIncludeCycle = errors.New("include cycle")
+
+// maxIncludeDepth limits how deeply includes may nest.
+const maxIncludeDepth = 8
+
+// parseIncludeTarget splits an include directive of the form
+// "path[@branch]" into the file and ref to load. An empty path refers to the
+// including file and an empty branch to the including ref.
+func parseIncludeTarget(spec, file, ref string) (string, string, error) {
+	target, branch := spec, ""
+	if i := strings.LastIndex(spec, "@"); i >= 0 {
+		target, branch = strings.TrimSpace(spec[:i]), strings.TrimSpace(spec[i+1:])
+	}
+	if target == "" {
+		target = file
+	} else {
+		p, err := CleanBookmarkPath(target)
+		if err != nil {
+			return "", "", err
+		}
+		target = p
+	}
+	switch {
+	case branch == "":
+	case strings.HasPrefix(branch, "refs/"):
+		ref = branch
+	default:
+		ref = "refs/heads/" + branch
+	}
+	return target, ref, nil
+}
+
+func includeKey(file, ref string) string {
+	if ref == "" {
+		ref = "refs/heads/main"
+	}
+	return file + "@" + ref
+}
+
+// ResolveIncludes loads the files referenced by "Include:" placeholders in
+// list and stores their categories in the placeholder. Included categories
+// are marked with the file they are defined in and are never written back.
+// Failures are recorded on the placeholder rather than returned so the rest
+// of the bookmarks still render.
+func ResolveIncludes(ctx context.Context, list BookmarkList, user, ref string, token *oauth2.Token) {
+	resolveIncludes(ctx, list, user, ref, token, []string{includeKey(bookmarkFileFromContext(ctx), ref)})
+}
+
+func resolveIncludes(ctx context.Context, list BookmarkList, user, ref string, token *oauth2.Token, stack []string) {
+	file := bookmarkFileFromContext(ctx)
+	for _, t := range list {
+		for _, p := range t.Pages {
+			for _, blk := range p.Blocks {
+				for _, col := range blk.Columns {
+					for _, c := range col.Categories {
+						if c.IsInclude() {
+							resolveInclude(ctx, c, file, user, ref, token, stack)
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+func resolveInclude(ctx context.Context, c *BookmarkCategory, file, user, ref string, token *oauth2.Token, stack []string) {
+	path, incRef, err := parseIncludeTarget(c.Include, file, ref)
+	if err != nil {
+		c.IncludeError = err.Error()
+		return
+	}
+	key := includeKey(path, incRef)
+	for _, s := range stack {
+		if s == key {
+			c.IncludeError = ErrIncludeCycle.Error() + ": " + strings.Join(append(stack, key), " -> ")
+			return
+		}
+	}
+	if len(stack) > maxIncludeDepth {
+		c.IncludeError = "includes nested too deeply"
+		return
+	}
+
+	ictx := withBookmarkFile(ctx, path)
+	text, _, err := GetBookmarks(ictx, user, incRef, token)
+	if err != nil {
+		c.IncludeError = err.Error()
+		return
+	}
+	sub := ParseBookmarks(text)
+	resolveIncludes(ictx, sub, user, incRef, token, append(stack, key))
+
+	source := path
+	if strings.Contains(c.Include, "@") {
+		source = path + "@" + strings.TrimPrefix(incRef, "refs/heads/")
+	}
+	for _, t := range sub {
+		for _, p := range t.Pages {
+			for _, blk := range p.Blocks {
+				for _, col := range blk.Columns {
+					for _, ic := range col.Categories {
+						if ic.IsInclude() {
+							if ic.IncludeError != "" {
+								c.Included = append(c.Included, ic)
+							}
+							c.Included = append(c.Included, ic.Included...)
+							continue
+						}
+						markIncluded(ic)
+						ic.IncludedFrom = source
+						c.Included = append(c.Included, ic)
+					}
+				}
+			}
+		}
+	}
+}
+
+// markIncluded clears the index of an included category and its
+// subcategories so they cannot be edited or moved.
+func markIncluded(c *BookmarkCategory) {
+	c.Index = -1
+	for _, sub := range c.Subcategories {
+		markIncluded(sub)
+	}
+}
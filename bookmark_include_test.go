@@ -0,0 +1,77 @@
+package gobookmarks
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestParseIncludeRoundTrip(t *testing.T) {
+	input := "Category: A\nhttp://a.com a\nInclude: shared/work.txt\nCategory: B\nhttp://b.com b\n"
+	list := ParseBookmarks(input)
+	if got := list.String(); got != input {
+		t.Fatalf("round trip mismatch:\n%q\n%q", input, got)
+	}
+	cats := list[0].Pages[0].Blocks[0].Columns[0].Categories
+	if len(cats) != 3 || !cats[1].IsInclude() || cats[1].Index != -1 {
+		t.Fatalf("unexpected categories %+v", cats)
+	}
+	if cats[2].Index != 1 {
+		t.Fatalf("include should not consume a category index, got %d", cats[2].Index)
+	}
+	if err := list.MoveCategoryBefore(1, 0); err != nil {
+		t.Fatalf("MoveCategoryBefore: %v", err)
+	}
+	want := "Category: B\nhttp://b.com b\nCategory: A\nhttp://a.com a\nInclude: shared/work.txt\n"
+	if got := list.String(); got != want {
+		t.Fatalf("after move got %q want %q", got, want)
+	}
+}
+
+func TestResolveIncludes(t *testing.T) {
+	Config.LocalGitPath = t.TempDir()
+	p := GitProvider{}
+	user := "alice"
+	ctx := context.WithValue(context.Background(), ContextValues("provider"), "git")
+	if err := p.CreateRepo(ctx, user, nil, Config.GetRepoName()); err != nil {
+		t.Fatalf("CreateRepo: %v", err)
+	}
+	main := "Category: Mine\nhttp://mine.com\nInclude: shared/work.txt\nInclude: @other\n"
+	if err := p.CreateBookmarks(ctx, user, nil, "main", main); err != nil {
+		t.Fatalf("CreateBookmarks: %v", err)
+	}
+	shared := "Category: Work\nhttp://work.com\nInclude: bookmarks.txt\n"
+	if err := p.CreateBookmarks(withBookmarkFile(ctx, "shared/work.txt"), user, nil, "main", shared); err != nil {
+		t.Fatalf("CreateBookmarks shared: %v", err)
+	}
+	if err := p.CreateBookmarks(ctx, user, nil, "other", "Category: Other\nhttp://other.com\n"); err != nil {
+		t.Fatalf("CreateBookmarks other: %v", err)
+	}
+
+	list := ParseBookmarks(main)
+	ResolveIncludes(ctx, list, user, "refs/heads/main", nil)
+	cats := list[0].Pages[0].Blocks[0].Columns[0].Categories
+	if len(cats) != 3 {
+		t.Fatalf("expected 3 categories got %d", len(cats))
+	}
+
+	work := cats[1]
+	if work.IncludeError != "" {
+		t.Fatalf("unexpected include error %q", work.IncludeError)
+	}
+	if len(work.Included) != 2 || work.Included[0].Name != "Work" || work.Included[0].IncludedFrom != "shared/work.txt" {
+		t.Fatalf("unexpected included categories %+v", work.Included)
+	}
+	if !strings.HasPrefix(work.Included[1].IncludeError, ErrIncludeCycle.Error()) {
+		t.Fatalf("expected cycle error, got %q", work.Included[1].IncludeError)
+	}
+
+	other := cats[2]
+	if len(other.Included) != 1 || other.Included[0].Name != "Other" || other.Included[0].IncludedFrom != "bookmarks.txt@other" {
+		t.Fatalf("unexpected branch include %+v", other.Included)
+	}
+
+	if got := list.String(); got != main {
+		t.Fatalf("resolving includes changed the serialized text: %q", got)
+	}
+}
@@ -298,3 +298,24 @@ func TestStringers(t *testing.T) {
 		t.Fatalf("stringWithContext omit")
 	}
 }
+
+func TestEntryDescription(t *testing.T) {
+	text := "Category: A\nhttp://a.com A #x\n> Use the EU login\n>\n> second paragraph\nhttp://b.com\n  Subcategory: B\n  http://c.com C\n  > nested note\n"
+	tabs := ParseBookmarks(text)
+	a := tabs.FindCategory(0)
+	if got := a.Entries[0].Description; got != "Use the EU login\n\nsecond paragraph" {
+		t.Fatalf("unexpected description %q", got)
+	}
+	if a.Entries[1].Description != "" {
+		t.Fatalf("description leaked to next entry: %q", a.Entries[1].Description)
+	}
+	if got := tabs.FindCategory(1).Entries[0].Description; got != "nested note" {
+		t.Fatalf("unexpected nested description %q", got)
+	}
+	if got := tabs.String(); got != text {
+		t.Fatalf("expected %q got %q", text, got)
+	}
+	if tabs := ParseBookmarks("Category: A\n> orphan\nhttp://a.com\n"); tabs.FindCategory(0).Entries[0].Description != "" {
+		t.Fatalf("note before any entry should be ignored")
+	}
+}
@@ -9,6 +9,11 @@ import (
 type BookmarkEntry struct {
 	Url  string
 	Name string
+	// Tags are written as trailing "#tag" words and stored without the "#".
+	Tags []string
+	// Description is a free text note written on the lines after the entry,
+	// each starting with ">". Lines are joined with newlines.
+	Description string
 }
 
 // String serializes the entry.
@@ -16,10 +21,28 @@ func (e *BookmarkEntry) String() string {
 	if e == nil {
 		return ""
 	}
+	var b strings.Builder
+	b.WriteString(e.Url)
 	if e.Name != "" && e.Name != e.Url {
-		return e.Url + " " + e.Name + "\n"
+		b.WriteString(" ")
+		b.WriteString(escapeEntryName(e.Name))
+	}
+	for _, t := range e.Tags {
+		b.WriteString(" #")
+		b.WriteString(t)
+	}
+	b.WriteString("\n")
+	if e.Description != "" {
+		for _, line := range strings.Split(e.Description, "\n") {
+			b.WriteString(">")
+			if line != "" {
+				b.WriteString(" ")
+				b.WriteString(line)
+			}
+			b.WriteString("\n")
+		}
 	}
-	return e.Url + "\n"
+	return b.String()
 }
 
 // BookmarkCategory groups entries together.
@@ -27,18 +50,109 @@ type BookmarkCategory struct {
 	Name    string
 	Entries []*BookmarkEntry
 	Index   int
+
+	// Subcategories are nested below the category's own entries. Indexes
+	// number categories depth first, so a category comes directly before
+	// its subcategories.
+	Subcategories []*BookmarkCategory
+	// EntriesBefore is the number of the parent's entries written before
+	// this subcategory, so entries outdented after it keep their place in
+	// the file.
+	EntriesBefore int
+
+	// Include holds the target of an "Include:" directive. Such categories
+	// are placeholders: they have no entries of their own and an Index of -1.
+	Include string
+	// Included holds the categories resolved for an include placeholder and
+	// IncludeError the reason resolution failed.
+	Included     []*BookmarkCategory
+	IncludeError string
+	// IncludedFrom names the file an included category is defined in.
+	IncludedFrom string
+}
+
+// IsInclude reports whether the category is an "Include:" placeholder.
+func (c *BookmarkCategory) IsInclude() bool {
+	return c.Include != ""
 }
 
 // String serializes the category.
 func (c *BookmarkCategory) String() string {
+	if c.IsInclude() {
+		return "Include: " + c.Include + "\n"
+	}
 	var b strings.Builder
-	b.WriteString("Category: ")
+	c.write(&b, 0)
+	return b.String()
+}
+
+// write serializes the category at the given nesting depth. Subcategories
+// are indented two spaces per level along with their entries.
+func (c *BookmarkCategory) write(b *strings.Builder, depth int) {
+	indent := strings.Repeat("  ", depth)
+	b.WriteString(indent)
+	if depth == 0 {
+		b.WriteString("Category: ")
+	} else {
+		b.WriteString("Subcategory: ")
+	}
 	b.WriteString(c.Name)
 	b.WriteString("\n")
-	for _, e := range c.Entries {
-		b.WriteString(e.String())
+	next := 0
+	for i, e := range c.Entries {
+		for ; next < len(c.Subcategories) && c.Subcategories[next].EntriesBefore <= i; next++ {
+			c.Subcategories[next].write(b, depth+1)
+		}
+		for _, line := range strings.SplitAfter(strings.TrimSuffix(e.String(), "\n"), "\n") {
+			b.WriteString(indent)
+			b.WriteString(line)
+		}
+		b.WriteString("\n")
 	}
-	return b.String()
+	for _, sub := range c.Subcategories[next:] {
+		sub.write(b, depth+1)
+	}
+}
+
+// insertEntry inserts e at position i of the entries. Subcategories written
+// after the entry at i move down with it, so the new entry comes before them.
+func (c *BookmarkCategory) insertEntry(i int, e *BookmarkEntry) {
+	if i < 0 || i > len(c.Entries) {
+		i = len(c.Entries)
+	}
+	c.Entries = append(c.Entries, nil)
+	copy(c.Entries[i+1:], c.Entries[i:])
+	c.Entries[i] = e
+	for _, sub := range c.Subcategories {
+		if sub.EntriesBefore >= i {
+			sub.EntriesBefore++
+		}
+	}
+}
+
+// removeEntry removes and returns the entry at position i.
+func (c *BookmarkCategory) removeEntry(i int) *BookmarkEntry {
+	e := c.Entries[i]
+	c.Entries = append(c.Entries[:i], c.Entries[i+1:]...)
+	for _, sub := range c.Subcategories {
+		if sub.EntriesBefore > i {
+			sub.EntriesBefore--
+		}
+	}
+	return e
+}
+
+// Contains reports whether other is c or one of its subcategories at any depth.
+func (c *BookmarkCategory) Contains(other *BookmarkCategory) bool {
+	if c == other {
+		return true
+	}
+	for _, sub := range c.Subcategories {
+		if sub.Contains(other) {
+			return true
+		}
+	}
+	return false
 }
 
 // BookmarkColumn contains a list of categories.
@@ -83,13 +197,7 @@ func (c *BookmarkCategory) MoveEntry(i, j int) {
 	if i < 0 || j < 0 || i >= len(c.Entries) || j >= len(c.Entries) || i == j {
 		return
 	}
-	entry := c.Entries[i]
-	if i < j {
-		copy(c.Entries[i:j], c.Entries[i+1:j+1])
-	} else {
-		copy(c.Entries[j+1:i+1], c.Entries[j:i])
-	}
-	c.Entries[j] = entry
+	c.insertEntry(j, c.removeEntry(i))
 }
 
 // BookmarkBlock groups columns and optional horizontal rule.
@@ -279,7 +387,15 @@ func ParseBookmarks(bookmarks string) BookmarkList {
 	var currentTab *BookmarkTab
 	var currentPage *BookmarkPage
 	var currentCategory *BookmarkCategory
-	idx := 0
+	// nesting holds the open subcategories of currentCategory, innermost
+	// last, with the indentation of their Subcategory line.
+	type openCategory struct {
+		indent int
+		cat    *BookmarkCategory
+	}
+	var nesting []openCategory
+	// lastEntry receives the description lines that follow it.
+	var lastEntry *BookmarkEntry
 
 	ensureTab := func() *BookmarkTab {
 		if currentTab == nil {
@@ -301,9 +417,9 @@ func ParseBookmarks(bookmarks string) BookmarkList {
 	}
 
 	flushCategory := func() {
+		nesting = nil
+		lastEntry = nil
 		if currentCategory != nil {
-			currentCategory.Index = idx
-			idx++
 			page := ensurePage()
 			lastBlock := page.Blocks[len(page.Blocks)-1]
 			lastColumn := lastBlock.Columns[len(lastBlock.Columns)-1]
@@ -313,6 +429,7 @@ func ParseBookmarks(bookmarks string) BookmarkList {
 	}
 
 	for _, line := range lines {
+		indent := len(line) - len(strings.TrimLeft(line, " \t"))
 		line = strings.TrimSpace(line)
 		lower := strings.ToLower(line)
 		if lower == "tab" || strings.HasPrefix(lower, "tab ") || strings.HasPrefix(lower, "tab:") {
@@ -356,13 +473,60 @@ func ParseBookmarks(bookmarks string) BookmarkList {
 			lastBlock.Columns = append(lastBlock.Columns, &BookmarkColumn{})
 			continue
 		}
+		if strings.HasPrefix(lower, "include:") {
+			rest := strings.TrimSpace(line[len("include:"):])
+			flushCategory()
+			if rest == "" {
+				continue
+			}
+			page := ensurePage()
+			lastBlock := page.Blocks[len(page.Blocks)-1]
+			lastColumn := lastBlock.Columns[len(lastBlock.Columns)-1]
+			lastColumn.AddCategory(&BookmarkCategory{Include: rest, Index: -1})
+			continue
+		}
+		if strings.HasPrefix(line, ">") {
+			if lastEntry != nil {
+				note := strings.TrimSpace(line[1:])
+				if lastEntry.Description == "" {
+					lastEntry.Description = note
+				} else {
+					lastEntry.Description += "\n" + note
+				}
+			}
+			continue
+		}
 		parts := strings.Fields(line)
 		if len(parts) == 0 {
 			continue
 		}
 		lowerFirst := strings.ToLower(parts[0])
-		if strings.HasPrefix(lowerFirst, "category") {
-			rest := strings.TrimSpace(line[len("category"):])
+		if strings.HasPrefix(lowerFirst, "subcategory") && currentCategory != nil {
+			rest := strings.TrimSpace(line[len("subcategory"):])
+			if strings.HasPrefix(rest, ":") {
+				rest = strings.TrimSpace(rest[1:])
+			}
+			if rest == "" {
+				rest = "Subcategory"
+			}
+			for len(nesting) > 0 && nesting[len(nesting)-1].indent >= indent {
+				nesting = nesting[:len(nesting)-1]
+			}
+			parent := currentCategory
+			if len(nesting) > 0 {
+				parent = nesting[len(nesting)-1].cat
+			}
+			lastEntry = nil
+			sub := &BookmarkCategory{Name: rest, EntriesBefore: len(parent.Entries)}
+			parent.Subcategories = append(parent.Subcategories, sub)
+			nesting = append(nesting, openCategory{indent, sub})
+		} else if strings.HasPrefix(lowerFirst, "category") || strings.HasPrefix(lowerFirst, "subcategory") {
+			// A Subcategory line outside any category starts a top-level one.
+			keyword := "category"
+			if strings.HasPrefix(lowerFirst, "subcategory") {
+				keyword = "subcategory"
+			}
+			rest := strings.TrimSpace(line[len(keyword):])
 			if strings.HasPrefix(rest, ":") {
 				rest = strings.TrimSpace(rest[1:])
 			}
@@ -373,15 +537,27 @@ func ParseBookmarks(bookmarks string) BookmarkList {
 			ensurePage()
 			currentCategory = &BookmarkCategory{Name: rest}
 		} else if currentCategory != nil {
-			entry := BookmarkEntry{Url: parts[0], Name: parts[0]}
+			parts, tags := splitEntryTags(parts)
+			entry := BookmarkEntry{Url: parts[0], Name: parts[0], Tags: tags}
 			if len(parts) > 1 {
 				entry.Name = strings.Join(parts[1:], " ")
 			}
-			currentCategory.Entries = append(currentCategory.Entries, &entry)
+			// An entry indented less than the open subcategories belongs
+			// to the parent they are nested in.
+			for len(nesting) > 0 && nesting[len(nesting)-1].indent > indent {
+				nesting = nesting[:len(nesting)-1]
+			}
+			target := currentCategory
+			if len(nesting) > 0 {
+				target = nesting[len(nesting)-1].cat
+			}
+			target.Entries = append(target.Entries, &entry)
+			lastEntry = &entry
 		}
 	}
 
 	flushCategory()
+	result.reindexCategories()
 
 	if len(result) == 0 {
 		t := &BookmarkTab{ExplicitTab: false}
@@ -403,118 +579,193 @@ func ValidateBookmarks(bookmarks string) (BookmarkList, error) {
 	return parsed, nil
 }
 
-// MoveCategory moves the category at fromIndex so it appears before toIndex.
-// If toIndex equals the total number of categories, the item is moved to the end.
-// When newColumn is true a new column directive is inserted before the moved category.
-func (b BookmarkList) MoveCategory(fromIndex, toIndex int, newColumn bool, destPage *BookmarkPage, destCol int) error {
-	type loc struct {
-		block  *BookmarkBlock
-		column *BookmarkColumn
-		cat    *BookmarkCategory
-		colIdx int
-		catIdx int
+// categoryLoc records where a category sits in the list.
+type categoryLoc struct {
+	page   *BookmarkPage
+	block  *BookmarkBlock
+	column *BookmarkColumn
+	// parent is nil for a category directly in the column.
+	parent *BookmarkCategory
+	cat    *BookmarkCategory
+}
+
+// siblings returns the slice holding the category.
+func (l categoryLoc) siblings() *[]*BookmarkCategory {
+	if l.parent != nil {
+		return &l.parent.Subcategories
+	}
+	return &l.column.Categories
+}
+
+// categoryLocs lists every category in index order: depth first, with each
+// category before its subcategories. Include placeholders are skipped.
+func (b BookmarkList) categoryLocs() []categoryLoc {
+	var cats []categoryLoc
+	var walk func(loc categoryLoc)
+	walk = func(loc categoryLoc) {
+		cats = append(cats, loc)
+		for _, sub := range loc.cat.Subcategories {
+			walk(categoryLoc{loc.page, loc.block, loc.column, loc.cat, sub})
+		}
 	}
-	var cats []loc
-	idx := 0
 	for _, t := range b {
 		for _, p := range t.Pages {
-			for _, b := range p.Blocks {
-				for ci, col := range b.Columns {
-					for cj, c := range col.Categories {
-						cats = append(cats, loc{b, col, c, ci, cj})
-						c.Index = idx
-						idx++
+			for _, blk := range p.Blocks {
+				for _, col := range blk.Columns {
+					for _, c := range col.Categories {
+						if c.IsInclude() {
+							continue
+						}
+						walk(categoryLoc{p, blk, col, nil, c})
 					}
 				}
 			}
 		}
 	}
+	return cats
+}
+
+// reindexCategories numbers the categories in index order.
+func (b BookmarkList) reindexCategories() {
+	for i, loc := range b.categoryLocs() {
+		loc.cat.Index = i
+	}
+}
+
+// FindCategory returns the category with the given index, which may be a
+// subcategory.
+func (b BookmarkList) FindCategory(index int) *BookmarkCategory {
+	cats := b.categoryLocs()
+	if index < 0 || index >= len(cats) {
+		return nil
+	}
+	return cats[index].cat
+}
+
+func indexOfCategory(list []*BookmarkCategory, c *BookmarkCategory) int {
+	for i, x := range list {
+		if x == c {
+			return i
+		}
+	}
+	return -1
+}
+
+func indexOfColumn(block *BookmarkBlock, col *BookmarkColumn) int {
+	for i, c := range block.Columns {
+		if c == col {
+			return i
+		}
+	}
+	return -1
+}
+
+// detachCategory removes the category at loc from its parent or column,
+// taking its subcategories with it. A column left empty is removed unless
+// it is keep.
+func detachCategory(loc categoryLoc, keep *BookmarkColumn) {
+	list := loc.siblings()
+	if i := indexOfCategory(*list, loc.cat); i >= 0 {
+		*list = append((*list)[:i], (*list)[i+1:]...)
+	}
+	if loc.parent == nil && len(loc.column.Categories) == 0 && loc.column != keep {
+		if colIdx := indexOfColumn(loc.block, loc.column); colIdx != -1 {
+			loc.block.Columns = append(loc.block.Columns[:colIdx], loc.block.Columns[colIdx+1:]...)
+		}
+	}
+}
+
+// MoveCategory moves the category at fromIndex so it appears before toIndex.
+// The category takes the place of toIndex, so it becomes a subcategory when
+// toIndex is one and a top-level category otherwise. Its own subcategories
+// move with it.
+// If toIndex equals the total number of categories, the item is moved to the end.
+// When newColumn is true a new column directive is inserted before the moved category.
+func (b BookmarkList) MoveCategory(fromIndex, toIndex int, newColumn bool, destPage *BookmarkPage, destCol int) error {
+	cats := b.categoryLocs()
+	for i, loc := range cats {
+		loc.cat.Index = i
+	}
 
 	if fromIndex < 0 || fromIndex >= len(cats) {
 		return fmt.Errorf("category index %d not found", fromIndex)
 	}
-	var beforeLoc *loc
+	src := cats[fromIndex]
+	var beforeLoc *categoryLoc
 	if toIndex >= 0 && toIndex < len(cats) {
 		beforeLoc = &cats[toIndex]
-	}
-
-	src := cats[fromIndex]
-	// remove from source column
-	src.column.Categories = append(src.column.Categories[:src.catIdx], src.column.Categories[src.catIdx+1:]...)
-	if beforeLoc != nil && toIndex > fromIndex && src.column == beforeLoc.column {
-		beforeLoc.catIdx--
+		if beforeLoc.cat == src.cat {
+			return nil
+		}
+		if src.cat.Contains(beforeLoc.cat) {
+			return fmt.Errorf("category %d cannot be moved inside itself", fromIndex)
+		}
 	}
 
 	var destColumn *BookmarkColumn
+	insertColumn := func(block *BookmarkBlock, after int) *BookmarkColumn {
+		newCol := &BookmarkColumn{}
+		block.Columns = append(block.Columns, nil)
+		copy(block.Columns[after+2:], block.Columns[after+1:])
+		block.Columns[after+1] = newCol
+		return newCol
+	}
 	if beforeLoc == nil { // append to end or specified column
 		destBlock := cats[len(cats)-1].block
-		destColObj := destBlock.Columns[len(destBlock.Columns)-1]
 		destColIdx := len(destBlock.Columns) - 1
 		if destPage != nil {
 			destBlock = destPage.Blocks[len(destPage.Blocks)-1]
 			if destCol >= len(destBlock.Columns) {
 				destCol = len(destBlock.Columns) - 1
 			}
-			destColObj = destBlock.Columns[destCol]
 			destColIdx = destCol
 		}
+		destColumn = destBlock.Columns[destColIdx]
 		if newColumn {
-			destColIdx++
-			newCol := &BookmarkColumn{}
-			destBlock.Columns = append(destBlock.Columns, nil)
-			copy(destBlock.Columns[destColIdx+1:], destBlock.Columns[destColIdx:])
-			destBlock.Columns[destColIdx] = newCol
-			destColObj = newCol
-		}
-		destColObj.Categories = append(destColObj.Categories, src.cat)
-		destColumn = destColObj
+			destColumn = insertColumn(destBlock, destColIdx)
+		}
+		detachCategory(src, destColumn)
+		destColumn.Categories = append(destColumn.Categories, src.cat)
 	} else {
 		dest := *beforeLoc
-		destBlock := dest.block
-		destColObj := dest.column
-		destColIdx := dest.colIdx
-		insertIdx := dest.catIdx
-		if newColumn {
-			destColIdx++
-			newCol := &BookmarkColumn{}
-			destBlock.Columns = append(destBlock.Columns, nil)
-			copy(destBlock.Columns[destColIdx+1:], destBlock.Columns[destColIdx:])
-			destBlock.Columns[destColIdx] = newCol
-			destColObj = newCol
-			insertIdx = 0
-		}
-		destColObj.InsertCategory(insertIdx, src.cat)
-		destColumn = destColObj
-	}
-
-	if len(src.column.Categories) == 0 && src.column != destColumn {
-		// Find the current index of the source column, as it may have shifted
-		colIdx := -1
-		for i, col := range src.block.Columns {
-			if col == src.column {
-				colIdx = i
-				break
-			}
-		}
-		if colIdx != -1 {
-			src.block.Columns = append(src.block.Columns[:colIdx], src.block.Columns[colIdx+1:]...)
+		destColumn = dest.column
+		if newColumn && dest.parent == nil {
+			destColumn = insertColumn(dest.block, indexOfColumn(dest.block, dest.column))
+			detachCategory(src, destColumn)
+			destColumn.Categories = append(destColumn.Categories, src.cat)
+		} else {
+			detachCategory(src, destColumn)
+			src.cat.EntriesBefore = dest.cat.EntriesBefore
+			list := dest.siblings()
+			i := indexOfCategory(*list, dest.cat)
+			*list = append(*list, nil)
+			copy((*list)[i+1:], (*list)[i:])
+			(*list)[i] = src.cat
 		}
 	}
 
-	// reindex
-	idx = 0
-	for _, t := range b {
-		for _, p := range t.Pages {
-			for _, b := range p.Blocks {
-				for _, col := range b.Columns {
-					for _, c := range col.Categories {
-						c.Index = idx
-						idx++
-					}
-				}
-			}
-		}
+	b.reindexCategories()
+	return nil
+}
+
+// MoveCategoryInto moves the category at fromIndex to the end of the
+// subcategories of the category at parentIndex.
+func (b BookmarkList) MoveCategoryInto(fromIndex, parentIndex int) error {
+	cats := b.categoryLocs()
+	if fromIndex < 0 || fromIndex >= len(cats) {
+		return fmt.Errorf("category index %d not found", fromIndex)
 	}
+	if parentIndex < 0 || parentIndex >= len(cats) {
+		return fmt.Errorf("category index %d not found", parentIndex)
+	}
+	src, parent := cats[fromIndex], cats[parentIndex].cat
+	if src.cat.Contains(parent) {
+		return fmt.Errorf("category %d cannot be moved inside itself", fromIndex)
+	}
+	detachCategory(src, nil)
+	src.cat.EntriesBefore = len(parent.Entries)
+	parent.Subcategories = append(parent.Subcategories, src.cat)
+	b.reindexCategories()
 	return nil
 }
 
@@ -543,24 +794,75 @@ func (b BookmarkList) MoveCategoryNewColumn(fromIndex int, page *BookmarkPage, d
 	return b.MoveCategory(fromIndex, -1, true, page, destCol)
 }
 
-// PageForCategory returns the page containing the category with the given index.
-func PageForCategory(tabs BookmarkList, index int) *BookmarkPage {
-	idx := 0
-	for _, t := range tabs {
-		for _, p := range t.Pages {
-			for _, b := range p.Blocks {
-				for _, col := range b.Columns {
-					for range col.Categories {
-						if idx == index {
-							return p
-						}
-						idx++
-					}
-				}
+// MoveEntryTo moves entry fromPos of the category at fromCat to position
+// toPos of the category at toCat. The categories may be anywhere in the list,
+// including the same one. A negative toPos, or one past the last entry,
+// appends the entry.
+func (b BookmarkList) MoveEntryTo(fromCat, fromPos, toCat, toPos int) error {
+	return b.transferEntry(fromCat, fromPos, toCat, toPos, false)
+}
+
+// CopyEntryTo copies entry fromPos of the category at fromCat to position
+// toPos of the category at toCat, as MoveEntryTo does but leaving the
+// original in place.
+func (b BookmarkList) CopyEntryTo(fromCat, fromPos, toCat, toPos int) error {
+	return b.transferEntry(fromCat, fromPos, toCat, toPos, true)
+}
+
+func (b BookmarkList) transferEntry(fromCat, fromPos, toCat, toPos int, copyEntry bool) error {
+	src := b.FindCategory(fromCat)
+	if src == nil {
+		return fmt.Errorf("category index %d not found", fromCat)
+	}
+	dst := b.FindCategory(toCat)
+	if dst == nil {
+		return fmt.Errorf("category index %d not found", toCat)
+	}
+	if fromPos < 0 || fromPos >= len(src.Entries) {
+		return fmt.Errorf("entry %d not found in category %d", fromPos, fromCat)
+	}
+	entry := src.Entries[fromPos]
+	if copyEntry {
+		dup := *entry
+		dup.Tags = append([]string(nil), entry.Tags...)
+		entry = &dup
+	} else {
+		src.removeEntry(fromPos)
+	}
+	dst.insertEntry(toPos, entry)
+	return nil
+}
+
+// CategoryOption names a category for a picker such as the "send to" menu.
+type CategoryOption struct {
+	Index int
+	// Label holds the tab name followed by the category path.
+	Label string
+}
+
+// CategoryOptions lists every category in index order.
+func (b BookmarkList) CategoryOptions() []CategoryOption {
+	var out []CategoryOption
+	b.walkCategoryPaths(func(tab, _ int, path string, c *BookmarkCategory) {
+		tabName := b[tab].DisplayName()
+		if tabName == "" {
+			tabName = fmt.Sprintf("Tab %d", tab+1)
+			if tab == 0 {
+				tabName = "Main"
 			}
 		}
+		out = append(out, CategoryOption{Index: c.Index, Label: tabName + ": " + path})
+	})
+	return out
+}
+
+// PageForCategory returns the page containing the category with the given index.
+func PageForCategory(tabs BookmarkList, index int) *BookmarkPage {
+	cats := tabs.categoryLocs()
+	if index < 0 || index >= len(cats) {
+		return nil
 	}
-	return nil
+	return cats[index].page
 }
 
 // FindPageBySha returns the page matching the sha.
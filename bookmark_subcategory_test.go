@@ -0,0 +1,255 @@
+package gobookmarks
+
+import (
+	"context"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const subcategoryBookmarkText = `Category: Work
+http://work.com Work
+  Subcategory: Docs
+  http://docs.com Docs
+    Subcategory: API
+    http://api.com API
+  Subcategory: Tools
+  http://tools.com Tools
+Column
+Category: Home
+http://home.com Home
+`
+
+func TestParseSubcategories(t *testing.T) {
+	tabs := ParseBookmarks(subcategoryBookmarkText)
+	cats := tabs[0].Pages[0].Blocks[0].Columns[0].Categories
+	if len(cats) != 1 {
+		t.Fatalf("expected 1 top-level category, got %d", len(cats))
+	}
+	work := cats[0]
+	if len(work.Entries) != 1 || len(work.Subcategories) != 2 {
+		t.Fatalf("unexpected work category %+v", work)
+	}
+	docs, tools := work.Subcategories[0], work.Subcategories[1]
+	if docs.Name != "Docs" || tools.Name != "Tools" || len(docs.Subcategories) != 1 || docs.Subcategories[0].Name != "API" {
+		t.Fatalf("unexpected nesting: %q %q", docs.Name, tools.Name)
+	}
+	if docs.Subcategories[0].Entries[0].Url != "http://api.com" {
+		t.Fatalf("entry not added to innermost subcategory")
+	}
+	want := map[string]int{"Work": 0, "Docs": 1, "API": 2, "Tools": 3, "Home": 4}
+	for name, idx := range want {
+		c := tabs.FindCategory(idx)
+		if c == nil || c.Name != name || c.Index != idx {
+			t.Errorf("index %d: got %+v want %s", idx, c, name)
+		}
+	}
+	if got := tabs.String(); got != subcategoryBookmarkText {
+		t.Fatalf("expected %q got %q", subcategoryBookmarkText, got)
+	}
+}
+
+func TestParseUnindentedSubcategories(t *testing.T) {
+	tabs := ParseBookmarks("Category: A\nSubcategory: B\nhttp://b.com\nSubcategory: C\nhttp://c.com\n")
+	a := tabs[0].Pages[0].Blocks[0].Columns[0].Categories[0]
+	if len(a.Subcategories) != 2 || len(a.Subcategories[0].Subcategories) != 0 {
+		t.Fatalf("unindented subcategories should be siblings: %+v", a.Subcategories)
+	}
+	out := tabs.String()
+	if diff := cmp.Diff(tabs, ParseBookmarks(out)); diff != "" {
+		t.Fatalf("round trip diff:\n%s", diff)
+	}
+}
+
+func TestSubcategoryShaChanges(t *testing.T) {
+	tabs := ParseBookmarks(subcategoryBookmarkText)
+	work := tabs.FindCategory(0)
+	before := work.Sha()
+	tabs.FindCategory(2).Entries[0].Name = "Changed"
+	if work.Sha() == before {
+		t.Fatalf("parent sha should change with a nested entry")
+	}
+}
+
+func TestMoveCategoryOutOfSubcategory(t *testing.T) {
+	tabs := ParseBookmarks(subcategoryBookmarkText)
+	// Move API before Home, making it a top-level category.
+	if err := tabs.MoveCategoryBefore(2, 4); err != nil {
+		t.Fatalf("MoveCategory: %v", err)
+	}
+	expected := `Category: Work
+http://work.com Work
+  Subcategory: Docs
+  http://docs.com Docs
+  Subcategory: Tools
+  http://tools.com Tools
+Column
+Category: API
+http://api.com API
+Category: Home
+http://home.com Home
+`
+	if got := tabs.String(); got != expected {
+		t.Fatalf("expected %q got %q", expected, got)
+	}
+	if c := tabs.FindCategory(3); c == nil || c.Name != "API" {
+		t.Fatalf("indexes not renumbered: %+v", c)
+	}
+}
+
+func TestMoveCategoryBeforeSubcategory(t *testing.T) {
+	tabs := ParseBookmarks(subcategoryBookmarkText)
+	// Move Home before Tools, making it a sibling subcategory. Its column
+	// is left empty and removed.
+	if err := tabs.MoveCategoryBefore(4, 3); err != nil {
+		t.Fatalf("MoveCategory: %v", err)
+	}
+	expected := `Category: Work
+http://work.com Work
+  Subcategory: Docs
+  http://docs.com Docs
+    Subcategory: API
+    http://api.com API
+  Subcategory: Home
+  http://home.com Home
+  Subcategory: Tools
+  http://tools.com Tools
+`
+	if got := tabs.String(); got != expected {
+		t.Fatalf("expected %q got %q", expected, got)
+	}
+}
+
+func TestMoveCategoryInto(t *testing.T) {
+	tabs := ParseBookmarks(subcategoryBookmarkText)
+	// Move Docs, with API, into Home.
+	if err := tabs.MoveCategoryInto(1, 4); err != nil {
+		t.Fatalf("MoveCategoryInto: %v", err)
+	}
+	expected := `Category: Work
+http://work.com Work
+  Subcategory: Tools
+  http://tools.com Tools
+Column
+Category: Home
+http://home.com Home
+  Subcategory: Docs
+  http://docs.com Docs
+    Subcategory: API
+    http://api.com API
+`
+	if got := tabs.String(); got != expected {
+		t.Fatalf("expected %q got %q", expected, got)
+	}
+	if c := tabs.FindCategory(4); c == nil || c.Name != "API" || c.Index != 4 {
+		t.Fatalf("indexes not renumbered: %+v", c)
+	}
+}
+
+func TestMoveCategoryIntoItself(t *testing.T) {
+	tabs := ParseBookmarks(subcategoryBookmarkText)
+	if err := tabs.MoveCategoryInto(1, 2); err == nil {
+		t.Fatalf("expected error moving a category into its own subcategory")
+	}
+	if err := tabs.MoveCategoryBefore(0, 3); err == nil {
+		t.Fatalf("expected error moving a category before its own subcategory")
+	}
+	if got := tabs.String(); got != subcategoryBookmarkText {
+		t.Fatalf("failed move changed bookmarks: %q", got)
+	}
+}
+
+func TestExtractSubcategoryByIndex(t *testing.T) {
+	got, err := ExtractCategoryByIndex(subcategoryBookmarkText, 1)
+	if err != nil {
+		t.Fatalf("ExtractCategoryByIndex: %v", err)
+	}
+	expected := "  Subcategory: Docs\n  http://docs.com Docs\n    Subcategory: API\n    http://api.com API"
+	if got != expected {
+		t.Fatalf("expected %q got %q", expected, got)
+	}
+	got, err = ExtractCategoryByIndex(subcategoryBookmarkText, 0)
+	if err != nil {
+		t.Fatalf("ExtractCategoryByIndex: %v", err)
+	}
+	if !strings.HasSuffix(got, "http://tools.com Tools") {
+		t.Fatalf("parent should include its subcategories: %q", got)
+	}
+	replaced, err := ReplaceCategoryByIndex(subcategoryBookmarkText, 3, "  Subcategory: Utilities\n  http://util.com Util")
+	if err != nil {
+		t.Fatalf("ReplaceCategoryByIndex: %v", err)
+	}
+	if c := ParseBookmarks(replaced).FindCategory(3); c == nil || c.Name != "Utilities" {
+		t.Fatalf("subcategory not replaced: %q", replaced)
+	}
+}
+
+func TestCategoryMoveIntoAction(t *testing.T) {
+	p, user, _, ctx := setupCategoryEditTest(t)
+	if err := p.CreateBookmarks(context.Background(), user, nil, "main", subcategoryBookmarkText); err != nil {
+		t.Fatalf("CreateBookmarks: %v", err)
+	}
+	pageSha := ParseBookmarks(subcategoryBookmarkText)[0].Pages[0].Sha()
+	form := url.Values{"from": {"3"}, "into": {"4"}, "branch": {"main"}, "ref": {"refs/heads/main"}, "pageSha": {pageSha}}
+	req := httptest.NewRequest("POST", "/moveCategoryInto", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req = req.WithContext(ctx)
+	w := httptest.NewRecorder()
+	if err := CategoryMoveIntoAction(w, req); err != nil {
+		t.Fatalf("CategoryMoveIntoAction: %v", err)
+	}
+	got, _, err := p.GetBookmarks(context.Background(), user, "refs/heads/main", nil)
+	if err != nil {
+		t.Fatalf("GetBookmarks after: %v", err)
+	}
+	tabs := ParseBookmarks(subcategoryBookmarkText)
+	if err := tabs.MoveCategoryInto(3, 4); err != nil {
+		t.Fatalf("MoveCategoryInto local: %v", err)
+	}
+	if expected := tabs.String(); got != expected {
+		t.Fatalf("expected %q got %q", expected, got)
+	}
+}
+
+func TestParseEntryAfterSubcategory(t *testing.T) {
+	text := "Category: Work\nhttp://work.com Work\n  Subcategory: Docs\n  http://docs.com Docs\n    Subcategory: API\n    http://api.com API\n  http://guide.com Guide\nhttp://mail.com Mail\n"
+	tabs := ParseBookmarks(text)
+	work := tabs[0].Pages[0].Blocks[0].Columns[0].Categories[0]
+	docs := work.Subcategories[0]
+	api := docs.Subcategories[0]
+	if len(api.Entries) != 1 || api.Entries[0].Url != "http://api.com" {
+		t.Fatalf("unexpected API entries %+v", api.Entries)
+	}
+	if len(docs.Entries) != 2 || docs.Entries[1].Url != "http://guide.com" {
+		t.Fatalf("entry at the Docs indent should return to Docs: %+v", docs.Entries)
+	}
+	if len(work.Entries) != 2 || work.Entries[1].Url != "http://mail.com" {
+		t.Fatalf("unindented entry should return to Work: %+v", work.Entries)
+	}
+	got, err := ExtractCategoryByIndex(text, 2)
+	if err != nil {
+		t.Fatalf("ExtractCategoryByIndex: %v", err)
+	}
+	if got != "    Subcategory: API\n    http://api.com API" {
+		t.Fatalf("subcategory range should stop at its parent's entries: %q", got)
+	}
+}
+
+func TestEntryAfterSubcategoryRoundTrip(t *testing.T) {
+	text := "Category: Work\nhttp://work.com Work\n  Subcategory: Docs\n  http://docs.com Docs\n    Subcategory: API\n    http://api.com API\n  http://guide.com Guide\nhttp://mail.com Mail\n"
+	tabs := ParseBookmarks(text)
+	if got := tabs.String(); got != text {
+		t.Fatalf("round trip changed the order:\n%s", got)
+	}
+	// Removing the entry before Docs leaves Mail after it.
+	if err := tabs.MoveEntryTo(0, 0, 1, 0); err != nil {
+		t.Fatalf("MoveEntryTo: %v", err)
+	}
+	want := "Category: Work\n  Subcategory: Docs\n  http://work.com Work\n  http://docs.com Docs\n    Subcategory: API\n    http://api.com API\n  http://guide.com Guide\nhttp://mail.com Mail\n"
+	if got := tabs.String(); got != want {
+		t.Fatalf("after moving an entry:\n%s", got)
+	}
+}
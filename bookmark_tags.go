@@ -0,0 +1,152 @@
+package gobookmarks
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// entryTagPattern matches a "#tag" word. Tags start with a letter so words
+// such as "#123" stay part of the name.
+var entryTagPattern = regexp.MustCompile(`^#[A-Za-z][\w-]*$`)
+
+// splitEntryTags removes the trailing "#tag" words from the fields of an
+// entry line and returns them without the "#". Only trailing words count so
+// a name such as "#1 Fan Site" is left alone. A trailing name word written as
+// "\#word" is kept in the name as "#word".
+func splitEntryTags(parts []string) ([]string, []string) {
+	end := len(parts)
+	for end > 1 && entryTagPattern.MatchString(parts[end-1]) {
+		end--
+	}
+	var tags []string
+	for _, p := range parts[end:] {
+		tags = append(tags, p[1:])
+	}
+	parts = parts[:end]
+	for i := len(parts) - 1; i > 0 && strings.HasPrefix(parts[i], `\`) && entryTagPattern.MatchString(parts[i][1:]); i-- {
+		parts[i] = parts[i][1:]
+	}
+	return parts, tags
+}
+
+// escapeEntryName escapes the trailing words of name that would otherwise be
+// read back as tags.
+func escapeEntryName(name string) string {
+	words := strings.Split(name, " ")
+	for i := len(words) - 1; i >= 0 && entryTagPattern.MatchString(words[i]); i-- {
+		words[i] = `\` + words[i]
+	}
+	return strings.Join(words, " ")
+}
+
+// normalizeTag lower cases a tag and drops a leading "#".
+func normalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+}
+
+// HasTag reports whether the entry carries tag, ignoring case.
+func (e *BookmarkEntry) HasTag(tag string) bool {
+	tag = normalizeTag(tag)
+	for _, t := range e.Tags {
+		if normalizeTag(t) == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// TagCount is a tag with the number of entries carrying it.
+type TagCount struct {
+	Name  string
+	Count int
+}
+
+// TaggedCategory holds the entries of one category that carry a tag.
+type TaggedCategory struct {
+	Tab     int
+	TabName string
+	Page    int
+	// Path names the category, preceded by its parents for a subcategory.
+	Path     string
+	Category *BookmarkCategory
+	Entries  []*BookmarkEntry
+}
+
+// walkCategoryPaths calls fn for every category in the list with the tab and
+// page it is on and its name preceded by those of its parents.
+func (b BookmarkList) walkCategoryPaths(fn func(tab, page int, path string, c *BookmarkCategory)) {
+	for ti, t := range b {
+		for pi, p := range t.Pages {
+			var walk func(prefix string, c *BookmarkCategory)
+			walk = func(prefix string, c *BookmarkCategory) {
+				path := prefix + c.DisplayName()
+				fn(ti, pi, path, c)
+				for _, sub := range c.Subcategories {
+					walk(path+" / ", sub)
+				}
+			}
+			for _, blk := range p.Blocks {
+				for _, col := range blk.Columns {
+					for _, c := range col.Categories {
+						if !c.IsInclude() {
+							walk("", c)
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+// Tags lists the tags used in the list sorted by name. Tags differing only
+// in case are counted together under their first spelling.
+func (b BookmarkList) Tags() []TagCount {
+	counts := map[string]*TagCount{}
+	var out []*TagCount
+	b.walkCategoryPaths(func(_, _ int, _ string, c *BookmarkCategory) {
+		for _, e := range c.Entries {
+			for _, t := range e.Tags {
+				key := normalizeTag(t)
+				tc, ok := counts[key]
+				if !ok {
+					tc = &TagCount{Name: t}
+					counts[key] = tc
+					out = append(out, tc)
+				}
+				tc.Count++
+			}
+		}
+	})
+	sort.Slice(out, func(i, j int) bool {
+		return normalizeTag(out[i].Name) < normalizeTag(out[j].Name)
+	})
+	tags := make([]TagCount, len(out))
+	for i, tc := range out {
+		tags[i] = *tc
+	}
+	return tags
+}
+
+// EntriesWithTag returns the entries carrying tag across every tab, grouped
+// by the category they are in, in file order.
+func (b BookmarkList) EntriesWithTag(tag string) []TaggedCategory {
+	var out []TaggedCategory
+	b.walkCategoryPaths(func(tab, page int, path string, c *BookmarkCategory) {
+		var entries []*BookmarkEntry
+		for _, e := range c.Entries {
+			if e.HasTag(tag) {
+				entries = append(entries, e)
+			}
+		}
+		if len(entries) == 0 {
+			return
+		}
+		tabName := b[tab].DisplayName()
+		if tabName == "" && tab == 0 {
+			tabName = "Main"
+		}
+		out = append(out, TaggedCategory{Tab: tab, TabName: tabName, Page: page, Path: path, Category: c, Entries: entries})
+	})
+	return out
+}
@@ -0,0 +1,96 @@
+package gobookmarks
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const taggedBookmarkText = `Category: Ops
+http://pager.com Pager #oncall #prod
+http://wiki.com #1 Wiki
+  Subcategory: Dashboards
+  http://grafana.com Grafana #prod
+Tab: Personal
+Category: Home
+http://mail.com Mail #OnCall
+`
+
+func TestParseEntryTags(t *testing.T) {
+	tabs := ParseBookmarks(taggedBookmarkText)
+	ops := tabs.FindCategory(0)
+	if diff := cmp.Diff([]string{"oncall", "prod"}, ops.Entries[0].Tags); diff != "" {
+		t.Fatalf("tags diff:\n%s", diff)
+	}
+	if ops.Entries[0].Name != "Pager" {
+		t.Fatalf("tags left in name: %q", ops.Entries[0].Name)
+	}
+	if ops.Entries[1].Name != "#1 Wiki" || ops.Entries[1].Tags != nil {
+		t.Fatalf("only trailing words are tags: %+v", ops.Entries[1])
+	}
+	if got := tabs.String(); got != taggedBookmarkText {
+		t.Fatalf("expected %q got %q", taggedBookmarkText, got)
+	}
+	e := ParseBookmarks("Category: A\nhttp://a.com #a\n").FindCategory(0).Entries[0]
+	if e.Name != e.Url || e.String() != "http://a.com #a\n" {
+		t.Fatalf("unexpected entry %+v", e)
+	}
+}
+
+func TestEntryNamesWithHashWords(t *testing.T) {
+	for _, tc := range []struct {
+		line string
+		name string
+		tags []string
+	}{
+		{"http://a.com Issue #123", "Issue #123", nil},
+		{"http://a.com Issue #123 #bugs", "Issue #123", []string{"bugs"}},
+		{"http://a.com Release #1.2", "Release #1.2", nil},
+		{"http://a.com Slack #ops", "Slack", []string{"ops"}},
+		{`http://a.com Slack \#ops`, "Slack #ops", nil},
+		{`http://a.com Slack \#ops #chat`, "Slack #ops", []string{"chat"}},
+		{"http://a.com C# #lang", "C#", []string{"lang"}},
+	} {
+		text := "Category: A\n" + tc.line + "\n"
+		tabs := ParseBookmarks(text)
+		e := tabs.FindCategory(0).Entries[0]
+		if e.Name != tc.name {
+			t.Errorf("%q: name %q want %q", tc.line, e.Name, tc.name)
+		}
+		if diff := cmp.Diff(tc.tags, e.Tags); diff != "" {
+			t.Errorf("%q: tags diff:\n%s", tc.line, diff)
+		}
+		if got := tabs.String(); got != text {
+			t.Errorf("%q: round trip %q", tc.line, got)
+		}
+	}
+	e := &BookmarkEntry{Url: "http://a.com", Name: "Slack #ops"}
+	if got := e.String(); got != "http://a.com Slack \\#ops\n" {
+		t.Fatalf("name ending in a tag word not escaped: %q", got)
+	}
+}
+
+func TestBookmarkListTags(t *testing.T) {
+	got := ParseBookmarks(taggedBookmarkText).Tags()
+	want := []TagCount{{Name: "oncall", Count: 2}, {Name: "prod", Count: 2}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("tags diff:\n%s", diff)
+	}
+}
+
+func TestEntriesWithTag(t *testing.T) {
+	got := ParseBookmarks(taggedBookmarkText).EntriesWithTag("#ONCALL")
+	if len(got) != 2 {
+		t.Fatalf("expected 2 categories, got %d", len(got))
+	}
+	if got[0].Path != "Ops" || got[0].Tab != 0 || got[0].TabName != "Ops" || len(got[0].Entries) != 1 {
+		t.Fatalf("unexpected first group %+v", got[0])
+	}
+	if got[1].Path != "Home" || got[1].Tab != 1 || got[1].TabName != "Personal" {
+		t.Fatalf("unexpected second group %+v", got[1])
+	}
+	prod := ParseBookmarks(taggedBookmarkText).EntriesWithTag("prod")
+	if len(prod) != 2 || prod[1].Path != "Ops / Dashboards" {
+		t.Fatalf("unexpected subcategory group %+v", prod)
+	}
+}
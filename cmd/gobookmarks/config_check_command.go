@@ -0,0 +1,97 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	gobookmarks "github.com/arran4/gobookmarks"
+)
+
+type ConfigCheckCommand struct {
+	parent Command
+	Flags  *flag.FlagSet
+
+	All bool
+}
+
+func (cc *ConfigCommand) NewConfigCheckCommand() (*ConfigCheckCommand, error) {
+	c := &ConfigCheckCommand{
+		parent: cc,
+		Flags:  flag.NewFlagSet("check", flag.ContinueOnError),
+	}
+	c.Flags.BoolVar(&c.All, "all", false, "also list keys left at their default")
+	return c, nil
+}
+
+func (c *ConfigCheckCommand) Name() string {
+	return c.Flags.Name()
+}
+
+func (c *ConfigCheckCommand) Parent() Command {
+	return c.parent
+}
+
+func (c *ConfigCheckCommand) FlagSet() *flag.FlagSet {
+	return c.Flags
+}
+
+func (c *ConfigCheckCommand) Subcommands() []Command {
+	return nil
+}
+
+func (c *ConfigCheckCommand) Execute(args []string) error {
+	c.FlagSet().Usage = func() { printHelp(c, nil) }
+	if err := c.FlagSet().Parse(args); err != nil {
+		printHelp(c, err)
+		return err
+	}
+	root := c.Parent().(*ConfigCommand).parent.(*RootCommand)
+	return checkConfig(os.Stdout, root.cfg, root.sources, c.All)
+}
+
+// checkConfig prints the effective configuration with secrets redacted and
+// the source of each value, then the problems found in it. It fails when
+// there are any.
+func checkConfig(w io.Writer, cfg gobookmarks.Configuration, sources gobookmarks.ConfigSources, all bool) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "KEY\tVALUE\tSOURCE")
+	for _, v := range gobookmarks.EffectiveConfig(cfg, sources) {
+		if v.Source == "default" && !all {
+			continue
+		}
+		value := v.Value
+		if value == "" {
+			value = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", v.Key, value, v.Source)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	err := cfg.Validate()
+	if err == nil {
+		fmt.Fprintln(w, "\nNo problems found.")
+		return nil
+	}
+	var problems []string
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, e := range joined.Unwrap() {
+			problems = append(problems, e.Error())
+		}
+	} else {
+		problems = []string{err.Error()}
+	}
+	fmt.Fprintln(w, "\nProblems:")
+	for _, p := range problems {
+		fmt.Fprintf(w, "  - %s\n", p)
+	}
+	if len(problems) == 1 {
+		return errors.New("configuration has 1 problem")
+	}
+	return fmt.Errorf("configuration has %d problems", len(problems))
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+)
+
+type ConfigCommand struct {
+	parent Command
+	Flags  *flag.FlagSet
+
+	CheckCommand *ConfigCheckCommand
+	HelpCmd      *HelpCommand
+}
+
+func (rc *RootCommand) NewConfigCommand() (*ConfigCommand, error) {
+	c := &ConfigCommand{
+		parent: rc,
+		Flags:  flag.NewFlagSet("config", flag.ContinueOnError),
+	}
+	c.CheckCommand, _ = c.NewConfigCheckCommand()
+	c.HelpCmd = NewHelpCommand(c)
+	return c, nil
+}
+
+func (c *ConfigCommand) Name() string {
+	return c.Flags.Name()
+}
+
+func (c *ConfigCommand) Parent() Command {
+	return c.parent
+}
+
+func (c *ConfigCommand) FlagSet() *flag.FlagSet {
+	return c.Flags
+}
+
+func (c *ConfigCommand) Subcommands() []Command {
+	return []Command{c.CheckCommand, c.HelpCmd}
+}
+
+func (c *ConfigCommand) Execute(args []string) error {
+	c.FlagSet().Usage = func() { printHelp(c, nil) }
+	if err := c.FlagSet().Parse(args); err != nil {
+		printHelp(c, err)
+		return err
+	}
+	remaining := c.FlagSet().Args()
+	if len(remaining) == 0 {
+		printHelp(c, nil)
+		return nil
+	}
+	switch remaining[0] {
+	case "-h", "--help", "help":
+		return c.HelpCmd.Execute(remaining[1:])
+	case c.CheckCommand.Name():
+		return c.CheckCommand.Execute(remaining[1:])
+	default:
+		err := fmt.Errorf("unknown config subcommand: %s", remaining[0])
+		printHelp(c, err)
+		return err
+	}
+}
@@ -11,6 +11,7 @@ type DbCommand struct {
 
 	UsersCommand         *DbUsersCommand
 	ResetPasswordCommand *DbResetPasswordCommand
+	Reset2FACommand      *DbReset2FACommand
 	HelpCmd              *HelpCommand
 }
 
@@ -21,6 +22,7 @@ func (rc *RootCommand) NewDbCommand() (*DbCommand, error) {
 	}
 	c.UsersCommand, _ = c.NewDbUsersCommand()
 	c.ResetPasswordCommand, _ = c.NewDbResetPasswordCommand()
+	c.Reset2FACommand, _ = c.NewDbReset2FACommand()
 	c.HelpCmd = NewHelpCommand(c)
 	return c, nil
 }
@@ -38,7 +40,7 @@ func (c *DbCommand) FlagSet() *flag.FlagSet {
 }
 
 func (c *DbCommand) Subcommands() []Command {
-	return []Command{c.UsersCommand, c.ResetPasswordCommand, c.HelpCmd}
+	return []Command{c.UsersCommand, c.ResetPasswordCommand, c.Reset2FACommand, c.HelpCmd}
 }
 
 func (c *DbCommand) Execute(args []string) error {
@@ -59,6 +61,8 @@ func (c *DbCommand) Execute(args []string) error {
 		return c.UsersCommand.Execute(remaining[1:])
 	case c.ResetPasswordCommand.Name():
 		return c.ResetPasswordCommand.Execute(remaining[1:])
+	case c.Reset2FACommand.Name():
+		return c.Reset2FACommand.Execute(remaining[1:])
 	default:
 		err := fmt.Errorf("unknown db subcommand: %s", remaining[0])
 		printHelp(c, err)
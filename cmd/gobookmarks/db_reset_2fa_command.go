@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	gobookmarks "github.com/arran4/gobookmarks"
+)
+
+type DbReset2FACommand struct {
+	parent Command
+	Flags  *flag.FlagSet
+
+	User     string
+	Provider string
+}
+
+func (dc *DbCommand) NewDbReset2FACommand() (*DbReset2FACommand, error) {
+	c := &DbReset2FACommand{
+		parent: dc,
+		Flags:  flag.NewFlagSet("reset-2fa", flag.ContinueOnError),
+	}
+	c.Flags.StringVar(&c.User, "user", "", "username to remove two-factor authentication from")
+	c.Flags.StringVar(&c.Provider, "provider", "sql", "provider storing the account (sql or git)")
+	return c, nil
+}
+
+func (c *DbReset2FACommand) Name() string {
+	return c.Flags.Name()
+}
+
+func (c *DbReset2FACommand) Parent() Command {
+	return c.parent
+}
+
+func (c *DbReset2FACommand) FlagSet() *flag.FlagSet {
+	return c.Flags
+}
+
+func (c *DbReset2FACommand) Subcommands() []Command {
+	return nil
+}
+
+func (c *DbReset2FACommand) Execute(args []string) error {
+	c.FlagSet().Usage = func() { printHelp(c, nil) }
+	if err := c.FlagSet().Parse(args); err != nil {
+		printHelp(c, err)
+		return err
+	}
+	if c.User == "" {
+		err := fmt.Errorf("user is required")
+		printHelp(c, err)
+		return err
+	}
+
+	cfg := c.Parent().(*DbCommand).parent.(*RootCommand).cfg
+
+	switch c.Provider {
+	case "sql":
+		if cfg.DBConnectionProvider == "" || cfg.DBConnectionString == "" {
+			err := fmt.Errorf("database connection not configured")
+			printHelp(c, err)
+			return err
+		}
+	case "git":
+		if cfg.LocalGitPath == "" {
+			err := fmt.Errorf("local git path not configured")
+			printHelp(c, err)
+			return err
+		}
+	default:
+		err := fmt.Errorf("provider must be sql or git, not %q", c.Provider)
+		printHelp(c, err)
+		return err
+	}
+
+	gobookmarks.Config = cfg
+
+	th, ok := gobookmarks.GetProvider(c.Provider).(gobookmarks.TwoFactorHandler)
+	if !ok {
+		err := fmt.Errorf("provider %s is not available", c.Provider)
+		printHelp(c, err)
+		return err
+	}
+	if err := th.SetTwoFactor(context.Background(), c.User, nil); err != nil {
+		printHelp(c, err)
+		return err
+	}
+
+	fmt.Printf("two-factor authentication for user %s has been reset\n", c.User)
+	return nil
+}
@@ -1,6 +1,9 @@
 package main
 
-import "strconv"
+import (
+	"strconv"
+	"strings"
+)
 
 type stringFlag struct {
 	value string
@@ -26,3 +29,9 @@ func (b *boolFlag) Set(v string) error {
 }
 
 func (b *boolFlag) String() string { return strconv.FormatBool(b.value) }
+
+// stringListFlag collects every value of a repeated flag.
+type stringListFlag []string
+
+func (s *stringListFlag) Set(v string) error { *s = append(*s, v); return nil }
+func (s *stringListFlag) String() string     { return strings.Join(*s, ",") }
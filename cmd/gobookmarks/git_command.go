@@ -0,0 +1,63 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+)
+
+type GitCommand struct {
+	parent Command
+	Flags  *flag.FlagSet
+
+	UsersCommand *GitUsersCommand
+	HelpCmd      *HelpCommand
+}
+
+func (rc *RootCommand) NewGitCommand() (*GitCommand, error) {
+	c := &GitCommand{
+		parent: rc,
+		Flags:  flag.NewFlagSet("git", flag.ContinueOnError),
+	}
+	c.UsersCommand, _ = c.NewGitUsersCommand()
+	c.HelpCmd = NewHelpCommand(c)
+	return c, nil
+}
+
+func (c *GitCommand) Name() string {
+	return c.Flags.Name()
+}
+
+func (c *GitCommand) Parent() Command {
+	return c.parent
+}
+
+func (c *GitCommand) FlagSet() *flag.FlagSet {
+	return c.Flags
+}
+
+func (c *GitCommand) Subcommands() []Command {
+	return []Command{c.UsersCommand, c.HelpCmd}
+}
+
+func (c *GitCommand) Execute(args []string) error {
+	c.FlagSet().Usage = func() { printHelp(c, nil) }
+	if err := c.FlagSet().Parse(args); err != nil {
+		printHelp(c, err)
+		return err
+	}
+	remaining := c.FlagSet().Args()
+	if len(remaining) == 0 {
+		printHelp(c, nil)
+		return nil
+	}
+	switch remaining[0] {
+	case "-h", "--help", "help":
+		return c.HelpCmd.Execute(remaining[1:])
+	case c.UsersCommand.Name():
+		return c.UsersCommand.Execute(remaining[1:])
+	default:
+		err := fmt.Errorf("unknown git subcommand: %s", remaining[0])
+		printHelp(c, err)
+		return err
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	gobookmarks "github.com/arran4/gobookmarks"
+)
+
+type GitUsersCommand struct {
+	parent Command
+	Flags  *flag.FlagSet
+
+	ListCommand    *GitUsersListCommand
+	DeleteCommand  *GitUsersDeleteCommand
+	RenameCommand  *GitUsersRenameCommand
+	MigrateCommand *GitUsersMigrateCommand
+	HelpCmd        *HelpCommand
+}
+
+func (gc *GitCommand) NewGitUsersCommand() (*GitUsersCommand, error) {
+	c := &GitUsersCommand{
+		parent: gc,
+		Flags:  flag.NewFlagSet("users", flag.ContinueOnError),
+	}
+	c.ListCommand, _ = c.NewGitUsersListCommand()
+	c.DeleteCommand, _ = c.NewGitUsersDeleteCommand()
+	c.RenameCommand, _ = c.NewGitUsersRenameCommand()
+	c.MigrateCommand, _ = c.NewGitUsersMigrateCommand()
+	c.HelpCmd = NewHelpCommand(c)
+	return c, nil
+}
+
+func (c *GitUsersCommand) Name() string {
+	return c.Flags.Name()
+}
+
+func (c *GitUsersCommand) Parent() Command {
+	return c.parent
+}
+
+func (c *GitUsersCommand) FlagSet() *flag.FlagSet {
+	return c.Flags
+}
+
+func (c *GitUsersCommand) Subcommands() []Command {
+	return []Command{c.ListCommand, c.DeleteCommand, c.RenameCommand, c.MigrateCommand, c.HelpCmd}
+}
+
+// config returns the root configuration after checking the git provider is
+// configured, and makes it the active configuration.
+func (c *GitUsersCommand) config() (gobookmarks.Configuration, error) {
+	cfg := c.Parent().(*GitCommand).parent.(*RootCommand).cfg
+	if cfg.LocalGitPath == "" {
+		return cfg, fmt.Errorf("local git path not configured")
+	}
+	gobookmarks.Config = cfg
+	return cfg, nil
+}
+
+func (c *GitUsersCommand) Execute(args []string) error {
+	c.FlagSet().Usage = func() { printHelp(c, nil) }
+	if err := c.FlagSet().Parse(args); err != nil {
+		printHelp(c, err)
+		return err
+	}
+	remaining := c.FlagSet().Args()
+	if len(remaining) == 0 {
+		printHelp(c, nil)
+		return nil
+	}
+	switch remaining[0] {
+	case "-h", "--help", "help":
+		return c.HelpCmd.Execute(remaining[1:])
+	case c.ListCommand.Name():
+		return c.ListCommand.Execute(remaining[1:])
+	case c.DeleteCommand.Name():
+		return c.DeleteCommand.Execute(remaining[1:])
+	case c.RenameCommand.Name():
+		return c.RenameCommand.Execute(remaining[1:])
+	case c.MigrateCommand.Name():
+		return c.MigrateCommand.Execute(remaining[1:])
+	default:
+		err := fmt.Errorf("unknown users subcommand: %s", remaining[0])
+		printHelp(c, err)
+		return err
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	gobookmarks "github.com/arran4/gobookmarks"
+)
+
+type GitUsersDeleteCommand struct {
+	parent Command
+	Flags  *flag.FlagSet
+
+	User string
+}
+
+func (uc *GitUsersCommand) NewGitUsersDeleteCommand() (*GitUsersDeleteCommand, error) {
+	c := &GitUsersDeleteCommand{
+		parent: uc,
+		Flags:  flag.NewFlagSet("delete", flag.ContinueOnError),
+	}
+	c.Flags.StringVar(&c.User, "user", "", "username to delete")
+	return c, nil
+}
+
+func (c *GitUsersDeleteCommand) Name() string {
+	return c.Flags.Name()
+}
+
+func (c *GitUsersDeleteCommand) Parent() Command {
+	return c.parent
+}
+
+func (c *GitUsersDeleteCommand) FlagSet() *flag.FlagSet {
+	return c.Flags
+}
+
+func (c *GitUsersDeleteCommand) Subcommands() []Command {
+	return nil
+}
+
+func (c *GitUsersDeleteCommand) Execute(args []string) error {
+	c.FlagSet().Usage = func() { printHelp(c, nil) }
+	if err := c.FlagSet().Parse(args); err != nil {
+		printHelp(c, err)
+		return err
+	}
+	if c.User == "" {
+		err := fmt.Errorf("user is required")
+		printHelp(c, err)
+		return err
+	}
+	if _, err := c.Parent().(*GitUsersCommand).config(); err != nil {
+		printHelp(c, err)
+		return err
+	}
+
+	if err := (gobookmarks.GitProvider{}).DeleteAccount(context.Background(), c.User); err != nil {
+		return fmt.Errorf("delete %s: %w", c.User, err)
+	}
+	fmt.Printf("user %s has been deleted\n", c.User)
+	return nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	gobookmarks "github.com/arran4/gobookmarks"
+)
+
+type GitUsersListCommand struct {
+	parent Command
+	Flags  *flag.FlagSet
+}
+
+func (uc *GitUsersCommand) NewGitUsersListCommand() (*GitUsersListCommand, error) {
+	c := &GitUsersListCommand{
+		parent: uc,
+		Flags:  flag.NewFlagSet("list", flag.ContinueOnError),
+	}
+	return c, nil
+}
+
+func (c *GitUsersListCommand) Name() string {
+	return c.Flags.Name()
+}
+
+func (c *GitUsersListCommand) Parent() Command {
+	return c.parent
+}
+
+func (c *GitUsersListCommand) FlagSet() *flag.FlagSet {
+	return c.Flags
+}
+
+func (c *GitUsersListCommand) Subcommands() []Command {
+	return nil
+}
+
+func formatUserTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
+
+func (c *GitUsersListCommand) Execute(args []string) error {
+	c.FlagSet().Usage = func() { printHelp(c, nil) }
+	if err := c.FlagSet().Parse(args); err != nil {
+		printHelp(c, err)
+		return err
+	}
+	if _, err := c.Parent().(*GitUsersCommand).config(); err != nil {
+		printHelp(c, err)
+		return err
+	}
+
+	accounts, err := gobookmarks.GitProvider{}.Accounts(context.Background())
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "USER\tCREATED\tLAST LOGIN\tPASSWORD\tDISABLED")
+	for _, a := range accounts {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%t\t%t\n", a.User, formatUserTime(a.Created), formatUserTime(a.LastLogin), a.HasPassword, a.Disabled)
+	}
+	return tw.Flush()
+}
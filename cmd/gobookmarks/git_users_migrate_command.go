@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	gobookmarks "github.com/arran4/gobookmarks"
+)
+
+type GitUsersMigrateCommand struct {
+	parent Command
+	Flags  *flag.FlagSet
+
+	Logs  stringListFlag
+	Users stringListFlag
+	Audit bool
+}
+
+func (uc *GitUsersCommand) NewGitUsersMigrateCommand() (*GitUsersMigrateCommand, error) {
+	c := &GitUsersMigrateCommand{
+		parent: uc,
+		Flags:  flag.NewFlagSet("migrate", flag.ContinueOnError),
+	}
+	c.Flags.Var(&c.Logs, "log", "server log file to read logins from (repeatable)")
+	c.Flags.Var(&c.Users, "user", "username to register if its repository exists (repeatable)")
+	c.Flags.BoolVar(&c.Audit, "audit", true, "read logins from the audit_log table when a database is configured")
+	return c, nil
+}
+
+func (c *GitUsersMigrateCommand) Name() string {
+	return c.Flags.Name()
+}
+
+func (c *GitUsersMigrateCommand) Parent() Command {
+	return c.parent
+}
+
+func (c *GitUsersMigrateCommand) FlagSet() *flag.FlagSet {
+	return c.Flags
+}
+
+func (c *GitUsersMigrateCommand) Subcommands() []Command {
+	return nil
+}
+
+func (c *GitUsersMigrateCommand) Execute(args []string) error {
+	c.FlagSet().Usage = func() { printHelp(c, nil) }
+	if err := c.FlagSet().Parse(args); err != nil {
+		printHelp(c, err)
+		return err
+	}
+	cfg, err := c.Parent().(*GitUsersCommand).config()
+	if err != nil {
+		printHelp(c, err)
+		return err
+	}
+
+	ctx := context.Background()
+	var events []gobookmarks.LoginEvent
+	for _, u := range c.Users {
+		events = append(events, gobookmarks.LoginEvent{User: u, Provider: "git"})
+	}
+	for _, path := range c.Logs {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		found, err := gobookmarks.ParseLoginLog(f)
+		_ = f.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		events = append(events, found...)
+	}
+	if c.Audit && cfg.DBConnectionProvider != "" && cfg.DBConnectionString != "" {
+		found, err := gobookmarks.AuditLoginEvents(ctx, "git")
+		if err != nil {
+			return fmt.Errorf("audit log: %w", err)
+		}
+		events = append(events, found...)
+	}
+
+	registered, err := gobookmarks.GitProvider{}.RegisterLogins(ctx, events)
+	for _, u := range registered {
+		fmt.Printf("registered %s\n", u)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%d users registered\n", len(registered))
+	return nil
+}
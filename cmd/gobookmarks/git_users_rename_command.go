@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	gobookmarks "github.com/arran4/gobookmarks"
+)
+
+type GitUsersRenameCommand struct {
+	parent Command
+	Flags  *flag.FlagSet
+
+	User string
+	To   string
+}
+
+func (uc *GitUsersCommand) NewGitUsersRenameCommand() (*GitUsersRenameCommand, error) {
+	c := &GitUsersRenameCommand{
+		parent: uc,
+		Flags:  flag.NewFlagSet("rename", flag.ContinueOnError),
+	}
+	c.Flags.StringVar(&c.User, "user", "", "current username")
+	c.Flags.StringVar(&c.To, "to", "", "new username")
+	return c, nil
+}
+
+func (c *GitUsersRenameCommand) Name() string {
+	return c.Flags.Name()
+}
+
+func (c *GitUsersRenameCommand) Parent() Command {
+	return c.parent
+}
+
+func (c *GitUsersRenameCommand) FlagSet() *flag.FlagSet {
+	return c.Flags
+}
+
+func (c *GitUsersRenameCommand) Subcommands() []Command {
+	return nil
+}
+
+func (c *GitUsersRenameCommand) Execute(args []string) error {
+	c.FlagSet().Usage = func() { printHelp(c, nil) }
+	if err := c.FlagSet().Parse(args); err != nil {
+		printHelp(c, err)
+		return err
+	}
+	if c.User == "" || c.To == "" {
+		err := fmt.Errorf("user and to are required")
+		printHelp(c, err)
+		return err
+	}
+	if gobookmarks.IsReservedUsername(c.To) {
+		err := fmt.Errorf("%s is a reserved username", c.To)
+		printHelp(c, err)
+		return err
+	}
+	if _, err := c.Parent().(*GitUsersCommand).config(); err != nil {
+		printHelp(c, err)
+		return err
+	}
+
+	if err := (gobookmarks.GitProvider{}).RenameAccount(context.Background(), c.User, c.To); err != nil {
+		return fmt.Errorf("rename %s: %w", c.User, err)
+	}
+	fmt.Printf("user %s has been renamed to %s\n", c.User, c.To)
+	return nil
+}
@@ -34,15 +34,19 @@ type RootCommand struct {
 	Flags       *flag.FlagSet
 	ConfigPath  string
 	cfg         gobookmarks.Configuration
+	sources     gobookmarks.ConfigSources
 	VersionInfo VersionInfo
 
 	ServeCmd       *ServeCommand
 	VersionCmd     *VersionCommand
 	DbCmd          *DbCommand
+	GitCmd         *GitCommand
 	VerifyFileCmd  *VerifyFileCommand
 	VerifyCredsCmd *VerifyCredsCommand
 	ImportCmd      *ImportCommand
 	ExportCmd      *ExportCommand
+	MigrateCmd     *MigrateCommand
+	ConfigCmd      *ConfigCommand
 	TestCmd        *TestCommand
 	HelpCmd        *HelpCommand
 }
@@ -57,10 +61,13 @@ func NewRootCommand() *RootCommand {
 	rc.ServeCmd, _ = rc.NewServeCommand()
 	rc.VersionCmd, _ = rc.NewVersionCommand()
 	rc.DbCmd, _ = rc.NewDbCommand()
+	rc.GitCmd, _ = rc.NewGitCommand()
 	rc.VerifyFileCmd, _ = rc.NewVerifyFileCommand()
 	rc.VerifyCredsCmd, _ = rc.NewVerifyCredsCommand()
 	rc.ImportCmd, _ = rc.NewImportCommand()
 	rc.ExportCmd, _ = rc.NewExportCommand()
+	rc.MigrateCmd, _ = rc.NewMigrateCommand()
+	rc.ConfigCmd, _ = rc.NewConfigCommand()
 	rc.TestCmd, _ = rc.NewTestCommand()
 	rc.HelpCmd = NewHelpCommand(rc)
 	return rc
@@ -79,7 +86,7 @@ func (c *RootCommand) FlagSet() *flag.FlagSet {
 }
 
 func (c *RootCommand) Subcommands() []Command {
-	return []Command{c.ServeCmd, c.VersionCmd, c.DbCmd, c.VerifyFileCmd, c.VerifyCredsCmd, c.ImportCmd, c.ExportCmd, c.TestCmd, c.HelpCmd}
+	return []Command{c.ServeCmd, c.VersionCmd, c.DbCmd, c.GitCmd, c.VerifyFileCmd, c.VerifyCredsCmd, c.ImportCmd, c.ExportCmd, c.MigrateCmd, c.ConfigCmd, c.TestCmd, c.HelpCmd}
 }
 
 func (c *RootCommand) Execute(args []string) error {
@@ -102,7 +109,7 @@ func (c *RootCommand) Execute(args []string) error {
 		return c.VersionCmd.Execute(remaining[1:])
 	case c.TestCmd.Name():
 		return c.TestCmd.Execute(remaining[1:])
-	case c.ServeCmd.Name(), c.DbCmd.Name(), c.VerifyFileCmd.Name(), c.VerifyCredsCmd.Name(), c.ImportCmd.Name(), c.ExportCmd.Name():
+	case c.ServeCmd.Name(), c.DbCmd.Name(), c.GitCmd.Name(), c.VerifyFileCmd.Name(), c.VerifyCredsCmd.Name(), c.ImportCmd.Name(), c.ExportCmd.Name(), c.MigrateCmd.Name(), c.ConfigCmd.Name():
 		loadCfg = true
 	default:
 		err := fmt.Errorf("unknown command: %s", remaining[0])
@@ -121,6 +128,8 @@ func (c *RootCommand) Execute(args []string) error {
 		return c.ServeCmd.Execute(remaining[1:])
 	case c.DbCmd.Name():
 		return c.DbCmd.Execute(remaining[1:])
+	case c.GitCmd.Name():
+		return c.GitCmd.Execute(remaining[1:])
 	case c.VerifyFileCmd.Name():
 		return c.VerifyFileCmd.Execute(remaining[1:])
 	case c.VerifyCredsCmd.Name():
@@ -129,6 +138,10 @@ func (c *RootCommand) Execute(args []string) error {
 		return c.ImportCmd.Execute(remaining[1:])
 	case c.ExportCmd.Name():
 		return c.ExportCmd.Execute(remaining[1:])
+	case c.MigrateCmd.Name():
+		return c.MigrateCmd.Execute(remaining[1:])
+	case c.ConfigCmd.Name():
+		return c.ConfigCmd.Execute(remaining[1:])
 	}
 	return nil
 }
@@ -142,15 +155,12 @@ func (c *RootCommand) loadConfig() error {
 		log.Printf("unable to load env file %s: %v", envPath, err)
 	}
 
-	c.cfg = gobookmarks.Configuration{
-		GithubClientID:       os.Getenv("GITHUB_CLIENT_ID"),
-		GithubSecret:         os.Getenv("GITHUB_SECRET"),
-		GitlabClientID:       os.Getenv("GITLAB_CLIENT_ID"),
-		GitlabSecret:         os.Getenv("GITLAB_SECRET"),
-		ExternalURL:          os.Getenv("EXTERNAL_URL"),
-		DBConnectionProvider: os.Getenv("DB_CONNECTION_PROVIDER"),
-		DBConnectionString:   os.Getenv("DB_CONNECTION_STRING"),
+	envCfg, sources, err := gobookmarks.LoadConfigEnv(os.LookupEnv)
+	if err != nil {
+		return fmt.Errorf("unable to load config from environment: %w", err)
 	}
+	c.cfg = envCfg
+	c.sources = sources
 
 	configPath := gobookmarks.DefaultConfigPath()
 	if envCfg := os.Getenv("GOBM_CONFIG_FILE"); envCfg != "" {
@@ -167,6 +177,7 @@ func (c *RootCommand) loadConfig() error {
 	}
 	if found {
 		gobookmarks.MergeConfig(&c.cfg, fileCfg)
+		c.sources.Track(fileCfg, "file "+configPath)
 	} else if cfgSpecified {
 		return fmt.Errorf("unable to load config file %s: not found", configPath)
 	}
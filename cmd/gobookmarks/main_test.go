@@ -1,10 +1,13 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -80,3 +83,35 @@ func TestLoadConfigUsesExternalURL(t *testing.T) {
 		t.Fatalf("external url not loaded from env: %q", rc.cfg.ExternalURL)
 	}
 }
+
+func TestLoadConfigEnvAndCheck(t *testing.T) {
+	rc := NewRootCommand()
+	t.Setenv("GOBM_CONFIG_FILE", "")
+	rc.ConfigPath = filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(rc.ConfigPath, []byte("title: From File\nprovider_order: [sql]\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GOBOOKMARKS_TITLE", "From Env")
+	t.Setenv("GOBOOKMARKS_SESSION_KEY", "hunter2")
+
+	if err := rc.loadConfig(); err != nil {
+		t.Fatalf("loadConfig returned error: %v", err)
+	}
+	if rc.cfg.Title != "From File" || rc.cfg.SessionKey != "hunter2" {
+		t.Fatalf("cfg = %+v", rc.cfg)
+	}
+
+	var out bytes.Buffer
+	err := checkConfig(&out, rc.cfg, rc.sources, false)
+	if err == nil {
+		t.Fatalf("contradiction not reported:\n%s", out.String())
+	}
+	for _, want := range []string{"file " + rc.ConfigPath, "<redacted>", "env GOBOOKMARKS_SESSION_KEY", "provider_order lists sql"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("missing %q in\n%s", want, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "hunter2") {
+		t.Errorf("secret printed:\n%s", out.String())
+	}
+}
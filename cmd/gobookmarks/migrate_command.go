@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	gobookmarks "github.com/arran4/gobookmarks"
+	"golang.org/x/oauth2"
+)
+
+type MigrateCommand struct {
+	parent    Command
+	Flags     *flag.FlagSet
+	From      string
+	To        string
+	User      string
+	ToUser    string
+	FromToken string
+	ToToken   string
+	StatePath string
+	DryRun    bool
+}
+
+func (rc *RootCommand) NewMigrateCommand() (*MigrateCommand, error) {
+	c := &MigrateCommand{
+		parent: rc,
+		Flags:  flag.NewFlagSet("migrate", flag.ContinueOnError),
+	}
+	c.Flags.StringVar(&c.From, "from", "", "provider to copy bookmarks from")
+	c.Flags.StringVar(&c.To, "to", "", "provider to copy bookmarks to")
+	c.Flags.StringVar(&c.User, "user", "", "user to migrate")
+	c.Flags.StringVar(&c.ToUser, "to-user", "", "destination user (defaults to --user)")
+	c.Flags.StringVar(&c.FromToken, "from-token", "", "access token for the source provider")
+	c.Flags.StringVar(&c.ToToken, "to-token", "", "access token for the destination provider")
+	c.Flags.StringVar(&c.StatePath, "state", "", "progress file used to resume (default gobookmarks-migrate-<user>.json)")
+	c.Flags.BoolVar(&c.DryRun, "dry-run", false, "print what would be migrated without writing")
+	return c, nil
+}
+
+func (c *MigrateCommand) Name() string {
+	return c.Flags.Name()
+}
+
+func (c *MigrateCommand) Parent() Command {
+	return c.parent
+}
+
+func (c *MigrateCommand) FlagSet() *flag.FlagSet {
+	return c.Flags
+}
+
+func (c *MigrateCommand) Subcommands() []Command {
+	return nil
+}
+
+func (c *MigrateCommand) Execute(args []string) error {
+	c.FlagSet().Usage = func() { printHelp(c, nil) }
+	if err := c.FlagSet().Parse(args); err != nil {
+		printHelp(c, err)
+		return err
+	}
+	if forwardHelpIfRequested(c, args) {
+		return nil
+	}
+	if c.From == "" || c.To == "" || c.User == "" {
+		err := fmt.Errorf("--from, --to and --user are required")
+		printHelp(c, err)
+		return err
+	}
+	if c.ToUser == "" {
+		c.ToUser = c.User
+	}
+	if c.From == c.To && c.User == c.ToUser {
+		err := fmt.Errorf("source and destination are the same")
+		printHelp(c, err)
+		return err
+	}
+	from := gobookmarks.GetProvider(c.From)
+	to := gobookmarks.GetProvider(c.To)
+	if from == nil || to == nil {
+		err := fmt.Errorf("unknown provider; available: %s", strings.Join(gobookmarks.ProviderNames(), ", "))
+		printHelp(c, err)
+		return err
+	}
+	gobookmarks.Config = c.parent.(*RootCommand).cfg
+
+	if c.StatePath == "" {
+		c.StatePath = fmt.Sprintf("gobookmarks-migrate-%s.json", c.User)
+	}
+	state, err := loadMigrateState(c.StatePath)
+	if err != nil {
+		printHelp(c, err)
+		return err
+	}
+
+	opts := gobookmarks.MigrateOptions{
+		User:      c.User,
+		DestUser:  c.ToUser,
+		FromToken: migrateToken(c.FromToken),
+		ToToken:   migrateToken(c.ToToken),
+		DryRun:    c.DryRun,
+		State:     state,
+		Logf: func(format string, args ...any) {
+			fmt.Printf(format+"\n", args...)
+		},
+	}
+	if !c.DryRun {
+		opts.Checkpoint = func(st *gobookmarks.MigrateState) error {
+			return saveMigrateState(c.StatePath, st)
+		}
+	}
+	summary, err := gobookmarks.MigrateHistory(context.Background(), from, to, opts)
+	if err != nil {
+		if !c.DryRun {
+			fmt.Fprintf(os.Stderr, "progress saved to %s; rerun the same command to resume\n", c.StatePath)
+		}
+		return err
+	}
+
+	if c.DryRun {
+		fmt.Printf("dry run: %s/%s -> %s/%s\n", c.From, c.User, c.To, c.ToUser)
+		fmt.Printf("branches: %s\n", strings.Join(summary.Branches, ", "))
+		fmt.Printf("tags: %s\n", strings.Join(summary.Tags, ", "))
+		fmt.Printf("commits: %d (%d already migrated)\n", summary.Commits, summary.Skipped)
+		return nil
+	}
+	fmt.Printf("migrated %d commits (%d already present), %d branches, %d tags\n",
+		summary.Replayed, summary.Skipped, len(summary.Branches), len(summary.Tags)-len(summary.Unmapped))
+	if len(summary.Unmapped) > 0 {
+		fmt.Printf("tags not migrated: %s\n", strings.Join(summary.Unmapped, ", "))
+	}
+	fmt.Printf("progress recorded in %s\n", c.StatePath)
+	return nil
+}
+
+func migrateToken(s string) *oauth2.Token {
+	if s == "" {
+		return nil
+	}
+	return &oauth2.Token{AccessToken: s}
+}
+
+func loadMigrateState(path string) (*gobookmarks.MigrateState, error) {
+	st := gobookmarks.NewMigrateState()
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return st, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, st); err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	return st, nil
+}
+
+func saveMigrateState(path string, st *gobookmarks.MigrateState) error {
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
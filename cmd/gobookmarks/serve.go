@@ -19,7 +19,7 @@ import (
 	"github.com/gorilla/securecookie"
 	"github.com/gorilla/sessions"
 	"io"
-	"log"
+	"log/slog"
 	"math/big"
 	"net/http"
 	"net/url"
@@ -30,6 +30,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 )
 
@@ -54,7 +55,23 @@ type ServeCommand struct {
 	DbProvider           stringFlag
 	DbConn               stringFlag
 	SessionKey           stringFlag
+	SessionStore         stringFlag
 	ProviderOrder        stringFlag
+	MetricsListen        stringFlag
+	LogLevel             stringFlag
+	LogFormat            stringFlag
+	ReadTimeout          stringFlag
+	WriteTimeout         stringFlag
+	IdleTimeout          stringFlag
+	ShutdownTimeout      stringFlag
+	MaxHeaderBytes       stringFlag
+	MaxBodyBytes         stringFlag
+	ContentSecurity      stringFlag
+	TLSCert              stringFlag
+	TLSKey               stringFlag
+	HTTPListen           stringFlag
+	HTTPSListen          stringFlag
+	RedirectHTTP         boolFlag
 	CSSColumns           boolFlag
 	NoFooter             boolFlag
 	DevMode              boolFlag
@@ -84,7 +101,23 @@ func (rc *RootCommand) NewServeCommand() (*ServeCommand, error) {
 	c.Flags.Var(&c.DbProvider, "db-provider", "SQL driver name")
 	c.Flags.Var(&c.DbConn, "db-conn", "SQL connection string")
 	c.Flags.Var(&c.SessionKey, "session-key", "session cookie key")
+	c.Flags.Var(&c.SessionStore, "session-store", "where sessions are kept: cookie, sql or file")
 	c.Flags.Var(&c.ProviderOrder, "provider-order", "comma-separated provider order")
+	c.Flags.Var(&c.MetricsListen, "metrics-listen", "address to serve /metrics on instead of the main site")
+	c.Flags.Var(&c.LogLevel, "log-level", "log level: debug, info, warn or error")
+	c.Flags.Var(&c.LogFormat, "log-format", "log format: text or json")
+	c.Flags.Var(&c.ReadTimeout, "read-timeout", "seconds allowed to read a request")
+	c.Flags.Var(&c.WriteTimeout, "write-timeout", "seconds allowed to write a response")
+	c.Flags.Var(&c.IdleTimeout, "idle-timeout", "seconds an idle keep-alive connection is kept open")
+	c.Flags.Var(&c.ShutdownTimeout, "shutdown-timeout", "seconds to wait for in-flight requests and saves on shutdown")
+	c.Flags.Var(&c.MaxHeaderBytes, "max-header-bytes", "max size of request headers in bytes")
+	c.Flags.Var(&c.MaxBodyBytes, "max-body-bytes", "max size of request bodies in bytes")
+	c.Flags.Var(&c.ContentSecurity, "content-security-policy", "Content-Security-Policy header, or none")
+	c.Flags.Var(&c.TLSCert, "tls-cert", "PEM certificate file for serving HTTPS")
+	c.Flags.Var(&c.TLSKey, "tls-key", "PEM private key file for serving HTTPS")
+	c.Flags.Var(&c.HTTPListen, "http-listen", "address of the HTTP listener")
+	c.Flags.Var(&c.HTTPSListen, "https-listen", "address of the HTTPS listener")
+	c.Flags.Var(&c.RedirectHTTP, "redirect-http", "redirect the HTTP listener to HTTPS when TLS is configured")
 	c.Flags.Var(&c.CSSColumns, "css-columns", "use CSS columns")
 	c.Flags.Var(&c.NoFooter, "no-footer", "disable footer on pages")
 	c.Flags.Var(&c.DevMode, "dev-mode", "enable dev mode helpers")
@@ -186,18 +219,75 @@ func (c *ServeCommand) Execute(args []string) error {
 	if c.SessionKey.set {
 		cfg.SessionKey = c.SessionKey.value
 	}
+	if c.SessionStore.set {
+		cfg.SessionStore = c.SessionStore.value
+	}
 	if c.ProviderOrder.set {
 		cfg.ProviderOrder = splitList(c.ProviderOrder.value)
 	}
+	if c.MetricsListen.set {
+		cfg.MetricsListen = c.MetricsListen.value
+	}
+	if c.LogLevel.set {
+		cfg.LogLevel = c.LogLevel.value
+	}
+	if c.LogFormat.set {
+		cfg.LogFormat = c.LogFormat.value
+	}
+	for _, f := range []struct {
+		flag *stringFlag
+		dst  *int
+	}{
+		{&c.ReadTimeout, &cfg.ReadTimeout},
+		{&c.WriteTimeout, &cfg.WriteTimeout},
+		{&c.IdleTimeout, &cfg.IdleTimeout},
+		{&c.ShutdownTimeout, &cfg.ShutdownTimeout},
+		{&c.MaxHeaderBytes, &cfg.MaxHeaderBytes},
+	} {
+		if f.flag.set {
+			if i, err := strconv.Atoi(f.flag.value); err == nil {
+				*f.dst = i
+			}
+		}
+	}
+	if c.MaxBodyBytes.set {
+		if i, err := strconv.ParseInt(c.MaxBodyBytes.value, 10, 64); err == nil {
+			cfg.MaxBodyBytes = i
+		}
+	}
+	if c.ContentSecurity.set {
+		cfg.ContentSecurityPolicy = c.ContentSecurity.value
+	}
+	if c.TLSCert.set {
+		cfg.TLSCertFile = c.TLSCert.value
+	}
+	if c.TLSKey.set {
+		cfg.TLSKeyFile = c.TLSKey.value
+	}
+	if c.HTTPListen.set {
+		cfg.HTTPListen = c.HTTPListen.value
+	}
+	if c.HTTPSListen.set {
+		cfg.HTTPSListen = c.HTTPSListen.value
+	}
+	if c.RedirectHTTP.set {
+		cfg.RedirectHTTP = c.RedirectHTTP.value
+	}
 
 	if c.DumpConfig.value {
 		data, _ := json.MarshalIndent(cfg, "", "  ")
 		fmt.Println(string(data))
 		return nil
 	}
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid configuration (see gobookmarks config check): %w", err)
+	}
 
 	// Update global Config
 	gobookmarks.Config = cfg
+	if err := gobookmarks.ConfigureLogging(os.Stderr); err != nil {
+		return err
+	}
 	if gobookmarks.Config.FaviconCacheSize == 0 {
 		gobookmarks.Config.FaviconCacheSize = gobookmarks.DefaultFaviconCacheSize
 	}
@@ -214,26 +304,71 @@ func (c *ServeCommand) Execute(args []string) error {
 
 	redirectURL := gobookmarks.Config.GetOauthRedirectURL()
 
-	// Update SessionName if needed (though it's in Config now, SessionStore logic uses Config.SessionName)
-	// But SessionStore is global.
-	// We need to initialize SessionStore
-	gobookmarks.SessionStore = sessions.NewCookieStore(loadSessionKey(gobookmarks.Config))
-
+	sessionKey, err := loadSessionKey(gobookmarks.Config)
+	if err != nil {
+		return err
+	}
+	store, err := gobookmarks.NewConfiguredSessionStore(sessionKey)
+	if err != nil {
+		return err
+	}
+	gobookmarks.SessionStore = store
+	if s, ok := store.(*gobookmarks.ServerSessionStore); ok {
+		go s.Cleanup(context.Background(), time.Hour)
+	}
+	attempts, err := gobookmarks.NewConfiguredLoginAttemptStore()
+	if err != nil {
+		return err
+	}
+	gobookmarks.LoginAttempts = attempts
+	if attempts != nil {
+		go gobookmarks.CleanupLoginAttempts(context.Background(), attempts, 10*time.Minute)
+	}
+	settings, err := gobookmarks.NewConfiguredSettingsStore()
+	if err != nil {
+		return err
+	}
+	gobookmarks.Settings = settings
 	if len(gobookmarks.ProviderNames()) == 0 {
 		return errors.New("no providers compiled")
 	}
+	if cfg.OIDCIssuer != "" {
+		storage := cfg.GetOIDCStorage()
+		if storage != "sql" && storage != "git" {
+			return fmt.Errorf("oidc_storage must be sql or git, not %q", storage)
+		}
+	}
+	if cfg.ProxyAuthHeader != "" {
+		storage := cfg.GetProxyAuthStorage()
+		if storage != "sql" && storage != "git" {
+			return fmt.Errorf("proxy_auth_storage must be sql or git, not %q", storage)
+		}
+		if len(cfg.TrustedProxies) == 0 {
+			return errors.New("proxy_auth_header requires trusted_proxies")
+		}
+	}
+	if err := gobookmarks.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		return err
+	}
 	if len(gobookmarks.ConfiguredProviderNames()) == 0 {
 		return errors.New("no providers available")
 	}
 
 	r := mux.NewRouter()
 
+	r.Use(gobookmarks.RequestIDMiddleware)
+	r.Use(gobookmarks.AccessLogMiddleware)
+	r.Use(gobookmarks.MetricsMiddleware)
 	r.Use(gobookmarks.UserAdderMiddleware)
+	r.Use(gobookmarks.ProxyAuthMiddleware)
 	r.Use(gobookmarks.CoreAdderMiddleware)
+	r.Use(gobookmarks.CSRFMiddleware)
 
 	r.HandleFunc("/main.css", func(writer http.ResponseWriter, _ *http.Request) {
+		writer.Header().Set("Content-Type", "text/css")
 		_, _ = writer.Write(gobookmarks.GetMainCSSData())
 	}).Methods("GET")
+	r.PathPrefix("/js/").Handler(http.StripPrefix("/js/", http.FileServer(http.FS(gobookmarks.GetScripts())))).Methods("GET")
 	r.HandleFunc("/favicon.ico", func(writer http.ResponseWriter, _ *http.Request) {
 		_, _ = writer.Write(gobookmarks.GetFavicon())
 	}).Methods("GET")
@@ -277,6 +412,7 @@ func (c *ServeCommand) Execute(args []string) error {
 	r.HandleFunc("/moveCategory", runHandlerChain(gobookmarks.CategoryMoveBeforeAction)).Methods("POST").MatcherFunc(RequiresAnAccount())
 	r.HandleFunc("/moveCategoryEnd", runHandlerChain(gobookmarks.CategoryMoveEndAction)).Methods("POST").MatcherFunc(RequiresAnAccount())
 	r.HandleFunc("/moveCategoryNewColumn", runHandlerChain(gobookmarks.CategoryMoveNewColumnAction)).Methods("POST").MatcherFunc(RequiresAnAccount())
+	r.HandleFunc("/moveCategoryInto", runHandlerChain(gobookmarks.CategoryMoveIntoAction)).Methods("POST").MatcherFunc(RequiresAnAccount())
 
 	r.HandleFunc("/editTab", runTemplate("loginPage.gohtml")).Methods("GET").MatcherFunc(gorillamuxlogic.Not(RequiresAnAccount()))
 	r.HandleFunc("/editTab", runHandlerChain(gobookmarks.EditTabPage)).Methods("GET").MatcherFunc(RequiresAnAccount())
@@ -303,103 +439,190 @@ func (c *ServeCommand) Execute(args []string) error {
 	r.HandleFunc("/tab/{tab}/movePage", runHandlerChain(gobookmarks.MovePageAction)).Methods("POST").MatcherFunc(RequiresAnAccount())
 	r.HandleFunc("/moveEntry", runHandlerChain(gobookmarks.MoveEntryAction)).Methods("POST").MatcherFunc(RequiresAnAccount())
 	r.HandleFunc("/tab/{tab}/moveEntry", runHandlerChain(gobookmarks.MoveEntryAction)).Methods("POST").MatcherFunc(RequiresAnAccount())
+	r.HandleFunc("/moveEntryTo", runHandlerChain(gobookmarks.EntryMoveToAction)).Methods("POST").MatcherFunc(RequiresAnAccount())
+	r.HandleFunc("/copyEntryTo", runHandlerChain(gobookmarks.EntryCopyToAction)).Methods("POST").MatcherFunc(RequiresAnAccount())
 
+	r.HandleFunc("/file", runHandlerChain(gobookmarks.BookmarkFileSwitchAction, redirectToHandler("/"))).Methods("POST").MatcherFunc(RequiresAnAccount())
+
+	r.HandleFunc("/tags", runTemplate("loginPage.gohtml")).Methods("GET").MatcherFunc(gorillamuxlogic.Not(RequiresAnAccount()))
+	r.HandleFunc("/tags", runTemplate("tags.gohtml")).Methods("GET").MatcherFunc(RequiresAnAccount())
 	r.HandleFunc("/history", runTemplate("loginPage.gohtml")).Methods("GET").MatcherFunc(gorillamuxlogic.Not(RequiresAnAccount()))
 	r.HandleFunc("/history", runTemplate("history.gohtml")).Methods("GET").MatcherFunc(RequiresAnAccount())
 
 	r.HandleFunc("/history/commits", runTemplate("loginPage.gohtml")).Methods("GET").MatcherFunc(gorillamuxlogic.Not(RequiresAnAccount()))
 	r.HandleFunc("/status", runTemplate("statusPage.gohtml")).Methods("GET")
+	r.HandleFunc("/sessions", runTemplate("loginPage.gohtml")).Methods("GET").MatcherFunc(gorillamuxlogic.Not(RequiresAnAccount()))
+	r.HandleFunc("/sessions", runTemplate("sessions.gohtml")).Methods("GET").MatcherFunc(RequiresAnAccount())
+	r.HandleFunc("/sessions/revoke", runHandlerChain(gobookmarks.SessionRevokeAction, redirectToHandler("/sessions"))).Methods("POST").MatcherFunc(RequiresAnAccount())
+	r.HandleFunc("/sessions/revoke-others", runHandlerChain(gobookmarks.SessionRevokeOthersAction, redirectToHandler("/sessions"))).Methods("POST").MatcherFunc(RequiresAnAccount())
+	r.HandleFunc("/settings", runTemplate("loginPage.gohtml")).Methods("GET").MatcherFunc(gorillamuxlogic.Not(RequiresAnAccount()))
+	r.HandleFunc("/settings", runHandlerChain(gobookmarks.SettingsPage)).Methods("GET").MatcherFunc(RequiresAnAccount())
+	r.HandleFunc("/settings/password", runHandlerChain(gobookmarks.SettingsPasswordAction, redirectToHandler("/settings"))).Methods("POST").MatcherFunc(RequiresAnAccount())
+	r.HandleFunc("/settings/email", runHandlerChain(gobookmarks.SettingsEmailAction, redirectToHandler("/settings"))).Methods("POST").MatcherFunc(RequiresAnAccount())
+	r.HandleFunc("/settings/export", runHandlerChain(gobookmarks.SettingsExportAction)).Methods("GET").MatcherFunc(RequiresAnAccount())
+	r.HandleFunc("/settings/delete", runHandlerChain(gobookmarks.SettingsDeleteAction, redirectToHandler("/"))).Methods("POST").MatcherFunc(RequiresAnAccount())
+	r.HandleFunc("/2fa", runTemplate("loginPage.gohtml")).Methods("GET").MatcherFunc(gorillamuxlogic.Not(RequiresAnAccount()))
+	r.HandleFunc("/2fa", runHandlerChain(gobookmarks.TwoFactorPage)).Methods("GET").MatcherFunc(RequiresAnAccount())
+	r.HandleFunc("/2fa/setup", runHandlerChain(gobookmarks.TwoFactorSetupAction, redirectToHandler("/2fa"))).Methods("POST").MatcherFunc(RequiresAnAccount())
+	r.HandleFunc("/2fa/confirm", runHandlerChain(gobookmarks.TwoFactorConfirmAction)).Methods("POST").MatcherFunc(RequiresAnAccount())
+	r.HandleFunc("/2fa/recovery", runHandlerChain(gobookmarks.TwoFactorRecoveryAction)).Methods("POST").MatcherFunc(RequiresAnAccount())
+	r.HandleFunc("/2fa/disable", runHandlerChain(gobookmarks.TwoFactorDisableAction, redirectToHandler("/2fa"))).Methods("POST").MatcherFunc(RequiresAnAccount())
+	r.HandleFunc("/admin", runTemplate("loginPage.gohtml")).Methods("GET").MatcherFunc(gorillamuxlogic.Not(RequiresAnAccount()))
+	r.HandleFunc("/admin", runHandlerChain(gobookmarks.AdminPage)).Methods("GET").MatcherFunc(RequiresAnAccount())
+	r.HandleFunc("/admin/disable", runHandlerChain(gobookmarks.AdminDisableAction, redirectToHandler("/admin"))).Methods("POST").MatcherFunc(RequiresAnAccount())
+	r.HandleFunc("/admin/enable", runHandlerChain(gobookmarks.AdminEnableAction, redirectToHandler("/admin"))).Methods("POST").MatcherFunc(RequiresAnAccount())
+	r.HandleFunc("/admin/delete", runHandlerChain(gobookmarks.AdminDeleteAction, redirectToHandler("/admin"))).Methods("POST").MatcherFunc(RequiresAnAccount())
+	r.HandleFunc("/admin/reset-password", runHandlerChain(gobookmarks.AdminResetPasswordAction)).Methods("POST").MatcherFunc(RequiresAnAccount())
+	r.HandleFunc("/admin/logout", runHandlerChain(gobookmarks.AdminForceLogoutAction, redirectToHandler("/admin"))).Methods("POST").MatcherFunc(RequiresAnAccount())
+	r.HandleFunc("/admin/impersonate", runHandlerChain(gobookmarks.AdminImpersonateAction, redirectToHandler("/"))).Methods("POST").MatcherFunc(RequiresAnAccount())
+	r.HandleFunc("/admin/impersonate/stop", runHandlerChain(gobookmarks.AdminImpersonateStopAction, redirectToHandler("/admin"))).Methods("POST").MatcherFunc(RequiresAnAccount())
+	r.HandleFunc("/mirror", runHandlerChain(gobookmarks.MirrorSetAction, redirectToHandler("/status"))).Methods("POST").MatcherFunc(RequiresAnAccount())
+	r.HandleFunc("/mirror/pull", runHandlerChain(gobookmarks.MirrorPullAction, redirectToHandler("/status"))).Methods("POST").MatcherFunc(RequiresAnAccount())
 	r.HandleFunc("/history/commits", runTemplate("historyCommits.gohtml")).Methods("GET").MatcherFunc(RequiresAnAccount())
 
 	r.HandleFunc("/login", runTemplate("loginPage.gohtml")).Methods("GET")
 	r.HandleFunc("/login/git", runTemplate("gitLoginPage.gohtml")).Methods("GET")
 	r.HandleFunc("/login/git", runHandlerChain(gobookmarks.GitLoginAction, redirectToHandler("/"))).Methods("POST")
 	r.HandleFunc("/signup/git", runHandlerChain(gobookmarks.GitSignupAction, redirectToHandler("/login/git"))).Methods("POST")
+	r.HandleFunc("/login/{provider:git|sql}/forgot", runHandlerChain(gobookmarks.ForgotPasswordPage)).Methods("GET")
+	r.HandleFunc("/login/{provider:git|sql}/forgot", runHandlerChain(gobookmarks.ForgotPasswordAction)).Methods("POST")
+	r.HandleFunc("/login/{provider:git|sql}/reset", runHandlerChain(gobookmarks.ResetPasswordPage)).Methods("GET")
+	r.HandleFunc("/login/{provider:git|sql}/reset", runHandlerChain(gobookmarks.ResetPasswordAction)).Methods("POST")
 	r.HandleFunc("/login/sql", runTemplate("sqlLoginPage.gohtml")).Methods("GET")
 	r.HandleFunc("/login/sql", runHandlerChain(gobookmarks.SqlLoginAction, redirectToHandler("/"))).Methods("POST")
 	r.HandleFunc("/signup/sql", runHandlerChain(gobookmarks.SqlSignupAction, redirectToHandler("/login/sql"))).Methods("POST")
+	r.HandleFunc("/login/2fa", runTemplate("twoFactorLogin.gohtml")).Methods("GET")
+	r.HandleFunc("/login/2fa", runHandlerChain(gobookmarks.TwoFactorLoginAction, redirectToHandler("/"))).Methods("POST")
+	r.HandleFunc("/login/oidc", runHandlerChain(gobookmarks.OIDCLoginAction)).Methods("GET")
 	r.HandleFunc("/login/{provider}", runHandlerChain(gobookmarks.LoginWithProvider)).Methods("GET")
 	r.HandleFunc("/logout", runHandlerChain(gobookmarks.UserLogoutAction, runTemplate("logoutPage.gohtml"))).Methods("GET")
 	r.HandleFunc("/oauth2Callback", runHandlerChain(gobookmarks.Oauth2CallbackPage, redirectToHandler("/"))).Methods("GET")
+	r.HandleFunc("/oidcCallback", runHandlerChain(gobookmarks.OIDCCallbackAction, redirectToHandler("/"))).Methods("GET")
 
 	r.HandleFunc("/proxy/favicon", gobookmarks.FaviconProxyHandler).Methods("GET")
 
-	http.Handle("/", r)
-
-	if !fileExists("cert.pem") || !fileExists("key.pem") {
-		CreatePEMFiles()
+	site := http.NewServeMux()
+	site.Handle("/", r)
+	site.HandleFunc("/healthz", gobookmarks.HealthzHandler)
+	site.HandleFunc("/readyz", gobookmarks.ReadyzHandler)
+	if cfg.MetricsListen == "" {
+		site.HandleFunc("/metrics", gobookmarks.MetricsHandler)
 	}
+	handler := gobookmarks.SecurityHeadersMiddleware(gobookmarks.MaxBodyMiddleware(site))
 
-	log.Printf("gobookmarks: %s, commit %s, built at %s", version, commit, date)
+	slog.Info("gobookmarks starting", "version", version, "commit", commit, "built", date)
 	gobookmarks.SetVersion(version, commit, date)
-	log.Printf("Redirect URL configured to: %s", redirectURL)
-	log.Println("Server started on http://localhost:8080")
-	log.Println("Server started on https://localhost:8443")
+	slog.Info("oauth redirect configured", "url", redirectURL)
+
+	limits := cfg.ServerLimits()
+	newServer := func(addr string, h http.Handler) *http.Server {
+		return &http.Server{
+			Addr:              addr,
+			Handler:           h,
+			ReadTimeout:       limits.ReadTimeout,
+			ReadHeaderTimeout: limits.ReadHeaderTimeout,
+			WriteTimeout:      limits.WriteTimeout,
+			IdleTimeout:       limits.IdleTimeout,
+			MaxHeaderBytes:    limits.MaxHeaderBytes,
+		}
+	}
 
-	// Create a context with a cancel function
-	_, cancel := context.WithCancel(context.Background())
-	defer cancel() // Ensure cancellation when main exits
+	// On SIGINT or SIGTERM stop accepting connections, let in-flight
+	// requests and saves finish, then close the databases.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	// Create an HTTP server with a handler
-	httpServer := &http.Server{
-		Addr: ":8080",
+	var servers []*http.Server
+	serveErr := make(chan error, 3)
+	listen := func(srv *http.Server, tls bool, certFile, keyFile string) {
+		servers = append(servers, srv)
+		go func() {
+			var err error
+			if tls {
+				slog.InfoContext(ctx, "https server listening", "addr", srv.Addr)
+				err = srv.ListenAndServeTLS(certFile, keyFile)
+			} else {
+				slog.InfoContext(ctx, "http server listening", "addr", srv.Addr)
+				err = srv.ListenAndServe()
+			}
+			if err != nil && err != http.ErrServerClosed {
+				serveErr <- fmt.Errorf("server %s: %w", srv.Addr, err)
+			}
+		}()
 	}
 
-	// Create an HTTPS server with a handler
-	httpsServer := &http.Server{
-		Addr: ":8443",
+	// With metrics_listen set, /metrics moves off the main site to its own
+	// listener.
+	if cfg.MetricsListen != "" {
+		metricsMux := http.NewServeMux()
+		metricsMux.HandleFunc("/metrics", gobookmarks.MetricsHandler)
+		metricsMux.HandleFunc("/healthz", gobookmarks.HealthzHandler)
+		metricsMux.HandleFunc("/readyz", gobookmarks.ReadyzHandler)
+		listen(&http.Server{Addr: cfg.MetricsListen, Handler: metricsMux, ReadHeaderTimeout: 10 * time.Second}, false, "", "")
 	}
 
-	var sigCh chan os.Signal
-	// Handle ^C signal (SIGINT) to gracefully shut down the servers
-	go func() {
-		sigCh = make(chan os.Signal, 1)
-		signal.Notify(sigCh, os.Interrupt)
-		<-sigCh
-
-		fmt.Println("Shutting down gracefully...")
-
-		// Cancel the context to signal shutdown to both servers
-		cancel()
-
-		// Give some time for active connections to finish
-		timeout := 5 * time.Second
-		ctx, cancel := context.WithTimeout(context.Background(), timeout)
-		defer cancel()
-
-		if err := httpServer.Shutdown(ctx); err != nil {
-			log.Printf("HTTP server error during shutdown: %v", err)
-		}
-
-		if err := httpsServer.Shutdown(ctx); err != nil {
-			log.Printf("HTTPS server error during shutdown: %v", err)
+	if cfg.TLSEnabled() {
+		certs, err := gobookmarks.NewCertReloader(cfg.TLSCertFile, cfg.TLSKeyFile)
+		if err != nil {
+			return err
 		}
+		go certs.Watch(ctx, 30*time.Second)
+		hup := make(chan os.Signal, 1)
+		signal.Notify(hup, syscall.SIGHUP)
+		defer signal.Stop(hup)
+		go func() {
+			for range hup {
+				if err := certs.Reload(); err != nil {
+					slog.ErrorContext(ctx, "certificate reload failed", "file", cfg.TLSCertFile, "err", err)
+				} else {
+					slog.InfoContext(ctx, "certificate reloaded", "file", cfg.TLSCertFile)
+				}
+			}
+		}()
 
-		fmt.Println("Servers gracefully shut down.")
-	}()
-
-	wg := sync.WaitGroup{}
-	wg.Add(2)
-	// Start the HTTP server
-	go func() {
-		defer wg.Done()
-		fmt.Println("HTTP server listening on :8080...")
-		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("HTTP server error: %v", err)
+		httpsServer := newServer(cfg.GetHTTPSListen(), handler)
+		httpsServer.TLSConfig = certs.TLSConfig()
+		listen(httpsServer, true, "", "")
+		if cfg.RedirectHTTP {
+			listen(newServer(cfg.GetHTTPListen(), http.HandlerFunc(gobookmarks.HTTPSRedirectHandler)), false, "", "")
 		}
-	}()
-
-	// Start the HTTPS server (TLS/SSL)
-	go func() {
-		defer wg.Done()
-		fmt.Println("HTTPS server listening on :8443...")
-		if err := httpsServer.ListenAndServeTLS("cert.pem", "key.pem"); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("HTTPS server error: %v", err)
+	} else {
+		if !fileExists("cert.pem") || !fileExists("key.pem") {
+			if err := CreatePEMFiles(); err != nil {
+				return err
+			}
 		}
-	}()
-
+		listen(newServer(cfg.GetHTTPListen(), handler), false, "", "")
+		listen(newServer(cfg.GetHTTPSListen(), handler), true, "cert.pem", "key.pem")
+	}
+
+	var runErr error
+	select {
+	case <-ctx.Done():
+		slog.InfoContext(ctx, "shutting down gracefully")
+	case runErr = <-serveErr:
+		slog.ErrorContext(ctx, "shutting down", "err", runErr)
+	}
+	stop()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), limits.ShutdownTimeout)
+	defer cancel()
+	var wg sync.WaitGroup
+	for _, srv := range servers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := srv.Shutdown(shutdownCtx); err != nil {
+				slog.ErrorContext(shutdownCtx, "server shutdown failed", "addr", srv.Addr, "err", err)
+			}
+		}()
+	}
 	wg.Wait()
-	return nil
+	if err := gobookmarks.Shutdown(shutdownCtx); err != nil {
+		slog.ErrorContext(shutdownCtx, "shutdown failed", "err", err)
+	}
+	slog.InfoContext(shutdownCtx, "servers shut down")
+	return runErr
 }
 
 func splitList(s string) []string {
@@ -416,13 +639,13 @@ func splitList(s string) []string {
 	return out
 }
 
-func CreatePEMFiles() {
+func CreatePEMFiles() error {
 	notBefore := time.Now()
 	notAfter := notBefore.Add(365 * 24 * time.Hour) // Valid for 1 year
 
 	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
 	if err != nil {
-		log.Fatalf("Failed to generate serial number: %v", err)
+		return fmt.Errorf("generate serial number: %w", err)
 	}
 
 	template := x509.Certificate{
@@ -439,35 +662,36 @@ func CreatePEMFiles() {
 
 	priv, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
 	if err != nil {
-		log.Fatalf("Failed to generate private key: %v", err)
+		return fmt.Errorf("generate private key: %w", err)
 	}
 
 	derBytes, err := x509.CreateCertificate(rand.Reader, &template, &template, &priv.PublicKey, priv)
 	if err != nil {
-		log.Fatalf("Failed to create certificate: %v", err)
+		return fmt.Errorf("create certificate: %w", err)
 	}
 
 	certFile, err := os.Create("cert.pem")
 	if err != nil {
-		log.Fatalf("Failed to create cert.pem file: %v", err)
+		return fmt.Errorf("create cert.pem: %w", err)
 	}
 	defer func() { _ = certFile.Close() }()
 	if err := pem.Encode(certFile, &pem.Block{Type: "CERTIFICATE", Bytes: derBytes}); err != nil {
-		log.Fatalf("Failed to write data to cert.pem: %v", err)
+		return fmt.Errorf("write cert.pem: %w", err)
 	}
 
 	keyFile, err := os.Create("key.pem")
 	if err != nil {
-		log.Fatalf("Failed to create key.pem file: %v", err)
+		return fmt.Errorf("create key.pem: %w", err)
 	}
 	defer func() { _ = keyFile.Close() }()
 	privBytes, err := x509.MarshalECPrivateKey(priv)
 	if err != nil {
-		log.Fatalf("Failed to marshal private key: %v", err)
+		return fmt.Errorf("marshal private key: %w", err)
 	}
 	if err := pem.Encode(keyFile, &pem.Block{Type: "EC PRIVATE KEY", Bytes: privBytes}); err != nil {
-		log.Fatalf("Failed to write data to key.pem: %v", err)
+		return fmt.Errorf("write key.pem: %w", err)
 	}
+	return nil
 }
 
 func runHandlerChain(chain ...any) func(http.ResponseWriter, *http.Request) {
@@ -485,11 +709,11 @@ func runHandlerChain(chain ...any) func(http.ResponseWriter, *http.Request) {
 					}
 					if errors.Is(err, gobookmarks.ErrSignedOut) {
 						if logoutErr := gobookmarks.UserLogoutAction(w, r); logoutErr != nil {
-							log.Printf("logout error: %v", logoutErr)
+							slog.ErrorContext(r.Context(), "logout failed", "err", logoutErr)
 						}
 						type Data struct{ *gobookmarks.CoreData }
 						if err := gobookmarks.GetCompiledTemplates(gobookmarks.NewFuncs(r)).ExecuteTemplate(w, "logoutPage.gohtml", Data{r.Context().Value(gobookmarks.ContextValues("coreData")).(*gobookmarks.CoreData)}); err != nil {
-							log.Printf("Logout Template Error: %s", err)
+							slog.ErrorContext(r.Context(), "logout template failed", "err", err)
 							http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 						}
 						return
@@ -506,7 +730,7 @@ func runHandlerChain(chain ...any) func(http.ResponseWriter, *http.Request) {
 						}
 						u, parseErr := url.Parse(dest)
 						if parseErr != nil {
-							log.Printf("user error parse referer: %v", parseErr)
+							slog.WarnContext(r.Context(), "user error referer unparsable", "err", parseErr)
 						} else {
 							q := u.Query()
 							q.Set("error", uerr.Msg)
@@ -523,7 +747,7 @@ func runHandlerChain(chain ...any) func(http.ResponseWriter, *http.Request) {
 						err = serr.Err
 					}
 
-					log.Printf("handler error: %v", err)
+					slog.ErrorContext(r.Context(), "handler failed", "err", err)
 
 					type ErrorData struct {
 						*gobookmarks.CoreData
@@ -533,13 +757,13 @@ func runHandlerChain(chain ...any) func(http.ResponseWriter, *http.Request) {
 						CoreData: r.Context().Value(gobookmarks.ContextValues("coreData")).(*gobookmarks.CoreData),
 						Error:    display,
 					}); err != nil {
-						log.Printf("Error Template Error: %s", err)
+						slog.ErrorContext(r.Context(), "error template failed", "err", err)
 						http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 					}
 					return
 				}
 			default:
-				log.Panicf("unknown input: %s", reflect.TypeOf(each))
+				panic(fmt.Sprintf("unknown input: %s", reflect.TypeOf(each)))
 			}
 		}
 	}
@@ -571,11 +795,11 @@ func runTemplate(tmpl string) func(http.ResponseWriter, *http.Request) {
 
 		if errors.Is(err, gobookmarks.ErrSignedOut) {
 			if logoutErr := gobookmarks.UserLogoutAction(w, r); logoutErr != nil {
-				log.Printf("logout error: %v", logoutErr)
+				slog.ErrorContext(r.Context(), "logout failed", "err", logoutErr)
 			}
 			type LogoutData struct{ *gobookmarks.CoreData }
 			if tplErr := gobookmarks.GetCompiledTemplates(gobookmarks.NewFuncs(r)).ExecuteTemplate(w, "logoutPage.gohtml", LogoutData{data.CoreData}); tplErr != nil {
-				log.Printf("Logout Template Error: %v", tplErr)
+				slog.ErrorContext(r.Context(), "logout template failed", "err", tplErr)
 				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 			}
 			return
@@ -588,7 +812,7 @@ func runTemplate(tmpl string) func(http.ResponseWriter, *http.Request) {
 			err = serr.Err
 		}
 
-		log.Printf("Template %s error: %v", tmpl, err)
+		slog.ErrorContext(r.Context(), "template failed", "template", tmpl, "err", err)
 
 		type ErrorData struct {
 			*gobookmarks.CoreData
@@ -599,7 +823,7 @@ func runTemplate(tmpl string) func(http.ResponseWriter, *http.Request) {
 			CoreData: data.CoreData,
 			Error:    display,
 		}); tplErr != nil {
-			log.Printf("Error Template Error: %v", tplErr)
+			slog.ErrorContext(r.Context(), "error template failed", "err", tplErr)
 			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		}
 	})
@@ -645,6 +869,9 @@ func redirectToHandlerBranchToRef(toURL string) func(http.ResponseWriter, *http.
 		u, _ := url.Parse(toURL)
 		qs := u.Query()
 		qs.Set("ref", "refs/heads/"+r.PostFormValue("branch"))
+		if c := r.FormValue("collection"); c != "" {
+			qs.Set("collection", c)
+		}
 		tab := gobookmarks.TabFromRequest(r)
 		// When saving from a modal, we want to return the user to the main page or tab view
 		if v, ok := r.Context().Value(gobookmarks.ContextValues("redirectTab")).(string); ok {
@@ -724,29 +951,29 @@ func fileExists(filename string) bool {
 	return !os.IsNotExist(err)
 }
 
-func loadSessionKey(cfg gobookmarks.Configuration) []byte {
+func loadSessionKey(cfg gobookmarks.Configuration) ([]byte, error) {
 	if cfg.SessionKey != "" {
-		return []byte(cfg.SessionKey)
+		return []byte(cfg.SessionKey), nil
 	}
 
 	path := gobookmarks.DefaultSessionKeyPath(false)
 	if b, err := os.ReadFile(path); err == nil {
-		return bytes.TrimSpace(b)
+		return bytes.TrimSpace(b), nil
 	}
 
 	key := securecookie.GenerateRandomKey(32)
 	if key == nil {
-		log.Fatal("unable to generate session key")
+		return nil, errors.New("unable to generate session key")
 	}
 
 	path = gobookmarks.DefaultSessionKeyPath(true)
 	if err := os.MkdirAll(filepath.Dir(path), 0o755); err == nil {
 		if err := os.WriteFile(path, key, 0o600); err != nil {
-			log.Printf("unable to write session key file %s: %v; sessions will not persist", path, err)
+			slog.Warn("unable to write session key file; sessions will not persist", "path", path, "err", err)
 		}
 	} else {
-		log.Printf("unable to create session key directory %s: %v; sessions will not persist", filepath.Dir(path), err)
+		slog.Warn("unable to create session key directory; sessions will not persist", "path", filepath.Dir(path), "err", err)
 	}
 
-	return key
+	return key, nil
 }
@@ -39,7 +39,7 @@ func getTemplates() *template.Template {
 func renderTemplate(cmd Command, err error) string {
 	data := templateContext(cmd, err)
 
-	tpl := getTemplates().Lookup(fmt.Sprintf("%s.gotmpl", cmd.Name()))
+	tpl := lookupTemplate(cmd, "%s.gotmpl")
 	if tpl == nil {
 		return fmt.Sprintf("missing help template for %s", cmd.Name())
 	}
@@ -51,7 +51,7 @@ func renderTemplate(cmd Command, err error) string {
 }
 
 func description(cmd Command) string {
-	tpl := getTemplates().Lookup(fmt.Sprintf("description/%s", cmd.Name()))
+	tpl := lookupTemplate(cmd, "description/%s")
 	if tpl == nil {
 		return ""
 	}
@@ -64,6 +64,18 @@ func description(cmd Command) string {
 	return strings.TrimSpace(out.String())
 }
 
+// lookupTemplate finds the template named by format for cmd. A name
+// qualified by the parent, such as "git-users", is preferred so commands
+// sharing a name under different parents can have their own help.
+func lookupTemplate(cmd Command, format string) *template.Template {
+	if p := cmd.Parent(); p != nil {
+		if tpl := getTemplates().Lookup(fmt.Sprintf(format, p.Name()+"-"+cmd.Name())); tpl != nil {
+			return tpl
+		}
+	}
+	return getTemplates().Lookup(fmt.Sprintf(format, cmd.Name()))
+}
+
 func firstLine(s string) string {
 	s = strings.TrimSpace(s)
 	if idx := strings.IndexByte(s, '\n'); idx >= 0 {
@@ -303,6 +303,7 @@ https://example.com Example Link
 			_, _ = w.Write(
 				gobookmarks.GetFavicon())
 		})
+		mux.Handle("/js/", http.StripPrefix("/js/", http.FileServer(http.FS(gobookmarks.GetScripts()))))
 		// Also proxy/favicon if possible, but that might require internet or network
 		mux.HandleFunc("/proxy/favicon", func(w http.ResponseWriter, r *http.Request) {
 			// Mock or minimal implementation
@@ -0,0 +1,272 @@
+package gobookmarks
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/sessions"
+	"golang.org/x/oauth2"
+)
+
+// ErrCollectionAccess indicates the user is not a member of the collection.
+var ErrCollectionAccess = errors.New("not a member of this collection")
+
+// ErrCollectionReadOnly indicates the user may read but not edit a collection.
+var ErrCollectionReadOnly = errors.New("collection is read only")
+
+// collectionStoragePrefix prefixes the storage key of collections without an
+// explicit owner. Usernames starting with it are reserved.
+const collectionStoragePrefix = "collection:"
+
+// CollectionRole describes what a member may do with a collection.
+type CollectionRole int
+
+const (
+	CollectionRoleNone CollectionRole = iota
+	CollectionRoleReader
+	CollectionRoleEditor
+)
+
+// TabList links to the tabs of bookmarks other than those being viewed, so
+// a member's collections are listed next to their own tabs.
+type TabList struct {
+	Name string
+	// Collection is empty for the user's own bookmarks.
+	Collection string
+	ReadOnly   bool
+	Tabs       []TabInfo
+}
+
+// StorageUser returns the account the collection's bookmarks are stored under.
+func (c CollectionConfig) StorageUser() string {
+	if c.Owner != "" {
+		return c.Owner
+	}
+	return collectionStoragePrefix + c.Name
+}
+
+// Role returns the role the account user of provider has in the collection.
+// Editors are implicitly readers.
+func (c CollectionConfig) Role(provider, user string) CollectionRole {
+	if provider == "" || user == "" {
+		return CollectionRoleNone
+	}
+	account := provider + ":" + user
+	if memberOf(c.Editors, account) {
+		return CollectionRoleEditor
+	}
+	if memberOf(c.Readers, account) {
+		return CollectionRoleReader
+	}
+	return CollectionRoleNone
+}
+
+// memberOf reports whether account, written provider:user, appears in
+// members either directly or through an "@group" reference.
+func memberOf(members []string, account string) bool {
+	for _, m := range members {
+		if strings.HasPrefix(m, "@") {
+			for _, gm := range Config.Groups[strings.TrimPrefix(m, "@")] {
+				if gm == account {
+					return true
+				}
+			}
+			continue
+		}
+		if m == account {
+			return true
+		}
+	}
+	return false
+}
+
+// validMemberAccount reports whether a collection or group member entry names
+// an account as provider:user.
+func validMemberAccount(m string) bool {
+	provider, user, ok := strings.Cut(m, ":")
+	return ok && provider != "" && user != ""
+}
+
+// contextProviderName returns the name of the provider the request signed in
+// with.
+func contextProviderName(ctx context.Context) string {
+	name, _ := ctx.Value(ContextValues("provider")).(string)
+	return name
+}
+
+// FindCollection returns the configured collection with the given name.
+func FindCollection(name string) *CollectionConfig {
+	for i := range Config.Collections {
+		if Config.Collections[i].Name == name {
+			return &Config.Collections[i]
+		}
+	}
+	return nil
+}
+
+// CollectionsForUser returns the collections the account user of provider
+// can read.
+func CollectionsForUser(provider, user string) []CollectionConfig {
+	var res []CollectionConfig
+	for _, c := range Config.Collections {
+		if c.Role(provider, user) != CollectionRoleNone {
+			res = append(res, c)
+		}
+	}
+	return res
+}
+
+// IsReservedUsername reports whether the name collides with collection or OIDC
+// storage keys and therefore cannot be used for a local account.
+func IsReservedUsername(user string) bool {
+	return isCollectionStorage(user) || strings.HasPrefix(user, oidcUserPrefix)
+}
+
+// isCollectionStorage reports whether user is the storage account of a
+// collection.
+func isCollectionStorage(user string) bool {
+	if strings.HasPrefix(user, collectionStoragePrefix) {
+		return true
+	}
+	for _, c := range Config.Collections {
+		if c.StorageUser() == user {
+			return true
+		}
+	}
+	return false
+}
+
+func collectionFromContext(ctx context.Context) *CollectionConfig {
+	c, _ := ctx.Value(ContextValues("collection")).(*CollectionConfig)
+	return c
+}
+
+// collectionFromRequest returns the collection named by the request's
+// "collection" parameter, or "" for the user's own bookmarks.
+func collectionFromRequest(r *http.Request) string {
+	if name := r.URL.Query().Get("collection"); name != "" {
+		return name
+	}
+	return r.PostFormValue("collection")
+}
+
+// requestCollection returns the name of the collection the request works on.
+func requestCollection(r *http.Request) string {
+	if r == nil {
+		return ""
+	}
+	if c := collectionFromContext(r.Context()); c != nil {
+		return c.Name
+	}
+	return ""
+}
+
+// collectionHref adds the collection parameter to href when collection is
+// set.
+func collectionHref(href, collection string) string {
+	if collection == "" {
+		return href
+	}
+	return AppendQueryParams(href, "collection", collection)
+}
+
+// otherTabLists returns the tabs of the user's own bookmarks, when a
+// collection is being viewed, followed by those of every other collection
+// the user can read.
+func otherTabLists(r *http.Request) []TabList {
+	if r == nil {
+		return nil
+	}
+	cd, _ := r.Context().Value(ContextValues("coreData")).(*CoreData)
+	if cd == nil || cd.UserRef == "" || cd.Impersonating != "" {
+		return nil
+	}
+	session, _ := r.Context().Value(ContextValues("session")).(*sessions.Session)
+	var token *oauth2.Token
+	if session != nil {
+		token, _ = session.Values["Token"].(*oauth2.Token)
+	}
+	var lists []TabList
+	if cd.Collection != "" {
+		ctx := context.WithValue(r.Context(), ContextValues("collection"), (*CollectionConfig)(nil))
+		lists = append(lists, TabList{Name: "Personal", Tabs: listTabLinks(ctx, cd.UserRef, token, "")})
+	}
+	provider := contextProviderName(r.Context())
+	for _, c := range CollectionsForUser(provider, cd.UserRef) {
+		if c.Name == cd.Collection {
+			continue
+		}
+		ctx := context.WithValue(r.Context(), ContextValues("collection"), &c)
+		lists = append(lists, TabList{
+			Name:       c.Name,
+			Collection: c.Name,
+			ReadOnly:   c.Role(provider, cd.UserRef) != CollectionRoleEditor,
+			Tabs:       listTabLinks(ctx, cd.UserRef, token, c.Name),
+		})
+	}
+	return lists
+}
+
+// listTabLinks links to the tabs of the default branch of the bookmarks ctx
+// selects. Bookmarks that cannot be read still link to their first tab.
+func listTabLinks(ctx context.Context, user string, token *oauth2.Token, collection string) []TabInfo {
+	text, _, err := GetBookmarks(ctx, user, "", token)
+	if err != nil && !errors.Is(err, ErrRepoNotFound) {
+		slog.WarnContext(ctx, "tab list unavailable", "collection", collection, "err", err)
+	}
+	var tabs []TabInfo
+	for i, t := range ParseBookmarks(text) {
+		name := t.DisplayName()
+		if name == "" && i == 0 {
+			name = "Main"
+		}
+		if name != "" {
+			tabs = append(tabs, TabInfo{Index: i, Name: t.Name, IndexName: name, Href: collectionHref(TabPath(i), collection)})
+		}
+	}
+	if len(tabs) == 0 {
+		tabs = append(tabs, TabInfo{IndexName: "Main", Href: collectionHref("/", collection)})
+	}
+	return tabs
+}
+
+// bookmarkOwner maps the signed in user onto the account whose bookmarks the
+// request operates on: the user being viewed by an administrator, the active
+// collection's storage account, or otherwise the user itself.
+func bookmarkOwner(ctx context.Context, user string, write bool) (string, error) {
+	if target := impersonatedUser(ctx); target != "" {
+		if write {
+			return "", NewUserError("Bookmarks viewed as another user are read only", ErrImpersonationReadOnly)
+		}
+		return target, nil
+	}
+	c := collectionFromContext(ctx)
+	if c == nil {
+		return user, nil
+	}
+	switch c.Role(contextProviderName(ctx), user) {
+	case CollectionRoleEditor:
+	case CollectionRoleReader:
+		if write {
+			return "", NewUserError("This collection is read only", ErrCollectionReadOnly)
+		}
+	default:
+		return "", NewUserError("You are not a member of this collection", ErrCollectionAccess)
+	}
+	return c.StorageUser(), nil
+}
+
+// withCommitAuthor records the name edits should be committed under.
+func withCommitAuthor(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, ContextValues("commitAuthor"), name)
+}
+
+// commitAuthorName returns the name set by withCommitAuthor, or "" when edits
+// should use the default gobookmarks identity.
+func commitAuthorName(ctx context.Context) string {
+	name, _ := ctx.Value(ContextValues("commitAuthor")).(string)
+	return name
+}
@@ -0,0 +1,189 @@
+package gobookmarks
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/sessions"
+)
+
+func setupCollectionTest(t *testing.T) GitProvider {
+	t.Helper()
+	Config.LocalGitPath = t.TempDir()
+	Config.Groups = map[string][]string{"ops": {"git:carol"}}
+	Config.Collections = []CollectionConfig{{
+		Name:    "team",
+		Editors: []string{"git:alice", "@ops"},
+		Readers: []string{"git:bob"},
+	}}
+	t.Cleanup(func() {
+		Config.Groups = nil
+		Config.Collections = nil
+	})
+	p := GitProvider{}
+	owner := FindCollection("team").StorageUser()
+	if err := p.CreateRepo(context.Background(), owner, nil, Config.GetRepoName()); err != nil {
+		t.Fatalf("CreateRepo: %v", err)
+	}
+	if err := p.CreateBookmarks(context.Background(), owner, nil, "main", "Category: Team\nhttp://a.com a\n"); err != nil {
+		t.Fatalf("CreateBookmarks: %v", err)
+	}
+	return p
+}
+
+func collectionContext(name string) context.Context {
+	ctx := context.WithValue(context.Background(), ContextValues("provider"), "git")
+	return context.WithValue(ctx, ContextValues("collection"), FindCollection(name))
+}
+
+func TestCollectionRoles(t *testing.T) {
+	setupCollectionTest(t)
+	c := FindCollection("team")
+	tests := map[string]CollectionRole{
+		"alice":   CollectionRoleEditor,
+		"carol":   CollectionRoleEditor,
+		"bob":     CollectionRoleReader,
+		"mallory": CollectionRoleNone,
+	}
+	for user, want := range tests {
+		if got := c.Role("git", user); got != want {
+			t.Errorf("Role(%s) = %v want %v", user, got, want)
+		}
+	}
+	// The same name on another provider is a different account.
+	if got := c.Role("sql", "alice"); got != CollectionRoleNone {
+		t.Errorf("Role(sql:alice) = %v", got)
+	}
+	if !IsReservedUsername("collection:team") {
+		t.Errorf("collection storage user should be reserved")
+	}
+}
+
+func TestCollectionEditByMember(t *testing.T) {
+	p := setupCollectionTest(t)
+	ctx := collectionContext("team")
+
+	text, sha, err := GetBookmarks(ctx, "carol", "refs/heads/main", nil)
+	if err != nil {
+		t.Fatalf("GetBookmarks: %v", err)
+	}
+	if err := UpdateBookmarks(ctx, "carol", nil, "refs/heads/main", "main", text+"http://b.com b\n", sha); err != nil {
+		t.Fatalf("UpdateBookmarks: %v", err)
+	}
+
+	commits, err := p.GetCommits(context.Background(), "collection:team", nil, "refs/heads/main", 1, 1)
+	if err != nil {
+		t.Fatalf("GetCommits: %v", err)
+	}
+	if len(commits) == 0 || commits[0].CommitterName != "carol" {
+		t.Fatalf("expected commit by carol, got %+v", commits)
+	}
+	if own, _, _ := p.GetBookmarks(context.Background(), "carol", "refs/heads/main", nil); own != "" {
+		t.Fatalf("personal bookmarks should be untouched, got %q", own)
+	}
+}
+
+func TestCollectionReaderAndOutsider(t *testing.T) {
+	setupCollectionTest(t)
+	ctx := collectionContext("team")
+
+	text, sha, err := GetBookmarks(ctx, "bob", "refs/heads/main", nil)
+	if err != nil {
+		t.Fatalf("GetBookmarks: %v", err)
+	}
+	if err := UpdateBookmarks(ctx, "bob", nil, "refs/heads/main", "main", text+"x", sha); !errors.Is(err, ErrCollectionReadOnly) {
+		t.Fatalf("expected read only error, got %v", err)
+	}
+	if _, _, err := GetBookmarks(ctx, "mallory", "refs/heads/main", nil); !errors.Is(err, ErrCollectionAccess) {
+		t.Fatalf("expected access error, got %v", err)
+	}
+}
+
+func collectionRequest(t *testing.T, user, target string, next http.HandlerFunc) *httptest.ResponseRecorder {
+	t.Helper()
+	session := sessions.NewSession(sessions.NewCookieStore([]byte("secret-key")), "gobookmarks")
+	session.Values["GithubUser"] = &User{Login: user}
+	session.Values["Provider"] = "git"
+	req := httptest.NewRequest("GET", target, nil)
+	req = req.WithContext(context.WithValue(req.Context(), ContextValues("session"), session))
+	w := httptest.NewRecorder()
+	CoreAdderMiddleware(next).ServeHTTP(w, req)
+	return w
+}
+
+func TestCollectionRequestParameter(t *testing.T) {
+	p := setupCollectionTest(t)
+	ctx := context.Background()
+	if err := p.CreateRepo(ctx, "alice", nil, Config.GetRepoName()); err != nil {
+		t.Fatalf("CreateRepo: %v", err)
+	}
+	if err := p.CreateBookmarks(ctx, "alice", nil, "main", "Tab: Mine\nCategory: Own\n"); err != nil {
+		t.Fatalf("CreateBookmarks: %v", err)
+	}
+
+	var text string
+	var lists []TabList
+	collectionRequest(t, "alice", "/?collection=team", func(w http.ResponseWriter, r *http.Request) {
+		text, _ = Bookmarks(r)
+		lists = otherTabLists(r)
+	})
+	if !strings.Contains(text, "Category: Team") {
+		t.Fatalf("collection not selected by parameter: %q", text)
+	}
+	if len(lists) != 1 || lists[0].Name != "Personal" || lists[0].Tabs[0].IndexName != "Mine" {
+		t.Fatalf("personal tabs not listed alongside: %+v", lists)
+	}
+
+	collectionRequest(t, "alice", "/", func(w http.ResponseWriter, r *http.Request) {
+		text, _ = Bookmarks(r)
+		lists = otherTabLists(r)
+	})
+	if !strings.Contains(text, "Category: Own") {
+		t.Fatalf("personal bookmarks not shown without the parameter: %q", text)
+	}
+	if len(lists) != 1 || lists[0].Collection != "team" || lists[0].Tabs[0].Href != "/?collection=team" {
+		t.Fatalf("collection tabs not listed alongside: %+v", lists)
+	}
+
+	w := collectionRequest(t, "mallory", "/?collection=team", func(w http.ResponseWriter, r *http.Request) {
+		t.Fatalf("outsider reached the handler")
+	})
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("expected forbidden for outsider, got %d", w.Code)
+	}
+}
+
+func TestCollectionIgnoresPersonalFile(t *testing.T) {
+	setupCollectionTest(t)
+	store, err := NewFileSettingsStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileSettingsStore: %v", err)
+	}
+	old := Settings
+	Settings = store
+	t.Cleanup(func() { Settings = old })
+	if err := store.SetAccountSettings(context.Background(), "git", "alice", &AccountSettings{BookmarkFile: "work.txt"}); err != nil {
+		t.Fatalf("SetAccountSettings: %v", err)
+	}
+
+	var text, file string
+	var switchErr error
+	collectionRequest(t, "alice", "/?collection=team", func(w http.ResponseWriter, r *http.Request) {
+		text, _ = Bookmarks(r)
+		file = bookmarkFileFromContext(r.Context())
+		switchErr = BookmarkFileSwitchAction(w, r)
+	})
+	if !strings.Contains(text, "Category: Team") || file != DefaultBookmarkFile {
+		t.Fatalf("personal file applied to the collection: %q from %s", text, file)
+	}
+	if !errors.Is(switchErr, ErrBookmarkFileFixed) {
+		t.Fatalf("expected file switch to be refused, got %v", switchErr)
+	}
+	if s, _ := store.AccountSettings(context.Background(), "git", "alice"); s == nil || s.BookmarkFile != "work.txt" {
+		t.Fatalf("personal settings changed: %+v", s)
+	}
+}
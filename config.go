@@ -4,7 +4,9 @@ import (
 	"bufio"
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
+	"net"
+	"net/url"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -23,12 +25,15 @@ const (
 	DefaultCommitsPerPage       int           = 100
 )
 
-// Configuration holds runtime configuration values.
+// Configuration holds runtime configuration values. Every field can be set
+// from the config file under its json name or from the environment as
+// GOBOOKMARKS_<NAME>; fields tagged secret are redacted when printed and may
+// be read from the file named by GOBOOKMARKS_<NAME>_FILE.
 type Configuration struct {
 	GithubClientID       string   `json:"github_client_id"`
-	GithubSecret         string   `json:"github_secret"`
+	GithubSecret         string   `json:"github_secret" secret:"true"`
 	GitlabClientID       string   `json:"gitlab_client_id"`
-	GitlabSecret         string   `json:"gitlab_secret"`
+	GitlabSecret         string   `json:"gitlab_secret" secret:"true"`
 	ExternalURL          string   `json:"external_url"`
 	CSSColumns           bool     `json:"css_columns"`
 	DevMode              *bool    `json:"dev_mode"`
@@ -41,12 +46,185 @@ type Configuration struct {
 	FaviconMaxCacheCount int      `json:"favicon_max_cache_count"`
 	LocalGitPath         string   `json:"local_git_path"`
 	NoFooter             bool     `json:"no_footer"`
-	SessionKey           string   `json:"session_key"`
+	SessionKey           string   `json:"session_key" secret:"true"`
 	SessionName          string   `json:"session_name"`
 	DBConnectionProvider string   `json:"db_connection_provider"`
-	DBConnectionString   string   `json:"db_connection_string"`
+	DBConnectionString   string   `json:"db_connection_string" secret:"true"`
 	ProviderOrder        []string `json:"provider_order"`
 	CommitsPerPage       int      `json:"commits_per_page"`
+	// Groups maps a group name to its member usernames. Groups can be
+	// referenced from collection member lists as "@name".
+	Groups      map[string][]string `json:"groups"`
+	Collections []CollectionConfig  `json:"collections"`
+	// GitMirrorAllowLocal permits git provider mirrors that point at the
+	// server itself or its network: plain paths, file:// and http:// URLs
+	// and loopback or private addresses.
+	GitMirrorAllowLocal bool `json:"git_mirror_allow_local"`
+	// GitMirrorSSHKey is the private key file used for ssh mirrors. Without
+	// it ssh mirrors are refused.
+	GitMirrorSSHKey string `json:"git_mirror_ssh_key"`
+	// GitMirrorSSHHosts lists the "[user@]host[/path]" entries ssh mirrors
+	// may point at. The key is shared by all users, so only list hosts and
+	// paths every user may write to.
+	GitMirrorSSHHosts []string `json:"git_mirror_ssh_hosts"`
+	// GitMirrorKnownHosts is the known_hosts file ssh mirror host keys are
+	// checked against, defaulting to the user's known_hosts.
+	GitMirrorKnownHosts string `json:"git_mirror_known_hosts"`
+	// SessionStore selects where sessions are kept: "cookie" (default),
+	// "sql" or "file".
+	SessionStore string `json:"session_store"`
+	// SessionDir is the directory used by the "file" session store.
+	SessionDir string `json:"session_dir"`
+	// SessionMaxAge is the idle lifetime of a session in seconds.
+	SessionMaxAge int `json:"session_max_age"`
+	// SettingsStore keeps each account's chosen repository and bookmark
+	// file: "file" (default) or "sql" to share them between instances.
+	SettingsStore string `json:"settings_store"`
+	// SettingsDir is the directory used by the "file" settings store.
+	SettingsDir string `json:"settings_dir"`
+	// LoginLimitStore keeps failed login attempts: "memory" (default),
+	// "sql" to share them between instances, or "none" to disable limits.
+	LoginLimitStore string `json:"login_limit_store"`
+	// LoginWindow is the sliding window in seconds over which failures count.
+	LoginWindow int `json:"login_window"`
+	// LoginMaxUserFailures and LoginMaxIPFailures are the failures within the
+	// window that lock a username or client address.
+	LoginMaxUserFailures int `json:"login_max_user_failures"`
+	LoginMaxIPFailures   int `json:"login_max_ip_failures"`
+	// LoginLockout is how long a lockout lasts in seconds.
+	LoginLockout int `json:"login_lockout"`
+	// LoginBackoffMax caps the delay in seconds between failed attempts.
+	LoginBackoffMax int `json:"login_backoff_max"`
+	// AuditStore additionally writes audit entries to "sql"; they are always
+	// logged.
+	AuditStore string `json:"audit_store"`
+	// OIDCIssuer enables OpenID Connect login against the given issuer.
+	OIDCIssuer   string `json:"oidc_issuer"`
+	OIDCClientID string `json:"oidc_client_id"`
+	OIDCSecret   string `json:"oidc_secret" secret:"true"`
+	// OIDCName is the label of the login button. Defaults to "SSO".
+	OIDCName string `json:"oidc_name"`
+	// OIDCUsernameClaim names the ID token claim that identifies a user.
+	// Accounts are stored as "oidc:<claim>". Defaults to "sub", which the
+	// user cannot change at the identity provider.
+	OIDCUsernameClaim string   `json:"oidc_username_claim"`
+	OIDCScopes        []string `json:"oidc_scopes"`
+	// OIDCStorage selects the provider ("sql" or "git") that stores the
+	// bookmarks of OIDC users. Defaults to sql when a database is configured.
+	OIDCStorage string `json:"oidc_storage"`
+	// ProxyAuthHeader names the header carrying the user authenticated by a
+	// reverse proxy, e.g. "X-Forwarded-User". Empty disables proxy auth.
+	ProxyAuthHeader string `json:"proxy_auth_header"`
+	// TrustedProxies lists the addresses or CIDRs of proxies whose headers
+	// are believed.
+	TrustedProxies []string `json:"trusted_proxies"`
+	// ProxyAuthStorage selects the provider ("sql" or "git") that stores the
+	// bookmarks of proxy-authenticated users.
+	ProxyAuthStorage string `json:"proxy_auth_storage"`
+	// Admins lists the users allowed into /admin as "provider:user".
+	Admins []string `json:"admins"`
+	// SMTPHost enables password reset emails sent through this server.
+	SMTPHost string `json:"smtp_host"`
+	// SMTPPort defaults to 587, or 465 when SMTPTLS is set.
+	SMTPPort     int    `json:"smtp_port"`
+	SMTPUsername string `json:"smtp_username"`
+	SMTPPassword string `json:"smtp_password" secret:"true"`
+	// SMTPFrom is the sender address of outgoing mail.
+	SMTPFrom string `json:"smtp_from"`
+	// SMTPTLS connects with TLS from the start instead of STARTTLS.
+	SMTPTLS bool `json:"smtp_tls"`
+	// MetricsListen serves /metrics on its own address, such as
+	// "127.0.0.1:9090", instead of on the main site.
+	MetricsListen string `json:"metrics_listen"`
+	// LogLevel is debug, info, warn or error. Defaults to info.
+	LogLevel string `json:"log_level"`
+	// LogFormat is text or json. Defaults to text.
+	LogFormat string `json:"log_format"`
+	// ReadTimeout, WriteTimeout and IdleTimeout bound, in seconds, how long
+	// the server waits to read a request, to write a response and between
+	// requests on a kept-alive connection.
+	ReadTimeout  int `json:"read_timeout"`
+	WriteTimeout int `json:"write_timeout"`
+	IdleTimeout  int `json:"idle_timeout"`
+	// ShutdownTimeout is how long in seconds in-flight requests and saves
+	// may take to finish once a shutdown signal arrives.
+	ShutdownTimeout int `json:"shutdown_timeout"`
+	// MaxHeaderBytes and MaxBodyBytes limit the size of request headers and
+	// bodies.
+	MaxHeaderBytes int   `json:"max_header_bytes"`
+	MaxBodyBytes   int64 `json:"max_body_bytes"`
+	// ContentSecurityPolicy replaces the default Content-Security-Policy
+	// header. "none" leaves the header out.
+	ContentSecurityPolicy string `json:"content_security_policy"`
+	// TLSCertFile and TLSKeyFile are the PEM certificate chain and key serve
+	// uses to answer HTTPS itself. They are reloaded when the files change
+	// or the process receives SIGHUP.
+	TLSCertFile string `json:"tls_cert_file"`
+	TLSKeyFile  string `json:"tls_key_file"`
+	// HTTPListen and HTTPSListen are the addresses of the plain and TLS
+	// listeners.
+	HTTPListen  string `json:"http_listen"`
+	HTTPSListen string `json:"https_listen"`
+	// RedirectHTTP keeps the plain listener running when TLS is configured
+	// and makes it redirect every request to HTTPS.
+	RedirectHTTP bool `json:"redirect_http"`
+}
+
+// ServerLimits are the timeouts and size limits of the HTTP server.
+type ServerLimits struct {
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	ShutdownTimeout   time.Duration
+	MaxHeaderBytes    int
+	MaxBodyBytes      int64
+}
+
+// ServerLimits returns the configured server limits with defaults filled in.
+func (c Configuration) ServerLimits() ServerLimits {
+	l := ServerLimits{
+		ReadTimeout:       30 * time.Second,
+		ReadHeaderTimeout: 10 * time.Second,
+		WriteTimeout:      2 * time.Minute,
+		IdleTimeout:       2 * time.Minute,
+		ShutdownTimeout:   30 * time.Second,
+		MaxHeaderBytes:    64 * 1024,
+		MaxBodyBytes:      10 * 1024 * 1024,
+	}
+	if c.ReadTimeout > 0 {
+		l.ReadTimeout = time.Duration(c.ReadTimeout) * time.Second
+		if l.ReadTimeout < l.ReadHeaderTimeout {
+			l.ReadHeaderTimeout = l.ReadTimeout
+		}
+	}
+	if c.WriteTimeout > 0 {
+		l.WriteTimeout = time.Duration(c.WriteTimeout) * time.Second
+	}
+	if c.IdleTimeout > 0 {
+		l.IdleTimeout = time.Duration(c.IdleTimeout) * time.Second
+	}
+	if c.ShutdownTimeout > 0 {
+		l.ShutdownTimeout = time.Duration(c.ShutdownTimeout) * time.Second
+	}
+	if c.MaxHeaderBytes > 0 {
+		l.MaxHeaderBytes = c.MaxHeaderBytes
+	}
+	if c.MaxBodyBytes > 0 {
+		l.MaxBodyBytes = c.MaxBodyBytes
+	}
+	return l
+}
+
+// CollectionConfig describes a shared bookmark collection. Owner is the
+// account the collection is stored under: an organisation or group for
+// GitHub/GitLab, or a storage key for the sql and git providers. When Owner is
+// empty "collection:<name>" is used.
+type CollectionConfig struct {
+	Name    string   `json:"name"`
+	Owner   string   `json:"owner"`
+	Editors []string `json:"editors"`
+	Readers []string `json:"readers"`
 }
 
 func (c Configuration) GetDevMode() bool {
@@ -71,9 +249,128 @@ func (c Configuration) GetRepoName() string {
 	return name
 }
 
+// TLSEnabled reports whether serve answers HTTPS with a configured
+// certificate rather than leaving TLS to a proxy.
+func (c Configuration) TLSEnabled() bool {
+	return c.TLSCertFile != "" && c.TLSKeyFile != ""
+}
+
+// GetHTTPListen returns the address of the plain HTTP listener.
+func (c Configuration) GetHTTPListen() string {
+	if c.HTTPListen != "" {
+		return c.HTTPListen
+	}
+	return ":8080"
+}
+
+// GetHTTPSListen returns the address of the HTTPS listener.
+func (c Configuration) GetHTTPSListen() string {
+	if c.HTTPSListen != "" {
+		return c.HTTPSListen
+	}
+	return ":8443"
+}
+
+// GetExternalURL returns ExternalURL without a trailing slash. When TLS is
+// enabled an http:// URL is upgraded to https://, and a port matching the
+// plain listener is swapped for the HTTPS one, so links and OAuth callbacks
+// never point at the redirecting listener.
+func (c Configuration) GetExternalURL() string {
+	ext := strings.TrimRight(c.ExternalURL, "/")
+	if !c.TLSEnabled() {
+		return ext
+	}
+	u, err := url.Parse(ext)
+	if err != nil || u.Scheme != "http" {
+		return ext
+	}
+	u.Scheme = "https"
+	if port := u.Port(); port != "" {
+		if _, httpPort, _ := net.SplitHostPort(c.GetHTTPListen()); port == httpPort {
+			_, httpsPort, _ := net.SplitHostPort(c.GetHTTPSListen())
+			u.Host = u.Hostname()
+			if httpsPort != "" && httpsPort != "443" {
+				u.Host = net.JoinHostPort(u.Hostname(), httpsPort)
+			}
+		}
+	}
+	return u.String()
+}
+
+// SecureCookies reports whether cookies should be limited to HTTPS: when
+// serve answers TLS itself or ExternalURL is an https:// URL.
+func (c Configuration) SecureCookies() bool {
+	return c.TLSEnabled() || strings.HasPrefix(strings.ToLower(c.ExternalURL), "https://")
+}
+
 func (c Configuration) GetOauthRedirectURL() string {
-	externalURL := strings.TrimRight(c.ExternalURL, "/")
-	return JoinURL(externalURL, "oauth2Callback")
+	return JoinURL(c.GetExternalURL(), "oauth2Callback")
+}
+
+// GetOIDCRedirectURL returns the callback URL registered with the OIDC
+// issuer.
+func (c Configuration) GetOIDCRedirectURL() string {
+	return JoinURL(c.GetExternalURL(), "oidcCallback")
+}
+
+// GetOIDCUsernameClaim returns the ID token claim that identifies OIDC users.
+func (c Configuration) GetOIDCUsernameClaim() string {
+	if c.OIDCUsernameClaim != "" {
+		return c.OIDCUsernameClaim
+	}
+	return "sub"
+}
+
+// GetOIDCStorage returns the provider that stores OIDC users' bookmarks.
+func (c Configuration) GetOIDCStorage() string {
+	if c.OIDCStorage != "" {
+		return c.OIDCStorage
+	}
+	return c.defaultLocalStorage()
+}
+
+// GetProxyAuthStorage returns the provider that stores the bookmarks of
+// users authenticated by a reverse proxy.
+func (c Configuration) GetProxyAuthStorage() string {
+	if c.ProxyAuthStorage != "" {
+		return c.ProxyAuthStorage
+	}
+	return c.defaultLocalStorage()
+}
+
+// LoginLimits returns the login throttling thresholds with defaults applied.
+func (c Configuration) LoginLimits() LoginLimits {
+	l := LoginLimits{
+		Window:          15 * time.Minute,
+		MaxUserFailures: 10,
+		MaxIPFailures:   50,
+		Lockout:         15 * time.Minute,
+		BackoffMax:      30 * time.Second,
+	}
+	if c.LoginWindow > 0 {
+		l.Window = time.Duration(c.LoginWindow) * time.Second
+	}
+	if c.LoginMaxUserFailures > 0 {
+		l.MaxUserFailures = c.LoginMaxUserFailures
+	}
+	if c.LoginMaxIPFailures > 0 {
+		l.MaxIPFailures = c.LoginMaxIPFailures
+	}
+	if c.LoginLockout > 0 {
+		l.Lockout = time.Duration(c.LoginLockout) * time.Second
+	}
+	if c.LoginBackoffMax > 0 {
+		l.BackoffMax = time.Duration(c.LoginBackoffMax) * time.Second
+	}
+	return l
+}
+
+// defaultLocalStorage is sql when a database is configured and git otherwise.
+func (c Configuration) defaultLocalStorage() string {
+	if c.DBConnectionProvider != "" {
+		return "sql"
+	}
+	return "git"
 }
 
 func (c Configuration) GetSessionName() string {
@@ -89,22 +386,28 @@ func (c Configuration) GetSessionName() string {
 func LoadConfigFile(path string) (Configuration, bool, error) {
 	var c Configuration
 
-	log.Printf("attempting to load config from %s", path)
+	slog.Info("loading config", "path", path)
 
 	data, err := os.ReadFile(path)
 	if err != nil {
 		if os.IsNotExist(err) {
-			log.Printf("config file %s not found", path)
+			slog.Info("config file not found", "path", path)
 			return c, false, nil
 		}
 		return c, false, fmt.Errorf("unable to read config file: %w", err)
 	}
 
+	if ext := strings.ToLower(filepath.Ext(path)); ext == ".yaml" || ext == ".yml" {
+		data, err = yamlToJSON(data)
+		if err != nil {
+			return c, true, fmt.Errorf("unable to parse config file: %w", err)
+		}
+	}
 	if err := json.Unmarshal(data, &c); err != nil {
 		return c, true, fmt.Errorf("unable to parse config file: %w", err)
 	}
 
-	log.Printf("successfully loaded config from %s (keys: %s)", path, strings.Join(loadedConfigKeys(c), ", "))
+	slog.Info("loaded config", "path", path, "keys", strings.Join(loadedConfigKeys(c), ","))
 
 	return c, true, nil
 }
@@ -193,26 +496,184 @@ func MergeConfig(dst *Configuration, src Configuration) {
 	if len(src.ProviderOrder) > 0 {
 		dst.ProviderOrder = append([]string(nil), src.ProviderOrder...)
 	}
+	if len(src.Groups) > 0 {
+		dst.Groups = make(map[string][]string, len(src.Groups))
+		for k, v := range src.Groups {
+			dst.Groups[k] = append([]string(nil), v...)
+		}
+	}
+	if len(src.Collections) > 0 {
+		dst.Collections = append([]CollectionConfig(nil), src.Collections...)
+	}
+	if src.GitMirrorAllowLocal {
+		dst.GitMirrorAllowLocal = true
+	}
+	if src.GitMirrorSSHKey != "" {
+		dst.GitMirrorSSHKey = src.GitMirrorSSHKey
+	}
+	if len(src.GitMirrorSSHHosts) > 0 {
+		dst.GitMirrorSSHHosts = append([]string(nil), src.GitMirrorSSHHosts...)
+	}
+	if src.GitMirrorKnownHosts != "" {
+		dst.GitMirrorKnownHosts = src.GitMirrorKnownHosts
+	}
+	if src.SessionStore != "" {
+		dst.SessionStore = src.SessionStore
+	}
+	if src.SessionDir != "" {
+		dst.SessionDir = src.SessionDir
+	}
+	if src.SessionMaxAge != 0 {
+		dst.SessionMaxAge = src.SessionMaxAge
+	}
+	if src.SettingsStore != "" {
+		dst.SettingsStore = src.SettingsStore
+	}
+	if src.SettingsDir != "" {
+		dst.SettingsDir = src.SettingsDir
+	}
+	if src.LoginLimitStore != "" {
+		dst.LoginLimitStore = src.LoginLimitStore
+	}
+	if src.LoginWindow != 0 {
+		dst.LoginWindow = src.LoginWindow
+	}
+	if src.LoginMaxUserFailures != 0 {
+		dst.LoginMaxUserFailures = src.LoginMaxUserFailures
+	}
+	if src.LoginMaxIPFailures != 0 {
+		dst.LoginMaxIPFailures = src.LoginMaxIPFailures
+	}
+	if src.LoginLockout != 0 {
+		dst.LoginLockout = src.LoginLockout
+	}
+	if src.LoginBackoffMax != 0 {
+		dst.LoginBackoffMax = src.LoginBackoffMax
+	}
+	if src.AuditStore != "" {
+		dst.AuditStore = src.AuditStore
+	}
+	if src.OIDCIssuer != "" {
+		dst.OIDCIssuer = src.OIDCIssuer
+	}
+	if src.OIDCClientID != "" {
+		dst.OIDCClientID = src.OIDCClientID
+	}
+	if src.OIDCSecret != "" {
+		dst.OIDCSecret = src.OIDCSecret
+	}
+	if src.OIDCName != "" {
+		dst.OIDCName = src.OIDCName
+	}
+	if src.OIDCUsernameClaim != "" {
+		dst.OIDCUsernameClaim = src.OIDCUsernameClaim
+	}
+	if len(src.OIDCScopes) > 0 {
+		dst.OIDCScopes = append([]string(nil), src.OIDCScopes...)
+	}
+	if src.OIDCStorage != "" {
+		dst.OIDCStorage = src.OIDCStorage
+	}
+	if src.ProxyAuthHeader != "" {
+		dst.ProxyAuthHeader = src.ProxyAuthHeader
+	}
+	if len(src.TrustedProxies) > 0 {
+		dst.TrustedProxies = append([]string(nil), src.TrustedProxies...)
+	}
+	if src.ProxyAuthStorage != "" {
+		dst.ProxyAuthStorage = src.ProxyAuthStorage
+	}
+	if len(src.Admins) > 0 {
+		dst.Admins = append([]string(nil), src.Admins...)
+	}
+	if src.SMTPHost != "" {
+		dst.SMTPHost = src.SMTPHost
+	}
+	if src.SMTPPort != 0 {
+		dst.SMTPPort = src.SMTPPort
+	}
+	if src.SMTPUsername != "" {
+		dst.SMTPUsername = src.SMTPUsername
+	}
+	if src.SMTPPassword != "" {
+		dst.SMTPPassword = src.SMTPPassword
+	}
+	if src.SMTPFrom != "" {
+		dst.SMTPFrom = src.SMTPFrom
+	}
+	if src.SMTPTLS {
+		dst.SMTPTLS = true
+	}
+	if src.MetricsListen != "" {
+		dst.MetricsListen = src.MetricsListen
+	}
+	if src.LogLevel != "" {
+		dst.LogLevel = src.LogLevel
+	}
+	if src.LogFormat != "" {
+		dst.LogFormat = src.LogFormat
+	}
+	if src.ReadTimeout != 0 {
+		dst.ReadTimeout = src.ReadTimeout
+	}
+	if src.WriteTimeout != 0 {
+		dst.WriteTimeout = src.WriteTimeout
+	}
+	if src.IdleTimeout != 0 {
+		dst.IdleTimeout = src.IdleTimeout
+	}
+	if src.ShutdownTimeout != 0 {
+		dst.ShutdownTimeout = src.ShutdownTimeout
+	}
+	if src.MaxHeaderBytes != 0 {
+		dst.MaxHeaderBytes = src.MaxHeaderBytes
+	}
+	if src.MaxBodyBytes != 0 {
+		dst.MaxBodyBytes = src.MaxBodyBytes
+	}
+	if src.ContentSecurityPolicy != "" {
+		dst.ContentSecurityPolicy = src.ContentSecurityPolicy
+	}
+	if src.TLSCertFile != "" {
+		dst.TLSCertFile = src.TLSCertFile
+	}
+	if src.TLSKeyFile != "" {
+		dst.TLSKeyFile = src.TLSKeyFile
+	}
+	if src.HTTPListen != "" {
+		dst.HTTPListen = src.HTTPListen
+	}
+	if src.HTTPSListen != "" {
+		dst.HTTPSListen = src.HTTPSListen
+	}
+	if src.RedirectHTTP {
+		dst.RedirectHTTP = true
+	}
 }
 
 // DefaultConfigPath returns the path to the config file depending on
 // environment and the effective user. If running as a non-root user and
 // XDG variables are set, the config lives under the XDG config directory.
-// Otherwise it falls back to /etc/gobookmarks/config.json.
+// Otherwise it falls back to /etc/gobookmarks/config.json. A config.yaml or
+// config.yml is used instead when no config.json exists in that directory.
 func DefaultConfigPath() string {
 	if p := os.Getenv("GOBM_CONFIG_FILE"); p != "" {
 		return p
 	}
+	dir := "/etc/gobookmarks"
 	if os.Geteuid() != 0 {
-		xdg := os.Getenv("XDG_CONFIG_HOME")
-		if xdg != "" {
-			return filepath.Join(xdg, "gobookmarks", "config.json")
+		if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+			dir = filepath.Join(xdg, "gobookmarks")
+		} else if home := os.Getenv("HOME"); home != "" {
+			dir = filepath.Join(home, ".config", "gobookmarks")
 		}
-		if home := os.Getenv("HOME"); home != "" {
-			return filepath.Join(home, ".config", "gobookmarks", "config.json")
+	}
+	for _, name := range []string{"config.yaml", "config.yml"} {
+		if p := filepath.Join(dir, name); fileExists(p) && !fileExists(filepath.Join(dir, "config.json")) {
+			return p
 		}
 	}
-	return "/etc/gobookmarks/config.json"
+	return filepath.Join(dir, "config.json")
 }
 
 // DefaultSessionKeyPath returns the location of the session key file.
@@ -264,6 +725,18 @@ func DefaultSessionKeyPath(writing bool) string {
 	return systemPath
 }
 
+// DefaultSessionDir returns the directory used by the file session store,
+// next to the session key.
+func DefaultSessionDir() string {
+	return filepath.Join(filepath.Dir(DefaultSessionKeyPath(true)), "sessions")
+}
+
+// DefaultSettingsDir returns the directory used by the file settings store,
+// next to the session key.
+func DefaultSettingsDir() string {
+	return filepath.Join(filepath.Dir(DefaultSessionKeyPath(true)), "settings")
+}
+
 func fileExists(path string) bool {
 	_, err := os.Stat(path)
 	return err == nil
@@ -0,0 +1,385 @@
+package gobookmarks
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigEnvPrefix starts the environment variable of every configuration
+// key: external_url is read from GOBOOKMARKS_EXTERNAL_URL.
+const ConfigEnvPrefix = "GOBOOKMARKS_"
+
+// configFileSuffix marks an environment variable naming a file that holds a
+// secret value, as with Docker and Kubernetes secrets.
+const configFileSuffix = "_FILE"
+
+// legacyConfigEnv lists the older variable names still read for some keys.
+// The GOBOOKMARKS_ names take precedence.
+var legacyConfigEnv = map[string]string{
+	"github_client_id":        "GITHUB_CLIENT_ID",
+	"github_secret":           "GITHUB_SECRET",
+	"gitlab_client_id":        "GITLAB_CLIENT_ID",
+	"gitlab_secret":           "GITLAB_SECRET",
+	"github_server":           "GITHUB_SERVER",
+	"gitlab_server":           "GITLAB_SERVER",
+	"external_url":            "EXTERNAL_URL",
+	"db_connection_provider":  "DB_CONNECTION_PROVIDER",
+	"db_connection_string":    "DB_CONNECTION_STRING",
+	"local_git_path":          "LOCAL_GIT_PATH",
+	"session_key":             "SESSION_KEY",
+	"provider_order":          "PROVIDER_ORDER",
+	"css_columns":             "GBM_CSS_COLUMNS",
+	"namespace":               "GBM_NAMESPACE",
+	"title":                   "GBM_TITLE",
+	"no_footer":               "GBM_NO_FOOTER",
+	"dev_mode":                "GBM_DEV_MODE",
+	"favicon_cache_dir":       "FAVICON_CACHE_DIR",
+	"favicon_cache_size":      "FAVICON_CACHE_SIZE",
+	"favicon_max_cache_count": "FAVICON_MAX_CACHE_COUNT",
+}
+
+// ConfigSources maps configuration keys to a description of where their
+// value came from, such as "env GOBOOKMARKS_TITLE" or "file config.yaml".
+// Keys left at their default are absent.
+type ConfigSources map[string]string
+
+// Track records src as the source of every key set in c. Call it in the
+// same order the configurations are merged so later sources win.
+func (s ConfigSources) Track(c Configuration, src string) {
+	for _, k := range loadedConfigKeys(c) {
+		s[k] = src
+	}
+}
+
+type configField struct {
+	index  int
+	key    string
+	secret bool
+}
+
+// EnvName returns the environment variable read for the key.
+func (f configField) EnvName() string {
+	return ConfigEnvPrefix + strings.ToUpper(f.key)
+}
+
+func configFields() []configField {
+	t := reflect.TypeOf(Configuration{})
+	fields := make([]configField, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		key, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if key == "" || key == "-" {
+			continue
+		}
+		fields = append(fields, configField{index: i, key: key, secret: t.Field(i).Tag.Get("secret") == "true"})
+	}
+	return fields
+}
+
+// LoadConfigEnv reads every configuration key from the environment through
+// lookup, normally os.LookupEnv. Lists are comma separated and groups and
+// collections are JSON. Secret keys can instead be read from the file named
+// by the variable with a _FILE suffix; a trailing newline is dropped.
+func LoadConfigEnv(lookup func(string) (string, bool)) (Configuration, ConfigSources, error) {
+	var c Configuration
+	sources := ConfigSources{}
+	v := reflect.ValueOf(&c).Elem()
+	var errs []error
+	for _, f := range configFields() {
+		name := f.EnvName()
+		value, ok := lookup(name)
+		src := "env " + name
+		lenient := false
+		if f.secret {
+			if path, fileOK := lookup(name + configFileSuffix); fileOK && path != "" {
+				if ok && value != "" {
+					errs = append(errs, fmt.Errorf("%s and %s%s are both set", name, name, configFileSuffix))
+					continue
+				}
+				data, err := os.ReadFile(path)
+				if err != nil {
+					errs = append(errs, fmt.Errorf("%s%s: %w", name, configFileSuffix, err))
+					continue
+				}
+				value, ok = strings.TrimRight(string(data), "\r\n"), true
+				src = "env " + name + configFileSuffix
+			}
+		}
+		if !ok || value == "" {
+			legacy, has := legacyConfigEnv[f.key]
+			if !has {
+				continue
+			}
+			if value, ok = lookup(legacy); !ok || value == "" {
+				continue
+			}
+			src = "env " + legacy
+			lenient = true
+		}
+		if err := setConfigValue(v.Field(f.index), value, lenient); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", strings.TrimPrefix(src, "env "), err))
+			continue
+		}
+		sources[f.key] = src
+	}
+	return c, sources, errors.Join(errs...)
+}
+
+// setConfigValue parses s into field. Lenient parsing treats any value a
+// boolean cannot be parsed from as true, matching older variables that were
+// only checked for being set.
+func setConfigValue(field reflect.Value, s string, lenient bool) error {
+	parseBool := func() (bool, error) {
+		b, err := strconv.ParseBool(strings.TrimSpace(s))
+		if err != nil && lenient {
+			return true, nil
+		}
+		return b, err
+	}
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(s)
+	case reflect.Bool:
+		b, err := parseBool()
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Pointer:
+		if field.Type().Elem().Kind() != reflect.Bool {
+			return fmt.Errorf("unsupported type %s", field.Type())
+		}
+		b, err := parseBool()
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(&b))
+	case reflect.Int, reflect.Int64:
+		i, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+		if err != nil {
+			return err
+		}
+		field.SetInt(i)
+	case reflect.Slice:
+		if field.Type().Elem().Kind() == reflect.String {
+			field.Set(reflect.ValueOf(splitConfigList(s)))
+			return nil
+		}
+		return json.Unmarshal([]byte(s), field.Addr().Interface())
+	case reflect.Map:
+		return json.Unmarshal([]byte(s), field.Addr().Interface())
+	default:
+		return fmt.Errorf("unsupported type %s", field.Type())
+	}
+	return nil
+}
+
+func splitConfigList(s string) []string {
+	var out []string
+	for _, p := range strings.Split(s, ",") {
+		if t := strings.TrimSpace(p); t != "" {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// ConfigValue is one line of the effective configuration.
+type ConfigValue struct {
+	Key    string
+	Env    string
+	Value  string
+	Source string
+}
+
+// redacted replaces secret values when the configuration is printed.
+const redacted = "<redacted>"
+
+// EffectiveConfig lists every key of c with its value and source. Secrets
+// are redacted and keys without a source are reported as "default".
+func EffectiveConfig(c Configuration, sources ConfigSources) []ConfigValue {
+	v := reflect.ValueOf(c)
+	var out []ConfigValue
+	for _, f := range configFields() {
+		field := v.Field(f.index)
+		cv := ConfigValue{Key: f.key, Env: f.EnvName(), Source: sources[f.key]}
+		if cv.Source == "" {
+			cv.Source = "default"
+		}
+		switch {
+		case field.IsZero():
+		case f.secret:
+			cv.Value = redacted
+		default:
+			cv.Value = formatConfigValue(field)
+		}
+		out = append(out, cv)
+	}
+	return out
+}
+
+func formatConfigValue(field reflect.Value) string {
+	switch field.Kind() {
+	case reflect.Pointer:
+		return formatConfigValue(field.Elem())
+	case reflect.String:
+		return field.String()
+	case reflect.Slice, reflect.Map:
+		if list, ok := field.Interface().([]string); ok {
+			return strings.Join(list, ",")
+		}
+		b, err := json.Marshal(field.Interface())
+		if err != nil {
+			return err.Error()
+		}
+		return string(b)
+	}
+	return fmt.Sprint(field.Interface())
+}
+
+// Validate reports settings that contradict each other or cannot work, such
+// as a store or provider that needs a database when none is configured.
+func (c Configuration) Validate() error {
+	var errs []error
+	problem := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+	oneOf := func(key, value string, allowed ...string) {
+		if value == "" {
+			return
+		}
+		for _, a := range allowed {
+			if strings.EqualFold(value, a) {
+				return
+			}
+		}
+		problem("%s must be one of %s, not %q", key, strings.Join(allowed, ", "), value)
+	}
+	oneOf("session_store", c.SessionStore, "cookie", "sql", "file")
+	oneOf("settings_store", c.SettingsStore, "file", "sql")
+	oneOf("login_limit_store", c.LoginLimitStore, "memory", "sql", "none")
+	oneOf("audit_store", c.AuditStore, "log", "sql")
+	oneOf("oidc_storage", c.OIDCStorage, "sql", "git")
+	oneOf("proxy_auth_storage", c.ProxyAuthStorage, "sql", "git")
+	oneOf("log_level", c.LogLevel, "debug", "info", "warn", "error")
+	oneOf("log_format", c.LogFormat, "text", "json")
+
+	if c.DBConnectionProvider == "" {
+		needsDB := map[string]bool{}
+		for _, p := range c.ProviderOrder {
+			if strings.EqualFold(p, "sql") {
+				needsDB["provider_order lists sql"] = true
+			}
+		}
+		for key, value := range map[string]string{
+			"session_store":      c.SessionStore,
+			"settings_store":     c.SettingsStore,
+			"login_limit_store":  c.LoginLimitStore,
+			"audit_store":        c.AuditStore,
+			"oidc_storage":       c.OIDCStorage,
+			"proxy_auth_storage": c.ProxyAuthStorage,
+		} {
+			if strings.EqualFold(value, "sql") {
+				needsDB[key+" is sql"] = true
+			}
+		}
+		if c.DBConnectionString != "" {
+			needsDB["db_connection_string is set"] = true
+		}
+		reasons := make([]string, 0, len(needsDB))
+		for r := range needsDB {
+			reasons = append(reasons, r)
+		}
+		sort.Strings(reasons)
+		for _, r := range reasons {
+			problem("%s but db_connection_provider is not set", r)
+		}
+	} else if c.DBConnectionString == "" {
+		problem("db_connection_provider is set but db_connection_string is not")
+	}
+
+	pairs := []struct{ a, b, av, bv string }{
+		{"github_client_id", "github_secret", c.GithubClientID, c.GithubSecret},
+		{"gitlab_client_id", "gitlab_secret", c.GitlabClientID, c.GitlabSecret},
+		{"oidc_issuer", "oidc_client_id", c.OIDCIssuer, c.OIDCClientID},
+		{"smtp_host", "smtp_from", c.SMTPHost, c.SMTPFrom},
+		{"tls_cert_file", "tls_key_file", c.TLSCertFile, c.TLSKeyFile},
+	}
+	for _, p := range pairs {
+		if (p.av == "") != (p.bv == "") {
+			problem("%s and %s must be set together", p.a, p.b)
+		}
+	}
+	if c.RedirectHTTP && !c.TLSEnabled() {
+		problem("redirect_http needs tls_cert_file and tls_key_file")
+	}
+	if c.TLSEnabled() && c.HTTPListen != "" && c.HTTPListen == c.HTTPSListen {
+		problem("http_listen and https_listen are both %s", c.HTTPListen)
+	}
+	if c.ProxyAuthHeader != "" && len(c.TrustedProxies) == 0 {
+		problem("proxy_auth_header needs trusted_proxies")
+	}
+	if _, err := ParseTrustedProxies(c.TrustedProxies); err != nil {
+		problem("trusted_proxies: %v", err)
+	}
+	if len(c.GitMirrorSSHHosts) > 0 && c.GitMirrorSSHKey == "" {
+		problem("git_mirror_ssh_hosts needs git_mirror_ssh_key")
+	}
+	files := []struct{ key, path string }{
+		{"git_mirror_ssh_key", c.GitMirrorSSHKey},
+		{"git_mirror_known_hosts", c.GitMirrorKnownHosts},
+	}
+	for _, f := range files {
+		if f.path == "" {
+			continue
+		}
+		if _, err := os.Stat(f.path); err != nil {
+			problem("%s: %v", f.key, err)
+		}
+	}
+	for _, col := range c.Collections {
+		for _, m := range append(append([]string(nil), col.Editors...), col.Readers...) {
+			if !strings.HasPrefix(m, "@") && !validMemberAccount(m) {
+				problem("collection %s member %q must be provider:user or @group", col.Name, m)
+			}
+		}
+	}
+	groups := make([]string, 0, len(c.Groups))
+	for g := range c.Groups {
+		groups = append(groups, g)
+	}
+	sort.Strings(groups)
+	for _, g := range groups {
+		for _, m := range c.Groups[g] {
+			if !validMemberAccount(m) {
+				problem("group %s member %q must be provider:user", g, m)
+			}
+		}
+	}
+	for _, a := range c.Admins {
+		if provider, user, ok := strings.Cut(a, ":"); !ok || provider == "" || user == "" {
+			problem("admins entry %q must be provider:user", a)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// yamlToJSON converts a YAML config file to JSON so it is decoded with the
+// same field names and rules as a JSON one.
+func yamlToJSON(data []byte) ([]byte, error) {
+	var doc map[string]any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if doc == nil {
+		doc = map[string]any{}
+	}
+	return json.Marshal(doc)
+}
@@ -0,0 +1,181 @@
+package gobookmarks
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func envLookup(env map[string]string) func(string) (string, bool) {
+	return func(k string) (string, bool) {
+		v, ok := env[k]
+		return v, ok
+	}
+}
+
+func TestLoadConfigEnv(t *testing.T) {
+	secret := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(secret, []byte("from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, sources, err := LoadConfigEnv(envLookup(map[string]string{
+		"GOBOOKMARKS_TITLE":              "Marks",
+		"GOBOOKMARKS_PROVIDER_ORDER":     "git, sql,",
+		"GOBOOKMARKS_LOGIN_WINDOW":       "60",
+		"GOBOOKMARKS_REDIRECT_HTTP":      "true",
+		"GOBOOKMARKS_DEV_MODE":           "false",
+		"GOBOOKMARKS_GROUPS":             `{"team":["alice","bob"]}`,
+		"GOBOOKMARKS_GITHUB_SECRET_FILE": secret,
+		"GOBOOKMARKS_EXTERNAL_URL":       "https://new.example",
+		"EXTERNAL_URL":                   "https://old.example",
+		"GBM_NO_FOOTER":                  "yes",
+		"GITLAB_SECRET":                  "legacy",
+	}))
+	if err != nil {
+		t.Fatalf("LoadConfigEnv: %v", err)
+	}
+	if cfg.Title != "Marks" || !reflect.DeepEqual(cfg.ProviderOrder, []string{"git", "sql"}) || cfg.LoginWindow != 60 || !cfg.RedirectHTTP {
+		t.Errorf("cfg = %+v", cfg)
+	}
+	if cfg.DevMode == nil || *cfg.DevMode || len(cfg.Groups["team"]) != 2 || !cfg.NoFooter {
+		t.Errorf("dev mode, groups or footer not loaded: %+v", cfg)
+	}
+	if cfg.GithubSecret != "from-file" || cfg.GitlabSecret != "legacy" || cfg.ExternalURL != "https://new.example" {
+		t.Errorf("secrets or precedence wrong: %+v", cfg)
+	}
+	want := ConfigSources{
+		"github_secret": "env GOBOOKMARKS_GITHUB_SECRET_FILE",
+		"gitlab_secret": "env GITLAB_SECRET",
+		"external_url":  "env GOBOOKMARKS_EXTERNAL_URL",
+	}
+	for k, v := range want {
+		if sources[k] != v {
+			t.Errorf("source of %s = %q, want %q", k, sources[k], v)
+		}
+	}
+
+	_, _, err = LoadConfigEnv(envLookup(map[string]string{
+		"GOBOOKMARKS_SESSION_KEY":      "inline",
+		"GOBOOKMARKS_SESSION_KEY_FILE": secret,
+		"GOBOOKMARKS_SMTP_PORT":        "twenty-five",
+		"GOBOOKMARKS_TITLE_FILE":       secret,
+	}))
+	if err == nil || !strings.Contains(err.Error(), "GOBOOKMARKS_SESSION_KEY_FILE are both set") || !strings.Contains(err.Error(), "GOBOOKMARKS_SMTP_PORT") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+// TestConfigKeysSettable sets every key from the environment and checks
+// MergeConfig copies it, so no field can be left out of either.
+func TestConfigKeysSettable(t *testing.T) {
+	samples := map[reflect.Kind]string{
+		reflect.String:  "x",
+		reflect.Bool:    "true",
+		reflect.Pointer: "true",
+		reflect.Int:     "7",
+		reflect.Int64:   "7",
+		reflect.Map:     `{"g":["a"]}`,
+	}
+	env := map[string]string{}
+	ct := reflect.TypeOf(Configuration{})
+	for _, f := range configFields() {
+		ft := ct.Field(f.index).Type
+		sample, ok := samples[ft.Kind()]
+		if ft.Kind() == reflect.Slice {
+			sample, ok = "a,b", true
+			if ft.Elem().Kind() != reflect.String {
+				sample = `[{"name":"n"}]`
+			}
+		}
+		if !ok {
+			t.Fatalf("no sample for %s (%s)", f.key, ft)
+		}
+		env[f.EnvName()] = sample
+	}
+	src, sources, err := LoadConfigEnv(envLookup(env))
+	if err != nil {
+		t.Fatalf("LoadConfigEnv: %v", err)
+	}
+	var dst Configuration
+	MergeConfig(&dst, src)
+	dv, sv := reflect.ValueOf(dst), reflect.ValueOf(src)
+	for _, f := range configFields() {
+		if sv.Field(f.index).IsZero() || sources[f.key] == "" {
+			t.Errorf("%s not loaded from %s", f.key, f.EnvName())
+		}
+		if !reflect.DeepEqual(dv.Field(f.index).Interface(), sv.Field(f.index).Interface()) {
+			t.Errorf("%s not copied by MergeConfig", f.key)
+		}
+	}
+}
+
+func TestLoadConfigFileYAML(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	data := "external_url: https://marks.example\nprovider_order: [git, sql]\nsmtp_port: 2525\ncss_columns: true\ncollections:\n  - name: team\n    owner: alice\n"
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, found, err := LoadConfigFile(path)
+	if err != nil || !found {
+		t.Fatalf("LoadConfigFile: %v %v", found, err)
+	}
+	if cfg.ExternalURL != "https://marks.example" || len(cfg.ProviderOrder) != 2 || cfg.SMTPPort != 2525 || !cfg.CSSColumns {
+		t.Errorf("cfg = %+v", cfg)
+	}
+	if len(cfg.Collections) != 1 || cfg.Collections[0].Owner != "alice" {
+		t.Errorf("collections = %+v", cfg.Collections)
+	}
+}
+
+func TestEffectiveConfig(t *testing.T) {
+	cfg := Configuration{Title: "Marks", SessionKey: "hunter2", ProviderOrder: []string{"git", "sql"}}
+	sources := ConfigSources{}
+	sources.Track(cfg, "file config.yaml")
+	got := map[string]ConfigValue{}
+	for _, v := range EffectiveConfig(cfg, sources) {
+		got[v.Key] = v
+	}
+	if v := got["session_key"]; v.Value != redacted || v.Source != "file config.yaml" {
+		t.Errorf("session_key = %+v", v)
+	}
+	if v := got["provider_order"]; v.Value != "git,sql" {
+		t.Errorf("provider_order = %+v", v)
+	}
+	if v := got["github_secret"]; v.Value != "" || v.Source != "default" || v.Env != "GOBOOKMARKS_GITHUB_SECRET" {
+		t.Errorf("github_secret = %+v", v)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	if err := (Configuration{}).Validate(); err != nil {
+		t.Fatalf("empty config: %v", err)
+	}
+	ok := Configuration{ProviderOrder: []string{"sql"}, DBConnectionProvider: "sqlite3", DBConnectionString: "x.db", SessionStore: "sql"}
+	if err := ok.Validate(); err != nil {
+		t.Fatalf("valid config: %v", err)
+	}
+	for _, tc := range []struct {
+		cfg  Configuration
+		want string
+	}{
+		{Configuration{ProviderOrder: []string{"git", "sql"}}, "provider_order lists sql but db_connection_provider is not set"},
+		{Configuration{SessionStore: "sql"}, "session_store is sql"},
+		{Configuration{SessionStore: "redis"}, "session_store must be one of"},
+		{Configuration{DBConnectionProvider: "mysql"}, "db_connection_string is not"},
+		{Configuration{GithubClientID: "id"}, "github_client_id and github_secret"},
+		{Configuration{RedirectHTTP: true}, "redirect_http needs"},
+		{Configuration{ProxyAuthHeader: "X-User"}, "proxy_auth_header needs trusted_proxies"},
+		{Configuration{TrustedProxies: []string{"10.0.0.0/8", "proxy.internal"}}, `trusted_proxies: invalid trusted proxy "proxy.internal"`},
+		{Configuration{LogFormat: "xml"}, "log_format"},
+		{Configuration{Admins: []string{"root"}}, `admins entry "root" must be provider:user`},
+		{Configuration{Collections: []CollectionConfig{{Name: "team", Editors: []string{"alice"}}}}, `collection team member "alice" must be provider:user`},
+		{Configuration{Groups: map[string][]string{"ops": {"git:bob", "carol"}}}, `group ops member "carol" must be provider:user`},
+	} {
+		err := tc.cfg.Validate()
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%+v: err = %v, want %q", tc.cfg, err, tc.want)
+		}
+	}
+}
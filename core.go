@@ -34,22 +34,82 @@ func CoreAdderMiddleware(next http.Handler) http.Handler {
 		}
 
 		ctx := context.WithValue(request.Context(), ContextValues("provider"), providerName)
-			tab := TabFromRequest(request)
+		tab := TabFromRequest(request)
+		collectionName := collectionFromRequest(request)
+		collectionReadOnly := false
+		collectionDenied := false
+		impersonateProvider, impersonating := impersonation(session, providerName, login)
+		if impersonating != "" {
+			// An administrator views another account with its own
+			// provider and default file, ignoring their own selections.
+			ctx = context.WithValue(ctx, ContextValues("provider"), impersonateProvider)
+			ctx = context.WithValue(ctx, ContextValues("impersonate"), impersonating)
+			collectionName = ""
+		} else {
+			if collectionName != "" {
+				c := FindCollection(collectionName)
+				if c != nil && c.Role(providerName, login) != CollectionRoleNone {
+					ctx = context.WithValue(ctx, ContextValues("collection"), c)
+					collectionReadOnly = c.Role(providerName, login) != CollectionRoleEditor
+				} else {
+					collectionDenied = true
+					collectionName = ""
+				}
+			}
+			// A collection lives in its own repository and default file, so
+			// the member's personal selections only apply outside it.
+			if collectionName == "" {
+				settings := accountSettings(ctx, session, providerName, login)
+				if settings.RepoName != "" {
+					ctx = withRepoName(ctx, settings.RepoName)
+				}
+				if settings.BookmarkFile != "" {
+					ctx = withBookmarkFile(ctx, settings.BookmarkFile)
+				}
+			}
+		}
+		addLogAttrs(ctx, "user", login, "provider", providerName, "impersonating", impersonating, "ref", request.URL.Query().Get("ref"))
 		ctx = context.WithValue(ctx, ContextValues("coreData"), &CoreData{
-			UserRef:      login,
-			Title:        title,
-			Tab:          tab,
-			requestCache: &requestCache{data: make(map[string]*bookmarkCacheEntry)},
+			UserRef:               login,
+			Title:                 title,
+			Tab:                   tab,
+			Collection:            collectionName,
+			CollectionReadOnly:    collectionReadOnly,
+			Impersonating:         impersonating,
+			ImpersonatingProvider: impersonateProvider,
+			RepoName:              repoNameFromContext(ctx),
+			BookmarkFile:          bookmarkFileFromContext(ctx),
+			requestCache:          &requestCache{data: make(map[string]*bookmarkCacheEntry)},
 		})
-		next.ServeHTTP(writer, request.WithContext(ctx))
+		request = request.WithContext(ctx)
+		if collectionDenied {
+			renderErrorPage(writer, request, http.StatusForbidden, "You are not a member of this collection")
+			return
+		}
+		if impersonating != "" && !safeMethod(request.Method) && !strings.HasPrefix(request.URL.Path, "/admin/") {
+			renderErrorPage(writer, request, http.StatusForbidden, "You are viewing "+impersonating+"'s bookmarks read only. Stop viewing as them to make changes.")
+			return
+		}
+		next.ServeHTTP(writer, request)
 	})
 }
 
 type CoreData struct {
-	Title        string
-	AutoRefresh  bool
-	UserRef      string
-	Tab          int
+	Title       string
+	AutoRefresh bool
+	UserRef     string
+	Tab         int
+	// Collection is the shared collection the request works on, named by
+	// its "collection" parameter. It is empty for the user's own bookmarks.
+	Collection         string
+	CollectionReadOnly bool
+	// Impersonating is the account an administrator is viewing read only
+	// and ImpersonatingProvider the provider storing it.
+	Impersonating         string
+	ImpersonatingProvider string
+	// RepoName and BookmarkFile locate the bookmarks being viewed.
+	RepoName     string
+	BookmarkFile string
 	requestCache *requestCache
 }
 
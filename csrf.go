@@ -0,0 +1,97 @@
+package gobookmarks
+
+import (
+	"crypto/subtle"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/sessions"
+)
+
+const (
+	csrfSessionKey = "CSRF"
+	csrfFormField  = "csrf_token"
+	csrfHeader     = "X-CSRF-Token"
+)
+
+// csrfMessage is shown when a state-changing request lacks a valid token.
+const csrfMessage = "This form has expired or was not sent from this site. Go back, reload the page and try again."
+
+// csrfToken returns the anti-forgery token of the request's session.
+func csrfToken(r *http.Request) string {
+	session, _ := r.Context().Value(ContextValues("session")).(*sessions.Session)
+	if session == nil {
+		return ""
+	}
+	token, _ := session.Values[csrfSessionKey].(string)
+	return token
+}
+
+// csrfFormInput renders the hidden form field carrying the token.
+func csrfFormInput(r *http.Request) template.HTML {
+	return template.HTML(`<input type="hidden" name="` + csrfFormField + `" value="` + template.HTMLEscapeString(csrfToken(r)) + `" />`)
+}
+
+// needsCSRFToken reports whether a page may contain forms: every page of a
+// signed-in user and the login pages. Other visitors are not given a session
+// just to hold a token.
+func needsCSRFToken(r *http.Request, session *sessions.Session) bool {
+	if _, ok := session.Values["GithubUser"].(*User); ok {
+		return true
+	}
+	return r.URL.Path == "/login" || strings.HasPrefix(r.URL.Path, "/login/")
+}
+
+func safeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+// CSRFMiddleware issues a per-session token on pages with forms and rejects
+// state-changing requests that do not echo it in the csrf_token form field or
+// the X-CSRF-Token header. Requests authenticated by a bearer token carry no
+// ambient credentials and are exempt. It must run after CoreAdderMiddleware.
+func CSRFMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		session, _ := r.Context().Value(ContextValues("session")).(*sessions.Session)
+		if session == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if safeMethod(r.Method) {
+			if csrfToken(r) == "" && needsCSRFToken(r, session) {
+				token, err := newSessionID()
+				if err == nil {
+					session.Values[csrfSessionKey] = token
+					session.Values["version"] = version
+					err = session.Save(r, w)
+				}
+				if err != nil {
+					slog.ErrorContext(r.Context(), "csrf token failed", "err", err)
+				}
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+		if strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		want := csrfToken(r)
+		got := r.Header.Get(csrfHeader)
+		if got == "" {
+			got = r.PostFormValue(csrfFormField)
+		}
+		if want == "" || subtle.ConstantTimeCompare([]byte(want), []byte(got)) != 1 {
+			slog.WarnContext(r.Context(), "csrf check rejected request", "method", r.Method, "path", r.URL.Path, "ip", clientIP(r))
+			renderErrorPage(w, r, http.StatusForbidden, csrfMessage)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
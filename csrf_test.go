@@ -0,0 +1,112 @@
+package gobookmarks
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/sessions"
+)
+
+func setupCSRFTest(t *testing.T) http.Handler {
+	t.Helper()
+	old := Config
+	t.Cleanup(func() { Config = old })
+	Config.SessionName = "gobookmarks"
+	SessionStore = sessions.NewCookieStore([]byte("secret-key"))
+	return UserAdderMiddleware(CoreAdderMiddleware(CSRFMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s := r.Context().Value(ContextValues("session")).(*sessions.Session)
+		if r.URL.Path == "/signin" {
+			s.Values["GithubUser"] = &User{Login: "alice"}
+			s.Values["version"] = version
+			if err := s.Save(r, w); err != nil {
+				t.Errorf("session save: %v", err)
+			}
+			return
+		}
+		w.Header().Set("X-Test-Token", csrfToken(r))
+	}))))
+}
+
+func csrfRequest(h http.Handler, method, path string, form url.Values, prev *httptest.ResponseRecorder) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(form.Encode()))
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	if prev != nil {
+		addLastCookies(req, prev)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+func TestCSRFTokenIssuedToSignedInUser(t *testing.T) {
+	h := setupCSRFTest(t)
+	w := csrfRequest(h, "GET", "/", nil, nil)
+	if got := w.Header().Get("X-Test-Token"); got != "" {
+		t.Fatalf("anonymous visitor given token %q", got)
+	}
+	if got := csrfRequest(h, "GET", "/login/git", nil, nil).Header().Get("X-Test-Token"); got == "" {
+		t.Fatalf("login page has no token")
+	}
+	w = csrfRequest(h, "GET", "/signin", nil, nil)
+	w = csrfRequest(h, "GET", "/", nil, w)
+	token := w.Header().Get("X-Test-Token")
+	if token == "" {
+		t.Fatalf("signed-in user has no token")
+	}
+	if got := csrfRequest(h, "GET", "/", nil, w).Header().Get("X-Test-Token"); got != token {
+		t.Fatalf("token changed between requests: %q != %q", got, token)
+	}
+}
+
+func TestCSRFRejectsMissingToken(t *testing.T) {
+	h := setupCSRFTest(t)
+	w := csrfRequest(h, "GET", "/signin", nil, nil)
+	w = csrfRequest(h, "GET", "/", nil, w)
+	token := w.Header().Get("X-Test-Token")
+
+	if got := csrfRequest(h, "POST", "/edit", url.Values{"text": {"x"}}, w); got.Code != http.StatusForbidden {
+		t.Fatalf("missing token: status %d", got.Code)
+	} else if !strings.Contains(got.Body.String(), "reload the page") {
+		t.Fatalf("error page missing explanation: %s", got.Body.String())
+	}
+	if got := csrfRequest(h, "POST", "/edit", url.Values{csrfFormField: {"wrong"}}, w); got.Code != http.StatusForbidden {
+		t.Fatalf("wrong token: status %d", got.Code)
+	}
+	if got := csrfRequest(h, "POST", "/edit", url.Values{csrfFormField: {token}}, w); got.Code != http.StatusOK {
+		t.Fatalf("form token: status %d", got.Code)
+	}
+
+	req := httptest.NewRequest("POST", "/moveTab?from=0&to=1", nil)
+	req.Header.Set(csrfHeader, token)
+	addLastCookies(req, w)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("header token: status %d", rec.Code)
+	}
+}
+
+func TestCSRFBearerExempt(t *testing.T) {
+	h := setupCSRFTest(t)
+	req := httptest.NewRequest("POST", "/edit", nil)
+	req.Header.Set("Authorization", "Bearer abc")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("bearer request: status %d", w.Code)
+	}
+}
+
+func TestCSRFTokenRotatesOnLogin(t *testing.T) {
+	session := sessions.NewSession(SessionStore, "gobookmarks")
+	session.Values[csrfSessionKey] = "old"
+	renewSession(httptest.NewRequest("GET", "/", nil), session)
+	if _, ok := session.Values[csrfSessionKey]; ok {
+		t.Fatalf("token kept across identity change")
+	}
+}
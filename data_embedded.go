@@ -5,6 +5,7 @@ package gobookmarks
 import (
 	"embed"
 	"html/template"
+	"io/fs"
 	"sync"
 )
 
@@ -15,6 +16,8 @@ var (
 	mainCSSData []byte
 	//go:embed "logo.png"
 	faviconData []byte
+	//go:embed js
+	scriptFS embed.FS
 
 	compiledTemplates *template.Template
 	compileOnce       sync.Once
@@ -45,3 +48,12 @@ func GetMainCSSData() []byte {
 func GetFavicon() []byte {
 	return faviconData
 }
+
+// GetScripts returns the JavaScript files served under /js/.
+func GetScripts() fs.FS {
+	sub, err := fs.Sub(scriptFS, "js")
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}
@@ -4,13 +4,14 @@ package gobookmarks
 
 import (
 	"html/template"
-	"log"
+	"io/fs"
+	"log/slog"
 	"os"
 	"path/filepath"
 )
 
 func init() {
-	log.Printf("Live data mode")
+	slog.Info("live data mode")
 }
 
 func GetCompiledTemplates(funcs template.FuncMap) *template.Template {
@@ -28,7 +29,7 @@ func GetCompiledTemplates(funcs template.FuncMap) *template.Template {
 	fsys := os.DirFS(fsPath)
 	parsed, err := ParseFSRecursive(t, fsys, ".", ".gohtml")
 	if err != nil {
-		log.Printf("ParseFSRecursive error: %v", err)
+		slog.Error("parse templates failed", "err", err)
 	}
 	return template.Must(parsed, err)
 }
@@ -62,3 +63,14 @@ func GetFavicon() []byte {
 	}
 	return b
 }
+
+func GetScripts() fs.FS {
+	fsPath := "js"
+	if _, err := os.Stat(fsPath); os.IsNotExist(err) {
+		fsPath = "../../js"
+	}
+	if _, err := os.Stat(fsPath); os.IsNotExist(err) {
+		fsPath = "../js"
+	}
+	return os.DirFS(fsPath)
+}
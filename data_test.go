@@ -51,10 +51,27 @@ func testFuncMap() template.FuncMap {
 		"LoginURL":           func(p string) string { return "https://example.com/login/" + p },
 		"Providers":          func() []string { return []string{"github", "gitlab"} },
 		"AllProviders":       func() []string { return []string{"github", "gitlab"} },
+		"oidcLoginName":      func() string { return "SSO" },
 		"ProviderConfigured": func(string) bool { return true },
 		"errorMsg":           func(s string) string { return s },
 		"ref":                func() string { return "refs/heads/main" },
 		"add1":               func(i int) int { return i + 1 },
+		"tagFilter":          func() string { return "" },
+		"categoryOptions": func() ([]CategoryOption, error) {
+			return []CategoryOption{{Index: 0, Label: "Main: Demo"}, {Index: 1, Label: "Main: Demo / Nested"}}, nil
+		},
+		"entryTags": func() ([]TagCount, error) {
+			return []TagCount{{Name: "oncall", Count: 1}}, nil
+		},
+		"taggedCategories": func(tag string) ([]TaggedCategory, error) {
+			return []TaggedCategory{{Path: "Demo", TabName: "Main", Entries: []*BookmarkEntry{{Url: "https://example.com", Name: "Home", Tags: []string{tag}}}}}, nil
+		},
+		"categoryView": func(c *BookmarkCategory, tab, page int) CategoryView {
+			return CategoryView{BookmarkCategory: c, Tab: tab, Page: page}
+		},
+		"entryView": func(e *BookmarkEntry, editable bool) EntryView {
+			return EntryView{BookmarkEntry: e, Editable: editable}
+		},
 		"sub1": func(i int) int {
 			if i > 0 {
 				return i - 1
@@ -90,7 +107,16 @@ func testFuncMap() template.FuncMap {
 											Name:  "Demo",
 											Index: 0,
 											Entries: []*BookmarkEntry{
-												{Name: "Home", Url: "https://example.com"},
+												{Name: "Home", Url: "https://example.com", Tags: []string{"demo"}, Description: "Demo note"},
+											},
+											Subcategories: []*BookmarkCategory{
+												{
+													Name:  "Nested",
+													Index: 1,
+													Entries: []*BookmarkEntry{
+														{Name: "Docs", Url: "https://example.com/docs"},
+													},
+												},
 											},
 										},
 									},
@@ -127,7 +153,7 @@ func testFuncMap() template.FuncMap {
 											Name:  "Demo",
 											Index: 0,
 											Entries: []*BookmarkEntry{
-												{Name: "Home", Url: "https://example.com"},
+												{Name: "Home", Url: "https://example.com", Tags: []string{"demo"}, Description: "Demo note"},
 											},
 										},
 									},
@@ -157,6 +183,26 @@ func testFuncMap() template.FuncMap {
 				CommitterDate:  time.Unix(0, 0),
 			}}, nil
 		},
+		"bookmarkFiles":          func() ([]string, error) { return []string{"bookmarks.txt", "work.txt"}, nil },
+		"supportsBookmarkFiles":  func() bool { return true },
+		"supportsRepoSelection":  func() bool { return true },
+		"serverSessionsEnabled":  func() bool { return true },
+		"twoFactorAvailable":     func() bool { return true },
+		"isAdmin":                func() bool { return true },
+		"passwordResetAvailable": func(string) bool { return true },
+		"qrCode":                 qrSVG,
+		"csrfToken":              func() string { return "token" },
+		"csrfField":              func() template.HTML { return "" },
+		"userSessions": func() ([]SessionInfo, error) {
+			return []SessionInfo{{ID: "abc", Device: "Firefox", IP: "127.0.0.1", Current: true}}, nil
+		},
+		"mirrorStatus": func() (*MirrorStatus, error) {
+			return &MirrorStatus{URL: "https://example.com/repo.git", LastResult: "up to date"}, nil
+		},
+		"collection": func() string { return "" },
+		"otherTabLists": func() []TabList {
+			return []TabList{{Name: "team", Collection: "team", Tabs: []TabInfo{{IndexName: "Main", Href: "/?collection=team"}}}}
+		},
 		"prevCommit":  func() string { return "prev" },
 		"nextCommit":  func() string { return "next" },
 		"isSearchURL": func(string) bool { return false },
@@ -221,6 +267,8 @@ func TestExecuteTemplates(t *testing.T) {
 		{"editCategory", "editCategory.gohtml", catData},
 		{"editPage", "editPage.gohtml", pageData},
 		{"history", "history.gohtml", baseData},
+		{"tags", "tags.gohtml", baseData},
+		{"taggedEntries", "taggedEntries", "oncall"},
 		{"historyCommits", "historyCommits.gohtml", baseData},
 		{"taskDone", "taskDoneAutoRefreshPage.gohtml", baseData},
 		{"error", "error.gohtml", struct {
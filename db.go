@@ -64,8 +64,23 @@ func ensureSQLSchema(db *sql.DB) error {
 		return fmt.Errorf("failed to query schema version: %w", err)
 	}
 
-	if ver != sqlSchemaVersion {
+	if ver > sqlSchemaVersion {
 		return fmt.Errorf("unsupported schema version %d", ver)
 	}
+	for ver < sqlSchemaVersion {
+		next := ver + 1
+		migrateFile := fmt.Sprintf("sql/migrate.%d.%s.sql", next, strings.ToLower(Config.DBConnectionProvider))
+		migration, err := sqlSchemas.ReadFile(migrateFile)
+		if err != nil {
+			return fmt.Errorf("failed to find sql migration %s: %w", migrateFile, err)
+		}
+		if _, err := db.Exec(string(migration)); err != nil {
+			return fmt.Errorf("failed to apply migration %s: %w", migrateFile, err)
+		}
+		if _, err := db.Exec("UPDATE meta SET version=?", next); err != nil {
+			return fmt.Errorf("failed to set schema version: %w", err)
+		}
+		ver = next
+	}
 	return nil
 }
@@ -0,0 +1,154 @@
+package gobookmarks
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+const entryTransferText = `Category: A
+http://a1.com A1 #x
+> note
+http://a2.com A2
+  Subcategory: B
+  http://b1.com B1
+Tab: Other
+Category: C
+http://c1.com C1
+`
+
+func TestMoveEntryTo(t *testing.T) {
+	tabs := ParseBookmarks(entryTransferText)
+	if err := tabs.MoveEntryTo(0, 0, 2, 0); err != nil {
+		t.Fatalf("MoveEntryTo: %v", err)
+	}
+	expected := `Category: A
+http://a2.com A2
+  Subcategory: B
+  http://b1.com B1
+Tab: Other
+Category: C
+http://a1.com A1 #x
+> note
+http://c1.com C1
+`
+	if got := tabs.String(); got != expected {
+		t.Fatalf("expected %q got %q", expected, got)
+	}
+}
+
+func TestMoveEntryToSameCategory(t *testing.T) {
+	tabs := ParseBookmarks(entryTransferText)
+	if err := tabs.MoveEntryTo(0, 0, 0, -1); err != nil {
+		t.Fatalf("MoveEntryTo: %v", err)
+	}
+	a := tabs.FindCategory(0)
+	if a.Entries[0].Url != "http://a2.com" || a.Entries[1].Url != "http://a1.com" {
+		t.Fatalf("unexpected order %v", a.Entries)
+	}
+}
+
+func TestCopyEntryTo(t *testing.T) {
+	tabs := ParseBookmarks(entryTransferText)
+	if err := tabs.CopyEntryTo(0, 0, 1, -1); err != nil {
+		t.Fatalf("CopyEntryTo: %v", err)
+	}
+	a, b := tabs.FindCategory(0), tabs.FindCategory(1)
+	if len(a.Entries) != 2 || len(b.Entries) != 2 {
+		t.Fatalf("unexpected entries %v %v", a.Entries, b.Entries)
+	}
+	copied := b.Entries[1]
+	if copied == a.Entries[0] || copied.String() != a.Entries[0].String() {
+		t.Fatalf("expected an independent copy, got %q", copied.String())
+	}
+	copied.Tags[0] = "y"
+	if a.Entries[0].Tags[0] != "x" {
+		t.Fatalf("copy shares tags with the original")
+	}
+}
+
+func TestMoveEntryToInvalid(t *testing.T) {
+	tabs := ParseBookmarks(entryTransferText)
+	if err := tabs.MoveEntryTo(0, 5, 1, 0); err == nil {
+		t.Fatalf("expected error for missing entry")
+	}
+	if err := tabs.MoveEntryTo(0, 0, 9, 0); err == nil {
+		t.Fatalf("expected error for missing category")
+	}
+	if got := tabs.String(); got != entryTransferText {
+		t.Fatalf("failed move changed bookmarks: %q", got)
+	}
+}
+
+func TestCategoryOptions(t *testing.T) {
+	opts := ParseBookmarks(entryTransferText).CategoryOptions()
+	want := []CategoryOption{{0, "A: A"}, {1, "A: A / B"}, {2, "Other: C"}}
+	if len(opts) != len(want) {
+		t.Fatalf("expected %v got %v", want, opts)
+	}
+	for i := range want {
+		if opts[i] != want[i] {
+			t.Errorf("option %d: expected %v got %v", i, want[i], opts[i])
+		}
+	}
+}
+
+func TestEntryMoveToAction(t *testing.T) {
+	p, user, _, ctx := setupCategoryEditTest(t)
+	if err := p.CreateBookmarks(context.Background(), user, nil, "main", entryTransferText); err != nil {
+		t.Fatalf("CreateBookmarks: %v", err)
+	}
+	_, sha, err := p.GetBookmarks(context.Background(), user, "refs/heads/main", nil)
+	if err != nil {
+		t.Fatalf("GetBookmarks: %v", err)
+	}
+	form := url.Values{"fromCategory": {"0"}, "from": {"1"}, "toCategory": {"2"}, "to": {""}, "branch": {"main"}, "ref": {"refs/heads/main"}, "sha": {sha}}
+	req := httptest.NewRequest("POST", "/moveEntryTo", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req = req.WithContext(ctx)
+	if err := EntryMoveToAction(httptest.NewRecorder(), req); err != nil {
+		t.Fatalf("EntryMoveToAction: %v", err)
+	}
+	got, _, err := p.GetBookmarks(context.Background(), user, "refs/heads/main", nil)
+	if err != nil {
+		t.Fatalf("GetBookmarks after: %v", err)
+	}
+	tabs := ParseBookmarks(entryTransferText)
+	if err := tabs.MoveEntryTo(0, 1, 2, -1); err != nil {
+		t.Fatalf("MoveEntryTo local: %v", err)
+	}
+	if expected := tabs.String(); got != expected {
+		t.Fatalf("expected %q got %q", expected, got)
+	}
+
+	// The SHA is now stale so a second request is rejected.
+	form.Set("from", "0")
+	req = httptest.NewRequest("POST", "/copyEntryTo", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req = req.WithContext(ctx)
+	w := httptest.NewRecorder()
+	if err := EntryCopyToAction(w, req); !errors.Is(err, ErrHandled) {
+		t.Fatalf("expected the error to be written, got %v", err)
+	}
+	if w.Code != http.StatusConflict || !strings.Contains(w.Body.String(), "Reload") {
+		t.Fatalf("expected conflict, got %d %q", w.Code, w.Body.String())
+	}
+
+	// A request without a SHA is rejected rather than applied blindly.
+	_, sha, _ = p.GetBookmarks(context.Background(), user, "refs/heads/main", nil)
+	form.Del("sha")
+	req = httptest.NewRequest("POST", "/copyEntryTo", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req = req.WithContext(ctx)
+	w = httptest.NewRecorder()
+	if err := EntryCopyToAction(w, req); !errors.Is(err, ErrHandled) || w.Code != http.StatusConflict {
+		t.Fatalf("expected a missing sha to be rejected, got %v %d", err, w.Code)
+	}
+	if _, after, _ := p.GetBookmarks(context.Background(), user, "refs/heads/main", nil); after != sha {
+		t.Fatalf("bookmarks changed without a sha")
+	}
+}
@@ -0,0 +1,104 @@
+package gobookmarks
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// exportCommit describes a history file in an export's commits.json.
+type exportCommit struct {
+	SHA     string    `json:"sha"`
+	Message string    `json:"message"`
+	Author  string    `json:"author"`
+	Email   string    `json:"email,omitempty"`
+	Date    time.Time `json:"date"`
+	File    string    `json:"file"`
+}
+
+// exportFiles returns the bookmark files stored for user on main.
+func exportFiles(ctx context.Context, p Provider, user string, token *oauth2.Token) ([]string, error) {
+	if fl, ok := p.(FileLister); ok {
+		files, err := fl.ListBookmarkFiles(ctx, user, token, "refs/heads/main")
+		if err != nil {
+			return nil, err
+		}
+		if len(files) > 0 {
+			return files, nil
+		}
+	}
+	return []string{DefaultBookmarkFile}, nil
+}
+
+// writeExport writes a zip archive holding each of user's bookmark files as
+// they are on main, followed by history/<file>/ with one text file for every
+// commit that changed the file, oldest first, and a commits.json describing
+// them.
+func writeExport(ctx context.Context, w io.Writer, p Provider, user string, token *oauth2.Token) error {
+	files, err := exportFiles(ctx, p, user, token)
+	if err != nil {
+		return fmt.Errorf("list bookmark files: %w", err)
+	}
+	commits, err := migrateCommitList(ctx, p, user, token, "refs/heads/main")
+	if err != nil {
+		return fmt.Errorf("list commits: %w", err)
+	}
+	zw := zip.NewWriter(w)
+	add := func(name string, modified time.Time, b []byte) error {
+		f, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modified})
+		if err != nil {
+			return err
+		}
+		_, err = f.Write(b)
+		return err
+	}
+	now := time.Now()
+	for _, file := range files {
+		fctx := withBookmarkFile(ctx, file)
+		text, _, err := p.GetBookmarks(fctx, user, "refs/heads/main", token)
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		if err := add(file, now, []byte(text)); err != nil {
+			return err
+		}
+		var written []exportCommit
+		prev := ""
+		for _, c := range commits {
+			text, _, err := p.GetBookmarks(fctx, user, c.SHA, token)
+			if err != nil {
+				return fmt.Errorf("%s at %s: %w", file, c.SHA, err)
+			}
+			if text == prev {
+				continue
+			}
+			prev = text
+			name := path.Join("history", file, fmt.Sprintf("%04d-%.12s.txt", len(written)+1, c.SHA))
+			if err := add(name, c.CommitterDate, []byte(text)); err != nil {
+				return err
+			}
+			written = append(written, exportCommit{
+				SHA:     c.SHA,
+				Message: c.Message,
+				Author:  c.CommitterName,
+				Email:   c.CommitterEmail,
+				Date:    c.CommitterDate,
+				File:    path.Base(name),
+			})
+		}
+		b, err := json.MarshalIndent(written, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := add(path.Join("history", file, "commits.json"), now, b); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
@@ -2,6 +2,7 @@ package gobookmarks
 
 import (
 	"bytes"
+	"context"
 	"crypto/sha1"
 	"encoding/hex"
 	"encoding/json"
@@ -13,7 +14,7 @@ import (
 	"image/jpeg"
 	"image/png"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"os"
@@ -83,19 +84,47 @@ func writeDiskFavicon(u string, f *FavIcon, expiry time.Time) {
 	if Config.FaviconCacheDir == "" {
 		return
 	}
+	defer trackWork()()
 	if err := os.MkdirAll(Config.FaviconCacheDir, 0o755); err != nil {
 		return
 	}
 	base := cacheFileBase(u)
 	dataPath := base + ".dat"
 	metaPath := base + ".json"
-	_ = os.WriteFile(dataPath, f.Data, 0o644)
+	if err := writeFileAtomic(dataPath, f.Data); err != nil {
+		return
+	}
 	m := diskMeta{ContentType: f.ContentType, Expiry: expiry}
 	mb, _ := json.Marshal(m)
-	_ = os.WriteFile(metaPath, mb, 0o644)
+	_ = writeFileAtomic(metaPath, mb)
 	enforceCacheLimit()
 }
 
+// writeFileAtomic writes data to a temporary file next to path and renames
+// it into place, so a process stopped part way never leaves a truncated
+// cache file behind.
+func writeFileAtomic(path string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		_ = os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	if err := os.Chmod(tmp, 0o644); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
 func enforceCacheLimit() {
 	if Config.FaviconCacheDir == "" || Config.FaviconCacheSize <= 0 {
 		return
@@ -144,7 +173,7 @@ func FaviconProxyHandler(w http.ResponseWriter, r *http.Request) {
 	up, err := url.Parse(urlParam)
 	if err != nil {
 		err := fmt.Errorf("parsing URL: %s", err)
-		log.Printf("Error %s", err)
+		slog.WarnContext(r.Context(), "favicon request rejected", "url", urlParam, "err", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -186,6 +215,7 @@ func FaviconProxyHandler(w http.ResponseWriter, r *http.Request) {
 	// 1. Try exact match (e.g. resized)
 	if size > 0 {
 		if icon := getFromCache(targetKey); icon != nil {
+			observeCacheLookup(faviconCacheRequests, true)
 			w.Header().Set("Content-Type", icon.ContentType)
 			_, _ = w.Write(icon.Data)
 			return
@@ -194,6 +224,7 @@ func FaviconProxyHandler(w http.ResponseWriter, r *http.Request) {
 
 	// 2. Try base match
 	cacheValue := getFromCache(urlParam)
+	observeCacheLookup(faviconCacheRequests, cacheValue != nil)
 	if cacheValue != nil {
 		icon := cacheValue
 		if size > 0 {
@@ -212,8 +243,10 @@ func FaviconProxyHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Fetch the root page content
-	rootPageContent, err := fetchURL(urlParam)
+	rootPageContent, err := fetchURL(r.Context(), urlParam)
 	if err != nil {
+		faviconFetchFailures.Inc()
+		slog.WarnContext(r.Context(), "favicon fetch failed", "url", urlParam, "err", err)
 		http.Error(w, fmt.Sprintf("Error fetching root page: %s", err), http.StatusInternalServerError)
 		return
 	}
@@ -221,6 +254,8 @@ func FaviconProxyHandler(w http.ResponseWriter, r *http.Request) {
 	// Find the favicon URL from the root page content
 	faviconURL, fileType, err := findFaviconURL(rootPageContent, up)
 	if err != nil {
+		faviconFetchFailures.Inc()
+		slog.WarnContext(r.Context(), "favicon not found in page", "url", urlParam, "err", err)
 		http.Error(w, fmt.Sprintf("Error finding favicon URL: %s", err), http.StatusInternalServerError)
 		return
 	}
@@ -229,13 +264,17 @@ func FaviconProxyHandler(w http.ResponseWriter, r *http.Request) {
 		fileType = "image/x-icon"
 	}
 	// Proxy the favicon request
-	faviconContent, hdr, err := downloadURL(faviconURL)
+	faviconContent, hdr, err := downloadURL(r.Context(), faviconURL)
 	if err != nil {
+		faviconFetchFailures.Inc()
+		slog.WarnContext(r.Context(), "favicon fetch failed", "url", faviconURL, "err", err)
 		http.Error(w, fmt.Sprintf("Error proxying favicon: %s", err), http.StatusInternalServerError)
 		return
 	}
 
 	if len(faviconContent) > 1*1024*1024 {
+		faviconFetchFailures.Inc()
+		slog.WarnContext(r.Context(), "favicon too large", "url", faviconURL, "bytes", len(faviconContent))
 		http.Error(w, fmt.Sprintf("Error proxying favicon: %s", "favicon too large"), http.StatusInternalServerError)
 		return
 	}
@@ -272,8 +311,8 @@ func FaviconProxyHandler(w http.ResponseWriter, r *http.Request) {
 	_, _ = w.Write(icon.Data)
 }
 
-func fetchURL(urlParam string) ([]byte, error) {
-	req, err := http.NewRequest("GET", urlParam, nil)
+func fetchURL(ctx context.Context, urlParam string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", urlParam, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -319,8 +358,8 @@ func findFaviconURL(pageContent []byte, baseURL *url.URL) (string, string, error
 	return p.String(), fileType, nil
 }
 
-func downloadURL(url string) ([]byte, http.Header, error) {
-	req, err := http.NewRequest("GET", url, nil)
+func downloadURL(ctx context.Context, url string) ([]byte, http.Header, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, nil, err
 	}
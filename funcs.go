@@ -29,6 +29,21 @@ type TabWithPages struct {
 	Pages []*BookmarkPage
 }
 
+// CategoryView carries a category into a nested template along with the
+// tab and page it is rendered on.
+type CategoryView struct {
+	*BookmarkCategory
+	Tab  int
+	Page int
+}
+
+// EntryView carries an entry into the "entry" template. Editable entries
+// get the move handle and the send button.
+type EntryView struct {
+	*BookmarkEntry
+	Editable bool
+}
+
 var (
 	defaultBookmarks = "Category: Example 1\nhttp://www.google.com.au Google\nColumn\nCategory: Example 2\nhttp://www.google.com.au Google\nhttp://www.google.com.au Google\n"
 	version          = "dev"
@@ -102,6 +117,15 @@ func NewFuncs(r *http.Request) template.FuncMap {
 			}
 			return names
 		},
+		"oidcLoginName": func() string {
+			if Config.OIDCIssuer == "" || Config.OIDCClientID == "" {
+				return ""
+			}
+			if Config.OIDCName != "" {
+				return Config.OIDCName
+			}
+			return "SSO"
+		},
 		"AllProviders": func() []string {
 			return ProviderNames()
 		},
@@ -110,6 +134,100 @@ func NewFuncs(r *http.Request) template.FuncMap {
 			return creds != nil && GetProvider(p) != nil
 		},
 		"errorMsg": errorMessage,
+		"bookmarkFiles": func() ([]string, error) {
+			session := r.Context().Value(ContextValues("session")).(*sessions.Session)
+			githubUser, _ := session.Values["GithubUser"].(*User)
+			token, _ := session.Values["Token"].(*oauth2.Token)
+			login := ""
+			if githubUser != nil {
+				login = githubUser.Login
+			}
+			files, err := ListBookmarkFiles(r.Context(), login, token, r.URL.Query().Get("ref"))
+			if err != nil {
+				if errors.Is(err, ErrRepoNotFound) {
+					return nil, nil
+				}
+				return nil, fmt.Errorf("bookmarkFiles: %w", err)
+			}
+			current := bookmarkFileFromContext(r.Context())
+			for _, f := range files {
+				if f == current {
+					return files, nil
+				}
+			}
+			return append(files, current), nil
+		},
+		"supportsBookmarkFiles": func() bool {
+			_, ok := providerFromContext(r.Context()).(FileLister)
+			return ok
+		},
+		// The local git provider keeps one repository per user so only the
+		// hosted providers let the repository be chosen.
+		"supportsRepoSelection": func() bool {
+			p := providerFromContext(r.Context())
+			return p != nil && (p.Name() == "github" || p.Name() == "gitlab")
+		},
+		"qrCode": qrSVG,
+		"csrfToken": func() string {
+			return csrfToken(r)
+		},
+		"csrfField": func() template.HTML {
+			return csrfFormInput(r)
+		},
+		"twoFactorAvailable": func() bool {
+			session, _ := r.Context().Value(ContextValues("session")).(*sessions.Session)
+			if session == nil {
+				return false
+			}
+			name, _ := session.Values["Provider"].(string)
+			_, ok := GetProvider(name).(TwoFactorHandler)
+			return ok
+		},
+		"isAdmin": func() bool {
+			session, _ := r.Context().Value(ContextValues("session")).(*sessions.Session)
+			if session == nil {
+				return false
+			}
+			user, _ := session.Values["GithubUser"].(*User)
+			if user == nil {
+				return false
+			}
+			name, _ := session.Values["Provider"].(string)
+			return IsAdmin(name, user.Login)
+		},
+		"passwordResetAvailable": passwordResetAvailable,
+		"serverSessionsEnabled": func() bool {
+			return serverSessionStore() != nil
+		},
+		"userSessions": func() ([]SessionInfo, error) {
+			return userSessions(r)
+		},
+		"mirrorStatus": func() (*MirrorStatus, error) {
+			m, ok := providerFromContext(r.Context()).(Mirrorer)
+			if !ok {
+				return nil, nil
+			}
+			session := r.Context().Value(ContextValues("session")).(*sessions.Session)
+			githubUser, _ := session.Values["GithubUser"].(*User)
+			if githubUser == nil {
+				return nil, nil
+			}
+			owner, err := bookmarkOwner(r.Context(), githubUser.Login, false)
+			if err != nil {
+				return nil, nil
+			}
+			st, err := m.MirrorStatus(r.Context(), owner)
+			if errors.Is(err, ErrRepoNotFound) {
+				return nil, nil
+			}
+			return st, err
+		},
+		"collection": func() string {
+			return requestCollection(r)
+		},
+		"otherTabLists": func() []TabList {
+			return otherTabLists(r)
+		},
 		"ref": func() string {
 			return r.URL.Query().Get("ref")
 		},
@@ -123,10 +241,10 @@ func NewFuncs(r *http.Request) template.FuncMap {
 			return TabEditPath(tab)
 		},
 		"currentTabPath": func() string {
-			return TabPath(TabFromRequest(r))
+			return collectionHref(TabPath(TabFromRequest(r)), requestCollection(r))
 		},
 		"tabEditHref": func(tab int, ref, name string) string {
-			return TabEditHref(tab, ref, name)
+			return collectionHref(TabEditHref(tab, ref, name), requestCollection(r))
 		},
 		"appendQuery": func(rawURL string, params ...string) string {
 			return AppendQueryParams(rawURL, params...)
@@ -137,6 +255,12 @@ func NewFuncs(r *http.Request) template.FuncMap {
 		"historyRef": func() string {
 			return r.URL.Query().Get("historyRef")
 		},
+		"categoryView": func(c *BookmarkCategory, tab, page int) CategoryView {
+			return CategoryView{BookmarkCategory: c, Tab: tab, Page: page}
+		},
+		"entryView": func(e *BookmarkEntry, editable bool) EntryView {
+			return EntryView{BookmarkEntry: e, Editable: editable}
+		},
 		"add1": func(i int) int {
 			return i + 1
 		},
@@ -281,7 +405,7 @@ func NewFuncs(r *http.Request) template.FuncMap {
 					indexName = "Main"
 				}
 				if indexName != "" {
-					href := TabHref(i, ref)
+					href := collectionHref(TabHref(i, ref), requestCollection(r))
 					lastSha := ""
 					if len(t.Pages) > 0 {
 						lastSha = t.Pages[len(t.Pages)-1].Sha()
@@ -314,6 +438,7 @@ func NewFuncs(r *http.Request) template.FuncMap {
 				bookmark = bookmarks
 			}
 			tabsData := ParseBookmarks(bookmark)
+			ResolveIncludes(r.Context(), tabsData, login, ref, token)
 			var tabs []TabWithPages
 			for i, t := range tabsData {
 				indexName := t.DisplayName()
@@ -321,7 +446,7 @@ func NewFuncs(r *http.Request) template.FuncMap {
 					indexName = "Main"
 				}
 				if indexName != "" {
-					href := TabHref(i, ref)
+					href := collectionHref(TabHref(i, ref), requestCollection(r))
 					lastSha := ""
 					if len(t.Pages) > 0 {
 						lastSha = t.Pages[len(t.Pages)-1].Sha()
@@ -398,6 +523,30 @@ func NewFuncs(r *http.Request) template.FuncMap {
 			}
 			return columns, nil
 		},
+		"tagFilter": func() string {
+			return r.URL.Query().Get("tag")
+		},
+		"entryTags": func() ([]TagCount, error) {
+			list, err := requestBookmarkList(r)
+			if err != nil {
+				return nil, fmt.Errorf("entryTags: %w", err)
+			}
+			return list.Tags(), nil
+		},
+		"taggedCategories": func(tag string) ([]TaggedCategory, error) {
+			list, err := requestBookmarkList(r)
+			if err != nil {
+				return nil, fmt.Errorf("taggedCategories: %w", err)
+			}
+			return list.EntriesWithTag(tag), nil
+		},
+		"categoryOptions": func() ([]CategoryOption, error) {
+			list, err := requestBookmarkList(r)
+			if err != nil {
+				return nil, fmt.Errorf("categoryOptions: %w", err)
+			}
+			return list.CategoryOptions(), nil
+		},
 		"tags": func() ([]*Tag, error) {
 			session := r.Context().Value(ContextValues("session")).(*sessions.Session)
 			githubUser, _ := session.Values["GithubUser"].(*User)
@@ -550,7 +699,40 @@ func errorMessage(code string) string {
 		return "Account already exists"
 	case "oauth":
 		return "Login expired or was cancelled. Please try signing in again."
+	case "reserved":
+		return "That username is reserved"
+	case "oidc":
+		return "Single sign-on failed. Please try again."
+	case "2fa":
+		return "Two-factor login expired or failed too often. Please sign in again."
+	case "code":
+		return "Invalid authentication code"
+	case "throttled":
+		return "Too many failed sign-in attempts. Please wait a moment and try again."
+	case "disabled":
+		return "This account has been disabled"
+	case "email":
+		return "Enter a valid email address"
 	default:
 		return code
 	}
 }
+
+// requestBookmarkList parses the bookmarks at the ref of the request. A
+// missing repository gives an empty list.
+func requestBookmarkList(r *http.Request) (BookmarkList, error) {
+	session := r.Context().Value(ContextValues("session")).(*sessions.Session)
+	githubUser, _ := session.Values["GithubUser"].(*User)
+	token, _ := session.Values["Token"].(*oauth2.Token)
+
+	login := ""
+	if githubUser != nil {
+		login = githubUser.Login
+	}
+
+	bookmarks, _, err := GetBookmarks(r.Context(), login, r.URL.Query().Get("ref"), token)
+	if err != nil && !errors.Is(err, ErrRepoNotFound) {
+		return nil, err
+	}
+	return ParseBookmarks(bookmarks), nil
+}
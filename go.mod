@@ -18,6 +18,7 @@ require (
 	golang.org/x/image v0.43.0
 	golang.org/x/oauth2 v0.34.0
 	golang.org/x/tools v0.44.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
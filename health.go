@@ -0,0 +1,230 @@
+package gobookmarks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+)
+
+// readyCheckTimeout bounds each readiness check so a hung dependency
+// cannot stall the probe.
+const readyCheckTimeout = 5 * time.Second
+
+// reachableTTL is how long the outcome of an API reachability check is
+// reused. Probes arriving more often share one outbound request, keeping
+// them within unauthenticated rate limits.
+const reachableTTL = time.Minute
+
+// HealthCheck is the result of one readiness check.
+type HealthCheck struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// HealthReport is the body of /healthz and /readyz. Status is "ok" when
+// every check passed and "fail" otherwise.
+type HealthReport struct {
+	Status string        `json:"status"`
+	Checks []HealthCheck `json:"checks,omitempty"`
+}
+
+type readyCheck struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+// readyChecks returns the checks that apply to the current configuration.
+func readyChecks() []readyCheck {
+	checks := []readyCheck{{"session_key", checkSessionKey}}
+	if Config.DBConnectionProvider != "" {
+		checks = append(checks, readyCheck{"sql", checkSQL})
+	}
+	for _, name := range ConfiguredProviderNames() {
+		switch name {
+		case "git":
+			checks = append(checks, readyCheck{"git", func(context.Context) error { return checkWritableDir(Config.LocalGitPath) }})
+		case "github":
+			checks = append(checks, readyCheck{"github", func(ctx context.Context) error { return checkReachableCached(ctx, githubAPIURL()) }})
+		case "gitlab":
+			checks = append(checks, readyCheck{"gitlab", func(ctx context.Context) error { return checkReachableCached(ctx, gitlabAPIURL()) }})
+		}
+	}
+	if Config.FaviconCacheDir != "" {
+		checks = append(checks, readyCheck{"favicon_cache_dir", func(context.Context) error { return checkWritableDir(Config.FaviconCacheDir) }})
+	}
+	return checks
+}
+
+// checkSessionKey signs a throwaway value to show the session store has a
+// key to sign cookies with.
+func checkSessionKey(context.Context) error {
+	var codecs []securecookie.Codec
+	switch s := SessionStore.(type) {
+	case *sessions.CookieStore:
+		codecs = s.Codecs
+	case *ServerSessionStore:
+		codecs = s.Codecs
+	case nil:
+		return errors.New("no session store")
+	default:
+		return nil
+	}
+	if len(codecs) == 0 {
+		return errors.New("no session key")
+	}
+	_, err := securecookie.EncodeMulti("readyz", "ok", codecs...)
+	return err
+}
+
+func checkSQL(ctx context.Context) error {
+	_, ok := GetProvider("sql").(*SQLProvider)
+	if !ok {
+		return errors.New("sql support not compiled in")
+	}
+	db, err := sharedDB()
+	if err != nil {
+		return err
+	}
+	return db.PingContext(ctx)
+}
+
+// checkWritableDir creates and removes a file in dir.
+func checkWritableDir(dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(dir, ".readyz-*")
+	if err != nil {
+		return err
+	}
+	name := f.Name()
+	err = f.Close()
+	if rmErr := os.Remove(name); err == nil {
+		err = rmErr
+	}
+	return err
+}
+
+func githubAPIURL() string {
+	server := strings.TrimRight(Config.GithubServer, "/")
+	if server == "" || server == "https://github.com" {
+		return "https://api.github.com/"
+	}
+	return server + "/api/v3/"
+}
+
+func gitlabAPIURL() string {
+	server := strings.TrimRight(Config.GitlabServer, "/")
+	if server == "" {
+		server = "https://gitlab.com"
+	}
+	return server + "/api/v4/version"
+}
+
+// checkReachable requests url without credentials. Any answer other than a
+// server error shows the API is up; an authentication error is expected.
+func checkReachable(ctx context.Context, url string) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", fetchUserAgent)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode >= 500 {
+		return fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+	return nil
+}
+
+// reachResult is the last reachability check of one URL.
+type reachResult struct {
+	mu      sync.Mutex
+	checked time.Time
+	err     error
+}
+
+var reachResults sync.Map // url -> *reachResult
+
+// checkReachableCached calls checkReachable at most once per reachableTTL
+// for each url. Concurrent probes wait for the check in progress.
+func checkReachableCached(ctx context.Context, url string) error {
+	v, _ := reachResults.LoadOrStore(url, &reachResult{})
+	res := v.(*reachResult)
+	res.mu.Lock()
+	defer res.mu.Unlock()
+	if !res.checked.IsZero() && time.Since(res.checked) < reachableTTL {
+		return res.err
+	}
+	err := checkReachable(ctx, url)
+	if !errors.Is(ctx.Err(), context.Canceled) {
+		// A probe abandoned by its caller says nothing about the API.
+		res.checked, res.err = time.Now(), err
+	}
+	return err
+}
+
+// runReadyChecks runs the checks concurrently and reports them in order.
+func runReadyChecks(ctx context.Context, checks []readyCheck) HealthReport {
+	report := HealthReport{Status: "ok", Checks: make([]HealthCheck, len(checks))}
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, readyCheckTimeout)
+			defer cancel()
+			start := time.Now()
+			err := c.fn(ctx)
+			hc := HealthCheck{Name: c.name, Status: "ok", LatencyMS: float64(time.Since(start).Microseconds()) / 1000}
+			if err != nil {
+				hc.Status = "fail"
+				hc.Error = err.Error()
+			}
+			report.Checks[i] = hc
+		}()
+	}
+	wg.Wait()
+	for _, c := range report.Checks {
+		if c.Status != "ok" {
+			report.Status = "fail"
+			slog.WarnContext(ctx, "readiness check failed", "check", c.Name, "err", c.Error)
+		}
+	}
+	return report
+}
+
+func writeHealthReport(w http.ResponseWriter, report HealthReport) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if report.Status != "ok" {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_ = json.NewEncoder(w).Encode(report)
+}
+
+// HealthzHandler reports that the process is up and serving requests.
+func HealthzHandler(w http.ResponseWriter, r *http.Request) {
+	writeHealthReport(w, HealthReport{Status: "ok"})
+}
+
+// ReadyzHandler checks the services the configured providers depend on and
+// answers 503 when any of them fails.
+func ReadyzHandler(w http.ResponseWriter, r *http.Request) {
+	writeHealthReport(w, runReadyChecks(r.Context(), readyChecks()))
+}
@@ -0,0 +1,87 @@
+package gobookmarks
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gorilla/sessions"
+)
+
+func TestReadyz(t *testing.T) {
+	var githubCalls atomic.Int32
+	github := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		githubCalls.Add(1)
+		if r.URL.Path != "/api/v3/" {
+			t.Errorf("github check requested %s", r.URL.Path)
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer github.Close()
+	gitlab := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer gitlab.Close()
+
+	old, oldStore := Config, SessionStore
+	t.Cleanup(func() { Config, SessionStore = old, oldStore })
+	Config = Configuration{
+		LocalGitPath:    t.TempDir(),
+		FaviconCacheDir: t.TempDir() + "/favicons",
+		GithubClientID:  "id",
+		GithubSecret:    "secret",
+		GithubServer:    github.URL,
+		GitlabClientID:  "id",
+		GitlabSecret:    "secret",
+		GitlabServer:    gitlab.URL,
+	}
+	SessionStore = sessions.NewCookieStore([]byte("secret-key"))
+
+	get := func() (int, HealthReport) {
+		w := httptest.NewRecorder()
+		ReadyzHandler(w, httptest.NewRequest("GET", "/readyz", nil))
+		var report HealthReport
+		if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+			t.Fatalf("decode %q: %v", w.Body.String(), err)
+		}
+		return w.Code, report
+	}
+	code, report := get()
+	if code != http.StatusServiceUnavailable || report.Status != "fail" {
+		t.Fatalf("readyz = %d %+v", code, report)
+	}
+	want := map[string]string{"session_key": "ok", "git": "ok", "github": "ok", "gitlab": "fail", "favicon_cache_dir": "ok"}
+	if len(report.Checks) != len(want) {
+		t.Fatalf("checks = %+v", report.Checks)
+	}
+	for _, c := range report.Checks {
+		if want[c.Name] != c.Status {
+			t.Errorf("%s = %s (%s)", c.Name, c.Status, c.Error)
+		}
+	}
+
+	Config.GitlabClientID = ""
+	SessionStore = sessions.NewCookieStore()
+	code, report = get()
+	if code != http.StatusServiceUnavailable || len(report.Checks) != 4 || report.Checks[0].Name != "session_key" || report.Checks[0].Status != "fail" {
+		t.Fatalf("missing session key: %d %+v", code, report)
+	}
+
+	SessionStore = sessions.NewCookieStore([]byte("secret-key"))
+	if code, report = get(); code != http.StatusOK || report.Status != "ok" {
+		t.Fatalf("readyz = %d %+v", code, report)
+	}
+	if n := githubCalls.Load(); n != 1 {
+		t.Fatalf("github reachability checked %d times, want 1 within the ttl", n)
+	}
+}
+
+func TestHealthz(t *testing.T) {
+	w := httptest.NewRecorder()
+	HealthzHandler(w, httptest.NewRequest("GET", "/healthz", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "{\"status\":\"ok\"}\n" {
+		t.Fatalf("healthz = %d %q", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,26 @@
+document.addEventListener('DOMContentLoaded', function () {
+    function setActivePage() {
+        const pages = document.querySelectorAll('.bookmarkPage');
+        const pageLinks = document.querySelectorAll('#page-list li');
+        let activePageIndex = -1;
+
+        pages.forEach((page, index) => {
+            const rect = page.getBoundingClientRect();
+            if (rect.top >= 0 && rect.top < window.innerHeight) {
+                activePageIndex = index;
+            }
+        });
+
+        pageLinks.forEach((link, index) => {
+            if (index === activePageIndex) {
+                link.classList.add('active-page');
+            } else {
+                link.classList.remove('active-page');
+            }
+        });
+    }
+
+    window.addEventListener('scroll', setActivePage);
+    document.addEventListener('gobookmarks:tab-rendered', setActivePage);
+    setActivePage(); // Set active page on initial load
+});
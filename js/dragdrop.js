@@ -0,0 +1,164 @@
+function enableDragSort(list, buildUrl) {
+    if (!list) return;
+    let dragEl;
+    list.querySelectorAll('li').forEach(li => {
+        const handle = li.querySelector('.move-handle') || li;
+        handle.draggable = true;
+        handle.addEventListener('dragstart', e => {
+            if (window.isDragUpdating || !document.body.classList.contains('edit-mode')) {
+                e.preventDefault();
+                return;
+            }
+            dragEl = li;
+            e.dataTransfer.effectAllowed = 'move';
+        });
+        handle.addEventListener('dragend', () => {
+            dragEl = null;
+        });
+        li.addEventListener('dragover', e => {
+            e.preventDefault();
+            e.dataTransfer.dropEffect = 'move';
+        });
+        li.addEventListener('drop', e => {
+            e.preventDefault();
+            if (window.isDragUpdating) {
+                return;
+            }
+            if (dragEl && dragEl !== li) {
+                const items = Array.from(list.querySelectorAll('li'));
+                const from = items.indexOf(dragEl);
+                const to = items.indexOf(li);
+                if (from >= 0 && to >= 0) {
+                    window.isDragUpdating = true;
+                    if (from < to) {
+                        li.after(dragEl);
+                    } else {
+                        li.before(dragEl);
+                    }
+                    fetch(withCollection(buildUrl(from, to)), {method:'POST', headers: csrfHeaders()}).then(() => location.reload());
+                }
+            }
+        });
+    });
+}
+
+document.addEventListener('DOMContentLoaded', () => {
+    const tabList = document.getElementById('tab-list');
+    enableDragSort(tabList, (f,t)=>`/moveTab?from=${f}&to=${t}`);
+    const pageList = document.getElementById('page-list');
+    const currentTab = document.body.dataset.tab || '0';
+    const tabPrefix = currentTab && currentTab !== '0' ? `/tab/${encodeURIComponent(currentTab)}` : '';
+    enableDragSort(pageList, (f,t)=>`${tabPrefix}/movePage?from=${f}&to=${t}`);
+    document.querySelectorAll('.bookmark-entries[data-index]').forEach(ul => {
+        const cat = ul.dataset.index;
+        const page = ul.dataset.page;
+        enableDragSort(ul, (f,t)=>`${tabPrefix}/moveEntry?category=${cat}&page=${page}&from=${f}&to=${t}`);
+        enableEntryTransfer(ul);
+    });
+    enableSendEntryDialog(document.getElementById('send-entry-dialog'));
+});
+
+// entryDragType carries the category and position of a dragged entry so it
+// can be dropped onto another category's list.
+const entryDragType = 'application/x-gobookmarks-entry';
+
+// sendEntry moves or copies an entry to another category and reloads. The
+// file SHA rendered with the page rejects the change if the bookmarks were
+// edited elsewhere in the meantime; the server's message is shown instead.
+function sendEntry(mode, fromCat, from, toCat, to) {
+    const params = new URLSearchParams(window.location.search);
+    const ref = params.get('ref') || 'refs/heads/main';
+    let branch = 'main';
+    if (ref.startsWith('refs/heads/')) {
+        branch = ref.slice(11);
+    } else if (ref.startsWith('refs/tags/')) {
+        branch = 'New' + ref.slice(10);
+    } else if (ref) {
+        branch = 'FromCommit' + ref;
+    }
+    const fd = new FormData();
+    fd.append('fromCategory', fromCat);
+    fd.append('from', from);
+    fd.append('toCategory', toCat);
+    fd.append('to', to);
+    fd.append('branch', branch);
+    fd.append('ref', ref);
+    const content = document.getElementById('tab-content');
+    if (content && content.dataset.sha) fd.append('sha', content.dataset.sha);
+    window.isDragUpdating = true;
+    return fetch(withCollection(`/${mode}EntryTo`), {method: 'POST', body: fd, headers: csrfHeaders(), credentials: 'same-origin'})
+        .then(res => {
+            if (res.ok) {
+                location.reload();
+                return;
+            }
+            return res.text().then(msg => {
+                window.isDragUpdating = false;
+                alert(msg.trim() || `The link could not be ${mode === 'copy' ? 'copied' : 'moved'}.`);
+            });
+        })
+        .catch(err => {
+            window.isDragUpdating = false;
+            alert(`The link could not be ${mode === 'copy' ? 'copied' : 'moved'}: ${err}`);
+        });
+}
+
+// enableEntryTransfer accepts entries dragged from other categories. Holding
+// Ctrl or Alt while dropping copies the entry instead of moving it.
+function enableEntryTransfer(ul) {
+    const cat = ul.dataset.index;
+    ul.querySelectorAll('li').forEach((li, i) => {
+        const handle = li.querySelector('.move-handle') || li;
+        handle.addEventListener('dragstart', e => {
+            if (!document.body.classList.contains('edit-mode')) return;
+            e.dataTransfer.setData(entryDragType, JSON.stringify({category: cat, index: i}));
+            e.dataTransfer.effectAllowed = 'copyMove';
+        });
+    });
+    ul.addEventListener('dragover', e => {
+        if (!e.dataTransfer.types.includes(entryDragType)) return;
+        e.preventDefault();
+        e.stopPropagation();
+        e.dataTransfer.dropEffect = e.ctrlKey || e.altKey ? 'copy' : 'move';
+        ul.classList.add('drag-over');
+    });
+    ul.addEventListener('dragleave', () => ul.classList.remove('drag-over'));
+    ul.addEventListener('drop', e => {
+        if (!e.dataTransfer.types.includes(entryDragType)) return;
+        e.preventDefault();
+        e.stopPropagation();
+        ul.classList.remove('drag-over');
+        if (window.isDragUpdating) return;
+        const src = JSON.parse(e.dataTransfer.getData(entryDragType));
+        if (src.category === cat) return; // reordering is handled per item
+        const items = Array.from(ul.querySelectorAll('li'));
+        const target = e.target.closest('li');
+        const to = target && items.includes(target) ? items.indexOf(target) : items.length;
+        sendEntry(e.ctrlKey || e.altKey ? 'copy' : 'move', src.category, src.index, cat, to);
+    });
+}
+
+// enableSendEntryDialog wires the "send to" buttons, which give keyboard
+// users the moves and copies otherwise done by dragging.
+function enableSendEntryDialog(dialog) {
+    if (!dialog) return;
+    let source = null;
+    document.querySelectorAll('.bookmark-entries[data-index] .send-entry').forEach(btn => {
+        btn.addEventListener('click', () => {
+            const li = btn.closest('li');
+            const ul = li.closest('.bookmark-entries');
+            source = {category: ul.dataset.index, index: Array.from(ul.querySelectorAll('li')).indexOf(li), button: btn};
+            dialog.querySelector('select').value = ul.dataset.index;
+            dialog.showModal();
+        });
+    });
+    dialog.addEventListener('close', () => {
+        if (source) source.button.focus();
+    });
+    dialog.querySelector('form').addEventListener('submit', e => {
+        if (!source || (e.submitter && e.submitter.value === 'cancel')) return;
+        e.preventDefault();
+        const form = e.target;
+        sendEntry(form.elements.mode.value, source.category, source.index, form.elements.toCategory.value, -1);
+    });
+}
@@ -0,0 +1,19 @@
+function csrfHeaders() {
+    return {'X-CSRF-Token': document.querySelector('meta[name="csrf-token"]').content};
+}
+// withCollection adds the collection being viewed to a request URL.
+function withCollection(url) {
+    var c = new URLSearchParams(window.location.search).get('collection');
+    if (!c) return url;
+    return url + (url.indexOf('?') >= 0 ? '&' : '?') + 'collection=' + encodeURIComponent(c);
+}
+if (localStorage.getItem('edit-mode') === '1') {
+    document.body.classList.add('edit-mode');
+}
+// Forms with data-confirm ask before submitting.
+document.addEventListener('submit', function (e) {
+    var msg = e.target.dataset && e.target.dataset.confirm;
+    if (msg && !confirm(msg)) {
+        e.preventDefault();
+    }
+});
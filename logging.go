@@ -0,0 +1,193 @@
+package gobookmarks
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Log lines use these attribute keys so they can be filtered the same way
+// wherever they come from: "request_id", "route", "user", "provider", "ref",
+// "ip" and "err".
+
+// NewLogger returns a logger writing to w. level is one of debug, info, warn
+// or error and format is text or json; empty values select info and text.
+func NewLogger(w io.Writer, level, format string) (*slog.Logger, error) {
+	var l slog.Level
+	if level != "" {
+		if err := l.UnmarshalText([]byte(level)); err != nil {
+			return nil, fmt.Errorf("log_level must be debug, info, warn or error, not %q", level)
+		}
+	}
+	opts := &slog.HandlerOptions{Level: l}
+	var h slog.Handler
+	switch strings.ToLower(format) {
+	case "", "text":
+		h = slog.NewTextHandler(w, opts)
+	case "json":
+		h = slog.NewJSONHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("log_format must be text or json, not %q", format)
+	}
+	return slog.New(contextHandler{h}), nil
+}
+
+// ConfigureLogging makes the logger selected by Config.LogLevel and
+// Config.LogFormat the default. Output of the standard log package is sent
+// through it as well.
+func ConfigureLogging(w io.Writer) error {
+	l, err := NewLogger(w, Config.LogLevel, Config.LogFormat)
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(l)
+	return nil
+}
+
+// requestLog holds the attributes added to every line logged with a
+// request's context. Middleware further down the chain fills it in as it
+// learns who the request belongs to, and the access log reads it back.
+type requestLog struct {
+	mu    sync.Mutex
+	attrs []slog.Attr
+}
+
+type requestLogKey struct{}
+
+func requestLogFrom(ctx context.Context) *requestLog {
+	l, _ := ctx.Value(requestLogKey{}).(*requestLog)
+	return l
+}
+
+// addLogAttrs adds attributes to the lines logged for the request ctx
+// belongs to, replacing earlier values of the same keys. Empty values are
+// skipped. It does nothing outside a request.
+func addLogAttrs(ctx context.Context, args ...string) {
+	l := requestLogFrom(ctx)
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i := 0; i+1 < len(args); i += 2 {
+		key, value := args[i], args[i+1]
+		if value == "" {
+			continue
+		}
+		replaced := false
+		for j := range l.attrs {
+			if l.attrs[j].Key == key {
+				l.attrs[j] = slog.String(key, value)
+				replaced = true
+			}
+		}
+		if !replaced {
+			l.attrs = append(l.attrs, slog.String(key, value))
+		}
+	}
+}
+
+func (l *requestLog) snapshot() []slog.Attr {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]slog.Attr(nil), l.attrs...)
+}
+
+// contextHandler adds the request attributes found in the context to each
+// record. Attributes already on the record win, so an audit line about
+// another user keeps that user.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if l := requestLogFrom(ctx); l != nil {
+		have := map[string]bool{}
+		r.Attrs(func(a slog.Attr) bool {
+			have[a.Key] = true
+			return true
+		})
+		for _, a := range l.snapshot() {
+			if !have[a.Key] {
+				r.AddAttrs(a)
+			}
+		}
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+// requestIDPattern limits the request IDs accepted from clients or proxies.
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+func newRequestID() string {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// RequestIDMiddleware gives each request an ID, taken from the X-Request-ID
+// header when a proxy already set one, and returns it in the response. The
+// ID and the matched route are attached to every line logged with the
+// request's context, including those from provider calls and favicon
+// fetches.
+func RequestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+		if !requestIDPattern.MatchString(id) {
+			id = newRequestID()
+		}
+		w.Header().Set("X-Request-ID", id)
+		l := &requestLog{attrs: []slog.Attr{slog.String("request_id", id), slog.String("route", routeName(r))}}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestLogKey{}, l)))
+	})
+}
+
+// RequestIDFromContext returns the ID given to the request by
+// RequestIDMiddleware.
+func RequestIDFromContext(ctx context.Context) string {
+	if l := requestLogFrom(ctx); l != nil {
+		for _, a := range l.snapshot() {
+			if a.Key == "request_id" {
+				return a.Value.String()
+			}
+		}
+	}
+	return ""
+}
+
+// AccessLogMiddleware logs each request once it has been answered.
+func AccessLogMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+		slog.InfoContext(r.Context(), "request",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", rec.status,
+			"bytes", rec.bytes,
+			"duration", time.Since(start),
+			"ip", clientIP(r))
+	})
+}
@@ -0,0 +1,121 @@
+package gobookmarks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func useLogger(t *testing.T, format string) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	l, err := NewLogger(&buf, "debug", format)
+	if err != nil {
+		t.Fatalf("NewLogger: %v", err)
+	}
+	old := slog.Default()
+	slog.SetDefault(l)
+	t.Cleanup(func() { slog.SetDefault(old) })
+	return &buf
+}
+
+func TestNewLoggerOptions(t *testing.T) {
+	if _, err := NewLogger(&bytes.Buffer{}, "loud", ""); err == nil {
+		t.Errorf("invalid level accepted")
+	}
+	if _, err := NewLogger(&bytes.Buffer{}, "", "xml"); err == nil {
+		t.Errorf("invalid format accepted")
+	}
+	var buf bytes.Buffer
+	l, err := NewLogger(&buf, "warn", "text")
+	if err != nil {
+		t.Fatalf("NewLogger: %v", err)
+	}
+	l.Info("hidden")
+	l.Warn("shown")
+	if out := buf.String(); strings.Contains(out, "hidden") || !strings.Contains(out, "msg=shown") {
+		t.Fatalf("output = %q", out)
+	}
+}
+
+func TestRequestLogging(t *testing.T) {
+	buf := useLogger(t, "json")
+	r := mux.NewRouter()
+	r.Use(RequestIDMiddleware)
+	r.Use(AccessLogMiddleware)
+	r.HandleFunc("/tab/{tab}", func(w http.ResponseWriter, r *http.Request) {
+		addLogAttrs(r.Context(), "user", "alice", "provider", "git")
+		slog.InfoContext(r.Context(), "audit", "user", "bob")
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	req := httptest.NewRequest("GET", "/tab/news", nil)
+	req.Header.Set("X-Request-ID", "abc-123")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if got := w.Header().Get("X-Request-ID"); got != "abc-123" {
+		t.Fatalf("X-Request-ID = %q", got)
+	}
+
+	var lines []map[string]any
+	for _, l := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var m map[string]any
+		if err := json.Unmarshal([]byte(l), &m); err != nil {
+			t.Fatalf("line %q: %v", l, err)
+		}
+		lines = append(lines, m)
+	}
+	if len(lines) != 2 {
+		t.Fatalf("lines = %v", lines)
+	}
+	if a := lines[0]; a["user"] != "bob" || a["provider"] != "git" || a["request_id"] != "abc-123" || a["route"] != "/tab/{tab}" {
+		t.Errorf("audit line = %v", a)
+	}
+	if a := lines[1]; a["msg"] != "request" || a["user"] != "alice" || a["status"] != float64(http.StatusTeapot) || a["path"] != "/tab/news" {
+		t.Errorf("access line = %v", a)
+	}
+
+	req = httptest.NewRequest("GET", "/tab/news", nil)
+	req.Header.Set("X-Request-ID", "bad id\n")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if got := w.Header().Get("X-Request-ID"); got == "" || strings.Contains(got, " ") {
+		t.Fatalf("untrusted request ID kept: %q", got)
+	}
+}
+
+func TestParseLoginLogStructured(t *testing.T) {
+	for _, format := range []string{"text", "json"} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			l, err := NewLogger(&buf, "", format)
+			if err != nil {
+				t.Fatalf("NewLogger: %v", err)
+			}
+			ctx := context.Background()
+			l.InfoContext(ctx, "audit", "event", "login", "user", "alice smith", "provider", "git")
+			l.InfoContext(ctx, "login failed", "provider", "sql", "user", `bob "b"`, "reason", "invalid password")
+			l.InfoContext(ctx, "request", "user", "carol")
+			events, err := ParseLoginLog(&buf)
+			if err != nil {
+				t.Fatalf("ParseLoginLog: %v", err)
+			}
+			if len(events) != 2 {
+				t.Fatalf("events = %+v", events)
+			}
+			if e := events[0]; e.User != "alice smith" || e.Provider != "git" || e.Time.IsZero() {
+				t.Errorf("login event = %+v", e)
+			}
+			if e := events[1]; e.User != `bob "b"` || e.Provider != "sql" || !e.Time.IsZero() {
+				t.Errorf("failure event = %+v", e)
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package gobookmarks
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LoginEvent is a sign in attempt found in the logs or the audit log. Time
+// is only set for successful logins. Provider is empty when the source does
+// not name one.
+type LoginEvent struct {
+	User     string
+	Provider string
+	Time     time.Time
+}
+
+const logTimeLayout = "2006/01/02 15:04:05"
+
+var (
+	auditLinePattern = regexp.MustCompile(`audit: event=(\S+) user=("(?:[^"\\]|\\.)*") provider=(\S*)`)
+	loginLinePattern = regexp.MustCompile(`(\w+) login (?:failed )?for (\S+?):? (?:invalid password|refused)`)
+	twoFactorPattern = regexp.MustCompile(`two-factor login for ("(?:[^"\\]|\\.)*")`)
+)
+
+// loginLogMessages are the structured log messages that name a user signing
+// in.
+var loginLogMessages = map[string]bool{
+	"audit":                   true,
+	"login failed":            true,
+	"login refused":           true,
+	"two-factor login failed": true,
+}
+
+// ParseLoginLog finds the users named by login and audit lines in a log
+// written by this program, in either the text or JSON format of log_format
+// or the plain format of earlier versions.
+func ParseLoginLog(r io.Reader) ([]LoginEvent, error) {
+	var events []LoginEvent
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		line := sc.Text()
+		if rec, ok := parseLogRecord(line); ok {
+			if !loginLogMessages[rec["msg"]] || rec["user"] == "" {
+				continue
+			}
+			e := LoginEvent{User: rec["user"], Provider: rec["provider"]}
+			if rec["msg"] == "audit" && rec["event"] == "login" {
+				e.Time, _ = time.Parse(time.RFC3339Nano, rec["time"])
+			}
+			events = append(events, e)
+			continue
+		}
+		var at time.Time
+		if len(line) >= len(logTimeLayout) {
+			at, _ = time.ParseInLocation(logTimeLayout, line[:len(logTimeLayout)], time.Local)
+		}
+		if m := auditLinePattern.FindStringSubmatch(line); m != nil {
+			user, err := strconv.Unquote(m[2])
+			if err != nil || user == "" {
+				continue
+			}
+			e := LoginEvent{User: user, Provider: m[3]}
+			if m[1] == "login" {
+				e.Time = at
+			}
+			events = append(events, e)
+		} else if m := loginLinePattern.FindStringSubmatch(line); m != nil {
+			events = append(events, LoginEvent{User: m[2], Provider: m[1]})
+		} else if m := twoFactorPattern.FindStringSubmatch(line); m != nil {
+			if user, err := strconv.Unquote(m[1]); err == nil && user != "" {
+				events = append(events, LoginEvent{User: user})
+			}
+		}
+	}
+	return events, sc.Err()
+}
+
+// parseLogRecord reads the top level attributes of a line written by the
+// slog text or JSON handler.
+func parseLogRecord(line string) (map[string]string, bool) {
+	line = strings.TrimSpace(line)
+	if strings.HasPrefix(line, "{") {
+		var raw map[string]any
+		if json.Unmarshal([]byte(line), &raw) != nil {
+			return nil, false
+		}
+		rec := make(map[string]string, len(raw))
+		for k, v := range raw {
+			if s, ok := v.(string); ok {
+				rec[k] = s
+			} else {
+				rec[k] = fmt.Sprint(v)
+			}
+		}
+		return rec, true
+	}
+	if !strings.HasPrefix(line, "time=") {
+		return nil, false
+	}
+	rec := map[string]string{}
+	for line != "" {
+		eq := strings.IndexByte(line, '=')
+		if eq <= 0 {
+			return nil, false
+		}
+		key := line[:eq]
+		line = line[eq+1:]
+		var value string
+		if strings.HasPrefix(line, `"`) {
+			q, err := strconv.QuotedPrefix(line)
+			if err != nil {
+				return nil, false
+			}
+			value, _ = strconv.Unquote(q)
+			line = line[len(q):]
+		} else if sp := strings.IndexByte(line, ' '); sp >= 0 {
+			value, line = line[:sp], line[sp:]
+		} else {
+			value, line = line, ""
+		}
+		rec[key] = value
+		line = strings.TrimLeft(line, " ")
+	}
+	return rec, true
+}
+
+// AuditLoginEvents reads the users of provider named in the audit_log table.
+func AuditLoginEvents(ctx context.Context, provider string) ([]LoginEvent, error) {
+	db, err := sharedDB()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx, "SELECT user, event, at FROM audit_log WHERE provider=?", provider)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	var events []LoginEvent
+	for rows.Next() {
+		var user, event string
+		var at sql.NullTime
+		if err := rows.Scan(&user, &event, &at); err != nil {
+			return nil, err
+		}
+		e := LoginEvent{User: user, Provider: provider}
+		if event == "login" && at.Valid {
+			e.Time = at.Time
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
@@ -0,0 +1,283 @@
+package gobookmarks
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LoginLimits are the thresholds applied to failed logins.
+type LoginLimits struct {
+	Window          time.Duration
+	MaxUserFailures int
+	MaxIPFailures   int
+	Lockout         time.Duration
+	BackoffMax      time.Duration
+}
+
+// loginBackoffFree is the number of failures within the window before each
+// further attempt has to wait. The wait starts at a second and doubles.
+const loginBackoffFree = 3
+
+// LoginAttemptStore records failed logins by key. Failures returns the
+// failure times since the given time in ascending order.
+type LoginAttemptStore interface {
+	RecordFailure(ctx context.Context, key string, at time.Time) error
+	Failures(ctx context.Context, key string, since time.Time) ([]time.Time, error)
+	Reset(ctx context.Context, key string) error
+	DeleteBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+// LoginAttempts is the store used by the login handlers. A nil store
+// disables throttling.
+var LoginAttempts LoginAttemptStore = NewMemoryLoginAttemptStore()
+
+// NewConfiguredLoginAttemptStore returns the store selected by
+// Config.LoginLimitStore.
+func NewConfiguredLoginAttemptStore() (LoginAttemptStore, error) {
+	switch strings.ToLower(Config.LoginLimitStore) {
+	case "", "memory":
+		return NewMemoryLoginAttemptStore(), nil
+	case "sql":
+		return &SQLLoginAttemptStore{}, nil
+	case "none":
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown login limit store %q, expected memory, sql or none", Config.LoginLimitStore)
+	}
+}
+
+// CleanupLoginAttempts periodically drops failures too old to matter until
+// ctx is done.
+func CleanupLoginAttempts(ctx context.Context, store LoginAttemptStore, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			l := Config.LoginLimits()
+			if _, err := store.DeleteBefore(ctx, time.Now().Add(-l.Window-l.Lockout)); err != nil {
+				slog.ErrorContext(ctx, "login attempt cleanup failed", "err", err)
+			}
+		}
+	}
+}
+
+type loginKey struct {
+	key  string
+	kind string
+	max  int
+}
+
+func loginKeys(ip, provider, user string) []loginKey {
+	l := Config.LoginLimits()
+	return []loginKey{
+		{key: "ip:" + ip, kind: "ip", max: l.MaxIPFailures},
+		{key: "user:" + provider + ":" + user, kind: "user", max: l.MaxUserFailures},
+	}
+}
+
+// loginDelay returns how long to wait after fails before the next attempt
+// and whether the wait is a lockout.
+func loginDelay(fails []time.Time, max int, l LoginLimits, now time.Time) (time.Duration, bool) {
+	if len(fails) == 0 {
+		return 0, false
+	}
+	last := fails[len(fails)-1]
+	n := 0
+	for _, f := range fails {
+		if !f.Before(last.Add(-l.Window)) {
+			n++
+		}
+	}
+	if n >= max {
+		if until := last.Add(l.Lockout); now.Before(until) {
+			return until.Sub(now), true
+		}
+	}
+	recent := 0
+	for _, f := range fails {
+		if !f.Before(now.Add(-l.Window)) {
+			recent++
+		}
+	}
+	if recent < loginBackoffFree {
+		return 0, false
+	}
+	delay := l.BackoffMax
+	if shift := recent - loginBackoffFree; shift < 30 {
+		if d := time.Second << uint(shift); d < delay {
+			delay = d
+		}
+	}
+	if until := last.Add(delay); now.Before(until) {
+		return until.Sub(now), false
+	}
+	return 0, false
+}
+
+// loginWait returns how long the client at ip must wait before trying user
+// again.
+func loginWait(ctx context.Context, ip, provider, user string) (time.Duration, error) {
+	store := LoginAttempts
+	if store == nil {
+		return 0, nil
+	}
+	l := Config.LoginLimits()
+	now := time.Now()
+	var wait time.Duration
+	for _, k := range loginKeys(ip, provider, user) {
+		fails, err := store.Failures(ctx, k.key, now.Add(-l.Window-l.Lockout))
+		if err != nil {
+			return 0, err
+		}
+		if d, _ := loginDelay(fails, k.max, l, now); d > wait {
+			wait = d
+		}
+	}
+	return wait, nil
+}
+
+// loginThrottled returns how long the client must wait before trying user
+// again. Errors are logged and do not block the login.
+func loginThrottled(r *http.Request, provider, user string) time.Duration {
+	wait, err := loginWait(r.Context(), clientIP(r), provider, user)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "login throttle check failed", "provider", provider, "user", user, "err", err)
+		return 0
+	}
+	if wait > 0 {
+		slog.WarnContext(r.Context(), "login throttled", "provider", provider, "user", user, "ip", clientIP(r), "wait", wait.Round(time.Second))
+	}
+	return wait
+}
+
+// rejectThrottled redirects to target with a Retry-After header.
+func rejectThrottled(w http.ResponseWriter, r *http.Request, target string, wait time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int((wait+time.Second-1)/time.Second)))
+	http.Redirect(w, r, target, http.StatusSeeOther)
+}
+
+// throttleLogin rejects a login attempt that arrives too soon after earlier
+// failures. It reports whether the response has been written.
+func throttleLogin(w http.ResponseWriter, r *http.Request, provider, user string) bool {
+	wait := loginThrottled(r, provider, user)
+	if wait <= 0 {
+		return false
+	}
+	rejectThrottled(w, r, "/login/"+provider+"?error=throttled", wait)
+	return true
+}
+
+// recordLoginFailure counts a failed attempt against the client and the
+// username and audits any lockout it causes.
+func recordLoginFailure(r *http.Request, provider, user string) {
+	loginsTotal.Inc(provider, "failure")
+	store := LoginAttempts
+	if store == nil {
+		return
+	}
+	ctx := r.Context()
+	ip := clientIP(r)
+	l := Config.LoginLimits()
+	now := time.Now()
+	for _, k := range loginKeys(ip, provider, user) {
+		if err := store.RecordFailure(ctx, k.key, now); err != nil {
+			slog.ErrorContext(ctx, "record login failure failed", "provider", provider, "user", user, "err", err)
+			continue
+		}
+		fails, err := store.Failures(ctx, k.key, now.Add(-l.Window))
+		if err != nil {
+			slog.ErrorContext(ctx, "record login failure failed", "provider", provider, "user", user, "err", err)
+			continue
+		}
+		if len(fails) == k.max {
+			Audit(ctx, AuditEntry{
+				Event:    "login_lockout",
+				User:     user,
+				Provider: provider,
+				IP:       ip,
+				Detail:   fmt.Sprintf("%s locked for %s after %d failures", k.kind, l.Lockout, len(fails)),
+			})
+		}
+	}
+}
+
+// recordLoginSuccess clears the username's failures. Failures from the
+// client address are kept so one valid account cannot reset them.
+func recordLoginSuccess(r *http.Request, provider, user string) {
+	if LoginAttempts == nil {
+		return
+	}
+	if err := LoginAttempts.Reset(r.Context(), "user:"+provider+":"+user); err != nil {
+		slog.ErrorContext(r.Context(), "reset login failures failed", "provider", provider, "user", user, "err", err)
+	}
+}
+
+// MemoryLoginAttemptStore keeps failures in memory for a single instance.
+type MemoryLoginAttemptStore struct {
+	mu       sync.Mutex
+	failures map[string][]time.Time
+}
+
+func NewMemoryLoginAttemptStore() *MemoryLoginAttemptStore {
+	return &MemoryLoginAttemptStore{failures: map[string][]time.Time{}}
+}
+
+func (s *MemoryLoginAttemptStore) RecordFailure(ctx context.Context, key string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fails := append(s.failures[key], at)
+	sort.Slice(fails, func(i, j int) bool { return fails[i].Before(fails[j]) })
+	s.failures[key] = fails
+	return nil
+}
+
+func (s *MemoryLoginAttemptStore) Failures(ctx context.Context, key string, since time.Time) ([]time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []time.Time
+	for _, f := range s.failures[key] {
+		if !f.Before(since) {
+			out = append(out, f)
+		}
+	}
+	return out, nil
+}
+
+func (s *MemoryLoginAttemptStore) Reset(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.failures, key)
+	return nil
+}
+
+func (s *MemoryLoginAttemptStore) DeleteBefore(ctx context.Context, before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var n int64
+	for key, fails := range s.failures {
+		kept := fails[:0]
+		for _, f := range fails {
+			if f.Before(before) {
+				n++
+				continue
+			}
+			kept = append(kept, f)
+		}
+		if len(kept) == 0 {
+			delete(s.failures, key)
+		} else {
+			s.failures[key] = kept
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,61 @@
+package gobookmarks
+
+import (
+	"context"
+	"time"
+)
+
+// SQLLoginAttemptStore keeps failures in the login_failures table so every
+// instance sharing the database applies the same limits.
+type SQLLoginAttemptStore struct{}
+
+func (s *SQLLoginAttemptStore) RecordFailure(ctx context.Context, key string, at time.Time) error {
+	db, err := sharedDB()
+	if err != nil {
+		return err
+	}
+	_, err = db.ExecContext(ctx, "INSERT INTO login_failures(name, at) VALUES(?, ?)", key, at.UTC())
+	return err
+}
+
+func (s *SQLLoginAttemptStore) Failures(ctx context.Context, key string, since time.Time) ([]time.Time, error) {
+	db, err := sharedDB()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx, "SELECT at FROM login_failures WHERE name=? AND at>=? ORDER BY at", key, since.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	var out []time.Time
+	for rows.Next() {
+		var at time.Time
+		if err := rows.Scan(&at); err != nil {
+			return nil, err
+		}
+		out = append(out, at)
+	}
+	return out, rows.Err()
+}
+
+func (s *SQLLoginAttemptStore) Reset(ctx context.Context, key string) error {
+	db, err := sharedDB()
+	if err != nil {
+		return err
+	}
+	_, err = db.ExecContext(ctx, "DELETE FROM login_failures WHERE name=?", key)
+	return err
+}
+
+func (s *SQLLoginAttemptStore) DeleteBefore(ctx context.Context, before time.Time) (int64, error) {
+	db, err := sharedDB()
+	if err != nil {
+		return 0, err
+	}
+	res, err := db.ExecContext(ctx, "DELETE FROM login_failures WHERE at<?", before.UTC())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
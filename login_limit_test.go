@@ -0,0 +1,155 @@
+package gobookmarks
+
+import (
+	"context"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gorilla/sessions"
+)
+
+// useLoginAttempts installs store for the duration of the test.
+func useLoginAttempts(t *testing.T, store LoginAttemptStore) {
+	t.Helper()
+	old := LoginAttempts
+	LoginAttempts = store
+	t.Cleanup(func() { LoginAttempts = old })
+}
+
+func TestLoginDelay(t *testing.T) {
+	l := LoginLimits{Window: 10 * time.Minute, Lockout: 5 * time.Minute, BackoffMax: 8 * time.Second}
+	now := time.Now()
+	fails := func(n int) []time.Time {
+		var out []time.Time
+		for i := n; i > 0; i-- {
+			out = append(out, now.Add(-time.Duration(i)*time.Millisecond))
+		}
+		return out
+	}
+	for n, want := range map[int]time.Duration{2: 0, 3: time.Second, 4: 2 * time.Second, 5: 4 * time.Second, 7: 8 * time.Second} {
+		d, locked := loginDelay(fails(n), 100, l, now)
+		if locked || d.Round(time.Second) != want {
+			t.Errorf("%d failures: wait %s locked %v, want %s", n, d, locked, want)
+		}
+	}
+	if d, locked := loginDelay(fails(6), 6, l, now); !locked || d.Round(time.Minute) != l.Lockout {
+		t.Fatalf("lockout: %s %v", d, locked)
+	}
+	if d, locked := loginDelay(fails(6), 6, l, now.Add(l.Lockout+time.Second)); locked || d != 0 {
+		t.Fatalf("lockout did not expire: %s %v", d, locked)
+	}
+	if d, _ := loginDelay(fails(5), 100, l, now.Add(l.Window+time.Second)); d != 0 {
+		t.Fatalf("failures outside the window still count: %s", d)
+	}
+}
+
+func loginAttemptStores(t *testing.T) map[string]LoginAttemptStore {
+	t.Helper()
+	Config.DBConnectionProvider = "sqlite3"
+	Config.DBConnectionString = filepath.Join(t.TempDir(), "limits.db")
+	t.Cleanup(func() {
+		Config.DBConnectionProvider = ""
+		Config.DBConnectionString = ""
+	})
+	sqlStore := &SQLLoginAttemptStore{}
+	t.Cleanup(func() { _ = closeSharedDB() })
+	return map[string]LoginAttemptStore{"memory": NewMemoryLoginAttemptStore(), "sql": sqlStore}
+}
+
+func TestLoginAttemptStores(t *testing.T) {
+	for name, store := range loginAttemptStores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			base := time.Now().Add(-time.Hour)
+			for i := 0; i < 3; i++ {
+				if err := store.RecordFailure(ctx, "user:git:alice", base.Add(time.Duration(i)*time.Minute)); err != nil {
+					t.Fatalf("RecordFailure: %v", err)
+				}
+			}
+			_ = store.RecordFailure(ctx, "user:git:bob", base)
+			fails, err := store.Failures(ctx, "user:git:alice", base.Add(30*time.Second))
+			if err != nil || len(fails) != 2 || !fails[0].Before(fails[1]) {
+				t.Fatalf("Failures = %v, %v", fails, err)
+			}
+			if n, err := store.DeleteBefore(ctx, base.Add(90*time.Second)); err != nil || n != 3 {
+				t.Fatalf("DeleteBefore = %d, %v", n, err)
+			}
+			if err := store.Reset(ctx, "user:git:alice"); err != nil {
+				t.Fatalf("Reset: %v", err)
+			}
+			if fails, _ := store.Failures(ctx, "user:git:alice", base); len(fails) != 0 {
+				t.Fatalf("failures after reset: %v", fails)
+			}
+		})
+	}
+}
+
+func TestLoginLockout(t *testing.T) {
+	for name, store := range loginAttemptStores(t) {
+		t.Run(name, func(t *testing.T) {
+			old := Config
+			t.Cleanup(func() { Config = old })
+			useLoginAttempts(t, store)
+			Config.LocalGitPath = t.TempDir()
+			Config.SessionName = "gobookmarks"
+			Config.LoginMaxUserFailures = 3
+			Config.AuditStore = "sql"
+			SessionStore = sessions.NewCookieStore([]byte("secret-key"))
+			if err := (GitProvider{}).CreateUser(context.Background(), "alice", "password"); err != nil {
+				t.Fatalf("CreateUser: %v", err)
+			}
+			login := func(pass string) string {
+				w := twoFactorPost(t, GitLoginAction, "/login/git", url.Values{"username": {"alice"}, "password": {pass}}, nil)
+				return w.Header().Get("Location")
+			}
+			db, err := sharedDB()
+			if err != nil {
+				t.Fatalf("audit db: %v", err)
+			}
+			audits := func() int {
+				var n int
+				if err := db.QueryRow("SELECT COUNT(1) FROM audit_log WHERE event='login_lockout' AND user='alice'").Scan(&n); err != nil {
+					t.Fatalf("audit query: %v", err)
+				}
+				return n
+			}
+			before := audits()
+			for i := 0; i < 3; i++ {
+				if loc := login("wrong"); loc != "/login/git?error=invalid" {
+					t.Fatalf("attempt %d: %q", i, loc)
+				}
+			}
+			if loc := login("password"); loc != "/login/git?error=throttled" {
+				t.Fatalf("locked account accepted a login: %q", loc)
+			}
+			if n := audits() - before; n != 1 {
+				t.Fatalf("lockout audit entries = %d", n)
+			}
+		})
+	}
+}
+
+func TestLoginSuccessResetsUserFailures(t *testing.T) {
+	old := Config
+	t.Cleanup(func() { Config = old })
+	store := NewMemoryLoginAttemptStore()
+	useLoginAttempts(t, store)
+	Config.LocalGitPath = t.TempDir()
+	Config.SessionName = "gobookmarks"
+	SessionStore = sessions.NewCookieStore([]byte("secret-key"))
+	_ = (GitProvider{}).CreateUser(context.Background(), "alice", "password")
+	twoFactorPost(t, GitLoginAction, "/login/git", url.Values{"username": {"alice"}, "password": {"wrong"}}, nil)
+	w := twoFactorPost(t, GitLoginAction, "/login/git", url.Values{"username": {"alice"}, "password": {"password"}}, nil)
+	if loc := w.Header().Get("Location"); loc != "" {
+		t.Fatalf("login failed: %q", loc)
+	}
+	ctx := context.Background()
+	if fails, _ := store.Failures(ctx, "user:git:alice", time.Time{}); len(fails) != 0 {
+		t.Fatalf("user failures kept: %v", fails)
+	}
+	if fails, _ := store.Failures(ctx, "ip:192.0.2.1", time.Time{}); len(fails) != 1 {
+		t.Fatalf("ip failures = %v", fails)
+	}
+}
@@ -0,0 +1,121 @@
+package gobookmarks
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Mailer sends plain text email.
+type Mailer interface {
+	Send(ctx context.Context, to, subject, body string) error
+}
+
+// SMTPMailer delivers mail through an SMTP server. STARTTLS is used when the
+// server offers it unless TLS is set, in which case the connection is
+// encrypted from the start.
+type SMTPMailer struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	TLS      bool
+}
+
+// configuredMailer returns the mailer set up by the smtp_* options, or nil
+// when email is not configured.
+func configuredMailer() Mailer {
+	if Config.SMTPHost == "" || Config.SMTPFrom == "" {
+		return nil
+	}
+	return SMTPMailer{
+		Host:     Config.SMTPHost,
+		Port:     Config.SMTPPort,
+		Username: Config.SMTPUsername,
+		Password: Config.SMTPPassword,
+		From:     Config.SMTPFrom,
+		TLS:      Config.SMTPTLS,
+	}
+}
+
+func (m SMTPMailer) addr() string {
+	port := m.Port
+	if port == 0 {
+		port = 587
+		if m.TLS {
+			port = 465
+		}
+	}
+	return net.JoinHostPort(m.Host, strconv.Itoa(port))
+}
+
+// headerValue removes line breaks so a value cannot add headers.
+func headerValue(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
+
+// Send delivers one message to a single recipient.
+func (m SMTPMailer) Send(ctx context.Context, to, subject, body string) error {
+	d := net.Dialer{Timeout: 30 * time.Second}
+	conn, err := d.DialContext(ctx, "tcp", m.addr())
+	if err != nil {
+		return fmt.Errorf("smtp dial: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	} else {
+		_ = conn.SetDeadline(time.Now().Add(time.Minute))
+	}
+	if m.TLS {
+		conn = tls.Client(conn, &tls.Config{ServerName: m.Host})
+	}
+	c, err := smtp.NewClient(conn, m.Host)
+	if err != nil {
+		_ = conn.Close()
+		return fmt.Errorf("smtp hello: %w", err)
+	}
+	defer func() { _ = c.Close() }()
+	if !m.TLS {
+		if ok, _ := c.Extension("STARTTLS"); ok {
+			if err := c.StartTLS(&tls.Config{ServerName: m.Host}); err != nil {
+				return fmt.Errorf("smtp starttls: %w", err)
+			}
+		}
+	}
+	if m.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", m.Username, m.Password, m.Host)); err != nil {
+			return fmt.Errorf("smtp auth: %w", err)
+		}
+	}
+	if err := c.Mail(m.From); err != nil {
+		return fmt.Errorf("smtp mail from: %w", err)
+	}
+	if err := c.Rcpt(to); err != nil {
+		return fmt.Errorf("smtp rcpt: %w", err)
+	}
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("smtp data: %w", err)
+	}
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", headerValue(m.From))
+	fmt.Fprintf(&msg, "To: %s\r\n", headerValue(to))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", headerValue(subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(strings.ReplaceAll(body, "\r\n", "\n"), "\n", "\r\n"))
+	if _, err := w.Write([]byte(msg.String())); err != nil {
+		return fmt.Errorf("smtp write: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("smtp data: %w", err)
+	}
+	return c.Quit()
+}
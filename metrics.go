@@ -0,0 +1,392 @@
+package gobookmarks
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Metrics are kept in memory and written in the Prometheus text exposition
+// format by MetricsHandler.
+
+// defaultLatencyBuckets are the upper bounds, in seconds, of the latency
+// histograms.
+var defaultLatencyBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+type metric interface {
+	writeMetric(w io.Writer)
+}
+
+var (
+	metricsMu sync.Mutex
+	registry  []metric
+)
+
+func registerMetric(m metric) {
+	metricsMu.Lock()
+	registry = append(registry, m)
+	metricsMu.Unlock()
+}
+
+// metricSeries is one labelled value of a counter or histogram.
+type metricSeries struct {
+	labels []string
+	value  float64
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+type seriesSet struct {
+	mu     sync.Mutex
+	labels []string
+	series map[string]*metricSeries
+}
+
+func (s *seriesSet) get(values []string) *metricSeries {
+	if len(values) != len(s.labels) {
+		panic(fmt.Sprintf("metrics: got %d label values for %d labels", len(values), len(s.labels)))
+	}
+	key := strings.Join(values, "\xff")
+	m, ok := s.series[key]
+	if !ok {
+		m = &metricSeries{labels: append([]string(nil), values...)}
+		s.series[key] = m
+	}
+	return m
+}
+
+// sorted returns the series ordered by their label values.
+func (s *seriesSet) sorted() []*metricSeries {
+	keys := make([]string, 0, len(s.series))
+	for k := range s.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	out := make([]*metricSeries, len(keys))
+	for i, k := range keys {
+		out[i] = s.series[k]
+	}
+	return out
+}
+
+// counterVec is a counter partitioned by labels.
+type counterVec struct {
+	name, help string
+	seriesSet
+}
+
+func newCounterVec(name, help string, labels ...string) *counterVec {
+	c := &counterVec{name: name, help: help, seriesSet: seriesSet{labels: labels, series: map[string]*metricSeries{}}}
+	registerMetric(c)
+	return c
+}
+
+// Inc adds one to the series with the given label values.
+func (c *counterVec) Inc(values ...string) {
+	c.mu.Lock()
+	c.get(values).value++
+	c.mu.Unlock()
+}
+
+// Value returns the current count of the series, which is zero when it has
+// not been incremented.
+func (c *counterVec) Value(values ...string) float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if m, ok := c.series[strings.Join(values, "\xff")]; ok {
+		return m.value
+	}
+	return 0
+}
+
+func (c *counterVec) writeMetric(w io.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	writeMetricHeader(w, c.name, c.help, "counter")
+	for _, m := range c.sorted() {
+		fmt.Fprintf(w, "%s%s %s\n", c.name, formatLabels(c.labels, m.labels), formatFloat(m.value))
+	}
+}
+
+// histogramVec is a histogram partitioned by labels.
+type histogramVec struct {
+	name, help string
+	buckets    []float64
+	seriesSet
+}
+
+func newHistogramVec(name, help string, buckets []float64, labels ...string) *histogramVec {
+	h := &histogramVec{name: name, help: help, buckets: buckets, seriesSet: seriesSet{labels: labels, series: map[string]*metricSeries{}}}
+	registerMetric(h)
+	return h
+}
+
+// Observe records v in the series with the given label values.
+func (h *histogramVec) Observe(v float64, values ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	m := h.get(values)
+	if m.counts == nil {
+		m.counts = make([]uint64, len(h.buckets))
+	}
+	for i, b := range h.buckets {
+		if v <= b {
+			m.counts[i]++
+		}
+	}
+	m.sum += v
+	m.count++
+}
+
+// Count returns the number of observations in the series.
+func (h *histogramVec) Count(values ...string) uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if m, ok := h.series[strings.Join(values, "\xff")]; ok {
+		return m.count
+	}
+	return 0
+}
+
+func (h *histogramVec) writeMetric(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	writeMetricHeader(w, h.name, h.help, "histogram")
+	names := append(append([]string(nil), h.labels...), "le")
+	for _, m := range h.sorted() {
+		values := append(append([]string(nil), m.labels...), "")
+		for i, b := range h.buckets {
+			values[len(values)-1] = formatFloat(b)
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, formatLabels(names, values), m.counts[i])
+		}
+		values[len(values)-1] = "+Inf"
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, formatLabels(names, values), m.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, formatLabels(h.labels, m.labels), formatFloat(m.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, formatLabels(h.labels, m.labels), m.count)
+	}
+}
+
+// gaugeFunc is a gauge read when the metrics are scraped. It is left out
+// when fn reports no value.
+type gaugeFunc struct {
+	name, help string
+	fn         func() (float64, bool)
+}
+
+func newGaugeFunc(name, help string, fn func() (float64, bool)) *gaugeFunc {
+	g := &gaugeFunc{name: name, help: help, fn: fn}
+	registerMetric(g)
+	return g
+}
+
+func (g *gaugeFunc) writeMetric(w io.Writer) {
+	v, ok := g.fn()
+	if !ok {
+		return
+	}
+	writeMetricHeader(w, g.name, g.help, "gauge")
+	fmt.Fprintf(w, "%s %s\n", g.name, formatFloat(v))
+}
+
+func writeMetricHeader(w io.Writer, name, help, kind string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, kind)
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatLabels(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, n := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, `%s="%s"`, n, labelValueEscaper.Replace(values[i]))
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	httpRequests = newCounterVec("gobookmarks_http_requests_total",
+		"HTTP requests by route, method and status code.", "route", "method", "code")
+	httpRequestDuration = newHistogramVec("gobookmarks_http_request_duration_seconds",
+		"Time taken to answer HTTP requests.", defaultLatencyBuckets, "route", "method")
+	providerCallDuration = newHistogramVec("gobookmarks_provider_call_duration_seconds",
+		"Time taken by calls to the bookmark providers.", defaultLatencyBuckets, "provider", "method")
+	providerCallErrors = newCounterVec("gobookmarks_provider_call_errors_total",
+		"Failed calls to the bookmark providers.", "provider", "method")
+	bookmarksCacheRequests = newCounterVec("gobookmarks_bookmarks_cache_requests_total",
+		"Bookmark cache lookups by result (hit or miss).", "result")
+	faviconCacheRequests = newCounterVec("gobookmarks_favicon_cache_requests_total",
+		"Favicon cache lookups by result (hit or miss).", "result")
+	faviconFetchFailures = newCounterVec("gobookmarks_favicon_fetch_failures_total",
+		"Favicons that could not be fetched from the site.")
+	loginsTotal = newCounterVec("gobookmarks_logins_total",
+		"Login attempts by provider and result (success or failure).", "provider", "result")
+
+	_ = newGaugeFunc("gobookmarks_favicon_cache_entries",
+		"Favicons held in the in-memory cache.", func() (float64, bool) {
+			FaviconCache.RLock()
+			defer FaviconCache.RUnlock()
+			return float64(len(FaviconCache.cache)), true
+		})
+	_ = newGaugeFunc("gobookmarks_favicon_cache_bytes",
+		"Size of the favicons held in the in-memory cache.", func() (float64, bool) {
+			FaviconCache.RLock()
+			defer FaviconCache.RUnlock()
+			var n int
+			for _, f := range FaviconCache.cache {
+				n += len(f.Data)
+			}
+			return float64(n), true
+		})
+	_ = newGaugeFunc("gobookmarks_favicon_disk_cache_bytes",
+		"Size of the favicons in favicon_cache_dir.", func() (float64, bool) {
+			if Config.FaviconCacheDir == "" {
+				return 0, false
+			}
+			entries, err := filepath.Glob(filepath.Join(Config.FaviconCacheDir, "*.dat"))
+			if err != nil {
+				return 0, false
+			}
+			var n int64
+			for _, p := range entries {
+				if fi, err := os.Stat(p); err == nil {
+					n += fi.Size()
+				}
+			}
+			return float64(n), true
+		})
+	_ = newGaugeFunc("gobookmarks_active_sessions",
+		"Unexpired server-side sessions. Missing when sessions are kept in cookies.", func() (float64, bool) {
+			s := serverSessionStore()
+			if s == nil {
+				return 0, false
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			n, err := s.Backend.CountActive(ctx, time.Now())
+			if err != nil {
+				return 0, false
+			}
+			return float64(n), true
+		})
+)
+
+// observeProviderCall records the duration of a provider call started at
+// start and counts it as failed when err is set. A missing repository is an
+// expected answer for new users and is not counted as a failure.
+func observeProviderCall(ctx context.Context, p Provider, method string, start time.Time, err error) {
+	d := time.Since(start)
+	providerCallDuration.Observe(d.Seconds(), p.Name(), method)
+	if err != nil && !errors.Is(err, ErrRepoNotFound) {
+		providerCallErrors.Inc(p.Name(), method)
+	}
+	slog.DebugContext(ctx, "provider call", "provider", p.Name(), "method", method, "duration", d, "err", err)
+}
+
+// observeCacheLookup counts a hit or miss of a cache.
+func observeCacheLookup(c *counterVec, hit bool) {
+	if hit {
+		c.Inc("hit")
+	} else {
+		c.Inc("miss")
+	}
+}
+
+// statusRecorder remembers the status code and size of the response
+// written through it.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	if s.status == 0 {
+		s.status = code
+	}
+	s.ResponseWriter.WriteHeader(code)
+}
+
+func (s *statusRecorder) Write(b []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	n, err := s.ResponseWriter.Write(b)
+	s.bytes += n
+	return n, err
+}
+
+func (s *statusRecorder) Flush() {
+	if f, ok := s.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+// routeName returns the path template of the matched route so that path
+// variables such as tab names do not each get their own series.
+func routeName(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if tpl, err := route.GetPathTemplate(); err == nil {
+			return tpl
+		}
+	}
+	return "unmatched"
+}
+
+// MetricsMiddleware counts requests and their latency per route.
+func MetricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+		route := routeName(r)
+		httpRequests.Inc(route, r.Method, strconv.Itoa(rec.status))
+		httpRequestDuration.Observe(time.Since(start).Seconds(), route, r.Method)
+	})
+}
+
+// MetricsHandler serves the metrics in the Prometheus text format.
+func MetricsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	bw := bufio.NewWriter(w)
+	metricsMu.Lock()
+	ms := append([]metric(nil), registry...)
+	metricsMu.Unlock()
+	for _, m := range ms {
+		m.writeMetric(bw)
+	}
+	_ = bw.Flush()
+}
@@ -0,0 +1,71 @@
+package gobookmarks
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func TestMetricsMiddleware(t *testing.T) {
+	r := mux.NewRouter()
+	r.Use(MetricsMiddleware)
+	r.HandleFunc("/metrics-test/{name}", func(w http.ResponseWriter, r *http.Request) {
+		if mux.Vars(r)["name"] == "missing" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}).Methods("GET")
+
+	for _, p := range []string{"/metrics-test/a", "/metrics-test/b", "/metrics-test/missing"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", p, nil))
+	}
+	if n := httpRequests.Value("/metrics-test/{name}", "GET", "200"); n != 2 {
+		t.Fatalf("200 responses = %v", n)
+	}
+	if n := httpRequests.Value("/metrics-test/{name}", "GET", "404"); n != 1 {
+		t.Fatalf("404 responses = %v", n)
+	}
+	if n := httpRequestDuration.Count("/metrics-test/{name}", "GET"); n != 3 {
+		t.Fatalf("latency observations = %d", n)
+	}
+}
+
+func TestMetricsHandler(t *testing.T) {
+	p := GitProvider{}
+	before := providerCallErrors.Value("git", "MetricsTest")
+	observeProviderCall(context.Background(), p, "MetricsTest", time.Now().Add(-30*time.Millisecond), nil)
+	observeProviderCall(context.Background(), p, "MetricsTest", time.Now(), errors.New("boom"))
+	observeProviderCall(context.Background(), p, "MetricsTest", time.Now(), ErrRepoNotFound)
+	if n := providerCallErrors.Value("git", "MetricsTest") - before; n != 1 {
+		t.Fatalf("provider errors = %v", n)
+	}
+	faviconCacheRequests.Inc(`odd"label`)
+
+	w := httptest.NewRecorder()
+	MetricsHandler(w, httptest.NewRequest("GET", "/metrics", nil))
+	body := w.Body.String()
+	for _, want := range []string{
+		"# TYPE gobookmarks_provider_call_duration_seconds histogram\n",
+		`gobookmarks_provider_call_duration_seconds_bucket{provider="git",method="MetricsTest",le="0.025"} 2` + "\n",
+		`gobookmarks_provider_call_duration_seconds_bucket{provider="git",method="MetricsTest",le="0.05"} 3` + "\n",
+		`gobookmarks_provider_call_duration_seconds_bucket{provider="git",method="MetricsTest",le="+Inf"} 3` + "\n",
+		`gobookmarks_provider_call_duration_seconds_count{provider="git",method="MetricsTest"} 3` + "\n",
+		`gobookmarks_provider_call_errors_total{provider="git",method="MetricsTest"} 1` + "\n",
+		`gobookmarks_favicon_cache_requests_total{result="odd\"label"} 1` + "\n",
+		"# TYPE gobookmarks_favicon_cache_entries gauge\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("missing %q in\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, "gobookmarks_active_sessions") && serverSessionStore() == nil {
+		t.Errorf("active sessions reported for cookie sessions")
+	}
+}
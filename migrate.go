@@ -0,0 +1,250 @@
+package gobookmarks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"golang.org/x/oauth2"
+)
+
+// migratePageSize is the number of commits requested per GetCommits call
+// while walking a branch's history.
+const migratePageSize = 100
+
+// MigrateState records progress so an interrupted migration can resume
+// without replaying commits twice.
+type MigrateState struct {
+	// Commits maps source commit shas to the destination commit created for
+	// them.
+	Commits  map[string]string `json:"commits"`
+	Branches map[string]bool   `json:"branches"`
+	Tags     map[string]bool   `json:"tags"`
+}
+
+// NewMigrateState returns an empty MigrateState.
+func NewMigrateState() *MigrateState {
+	return &MigrateState{
+		Commits:  map[string]string{},
+		Branches: map[string]bool{},
+		Tags:     map[string]bool{},
+	}
+}
+
+// MigrateOptions configures MigrateHistory.
+type MigrateOptions struct {
+	User      string
+	DestUser  string // defaults to User
+	FromToken *oauth2.Token
+	ToToken   *oauth2.Token
+	DryRun    bool
+	State     *MigrateState
+	// Checkpoint, when set, is called after every step that changes State.
+	Checkpoint func(*MigrateState) error
+	// Logf, when set, receives progress messages.
+	Logf func(format string, args ...any)
+}
+
+// MigrateSummary describes what a migration did, or would do for a dry run.
+type MigrateSummary struct {
+	Branches []string
+	Tags     []string
+	Commits  int // commits found in the source
+	Replayed int // commits written to the destination
+	Skipped  int // commits already present from an earlier run
+	// Unmapped lists tags whose commit could not be located in the
+	// destination.
+	Unmapped []string
+}
+
+// MigrateHistory copies a user's bookmarks from one provider to another,
+// replaying every commit on every branch oldest first and recreating tags.
+// The destination must implement HistoryReplayer so the original messages,
+// authors and dates are kept. History is replayed as a linear sequence per
+// branch.
+func MigrateHistory(ctx context.Context, from, to Provider, opts MigrateOptions) (*MigrateSummary, error) {
+	replayer, ok := to.(HistoryReplayer)
+	if !ok {
+		return nil, fmt.Errorf("%s cannot store migrated history: %w", to.Name(), errors.ErrUnsupported)
+	}
+	if opts.DestUser == "" {
+		opts.DestUser = opts.User
+	}
+	if opts.State == nil {
+		opts.State = NewMigrateState()
+	}
+	st := opts.State
+	if st.Commits == nil {
+		st.Commits = map[string]string{}
+	}
+	if st.Branches == nil {
+		st.Branches = map[string]bool{}
+	}
+	if st.Tags == nil {
+		st.Tags = map[string]bool{}
+	}
+	logf := opts.Logf
+	if logf == nil {
+		logf = func(string, ...any) {}
+	}
+	checkpoint := func() error {
+		if opts.Checkpoint == nil {
+			return nil
+		}
+		return opts.Checkpoint(st)
+	}
+	branches, err := from.GetBranches(ctx, opts.User, opts.FromToken)
+	if err != nil {
+		return nil, fmt.Errorf("list branches: %w", err)
+	}
+	tags, err := from.GetTags(ctx, opts.User, opts.FromToken)
+	if err != nil {
+		return nil, fmt.Errorf("list tags: %w", err)
+	}
+
+	summary := &MigrateSummary{}
+	if !opts.DryRun {
+		exists, err := to.RepoExists(ctx, opts.DestUser, opts.ToToken, Config.GetRepoName())
+		if err != nil {
+			return nil, fmt.Errorf("check destination: %w", err)
+		}
+		if !exists {
+			if err := to.CreateRepo(ctx, opts.DestUser, opts.ToToken, Config.GetRepoName()); err != nil {
+				return nil, fmt.Errorf("create destination: %w", err)
+			}
+		}
+	}
+
+	seen := map[string]bool{}
+	for _, b := range branches {
+		summary.Branches = append(summary.Branches, b.Name)
+		ref := "refs/heads/" + b.Name
+		commits, err := migrateCommitList(ctx, from, opts.User, opts.FromToken, ref)
+		if err != nil {
+			return nil, fmt.Errorf("branch %s: %w", b.Name, err)
+		}
+		for _, c := range commits {
+			if !seen[c.SHA] {
+				seen[c.SHA] = true
+				summary.Commits++
+				if _, ok := st.Commits[c.SHA]; ok {
+					summary.Skipped++
+				}
+			}
+		}
+		if opts.DryRun {
+			continue
+		}
+
+		parent := ""
+		for _, c := range commits {
+			if dst, ok := st.Commits[c.SHA]; ok && dst != "" {
+				parent = dst
+				continue
+			}
+			text, _, err := from.GetBookmarks(ctx, opts.User, c.SHA, opts.FromToken)
+			if err != nil {
+				return nil, fmt.Errorf("read %s: %w", c.SHA, err)
+			}
+			dst, err := replayer.ReplayCommit(ctx, opts.DestUser, opts.ToToken, ReplayCommit{
+				Parent:      parent,
+				Text:        text,
+				Message:     c.Message,
+				AuthorName:  c.AuthorName,
+				AuthorEmail: c.AuthorEmail,
+				Date:        c.AuthorDate,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("replay %s: %w", c.SHA, err)
+			}
+			if _, ok := st.Commits[c.SHA]; !ok {
+				summary.Replayed++
+			}
+			st.Commits[c.SHA] = dst
+			parent = dst
+			logf("%s: %s", b.Name, c.SHA)
+			if err := checkpoint(); err != nil {
+				return nil, err
+			}
+		}
+
+		tip := migrateTip(ctx, from, opts.User, opts.FromToken, ref, st, commits)
+		if tip == "" {
+			logf("%s: no commits", b.Name)
+			continue
+		}
+		if err := replayer.SetRef(ctx, opts.DestUser, opts.ToToken, ref, tip); err != nil {
+			return nil, fmt.Errorf("branch %s: %w", b.Name, err)
+		}
+		st.Branches[b.Name] = true
+		if err := checkpoint(); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, t := range tags {
+		summary.Tags = append(summary.Tags, t.Name)
+		if opts.DryRun || st.Tags[t.Name] {
+			continue
+		}
+		ref := "refs/tags/" + t.Name
+		tip := migrateTip(ctx, from, opts.User, opts.FromToken, ref, st, nil)
+		if tip == "" {
+			logf("tag %s: commit not found in migrated history", t.Name)
+			summary.Unmapped = append(summary.Unmapped, t.Name)
+			continue
+		}
+		if err := replayer.SetRef(ctx, opts.DestUser, opts.ToToken, ref, tip); err != nil {
+			return nil, fmt.Errorf("tag %s: %w", t.Name, err)
+		}
+		st.Tags[t.Name] = true
+		if err := checkpoint(); err != nil {
+			return nil, err
+		}
+	}
+	return summary, nil
+}
+
+// migrateCommitList returns every commit reachable from ref, oldest first.
+func migrateCommitList(ctx context.Context, p Provider, user string, token *oauth2.Token, ref string) ([]*Commit, error) {
+	var all []*Commit
+	for page := 1; ; page++ {
+		commits, err := p.GetCommits(ctx, user, token, ref, page, migratePageSize)
+		if err != nil {
+			if errors.Is(err, ErrRepoNotFound) {
+				break
+			}
+			return nil, err
+		}
+		all = append(all, commits...)
+		if len(commits) < migratePageSize {
+			break
+		}
+	}
+	for i, j := 0, len(all)-1; i < j; i, j = i+1, j-1 {
+		all[i], all[j] = all[j], all[i]
+	}
+	return all, nil
+}
+
+// migrateTip finds the destination commit that ref should point at. The sha
+// reported by GetBookmarks is used when it names a migrated commit; GitHub
+// reports file shas instead, so the newest commit on the ref is tried next.
+func migrateTip(ctx context.Context, p Provider, user string, token *oauth2.Token, ref string, st *MigrateState, commits []*Commit) string {
+	if _, sha, err := p.GetBookmarks(ctx, user, ref, token); err == nil {
+		if dst := st.Commits[sha]; dst != "" {
+			return dst
+		}
+	}
+	if commits == nil {
+		if latest, err := p.GetCommits(ctx, user, token, ref, 1, 1); err == nil {
+			commits = latest
+		}
+	} else if len(commits) > 0 {
+		commits = commits[len(commits)-1:]
+	}
+	if len(commits) > 0 {
+		return st.Commits[commits[0].SHA]
+	}
+	return ""
+}
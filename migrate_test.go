@@ -0,0 +1,268 @@
+package gobookmarks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+func setupMigrateTest(t *testing.T) (*SQLProvider, GitProvider, string) {
+	t.Helper()
+	Config.DBConnectionProvider = "sqlite3"
+	Config.DBConnectionString = filepath.Join(t.TempDir(), "bookmarks.db")
+	Config.LocalGitPath = t.TempDir()
+	t.Cleanup(func() {
+		Config.DBConnectionProvider = ""
+		Config.DBConnectionString = ""
+	})
+	src := &SQLProvider{}
+	t.Cleanup(func() { _ = closeSharedDB() })
+	user := "alice"
+	ctx := context.Background()
+	if err := src.CreateRepo(ctx, user, nil, Config.GetRepoName()); err != nil {
+		t.Fatalf("CreateRepo: %v", err)
+	}
+	if err := src.CreateBookmarks(withCommitAuthor(ctx, "bob"), user, nil, "main", "Category: One\n"); err != nil {
+		t.Fatalf("CreateBookmarks: %v", err)
+	}
+	for _, text := range []string{"Category: Two\n", "Category: Three\n"} {
+		if err := src.UpdateBookmarks(ctx, user, nil, "refs/heads/main", "main", text, ""); err != nil {
+			t.Fatalf("UpdateBookmarks: %v", err)
+		}
+	}
+	db, _ := sharedDB()
+	var first string
+	if err := db.QueryRow("SELECT sha FROM history WHERE user=? ORDER BY id LIMIT 1", user).Scan(&first); err != nil {
+		t.Fatalf("select: %v", err)
+	}
+	if _, err := db.Exec("INSERT INTO tags(user, name, sha) VALUES(?,?,?)", user, "v1", first); err != nil {
+		t.Fatalf("insert tag: %v", err)
+	}
+	return src, GitProvider{}, user
+}
+
+func TestMigrateSQLToGit(t *testing.T) {
+	src, dst, user := setupMigrateTest(t)
+	ctx := context.Background()
+
+	summary, err := MigrateHistory(ctx, src, dst, MigrateOptions{User: user, DryRun: true})
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if summary.Commits != 3 || summary.Replayed != 0 || strings.Join(summary.Tags, ",") != "v1" {
+		t.Fatalf("unexpected dry run summary %+v", summary)
+	}
+	if exists, _ := dst.RepoExists(ctx, user, nil, Config.GetRepoName()); exists {
+		t.Fatalf("dry run created the destination")
+	}
+
+	summary, err = MigrateHistory(ctx, src, dst, MigrateOptions{User: user})
+	if err != nil {
+		t.Fatalf("MigrateHistory: %v", err)
+	}
+	if summary.Replayed != 3 || len(summary.Unmapped) != 0 {
+		t.Fatalf("unexpected summary %+v", summary)
+	}
+
+	got, _, err := dst.GetBookmarks(ctx, user, "refs/heads/main", nil)
+	if err != nil || got != "Category: Three\n" {
+		t.Fatalf("main = %q, %v", got, err)
+	}
+	if got, _, _ := dst.GetBookmarks(ctx, user, "refs/tags/v1", nil); got != "Category: One\n" {
+		t.Fatalf("tag v1 = %q", got)
+	}
+	srcCommits, _ := src.GetCommits(ctx, user, nil, "refs/heads/main", 1, 10)
+	commits, err := dst.GetCommits(ctx, user, nil, "refs/heads/main", 1, 10)
+	if err != nil || len(commits) != 3 {
+		t.Fatalf("GetCommits = %d, %v", len(commits), err)
+	}
+	for i := range commits {
+		if commits[i].Message != srcCommits[i].Message || !commits[i].CommitterDate.Equal(srcCommits[i].CommitterDate.Truncate(1e9)) {
+			t.Errorf("commit %d: got %q %v want %q %v", i, commits[i].Message, commits[i].CommitterDate, srcCommits[i].Message, srcCommits[i].CommitterDate)
+		}
+	}
+	if commits[2].CommitterName != "bob" {
+		t.Errorf("author not kept: %q", commits[2].CommitterName)
+	}
+}
+
+func TestMigrateResume(t *testing.T) {
+	src, dst, user := setupMigrateTest(t)
+	ctx := context.Background()
+	state := NewMigrateState()
+	stop := errors.New("interrupted")
+	_, err := MigrateHistory(ctx, src, dst, MigrateOptions{
+		User:  user,
+		State: state,
+		Checkpoint: func(st *MigrateState) error {
+			if len(st.Commits) == 2 {
+				return stop
+			}
+			return nil
+		},
+	})
+	if !errors.Is(err, stop) {
+		t.Fatalf("expected interruption, got %v", err)
+	}
+
+	summary, err := MigrateHistory(ctx, src, dst, MigrateOptions{User: user, State: state})
+	if err != nil {
+		t.Fatalf("resume: %v", err)
+	}
+	if summary.Skipped != 2 || summary.Replayed != 1 {
+		t.Fatalf("unexpected resume summary %+v", summary)
+	}
+	commits, _ := dst.GetCommits(ctx, user, nil, "refs/heads/main", 1, 10)
+	if len(commits) != 3 {
+		t.Fatalf("expected 3 commits after resume, got %d", len(commits))
+	}
+}
+
+// plainProvider hides the HistoryReplayer methods of the provider it wraps.
+type plainProvider struct{ Provider }
+
+// serverCommitter reports every commit as committed by the server, as GitHub
+// and GitLab do for commits made through their APIs.
+type serverCommitter struct{ Provider }
+
+func (p serverCommitter) GetCommits(ctx context.Context, user string, token *oauth2.Token, ref string, page, perPage int) ([]*Commit, error) {
+	cs, err := p.Provider.GetCommits(ctx, user, token, ref, page, perPage)
+	for _, c := range cs {
+		c.CommitterName = "server"
+		c.CommitterEmail = "server@example.com"
+	}
+	return cs, err
+}
+
+func TestMigrateKeepsAuthor(t *testing.T) {
+	src, dst, user := setupMigrateTest(t)
+	ctx := context.Background()
+	if _, err := MigrateHistory(ctx, serverCommitter{src}, dst, MigrateOptions{User: user}); err != nil {
+		t.Fatalf("MigrateHistory: %v", err)
+	}
+	commits, err := dst.GetCommits(ctx, user, nil, "refs/heads/main", 1, 10)
+	if err != nil || len(commits) != 3 {
+		t.Fatalf("GetCommits = %d, %v", len(commits), err)
+	}
+	if commits[2].AuthorName != "bob" {
+		t.Errorf("author = %q, want bob", commits[2].AuthorName)
+	}
+}
+
+func TestMigrateRequiresReplayer(t *testing.T) {
+	src, dst, user := setupMigrateTest(t)
+	ctx := context.Background()
+	if _, err := MigrateHistory(ctx, src, plainProvider{dst}, MigrateOptions{User: user}); !errors.Is(err, errors.ErrUnsupported) {
+		t.Fatalf("expected unsupported destination error, got %v", err)
+	}
+	if exists, _ := dst.RepoExists(ctx, user, nil, Config.GetRepoName()); exists {
+		t.Fatalf("failed migration created the destination")
+	}
+}
+
+func TestGitHubReplayCommit(t *testing.T) {
+	var mu sync.Mutex
+	var trees, commits []map[string]any
+	refs := map[string]string{"heads/main": "init"}
+	base := "/api/v3/repos/alice/" + Config.GetRepoName() + "/git"
+	mux := http.NewServeMux()
+	decode := func(r *http.Request) map[string]any {
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		return body
+	}
+	mux.HandleFunc("GET "+base+"/commits/{sha}", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{"sha": r.PathValue("sha"), "tree": map[string]string{"sha": "tree-" + r.PathValue("sha")}})
+	})
+	mux.HandleFunc("POST "+base+"/trees", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		trees = append(trees, decode(r))
+		_ = json.NewEncoder(w).Encode(map[string]any{"sha": fmt.Sprintf("tree%d", len(trees))})
+	})
+	mux.HandleFunc("POST "+base+"/commits", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		commits = append(commits, decode(r))
+		_ = json.NewEncoder(w).Encode(map[string]any{"sha": fmt.Sprintf("c%d", len(commits))})
+	})
+	mux.HandleFunc(base+"/refs/{ref...}", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		ref := r.PathValue("ref")
+		switch r.Method {
+		case http.MethodGet:
+			if _, ok := refs[ref]; !ok {
+				http.NotFound(w, r)
+				return
+			}
+		case http.MethodPatch:
+			refs[ref] = decode(r)["sha"].(string)
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"ref": "refs/" + ref, "object": map[string]string{"sha": refs[ref]}})
+	})
+	mux.HandleFunc("POST "+base+"/refs", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		body := decode(r)
+		refs[strings.TrimPrefix(body["ref"].(string), "refs/")] = body["sha"].(string)
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(map[string]any{"ref": body["ref"], "object": map[string]any{"sha": body["sha"]}})
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	old := Config
+	t.Cleanup(func() { Config = old })
+	Config.GithubServer = srv.URL
+
+	ctx := context.Background()
+	p := GitHubProvider{}
+	token := &oauth2.Token{AccessToken: "t"}
+	date := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	sha, err := p.ReplayCommit(ctx, "alice", token, ReplayCommit{Parent: "p1", Text: "Category: A\n", Message: "first", AuthorName: "bob", AuthorEmail: "bob@example.com", Date: date})
+	if err != nil || sha != "c1" {
+		t.Fatalf("ReplayCommit = %q, %v", sha, err)
+	}
+	if trees[0]["base_tree"] != "tree-p1" {
+		t.Errorf("base tree not the parent's: %v", trees[0])
+	}
+	c := commits[0]
+	author, _ := c["author"].(map[string]any)
+	if c["message"] != "first" || c["tree"] != "tree1" || author["name"] != "bob" || author["date"] != "2020-01-02T03:04:05Z" {
+		t.Errorf("unexpected commit %v", c)
+	}
+	if parents, _ := c["parents"].([]any); len(parents) != 1 || parents[0] != "p1" {
+		t.Errorf("unexpected parents %v", c["parents"])
+	}
+	if err := p.SetRef(ctx, "alice", token, "refs/heads/main", "c1"); err != nil {
+		t.Fatalf("SetRef main: %v", err)
+	}
+	if err := p.SetRef(ctx, "alice", token, "refs/tags/v1", "c1"); err != nil {
+		t.Fatalf("SetRef tag: %v", err)
+	}
+	if refs["heads/main"] != "c1" || refs["tags/v1"] != "c1" {
+		t.Fatalf("refs not moved: %v", refs)
+	}
+}
+
+func TestSQLGetBookmarksRefs(t *testing.T) {
+	src, _, user := setupMigrateTest(t)
+	ctx := context.Background()
+	if got, _, _ := src.GetBookmarks(ctx, user, "refs/tags/v1", nil); got != "Category: One\n" {
+		t.Fatalf("tag lookup = %q", got)
+	}
+	commits, _ := src.GetCommits(ctx, user, nil, "refs/heads/main", 1, 10)
+	if got, _, _ := src.GetBookmarks(ctx, user, commits[1].SHA, nil); got != "Category: Two\n" {
+		t.Fatalf("sha lookup = %q", got)
+	}
+}
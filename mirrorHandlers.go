@@ -0,0 +1,66 @@
+package gobookmarks
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/sessions"
+)
+
+// ErrMirrorUnsupported indicates the active provider cannot mirror
+// repositories.
+var ErrMirrorUnsupported = errors.New("provider does not support mirroring")
+
+// requestMirrorer returns the active provider's Mirrorer and the account the
+// request operates on.
+func requestMirrorer(r *http.Request, write bool) (Mirrorer, string, error) {
+	session := r.Context().Value(ContextValues("session")).(*sessions.Session)
+	githubUser, _ := session.Values["GithubUser"].(*User)
+	login := ""
+	if githubUser != nil {
+		login = githubUser.Login
+	}
+	m, ok := providerFromContext(r.Context()).(Mirrorer)
+	if !ok {
+		return nil, "", ErrMirrorUnsupported
+	}
+	owner, err := bookmarkOwner(r.Context(), login, write)
+	if err != nil {
+		return nil, "", err
+	}
+	return m, owner, nil
+}
+
+// MirrorSetAction configures the remote the user's repository is mirrored to.
+func MirrorSetAction(w http.ResponseWriter, r *http.Request) error {
+	m, owner, err := requestMirrorer(r, true)
+	if err != nil {
+		return err
+	}
+	if err := m.SetMirror(r.Context(), owner, MirrorRemote{
+		URL:      r.PostFormValue("url"),
+		Username: r.PostFormValue("username"),
+		Password: r.PostFormValue("password"),
+	}); err != nil {
+		if errors.Is(err, ErrMirrorURL) {
+			return NewUserError("Unsupported mirror URL", err)
+		}
+		return NewUserError(fmt.Sprintf("Mirror update failed: %v", err), err)
+	}
+	return nil
+}
+
+// MirrorPullAction pulls changes from the mirror remote.
+func MirrorPullAction(w http.ResponseWriter, r *http.Request) error {
+	m, owner, err := requestMirrorer(r, true)
+	if err != nil {
+		return err
+	}
+	if err := m.PullMirror(r.Context(), owner); err != nil {
+		invalidateRequestCache(r.Context(), owner)
+		return NewUserError(fmt.Sprintf("Pull failed: %v", err), err)
+	}
+	invalidateRequestCache(r.Context(), owner)
+	return nil
+}
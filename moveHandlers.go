@@ -88,16 +88,8 @@ func MoveEntryAction(w http.ResponseWriter, r *http.Request) error {
 	if tabIdx >= 0 && tabIdx < len(list) {
 		t := list[tabIdx]
 		if pageIdx >= 0 && pageIdx < len(t.Pages) {
-			page := t.Pages[pageIdx]
-			for _, blk := range page.Blocks {
-				for _, col := range blk.Columns {
-					for _, c := range col.Categories {
-						if c.Index == catIdx {
-							c.MoveEntry(from, to)
-							break
-						}
-					}
-				}
+			if c := list.FindCategory(catIdx); c != nil && PageForCategory(list, catIdx) == t.Pages[pageIdx] {
+				c.MoveEntry(from, to)
 			}
 		}
 	}
@@ -0,0 +1,157 @@
+package gobookmarks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"golang.org/x/oauth2"
+)
+
+// oidcUserPrefix namespaces the storage accounts of OIDC users so an
+// identity provider claim can never name a local password account.
+const oidcUserPrefix = "oidc:"
+
+// oidcStorageUser returns the storage account for the OIDC subject.
+func oidcStorageUser(subject string) string {
+	return oidcUserPrefix + subject
+}
+
+// OIDCLoginAction starts an OpenID Connect login.
+func OIDCLoginAction(w http.ResponseWriter, r *http.Request) error {
+	c, err := configuredOIDC()
+	if err != nil {
+		http.NotFound(w, r)
+		return ErrHandled
+	}
+	session, err := getSession(w, r)
+	if session, err = sanitizeSession(w, r, session, err); err != nil {
+		return fmt.Errorf("session error: %w", err)
+	}
+	state, err := newSessionID()
+	if err != nil {
+		return err
+	}
+	nonce, err := newSessionID()
+	if err != nil {
+		return err
+	}
+	verifier := oauth2.GenerateVerifier()
+	target, err := c.AuthCodeURL(r.Context(), state, nonce, verifier)
+	if err != nil {
+		return fmt.Errorf("oidc: %w", err)
+	}
+
+	session.Values["OIDCState"] = state
+	session.Values["OIDCNonce"] = nonce
+	session.Values["OIDCVerifier"] = verifier
+	session.Values["version"] = version
+	delete(session.Values, "Redirect")
+	if redirect := r.URL.Query().Get("redirect"); redirect != "" && redirect != "/" && len(redirect) < 2048 {
+		session.Values["Redirect"] = redirect
+	}
+	if err := session.Save(r, w); err != nil {
+		return fmt.Errorf("session save: %w", err)
+	}
+	http.Redirect(w, r, target, http.StatusTemporaryRedirect)
+	return ErrHandled
+}
+
+// OIDCCallbackAction completes an OpenID Connect login and signs the user in
+// to the configured storage provider.
+func OIDCCallbackAction(w http.ResponseWriter, r *http.Request) error {
+	session, err := getSession(w, r)
+	if session, err = sanitizeSession(w, r, session, err); err != nil {
+		return fmt.Errorf("session error: %w", err)
+	}
+	state, _ := session.Values["OIDCState"].(string)
+	nonce, _ := session.Values["OIDCNonce"].(string)
+	verifier, _ := session.Values["OIDCVerifier"].(string)
+	delete(session.Values, "OIDCState")
+	delete(session.Values, "OIDCNonce")
+	delete(session.Values, "OIDCVerifier")
+
+	fail := func(reason string, err error) error {
+		slog.WarnContext(r.Context(), "login failed", "provider", "oidc", "reason", reason, "err", err)
+		if saveErr := session.Save(r, w); saveErr != nil {
+			slog.ErrorContext(r.Context(), "session save failed", "err", saveErr)
+		}
+		http.Redirect(w, r, "/login?error=oidc", http.StatusSeeOther)
+		return ErrHandled
+	}
+
+	q := r.URL.Query()
+	if e := q.Get("error"); e != "" {
+		return fail("issuer error", fmt.Errorf("%s: %s", e, q.Get("error_description")))
+	}
+	if state == "" || q.Get("state") != state {
+		return fail("state", fmt.Errorf("state mismatch"))
+	}
+	c, err := configuredOIDC()
+	if err != nil {
+		return fail("config", err)
+	}
+	claims, err := c.Exchange(r.Context(), q.Get("code"), verifier, nonce)
+	if err != nil {
+		return fail("exchange", err)
+	}
+	claim := Config.GetOIDCUsernameClaim()
+	subject, _ := claims[claim].(string)
+	if subject == "" {
+		return fail("username", fmt.Errorf("claim %q missing", claim))
+	}
+	user := oidcStorageUser(subject)
+
+	storage := Config.GetOIDCStorage()
+	p := GetProvider(storage)
+	if p == nil || providerCreds(storage) == nil {
+		return fmt.Errorf("oidc storage provider %q is not available", storage)
+	}
+	if ph, ok := p.(PasswordHandler); ok {
+		has, err := ph.HasPassword(r.Context(), user)
+		if err != nil && !errors.Is(err, ErrUserNotFound) {
+			return fail("password lookup", err)
+		}
+		if has {
+			return fail("username", fmt.Errorf("%q belongs to a password account", user))
+		}
+	}
+	if err := ensureRepo(r.Context(), p, user, nil); err != nil {
+		return fmt.Errorf("repository setup failed: %w", err)
+	}
+	if accountDisabled(r.Context(), p, user) {
+		slog.InfoContext(r.Context(), "login refused", "provider", storage, "user", user, "reason", "account disabled")
+		if err := session.Save(r, w); err != nil {
+			slog.ErrorContext(r.Context(), "session save failed", "err", err)
+		}
+		http.Redirect(w, r, "/login?error=disabled", http.StatusSeeOther)
+		return ErrHandled
+	}
+	recordAccountLogin(r, p, user)
+
+	renewSession(r, session)
+	session.Values["Provider"] = storage
+	session.Values["GithubUser"] = &User{Login: user}
+	session.Values["Token"] = nil
+	session.Values["version"] = version
+	if err := session.Save(r, w); err != nil {
+		return fmt.Errorf("session save: %w", err)
+	}
+	return nil
+}
+
+// ssoAccountExists reports whether user already has bookmarks in p while p
+// stores OIDC or proxy-authenticated accounts. Local signup must not claim
+// such an account, since it may belong to a single sign-on user without a
+// password.
+func ssoAccountExists(ctx context.Context, p Provider, user string) bool {
+	oidc := Config.OIDCIssuer != "" && Config.GetOIDCStorage() == p.Name()
+	proxy := Config.ProxyAuthHeader != "" && Config.GetProxyAuthStorage() == p.Name()
+	if !oidc && !proxy {
+		return false
+	}
+	exists, err := p.RepoExists(ctx, user, nil, Config.GetRepoName())
+	return err != nil || exists
+}
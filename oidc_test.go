@@ -0,0 +1,275 @@
+package gobookmarks
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/sessions"
+)
+
+// testIdP is a minimal OpenID Connect issuer.
+type testIdP struct {
+	*httptest.Server
+	key      *rsa.PrivateKey
+	mu       sync.Mutex
+	codes    map[string]url.Values // code -> authorization request
+	claims   map[string]any        // extra/overriding ID token claims
+	tampered bool
+}
+
+func newTestIdP(t *testing.T) *testIdP {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	idp := &testIdP{key: key, codes: map[string]url.Values{}, claims: map[string]any{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 idp.URL,
+			"authorization_endpoint": idp.URL + "/authorize",
+			"token_endpoint":         idp.URL + "/token",
+			"jwks_uri":               idp.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		e := big.NewInt(int64(key.E)).Bytes()
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "RSA", "kid": "k1", "use": "sig",
+			"n": base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e": base64.RawURLEncoding.EncodeToString(e),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		idp.mu.Lock()
+		auth, ok := idp.codes[r.PostForm.Get("code")]
+		delete(idp.codes, r.PostForm.Get("code"))
+		idp.mu.Unlock()
+		sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+		if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != auth.Get("code_challenge") {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"access_token": "at", "token_type": "Bearer", "expires_in": 3600,
+			"id_token": idp.idToken(t, auth.Get("client_id"), auth.Get("nonce")),
+		})
+	})
+	idp.Server = httptest.NewServer(mux)
+	t.Cleanup(idp.Close)
+	return idp
+}
+
+// authorize simulates the user approving the request in authURL and returns
+// the code and state sent back to the client.
+func (idp *testIdP) authorize(t *testing.T, authURL string) (string, string) {
+	t.Helper()
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatalf("parse auth url: %v", err)
+	}
+	q := u.Query()
+	if q.Get("code_challenge_method") != "S256" || q.Get("nonce") == "" {
+		t.Fatalf("auth request missing pkce or nonce: %s", authURL)
+	}
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	code := "code-" + q.Get("state")
+	idp.codes[code] = q
+	return code, q.Get("state")
+}
+
+func (idp *testIdP) idToken(t *testing.T, aud, nonce string) string {
+	claims := map[string]any{
+		"iss": idp.URL, "aud": aud, "sub": "123", "nonce": nonce,
+		"iat": time.Now().Unix(), "exp": time.Now().Add(time.Hour).Unix(),
+		"preferred_username": "alice",
+	}
+	for k, v := range idp.claims {
+		claims[k] = v
+	}
+	enc := func(v any) string {
+		b, _ := json.Marshal(v)
+		return base64.RawURLEncoding.EncodeToString(b)
+	}
+	signed := enc(map[string]string{"alg": "RS256", "kid": "k1", "typ": "JWT"}) + "." + enc(claims)
+	sum := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, idp.key, crypto.SHA256, sum[:])
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	if idp.tampered {
+		sig[0] ^= 0xff
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func setupOIDCTest(t *testing.T) *testIdP {
+	t.Helper()
+	idp := newTestIdP(t)
+	old := Config
+	t.Cleanup(func() { Config = old })
+	Config.OIDCIssuer = idp.URL
+	Config.OIDCClientID = "gobookmarks"
+	Config.OIDCSecret = "secret"
+	Config.OIDCStorage = "git"
+	Config.ExternalURL = "http://bookmarks.test"
+	Config.LocalGitPath = t.TempDir()
+	Config.SessionName = "gobookmarks"
+	SessionStore = sessions.NewCookieStore([]byte("secret-key"))
+	return idp
+}
+
+// oidcLogin runs the login and callback handlers and returns the session
+// after the callback along with the callback response.
+func oidcLogin(t *testing.T, idp *testIdP, modify func(state string) string) (*sessions.Session, *httptest.ResponseRecorder) {
+	t.Helper()
+	req := httptest.NewRequest("GET", "/login/oidc", nil)
+	w := httptest.NewRecorder()
+	if err := OIDCLoginAction(w, req); err != ErrHandled {
+		t.Fatalf("OIDCLoginAction: %v", err)
+	}
+	code, state := idp.authorize(t, w.Header().Get("Location"))
+	if modify != nil {
+		state = modify(state)
+	}
+	cb := httptest.NewRequest("GET", "/oidcCallback?code="+url.QueryEscape(code)+"&state="+url.QueryEscape(state), nil)
+	addLastCookies(cb, w)
+	cw := httptest.NewRecorder()
+	if err := OIDCCallbackAction(cw, cb); err != nil && err != ErrHandled {
+		t.Fatalf("OIDCCallbackAction: %v", err)
+	}
+	check := httptest.NewRequest("GET", "/", nil)
+	addLastCookies(check, cw)
+	s, _ := SessionStore.New(check, Config.GetSessionName())
+	return s, cw
+}
+
+// addLastCookies adds the cookies set by w to req, keeping the last value
+// for each name as a browser would.
+func addLastCookies(req *http.Request, w *httptest.ResponseRecorder) {
+	last := map[string]*http.Cookie{}
+	for _, c := range w.Result().Cookies() {
+		last[c.Name] = c
+	}
+	for _, c := range last {
+		req.AddCookie(c)
+	}
+}
+
+func TestOIDCLogin(t *testing.T) {
+	idp := setupOIDCTest(t)
+	s, w := oidcLogin(t, idp, nil)
+	u, _ := s.Values["GithubUser"].(*User)
+	if u == nil || u.Login != "oidc:123" || s.Values["Provider"] != "git" {
+		t.Fatalf("not signed in: %v (%s)", s.Values, w.Header().Get("Location"))
+	}
+	if _, ok := s.Values["OIDCNonce"]; ok {
+		t.Fatalf("login state left in session")
+	}
+	if exists, _ := (GitProvider{}).RepoExists(context.Background(), "oidc:123", nil, Config.GetRepoName()); !exists {
+		t.Fatalf("storage repository not created")
+	}
+}
+
+func TestOIDCUsernameClaim(t *testing.T) {
+	idp := setupOIDCTest(t)
+	Config.OIDCUsernameClaim = "email"
+	idp.claims["email"] = "bob@example.com"
+	s, _ := oidcLogin(t, idp, nil)
+	if u, _ := s.Values["GithubUser"].(*User); u == nil || u.Login != "oidc:bob@example.com" {
+		t.Fatalf("claim not used: %v", s.Values)
+	}
+}
+
+func TestOIDCLoginRejected(t *testing.T) {
+	cases := map[string]struct {
+		setup  func(*testIdP)
+		modify func(string) string
+	}{
+		"state":     {modify: func(string) string { return "forged" }},
+		"nonce":     {setup: func(idp *testIdP) { idp.claims["nonce"] = "replayed" }},
+		"signature": {setup: func(idp *testIdP) { idp.tampered = true }},
+		"audience":  {setup: func(idp *testIdP) { idp.claims["aud"] = "someone-else" }},
+		"expired":   {setup: func(idp *testIdP) { idp.claims["exp"] = time.Now().Add(-time.Hour).Unix() }},
+		"issuer":    {setup: func(idp *testIdP) { idp.claims["iss"] = "https://evil.example" }},
+		"no claim":  {setup: func(idp *testIdP) { idp.claims["sub"] = "" }},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			idp := setupOIDCTest(t)
+			if tc.setup != nil {
+				tc.setup(idp)
+			}
+			s, w := oidcLogin(t, idp, tc.modify)
+			if _, ok := s.Values["GithubUser"]; ok {
+				t.Fatalf("signed in despite bad %s", name)
+			}
+			if loc := w.Header().Get("Location"); !strings.Contains(loc, "error=oidc") {
+				t.Fatalf("expected error redirect, got %q", loc)
+			}
+		})
+	}
+}
+
+func TestOIDCAccountBlocksLocalSignup(t *testing.T) {
+	idp := setupOIDCTest(t)
+	oidcLogin(t, idp, nil)
+	form := url.Values{"username": {"oidc:123"}, "password": {"pw"}}
+	req := httptest.NewRequest("POST", "/signup/git", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	if err := GitSignupAction(w, req); err != nil {
+		t.Fatalf("GitSignupAction: %v", err)
+	}
+	if loc := w.Header().Get("Location"); loc != "/login/git?error=reserved" {
+		t.Fatalf("signup for sso account allowed: %q", loc)
+	}
+}
+
+func TestOIDCLoginRefusedForPasswordAccount(t *testing.T) {
+	idp := setupOIDCTest(t)
+	Config.OIDCUsernameClaim = "preferred_username"
+	idp.claims["preferred_username"] = "admin"
+	if err := (GitProvider{}).CreateUser(context.Background(), "oidc:admin", "pw"); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	s, w := oidcLogin(t, idp, nil)
+	if _, ok := s.Values["GithubUser"]; ok {
+		t.Fatalf("signed in to a password account: %v", s.Values)
+	}
+	if loc := w.Header().Get("Location"); !strings.Contains(loc, "error=oidc") {
+		t.Fatalf("expected error redirect, got %q", loc)
+	}
+}
+
+func TestOIDCClaimCannotNameLocalAccount(t *testing.T) {
+	idp := setupOIDCTest(t)
+	Config.OIDCUsernameClaim = "preferred_username"
+	idp.claims["preferred_username"] = "admin"
+	if err := (GitProvider{}).CreateUser(context.Background(), "admin", "pw"); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	s, _ := oidcLogin(t, idp, nil)
+	if u, _ := s.Values["GithubUser"].(*User); u == nil || u.Login != "oidc:admin" {
+		t.Fatalf("expected namespaced account, got %v", s.Values)
+	}
+}
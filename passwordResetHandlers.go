@@ -0,0 +1,219 @@
+package gobookmarks
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	passwordResetTimeout = time.Hour
+	// passwordResetResend is how long a new reset request for the same
+	// account is ignored, so the form cannot be used to flood a mailbox.
+	passwordResetResend = time.Minute
+)
+
+// validEmail reports whether s is a bare email address.
+func validEmail(s string) bool {
+	a, err := mail.ParseAddress(s)
+	return err == nil && a.Address == s
+}
+
+// passwordResetAvailable reports whether users of provider can reset a
+// forgotten password by email.
+func passwordResetAvailable(provider string) bool {
+	if configuredMailer() == nil || Config.ExternalURL == "" {
+		return false
+	}
+	_, ok := GetProvider(provider).(EmailHandler)
+	return ok
+}
+
+func hashResetToken(token string) string {
+	h := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(h[:])
+}
+
+// resetProvider returns the provider named in the URL when it supports
+// password resets.
+func resetProvider(r *http.Request) (string, PasswordHandler, EmailHandler, error) {
+	name := mux.Vars(r)["provider"]
+	if !passwordResetAvailable(name) {
+		return "", nil, nil, NewUserError("Password reset by email is not available", nil)
+	}
+	p := GetProvider(name)
+	ph, ok := p.(PasswordHandler)
+	if !ok {
+		return "", nil, nil, NewUserError("Password reset by email is not available", nil)
+	}
+	return name, ph, p.(EmailHandler), nil
+}
+
+// PasswordResetPageData is rendered by forgotPassword.gohtml and
+// resetPassword.gohtml.
+type PasswordResetPageData struct {
+	*CoreData
+	Error    string
+	Provider string
+	// Sent is set once a reset has been requested.
+	Sent  bool
+	User  string
+	Token string
+	// Valid is false when the reset link is unknown or has expired.
+	Valid bool
+	// Done is set after the password was changed.
+	Done bool
+}
+
+func renderPasswordResetPage(w http.ResponseWriter, r *http.Request, name string, data PasswordResetPageData) error {
+	data.CoreData = r.Context().Value(ContextValues("coreData")).(*CoreData)
+	if data.Error == "" {
+		data.Error = r.URL.Query().Get("error")
+	}
+	data.Provider = mux.Vars(r)["provider"]
+	if err := GetCompiledTemplates(NewFuncs(r)).ExecuteTemplate(w, name, data); err != nil {
+		return fmt.Errorf("template: %w", err)
+	}
+	return nil
+}
+
+// ForgotPasswordPage asks for the username whose password should be reset.
+func ForgotPasswordPage(w http.ResponseWriter, r *http.Request) error {
+	if _, _, _, err := resetProvider(r); err != nil {
+		return err
+	}
+	return renderPasswordResetPage(w, r, "forgotPassword.gohtml", PasswordResetPageData{Sent: r.URL.Query().Get("sent") != ""})
+}
+
+// ForgotPasswordAction emails a reset link to the address of the account.
+// The response is the same whether or not the account exists or has an
+// address.
+func ForgotPasswordAction(w http.ResponseWriter, r *http.Request) error {
+	name, _, eh, err := resetProvider(r)
+	if err != nil {
+		return err
+	}
+	user := r.PostFormValue("username")
+	if err := sendPasswordReset(r, name, eh, user); err != nil {
+		slog.ErrorContext(r.Context(), "password reset request failed", "provider", name, "user", user, "err", err)
+	}
+	http.Redirect(w, r, "/login/"+name+"/forgot?sent=1", http.StatusSeeOther)
+	return ErrHandled
+}
+
+func sendPasswordReset(r *http.Request, provider string, eh EmailHandler, user string) error {
+	ctx := r.Context()
+	email, err := eh.Email(ctx, user)
+	if err != nil || email == "" {
+		return err
+	}
+	now := time.Now()
+	if reset, err := eh.PasswordReset(ctx, user); err != nil {
+		return err
+	} else if reset != nil && reset.Expires.After(now.Add(passwordResetTimeout-passwordResetResend)) {
+		return nil
+	}
+	token, err := newSessionID()
+	if err != nil {
+		return err
+	}
+	if err := eh.SetPasswordReset(ctx, user, &PasswordReset{TokenHash: hashResetToken(token), Expires: now.Add(passwordResetTimeout).UTC()}); err != nil {
+		return err
+	}
+	link := JoinURL(Config.GetExternalURL(), "login/"+provider+"/reset") + "?" + url.Values{"user": {user}, "token": {token}}.Encode()
+	body := fmt.Sprintf("A password reset was requested for the account %s.\n\n"+
+		"Open this link within %s to choose a new password:\n\n%s\n\n"+
+		"If you did not ask for this you can ignore this email; your password has not changed.\n",
+		user, passwordResetTimeout, link)
+	if err := configuredMailer().Send(ctx, email, "Reset your bookmarks password", body); err != nil {
+		return err
+	}
+	Audit(ctx, AuditEntry{Event: "password_reset_request", User: user, Provider: provider, IP: clientIP(r)})
+	return nil
+}
+
+// checkResetToken reports whether token is the pending reset of user.
+func checkResetToken(r *http.Request, eh EmailHandler, user, token string) (bool, error) {
+	if user == "" || token == "" {
+		return false, nil
+	}
+	reset, err := eh.PasswordReset(r.Context(), user)
+	if err != nil || reset == nil {
+		return false, nil
+	}
+	if time.Now().After(reset.Expires) {
+		return false, nil
+	}
+	return subtle.ConstantTimeCompare([]byte(hashResetToken(token)), []byte(reset.TokenHash)) == 1, nil
+}
+
+// ResetPasswordPage shows the new password form of a reset link.
+func ResetPasswordPage(w http.ResponseWriter, r *http.Request) error {
+	_, _, eh, err := resetProvider(r)
+	if err != nil {
+		return err
+	}
+	user, token := r.URL.Query().Get("user"), r.URL.Query().Get("token")
+	valid, err := checkResetToken(r, eh, user, token)
+	if err != nil {
+		return err
+	}
+	return renderPasswordResetPage(w, r, "resetPassword.gohtml", PasswordResetPageData{User: user, Token: token, Valid: valid})
+}
+
+// ResetPasswordAction sets the new password of a reset link, which can only
+// be used once, and signs the account out everywhere.
+func ResetPasswordAction(w http.ResponseWriter, r *http.Request) error {
+	name, ph, eh, err := resetProvider(r)
+	if err != nil {
+		return err
+	}
+	user, token := r.PostFormValue("user"), r.PostFormValue("token")
+	data := PasswordResetPageData{User: user, Token: token, Valid: true}
+	if wait := loginThrottled(r, name, user); wait > 0 {
+		rejectThrottled(w, r, "/login/"+name+"?error=throttled", wait)
+		return ErrHandled
+	}
+	valid, err := checkResetToken(r, eh, user, token)
+	if err != nil {
+		return err
+	}
+	if !valid {
+		slog.InfoContext(r.Context(), "login failed", "provider", name, "user", user, "reason", "invalid or expired reset link")
+		recordLoginFailure(r, name, user)
+		data.Valid = false
+		return renderPasswordResetPage(w, r, "resetPassword.gohtml", data)
+	}
+	password := r.PostFormValue("password")
+	switch {
+	case password == "":
+		data.Error = "Enter a new password"
+	case password != r.PostFormValue("confirm"):
+		data.Error = "The new passwords do not match"
+	}
+	if data.Error != "" {
+		return renderPasswordResetPage(w, r, "resetPassword.gohtml", data)
+	}
+	if err := eh.SetPasswordReset(r.Context(), user, nil); err != nil {
+		return fmt.Errorf("clear password reset: %w", err)
+	}
+	if err := ph.SetPassword(r.Context(), user, password); err != nil {
+		return fmt.Errorf("set password: %w", err)
+	}
+	if store := serverSessionStore(); store != nil {
+		if _, err := store.RevokeAll(r.Context(), name, user, ""); err != nil {
+			slog.ErrorContext(r.Context(), "revoke sessions failed", "action", "password reset", "provider", name, "user", user, "err", err)
+		}
+	}
+	recordLoginSuccess(r, name, user)
+	Audit(r.Context(), AuditEntry{Event: "password_reset", User: user, Provider: name, IP: clientIP(r)})
+	return renderPasswordResetPage(w, r, "resetPassword.gohtml", PasswordResetPageData{Done: true})
+}
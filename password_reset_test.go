@@ -0,0 +1,272 @@
+package gobookmarks
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/sessions"
+)
+
+// smtpMessage is a message accepted by the SMTP stand-in.
+type smtpMessage struct {
+	From string
+	To   []string
+	Data string
+}
+
+// startSMTPStandIn runs a minimal SMTP server on a local port for the test
+// and returns its port with the messages it receives.
+func startSMTPStandIn(t *testing.T) (int, <-chan smtpMessage) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+	msgs := make(chan smtpMessage, 10)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serveSMTP(conn, msgs)
+		}
+	}()
+	return ln.Addr().(*net.TCPAddr).Port, msgs
+}
+
+func serveSMTP(conn net.Conn, msgs chan<- smtpMessage) {
+	defer func() { _ = conn.Close() }()
+	r := bufio.NewReader(conn)
+	reply := func(s string) { _, _ = conn.Write([]byte(s + "\r\n")) }
+	reply("220 localhost ESMTP stand-in")
+	var msg smtpMessage
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch {
+		case cmd == "EHLO":
+			reply("250 localhost")
+		case cmd == "HELO", cmd == "NOOP", cmd == "RSET":
+			reply("250 OK")
+		case strings.HasPrefix(strings.ToUpper(line), "MAIL FROM:"):
+			msg = smtpMessage{From: strings.Trim(line[len("MAIL FROM:"):], "<> ")}
+			reply("250 OK")
+		case strings.HasPrefix(strings.ToUpper(line), "RCPT TO:"):
+			msg.To = append(msg.To, strings.Trim(line[len("RCPT TO:"):], "<> "))
+			reply("250 OK")
+		case cmd == "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(l, "."))
+			}
+			msg.Data = data.String()
+			msgs <- msg
+			reply("250 OK queued")
+		case cmd == "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Command not implemented")
+		}
+	}
+}
+
+func TestSMTPMailer(t *testing.T) {
+	port, msgs := startSMTPStandIn(t)
+	m := SMTPMailer{Host: "127.0.0.1", Port: port, From: "bookmarks@example.com"}
+	if err := m.Send(context.Background(), "alice@example.com", "Hello\r\nBcc: evil@example.com", "line one\nline two\n"); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	msg := <-msgs
+	if msg.From != "bookmarks@example.com" || len(msg.To) != 1 || msg.To[0] != "alice@example.com" {
+		t.Fatalf("envelope = %+v", msg)
+	}
+	if !strings.Contains(msg.Data, "Subject: HelloBcc: evil@example.com\r\n") || strings.Contains(msg.Data, "\r\nBcc:") {
+		t.Fatalf("subject not sanitised: %q", msg.Data)
+	}
+	if !strings.Contains(msg.Data, "\r\n\r\nline one\r\nline two\r\n") {
+		t.Fatalf("body = %q", msg.Data)
+	}
+}
+
+func TestEmailHandlers(t *testing.T) {
+	for name, p := range accountManagers(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			eh := p.(EmailHandler)
+			if _, err := eh.Email(ctx, "alice"); !errors.Is(err, ErrUserNotFound) {
+				t.Fatalf("Email without account: %v", err)
+			}
+			if err := p.CreateUser(ctx, "alice", "password"); err != nil {
+				t.Fatalf("CreateUser: %v", err)
+			}
+			if email, err := eh.Email(ctx, "alice"); err != nil || email != "" {
+				t.Fatalf("Email = %q, %v", email, err)
+			}
+			if err := eh.SetEmail(ctx, "alice", "alice@example.com"); err != nil {
+				t.Fatalf("SetEmail: %v", err)
+			}
+			if email, _ := eh.Email(ctx, "alice"); email != "alice@example.com" {
+				t.Fatalf("Email = %q", email)
+			}
+			reset := &PasswordReset{TokenHash: "abc", Expires: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)}
+			if err := eh.SetPasswordReset(ctx, "alice", reset); err != nil {
+				t.Fatalf("SetPasswordReset: %v", err)
+			}
+			if got, err := eh.PasswordReset(ctx, "alice"); err != nil || got.TokenHash != "abc" || !got.Expires.Equal(reset.Expires) {
+				t.Fatalf("PasswordReset = %+v, %v", got, err)
+			}
+			if err := eh.SetPasswordReset(ctx, "alice", nil); err != nil {
+				t.Fatalf("clear reset: %v", err)
+			}
+			if got, _ := eh.PasswordReset(ctx, "alice"); got != nil {
+				t.Fatalf("reset kept: %+v", got)
+			}
+		})
+	}
+}
+
+func setupPasswordResetTest(t *testing.T) (http.Handler, <-chan smtpMessage) {
+	t.Helper()
+	useLoginAttempts(t, nil)
+	port, msgs := startSMTPStandIn(t)
+	old := Config
+	t.Cleanup(func() { Config = old })
+	Config.LocalGitPath = t.TempDir()
+	Config.SessionName = "gobookmarks"
+	Config.ExternalURL = "http://bookmarks.test"
+	Config.SMTPHost = "127.0.0.1"
+	Config.SMTPPort = port
+	Config.SMTPFrom = "bookmarks@example.com"
+	SessionStore = sessions.NewCookieStore([]byte("secret-key"))
+	ctx := context.Background()
+	for _, u := range []string{"alice", "bob"} {
+		if err := (GitProvider{}).CreateUser(ctx, u, "password"); err != nil {
+			t.Fatalf("CreateUser: %v", err)
+		}
+	}
+	if err := (GitProvider{}).SetEmail(ctx, "alice", "alice@example.com"); err != nil {
+		t.Fatalf("SetEmail: %v", err)
+	}
+	r := mux.NewRouter()
+	handle := func(path, method string, h func(http.ResponseWriter, *http.Request) error) {
+		r.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			if err := h(w, r); err != nil && !errors.Is(err, ErrHandled) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			}
+		}).Methods(method)
+	}
+	handle("/login/{provider}/forgot", "GET", ForgotPasswordPage)
+	handle("/login/{provider}/forgot", "POST", ForgotPasswordAction)
+	handle("/login/{provider}/reset", "GET", ResetPasswordPage)
+	handle("/login/{provider}/reset", "POST", ResetPasswordAction)
+	return UserAdderMiddleware(CoreAdderMiddleware(r)), msgs
+}
+
+var resetLinkPattern = regexp.MustCompile(`http://bookmarks\.test/login/git/reset\?\S+`)
+
+func TestPasswordReset(t *testing.T) {
+	h, msgs := setupPasswordResetTest(t)
+	ctx := context.Background()
+
+	if w := adminRequest(h, "GET", "/login/git/forgot", nil, nil); w.Code != http.StatusOK {
+		t.Fatalf("forgot page: %d %s", w.Code, w.Body.String())
+	}
+	for _, user := range []string{"bob", "nobody"} {
+		w := adminRequest(h, "POST", "/login/git/forgot", url.Values{"username": {user}}, nil)
+		if loc := w.Header().Get("Location"); loc != "/login/git/forgot?sent=1" {
+			t.Fatalf("%s: redirected to %q", user, loc)
+		}
+	}
+	select {
+	case msg := <-msgs:
+		t.Fatalf("mail sent to account without email: %+v", msg)
+	default:
+	}
+
+	adminRequest(h, "POST", "/login/git/forgot", url.Values{"username": {"alice"}}, nil)
+	msg := <-msgs
+	if msg.To[0] != "alice@example.com" {
+		t.Fatalf("mail sent to %v", msg.To)
+	}
+	link := resetLinkPattern.FindString(msg.Data)
+	if link == "" {
+		t.Fatalf("no reset link in %q", msg.Data)
+	}
+	u, _ := url.Parse(link)
+	q := u.Query()
+
+	// a second request right away does not send another mail or replace
+	// the link
+	adminRequest(h, "POST", "/login/git/forgot", url.Values{"username": {"alice"}}, nil)
+	select {
+	case msg := <-msgs:
+		t.Fatalf("second mail sent: %+v", msg)
+	default:
+	}
+
+	if w := adminRequest(h, "GET", u.RequestURI(), nil, nil); !strings.Contains(w.Body.String(), `name="token"`) {
+		t.Fatalf("reset page: %d %s", w.Code, w.Body.String())
+	}
+	form := url.Values{"user": {q.Get("user")}, "token": {q.Get("token")}, "password": {"new"}, "confirm": {"other"}}
+	if w := adminRequest(h, "POST", "/login/git/reset", form, nil); !strings.Contains(w.Body.String(), "do not match") {
+		t.Fatalf("mismatch accepted: %s", w.Body.String())
+	}
+	form.Set("confirm", "new")
+	if w := adminRequest(h, "POST", "/login/git/reset", form, nil); !strings.Contains(w.Body.String(), "has been changed") {
+		t.Fatalf("reset: %d %s", w.Code, w.Body.String())
+	}
+	if ok, _ := (GitProvider{}).CheckPassword(ctx, "alice", "new"); !ok {
+		t.Fatalf("password not changed")
+	}
+
+	form.Set("password", "again")
+	form.Set("confirm", "again")
+	if w := adminRequest(h, "POST", "/login/git/reset", form, nil); !strings.Contains(w.Body.String(), "invalid") {
+		t.Fatalf("link used twice: %s", w.Body.String())
+	}
+	if ok, _ := (GitProvider{}).CheckPassword(ctx, "alice", "again"); ok {
+		t.Fatalf("password changed by used link")
+	}
+}
+
+func TestPasswordResetExpired(t *testing.T) {
+	h, _ := setupPasswordResetTest(t)
+	token := "expiredtoken"
+	if err := (GitProvider{}).SetPasswordReset(context.Background(), "alice", &PasswordReset{
+		TokenHash: hashResetToken(token),
+		Expires:   time.Now().Add(-time.Minute),
+	}); err != nil {
+		t.Fatalf("SetPasswordReset: %v", err)
+	}
+	form := url.Values{"user": {"alice"}, "token": {token}, "password": {"new"}, "confirm": {"new"}}
+	if w := adminRequest(h, "POST", "/login/git/reset", form, nil); !strings.Contains(w.Body.String(), "expired") {
+		t.Fatalf("expired link accepted: %s", w.Body.String())
+	}
+	if ok, _ := (GitProvider{}).CheckPassword(context.Background(), "alice", "new"); ok {
+		t.Fatalf("password changed by expired link")
+	}
+}
@@ -25,6 +25,9 @@ type Commit struct {
 	CommitterName  string
 	CommitterEmail string
 	CommitterDate  time.Time
+	AuthorName     string
+	AuthorEmail    string
+	AuthorDate     time.Time
 }
 
 type Provider interface {
@@ -47,17 +50,135 @@ type AdjacentCommitProvider interface {
 	AdjacentCommits(ctx context.Context, user string, token *oauth2.Token, ref, sha string) (string, string, error)
 }
 
+// FileLister is implemented by providers that can store several bookmark
+// files in a repository. ListBookmarkFiles returns the repository relative
+// paths of the candidate files at ref.
+type FileLister interface {
+	ListBookmarkFiles(ctx context.Context, user string, token *oauth2.Token, ref string) ([]string, error)
+}
+
+// MirrorStatus describes the remote a repository is mirrored to and the
+// outcome of the last synchronisation.
+type MirrorStatus struct {
+	URL string
+	// Username is the HTTPS user and HasPassword reports whether a password
+	// or token is stored for it. The password itself is never returned.
+	Username    string
+	HasPassword bool
+	// SSHPublicKey is the server's key for ssh mirrors in authorized_keys
+	// format, empty when no key is configured.
+	SSHPublicKey string
+	LastPush     time.Time
+	LastPull     time.Time
+	LastError    string
+	// LastResult summarises the last pull, e.g. "fast-forward" or "merged".
+	LastResult string
+}
+
+// MirrorRemote is the remote a repository is mirrored to. Username and
+// Password authenticate HTTPS remotes; an empty Password keeps the stored one
+// when the URL and Username are unchanged.
+type MirrorRemote struct {
+	URL      string
+	Username string
+	Password string
+}
+
+// Mirrorer is implemented by providers that can mirror a user's repository to
+// a remote. Changes are pushed in the background after every commit and
+// pulled on demand.
+type Mirrorer interface {
+	MirrorStatus(ctx context.Context, user string) (*MirrorStatus, error)
+	SetMirror(ctx context.Context, user string, remote MirrorRemote) error
+	PullMirror(ctx context.Context, user string) error
+}
+
+// ReplayCommit describes a commit copied from another provider. Parent is the
+// destination sha of the previous commit, empty for the first commit.
+type ReplayCommit struct {
+	Parent      string
+	Text        string
+	Message     string
+	AuthorName  string
+	AuthorEmail string
+	Date        time.Time
+}
+
+// HistoryReplayer is implemented by providers that can store commits with
+// their original metadata. ReplayCommit returns the sha of the new commit
+// without moving any branch; SetRef points a "refs/heads/" or "refs/tags/"
+// reference at it.
+type HistoryReplayer interface {
+	ReplayCommit(ctx context.Context, user string, token *oauth2.Token, c ReplayCommit) (string, error)
+	SetRef(ctx context.Context, user string, token *oauth2.Token, ref, sha string) error
+}
+
 // PasswordHandler is implemented by providers that manage passwords.
 // PasswordHandler manages user accounts for providers that do not rely on
 // external authentication.
 //
 // CreateUser registers a new account and returns ErrUserExists if the user is
 // already present. SetPassword updates the password for an existing user and
-// returns ErrUserNotFound when the account does not exist.
+// returns ErrUserNotFound when the account does not exist. HasPassword is
+// false for accounts created by single sign-on.
 type PasswordHandler interface {
 	CreateUser(ctx context.Context, user, password string) error
 	SetPassword(ctx context.Context, user, password string) error
 	CheckPassword(ctx context.Context, user, password string) (bool, error)
+	HasPassword(ctx context.Context, user string) (bool, error)
+}
+
+// TwoFactorHandler stores TOTP enrollment alongside a PasswordHandler's
+// password data. TwoFactor returns nil when the user has not enrolled and
+// ErrUserNotFound when the user has no password account. Passing nil to
+// SetTwoFactor removes the enrollment.
+type TwoFactorHandler interface {
+	TwoFactor(ctx context.Context, user string) (*TwoFactor, error)
+	SetTwoFactor(ctx context.Context, user string, tf *TwoFactor) error
+}
+
+// PasswordReset is a pending password reset. Only a hash of the token sent
+// by email is kept.
+type PasswordReset struct {
+	TokenHash string    `json:"token_hash"`
+	Expires   time.Time `json:"expires"`
+}
+
+// EmailHandler stores an optional email address and a pending password reset
+// alongside a PasswordHandler's password data. Email returns "" when no
+// address is set and PasswordReset returns nil when no reset is pending. All
+// methods return ErrUserNotFound when the user has no password account.
+// Passing nil to SetPasswordReset removes the pending reset.
+type EmailHandler interface {
+	Email(ctx context.Context, user string) (string, error)
+	SetEmail(ctx context.Context, user, email string) error
+	PasswordReset(ctx context.Context, user string) (*PasswordReset, error)
+	SetPasswordReset(ctx context.Context, user string, reset *PasswordReset) error
+}
+
+// Account describes an account stored by an AccountManager.
+type Account struct {
+	User string
+	// Created is zero when the provider does not record it.
+	Created   time.Time
+	LastLogin time.Time
+	Disabled  bool
+	// HasPassword is false for single sign-on accounts.
+	HasPassword bool
+	// BookmarkBytes is the size of the bookmark file on main.
+	BookmarkBytes int
+	Commits       int
+}
+
+// AccountManager is implemented by providers that keep accounts themselves
+// so administrators can list and manage them. Methods taking a user return
+// ErrUserNotFound when the account does not exist.
+type AccountManager interface {
+	Accounts(ctx context.Context) ([]*Account, error)
+	AccountDisabled(ctx context.Context, user string) (bool, error)
+	SetAccountDisabled(ctx context.Context, user string, disabled bool) error
+	RecordLogin(ctx context.Context, user string, at time.Time) error
+	DeleteAccount(ctx context.Context, user string) error
 }
 
 var (
@@ -21,7 +21,7 @@ var bookmarksCache = struct {
 	data map[string]*bookmarkCacheEntry
 }{data: make(map[string]*bookmarkCacheEntry)}
 
-func cacheKey(user, ref string) string { return user + "|" + ref }
+func cacheKey(user, ref, file string) string { return user + "|" + ref + "|" + file }
 
 type requestCache struct {
 	sync.RWMutex
@@ -41,8 +41,7 @@ func invalidateRequestCache(ctx context.Context, user string) {
 	}
 }
 
-func getCachedBookmarks(user, ref string) (string, string, bool) {
-	key := cacheKey(user, ref)
+func getCachedBookmarks(key string) (string, string, bool) {
 	bookmarksCache.RLock()
 	entry, ok := bookmarksCache.data[key]
 	bookmarksCache.RUnlock()
@@ -108,8 +107,14 @@ func GetTags(ctx context.Context, user string, token *oauth2.Token) ([]*Tag, err
 	if p == nil {
 		return nil, ErrNoProvider
 	}
-	tags, err := p.GetTags(ctx, user, token)
-	if errors.Is(err, ErrRepoNotFound) && p.Name() == "git" {
+	owner, err := bookmarkOwner(ctx, user, false)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	tags, err := p.GetTags(ctx, owner, token)
+	observeProviderCall(ctx, p, "GetTags", start, err)
+	if errors.Is(err, ErrRepoNotFound) && p.Name() == "git" && owner == user {
 		return nil, ErrSignedOut
 	}
 	return tags, err
@@ -120,8 +125,14 @@ func GetBranches(ctx context.Context, user string, token *oauth2.Token) ([]*Bran
 	if p == nil {
 		return nil, ErrNoProvider
 	}
-	bs, err := p.GetBranches(ctx, user, token)
-	if errors.Is(err, ErrRepoNotFound) && p.Name() == "git" {
+	owner, err := bookmarkOwner(ctx, user, false)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	bs, err := p.GetBranches(ctx, owner, token)
+	observeProviderCall(ctx, p, "GetBranches", start, err)
+	if errors.Is(err, ErrRepoNotFound) && p.Name() == "git" && owner == user {
 		return nil, ErrSignedOut
 	}
 	return bs, err
@@ -132,8 +143,14 @@ func GetCommits(ctx context.Context, user string, token *oauth2.Token, ref strin
 	if p == nil {
 		return nil, ErrNoProvider
 	}
-	cs, err := p.GetCommits(ctx, user, token, ref, page, perPage)
-	if errors.Is(err, ErrRepoNotFound) && p.Name() == "git" {
+	owner, err := bookmarkOwner(ctx, user, false)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	cs, err := p.GetCommits(ctx, owner, token, ref, page, perPage)
+	observeProviderCall(ctx, p, "GetCommits", start, err)
+	if errors.Is(err, ErrRepoNotFound) && p.Name() == "git" && owner == user {
 		return nil, ErrSignedOut
 	}
 	return cs, err
@@ -145,8 +162,14 @@ func GetAdjacentCommits(ctx context.Context, user string, token *oauth2.Token, r
 		return "", "", ErrNoProvider
 	}
 	if ap, ok := p.(AdjacentCommitProvider); ok {
-		prev, next, err := ap.AdjacentCommits(ctx, user, token, ref, sha)
-		if errors.Is(err, ErrRepoNotFound) && p.Name() == "git" {
+		owner, err := bookmarkOwner(ctx, user, false)
+		if err != nil {
+			return "", "", err
+		}
+		start := time.Now()
+		prev, next, err := ap.AdjacentCommits(ctx, owner, token, ref, sha)
+		observeProviderCall(ctx, p, "AdjacentCommits", start, err)
+		if errors.Is(err, ErrRepoNotFound) && p.Name() == "git" && owner == user {
 			return "", "", ErrSignedOut
 		}
 		return prev, next, err
@@ -154,8 +177,36 @@ func GetAdjacentCommits(ctx context.Context, user string, token *oauth2.Token, r
 	return "", "", nil
 }
 
+// ListBookmarkFiles returns the bookmark files available to user at ref. It
+// returns nil when the provider only stores a single file.
+func ListBookmarkFiles(ctx context.Context, user string, token *oauth2.Token, ref string) ([]string, error) {
+	p := providerFromContext(ctx)
+	if p == nil {
+		return nil, ErrNoProvider
+	}
+	fl, ok := p.(FileLister)
+	if !ok {
+		return nil, nil
+	}
+	owner, err := bookmarkOwner(ctx, user, false)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	files, err := fl.ListBookmarkFiles(ctx, owner, token, ref)
+	observeProviderCall(ctx, p, "ListBookmarkFiles", start, err)
+	if errors.Is(err, ErrRepoNotFound) && p.Name() == "git" && owner == user {
+		return nil, ErrSignedOut
+	}
+	return files, err
+}
+
 func GetBookmarks(ctx context.Context, user, ref string, token *oauth2.Token) (string, string, error) {
-	key := cacheKey(user, ref)
+	owner, err := bookmarkOwner(ctx, user, false)
+	if err != nil {
+		return "", "", err
+	}
+	key := cacheKey(owner, ref, repoNameFromContext(ctx)+"/"+bookmarkFileFromContext(ctx))
 	if cd, ok := ctx.Value(ContextValues("coreData")).(*CoreData); ok && cd.requestCache != nil {
 		cd.requestCache.RLock()
 		if entry, ok := cd.requestCache.data[key]; ok {
@@ -165,15 +216,19 @@ func GetBookmarks(ctx context.Context, user, ref string, token *oauth2.Token) (s
 		cd.requestCache.RUnlock()
 	}
 
-	if b, sha, ok := getCachedBookmarks(user, ref); ok {
+	b, sha, ok := getCachedBookmarks(key)
+	observeCacheLookup(bookmarksCacheRequests, ok)
+	if ok {
 		return b, sha, nil
 	}
 	p := providerFromContext(ctx)
 	if p == nil {
 		return "", "", ErrNoProvider
 	}
-	b, sha, err := p.GetBookmarks(ctx, user, ref, token)
-	if errors.Is(err, ErrRepoNotFound) && p.Name() == "git" {
+	start := time.Now()
+	b, sha, err = p.GetBookmarks(ctx, owner, ref, token)
+	observeProviderCall(ctx, p, "GetBookmarks", start, err)
+	if errors.Is(err, ErrRepoNotFound) && p.Name() == "git" && owner == user {
 		return "", "", ErrSignedOut
 	}
 	if err == nil {
@@ -191,11 +246,21 @@ func UpdateBookmarks(ctx context.Context, user string, token *oauth2.Token, sour
 	if p == nil {
 		return ErrNoProvider
 	}
-	err := p.UpdateBookmarks(ctx, user, token, sourceRef, branch, text, expectSHA)
+	owner, err := bookmarkOwner(ctx, user, true)
+	if err != nil {
+		return err
+	}
+	if owner != user {
+		ctx = withCommitAuthor(ctx, user)
+	}
+	defer trackWork()()
+	start := time.Now()
+	err = p.UpdateBookmarks(ctx, owner, token, sourceRef, branch, text, expectSHA)
+	observeProviderCall(ctx, p, "UpdateBookmarks", start, err)
 	if err == nil {
-		invalidateBookmarkCache(user)
-		invalidateRequestCache(ctx, user)
-	} else if errors.Is(err, ErrRepoNotFound) && p.Name() == "git" {
+		invalidateBookmarkCache(owner)
+		invalidateRequestCache(ctx, owner)
+	} else if errors.Is(err, ErrRepoNotFound) && p.Name() == "git" && owner == user {
 		return ErrSignedOut
 	}
 	return err
@@ -206,11 +271,21 @@ func CreateBookmarks(ctx context.Context, user string, token *oauth2.Token, bran
 	if p == nil {
 		return ErrNoProvider
 	}
-	err := p.CreateBookmarks(ctx, user, token, branch, text)
+	owner, err := bookmarkOwner(ctx, user, true)
+	if err != nil {
+		return err
+	}
+	if owner != user {
+		ctx = withCommitAuthor(ctx, user)
+	}
+	defer trackWork()()
+	start := time.Now()
+	err = p.CreateBookmarks(ctx, owner, token, branch, text)
+	observeProviderCall(ctx, p, "CreateBookmarks", start, err)
 	if err == nil {
-		invalidateBookmarkCache(user)
-		invalidateRequestCache(ctx, user)
-	} else if errors.Is(err, ErrRepoNotFound) && p.Name() == "git" {
+		invalidateBookmarkCache(owner)
+		invalidateRequestCache(ctx, owner)
+	} else if errors.Is(err, ErrRepoNotFound) && p.Name() == "git" && owner == user {
 		return ErrSignedOut
 	}
 	return err
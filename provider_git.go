@@ -6,9 +6,12 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"golang.org/x/crypto/bcrypt"
@@ -37,6 +40,69 @@ func userDir(user string) string {
 	return filepath.Join(Config.LocalGitPath, hex.EncodeToString(h[:]))
 }
 
+// commitSignature returns the identity used for commits made on behalf of the
+// request, preferring the editor recorded by withCommitAuthor.
+func commitSignature(ctx context.Context) *object.Signature {
+	sig := &object.Signature{Name: "Gobookmarks", Email: "Gobookmarks@arran.net.au", When: time.Now()}
+	if name := commitAuthorName(ctx); name != "" {
+		sig.Name = name
+	}
+	return sig
+}
+
+// writeBookmarkFile writes text to the repository relative file and stages it.
+func writeBookmarkFile(wt *git.Worktree, user, file, text string) error {
+	full := filepath.Join(userDir(user), filepath.FromSlash(file))
+	if err := os.MkdirAll(filepath.Dir(full), 0700); err != nil {
+		return err
+	}
+	if err := os.WriteFile(full, []byte(text), 0600); err != nil {
+		return err
+	}
+	_, err := wt.Add(file)
+	return err
+}
+
+// gitIgnored lists the account files kept in the repository's working tree
+// that must never be committed.
+var gitIgnored = []string{".password", ".totp", ".email", ".reset"}
+
+// ensureGitignore adds any entry of gitIgnored missing from the .gitignore in
+// dir and stages the file. It reports whether the file changed, so
+// repositories created before an entry was introduced pick it up on their
+// next save.
+func ensureGitignore(wt *git.Worktree, dir string) (bool, error) {
+	path := filepath.Join(dir, ".gitignore")
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return false, err
+	}
+	have := map[string]bool{}
+	for _, line := range strings.Split(string(data), "\n") {
+		have[strings.TrimSpace(line)] = true
+	}
+	text := string(data)
+	for _, name := range gitIgnored {
+		if have[name] {
+			continue
+		}
+		if text != "" && !strings.HasSuffix(text, "\n") {
+			text += "\n"
+		}
+		text += name + "\n"
+	}
+	if text == string(data) {
+		return false, nil
+	}
+	if err := os.WriteFile(path, []byte(text), 0600); err != nil {
+		return false, err
+	}
+	if _, err := wt.Add(".gitignore"); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
 func openRepo(user string) (*git.Repository, error) {
 	r, err := git.PlainOpen(userDir(user))
 	if err != nil {
@@ -106,7 +172,8 @@ func (GitProvider) GetCommits(ctx context.Context, user string, token *oauth2.To
 	if err != nil {
 		return nil, nil
 	}
-	iter, err := r.Log(&git.LogOptions{From: *h})
+	file := bookmarkFileFromContext(ctx)
+	iter, err := r.Log(&git.LogOptions{From: *h, FileName: &file})
 	if err != nil {
 		return nil, err
 	}
@@ -127,6 +194,9 @@ func (GitProvider) GetCommits(ctx context.Context, user string, token *oauth2.To
 			CommitterName:  c.Committer.Name,
 			CommitterEmail: c.Committer.Email,
 			CommitterDate:  c.Committer.When,
+			AuthorName:     c.Author.Name,
+			AuthorEmail:    c.Author.Email,
+			AuthorDate:     c.Author.When,
 		})
 		i++
 		return nil
@@ -149,23 +219,24 @@ func (GitProvider) AdjacentCommits(ctx context.Context, user string, token *oaut
 	if err != nil {
 		return "", "", err
 	}
-	iter, err := r.Log(&git.LogOptions{From: *h})
+	file := bookmarkFileFromContext(ctx)
+	iter, err := r.Log(&git.LogOptions{From: *h, FileName: &file})
 	if err != nil {
 		return "", "", err
 	}
 	var prev, next string
 	var last string
+	found := false
 	err = iter.ForEach(func(c *object.Commit) error {
+		if found {
+			// previous commit in the file's history (older)
+			prev = c.Hash.String()
+			return storer.ErrStop
+		}
 		if c.Hash.String() == sha {
-			// previous commit in history (older)
-			if c.NumParents() > 0 {
-				p, err := c.Parent(0)
-				if err == nil {
-					prev = p.Hash.String()
-				}
-			}
+			found = true
 			next = last
-			return storer.ErrStop
+			return nil
 		}
 		last = c.Hash.String()
 		return nil
@@ -195,7 +266,7 @@ func (GitProvider) GetBookmarks(ctx context.Context, user, ref string, token *oa
 	if err != nil {
 		return "", "", err
 	}
-	file, err := commit.File("bookmarks.txt")
+	file, err := commit.File(bookmarkFileFromContext(ctx))
 	if err != nil {
 		if err == object.ErrFileNotFound {
 			return "", commit.Hash.String(), nil
@@ -209,6 +280,39 @@ func (GitProvider) GetBookmarks(ctx context.Context, user, ref string, token *oa
 	return data, commit.Hash.String(), nil
 }
 
+func (GitProvider) ListBookmarkFiles(ctx context.Context, user string, token *oauth2.Token, ref string) ([]string, error) {
+	r, err := openRepo(user)
+	if err != nil {
+		return nil, err
+	}
+	if ref == "" {
+		ref = "refs/heads/main"
+	}
+	h, err := r.ResolveRevision(plumbing.Revision(ref))
+	if err != nil {
+		if errors.Is(err, plumbing.ErrReferenceNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	commit, err := r.CommitObject(*h)
+	if err != nil {
+		return nil, err
+	}
+	iter, err := commit.Files()
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	err = iter.ForEach(func(f *object.File) error {
+		if isBookmarkFileName(f.Name) {
+			files = append(files, f.Name)
+		}
+		return nil
+	})
+	return files, err
+}
+
 func (GitProvider) UpdateBookmarks(ctx context.Context, user string, token *oauth2.Token, sourceRef, branch, text, expectSHA string) error {
 	if branch == "" {
 		branch = "main"
@@ -232,18 +336,22 @@ func (GitProvider) UpdateBookmarks(ctx context.Context, user string, token *oaut
 	if expectSHA != "" && head.Hash().String() != expectSHA {
 		return errors.New("sha mismatch")
 	}
-	if err := os.WriteFile(filepath.Join(userDir(user), "bookmarks.txt"), []byte(text), 0600); err != nil {
+	if err := writeBookmarkFile(wt, user, bookmarkFileFromContext(ctx), text); err != nil {
 		return err
 	}
-	if _, err := wt.Add("bookmarks.txt"); err != nil {
+	if _, err := ensureGitignore(wt, userDir(user)); err != nil {
 		return err
 	}
 	_, err = wt.Commit("Auto change from web", &git.CommitOptions{
-		Author: &object.Signature{Name: "Gobookmarks", Email: "Gobookmarks@arran.net.au", When: time.Now()},
+		Author: commitSignature(ctx),
 	})
-	if err != nil && !errors.Is(err, git.ErrEmptyCommit) {
+	if err != nil {
+		if errors.Is(err, git.ErrEmptyCommit) {
+			return nil
+		}
 		return err
 	}
+	schedulePushMirror(ctx, user, r)
 	return nil
 }
 
@@ -268,18 +376,22 @@ func (GitProvider) CreateBookmarks(ctx context.Context, user string, token *oaut
 			}
 		}
 	}
-	if err := os.WriteFile(filepath.Join(userDir(user), "bookmarks.txt"), []byte(text), 0600); err != nil {
+	if err := writeBookmarkFile(wt, user, bookmarkFileFromContext(ctx), text); err != nil {
 		return err
 	}
-	if _, err := wt.Add("bookmarks.txt"); err != nil {
+	if _, err := ensureGitignore(wt, userDir(user)); err != nil {
 		return err
 	}
 	_, err = wt.Commit("Auto create from web", &git.CommitOptions{
-		Author: &object.Signature{Name: "Gobookmarks", Email: "Gobookmarks@arran.net.au", When: time.Now()},
+		Author: commitSignature(ctx),
 	})
-	if err != nil && !errors.Is(err, git.ErrEmptyCommit) {
+	if err != nil {
+		if errors.Is(err, git.ErrEmptyCommit) {
+			return nil
+		}
 		return err
 	}
+	schedulePushMirror(ctx, user, r)
 	return nil
 }
 
@@ -312,13 +424,9 @@ func (GitProvider) CreateRepo(ctx context.Context, user string, token *oauth2.To
 		}
 		added = true
 	}
-	if _, err := os.Stat(filepath.Join(path, ".gitignore")); os.IsNotExist(err) {
-		if err := os.WriteFile(filepath.Join(path, ".gitignore"), []byte(".password\n"), 0600); err != nil {
-			return err
-		}
-		if _, err := wt.Add(".gitignore"); err != nil {
-			return err
-		}
+	if changed, err := ensureGitignore(wt, path); err != nil {
+		return err
+	} else if changed {
 		added = true
 	}
 	if added {
@@ -329,7 +437,7 @@ func (GitProvider) CreateRepo(ctx context.Context, user string, token *oauth2.To
 			return err
 		}
 	}
-	return nil
+	return updateGitAccount(user, func(*gitAccount) {})
 }
 
 func (GitProvider) RepoExists(ctx context.Context, user string, token *oauth2.Token, name string) (bool, error) {
@@ -402,3 +510,148 @@ func (GitProvider) CheckPassword(ctx context.Context, user, password string) (bo
 	}
 	return true, nil
 }
+
+// HasPassword reports whether the user has a password file.
+func (GitProvider) HasPassword(ctx context.Context, user string) (bool, error) {
+	if _, err := os.Stat(passwordPath(user)); err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func twoFactorPath(user string) string {
+	return filepath.Join(filepath.Dir(passwordPath(user)), ".totp")
+}
+
+// TwoFactor reads the user's TOTP enrollment from the .totp file kept next to
+// the password hash.
+func (GitProvider) TwoFactor(ctx context.Context, user string) (*TwoFactor, error) {
+	if _, err := os.Stat(passwordPath(user)); err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	data, err := os.ReadFile(twoFactorPath(user))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var tf TwoFactor
+	if err := json.Unmarshal(data, &tf); err != nil {
+		return nil, fmt.Errorf("decode two-factor data: %w", err)
+	}
+	return &tf, nil
+}
+
+// SetTwoFactor writes or, when tf is nil, removes the user's TOTP enrollment.
+func (GitProvider) SetTwoFactor(ctx context.Context, user string, tf *TwoFactor) error {
+	if _, err := os.Stat(passwordPath(user)); err != nil {
+		if os.IsNotExist(err) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+	p := twoFactorPath(user)
+	if tf == nil {
+		if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	data, err := json.Marshal(tf)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(p, data, 0600)
+}
+
+func emailPath(user string) string {
+	return filepath.Join(filepath.Dir(passwordPath(user)), ".email")
+}
+
+func resetPath(user string) string {
+	return filepath.Join(filepath.Dir(passwordPath(user)), ".reset")
+}
+
+// requirePassword returns ErrUserNotFound when user has no password file.
+func requirePassword(user string) error {
+	if _, err := os.Stat(passwordPath(user)); err != nil {
+		if os.IsNotExist(err) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// Email reads the address kept in the .email file next to the password hash.
+func (GitProvider) Email(ctx context.Context, user string) (string, error) {
+	if err := requirePassword(user); err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(emailPath(user))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// SetEmail writes or, when email is empty, removes the user's address.
+func (GitProvider) SetEmail(ctx context.Context, user, email string) error {
+	if err := requirePassword(user); err != nil {
+		return err
+	}
+	if email == "" {
+		if err := os.Remove(emailPath(user)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	return os.WriteFile(emailPath(user), []byte(email+"\n"), 0600)
+}
+
+// PasswordReset reads the pending reset from the .reset file.
+func (GitProvider) PasswordReset(ctx context.Context, user string) (*PasswordReset, error) {
+	if err := requirePassword(user); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(resetPath(user))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var reset PasswordReset
+	if err := json.Unmarshal(data, &reset); err != nil {
+		return nil, fmt.Errorf("decode password reset: %w", err)
+	}
+	return &reset, nil
+}
+
+// SetPasswordReset writes or, when reset is nil, removes the pending reset.
+func (GitProvider) SetPasswordReset(ctx context.Context, user string, reset *PasswordReset) error {
+	if err := requirePassword(user); err != nil {
+		return err
+	}
+	if reset == nil {
+		if err := os.Remove(resetPath(user)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	data, err := json.Marshal(reset)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(resetPath(user), data, 0600)
+}
@@ -0,0 +1,274 @@
+//go:build !excludegitprovider
+
+package gobookmarks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+const accountStateFile = "gobookmarks-account.json"
+
+// gitAccount is kept inside each repository's .git directory. Directories
+// are named by a hash of the username, so the name is recorded here to let
+// accounts be listed.
+type gitAccount struct {
+	User      string    `json:"user"`
+	Created   time.Time `json:"created"`
+	LastLogin time.Time `json:"last_login,omitempty"`
+	Disabled  bool      `json:"disabled,omitempty"`
+}
+
+func accountStatePath(user string) string {
+	return filepath.Join(userDir(user), ".git", accountStateFile)
+}
+
+func loadGitAccount(user string) (*gitAccount, error) {
+	b, err := os.ReadFile(accountStatePath(user))
+	if err != nil {
+		return nil, err
+	}
+	var a gitAccount
+	if err := json.Unmarshal(b, &a); err != nil {
+		return nil, fmt.Errorf("decode account state: %w", err)
+	}
+	return &a, nil
+}
+
+// updateGitAccount applies fn to the user's account state, creating it when
+// the repository has none yet.
+func updateGitAccount(user string, fn func(*gitAccount)) error {
+	if exists, err := (GitProvider{}).RepoExists(context.Background(), user, nil, ""); err != nil {
+		return err
+	} else if !exists {
+		return ErrUserNotFound
+	}
+	a, err := loadGitAccount(user)
+	if errors.Is(err, os.ErrNotExist) {
+		a = &gitAccount{Created: time.Now().UTC()}
+	} else if err != nil {
+		return err
+	}
+	a.User = user
+	fn(a)
+	b, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(accountStatePath(user), b, 0600)
+}
+
+// gitRepoStats returns the size of the default bookmark file on main and the
+// number of commits reachable from it.
+func gitRepoStats(user string) (int, int, error) {
+	r, err := openRepo(user)
+	if err != nil {
+		return 0, 0, err
+	}
+	ref, err := r.Reference(plumbing.NewBranchReferenceName("main"), true)
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return 0, 0, nil
+	} else if err != nil {
+		return 0, 0, err
+	}
+	c, err := r.CommitObject(ref.Hash())
+	if err != nil {
+		return 0, 0, err
+	}
+	size := 0
+	if f, err := c.File(DefaultBookmarkFile); err == nil {
+		size = int(f.Size)
+	} else if !errors.Is(err, object.ErrFileNotFound) {
+		return 0, 0, err
+	}
+	iter, err := r.Log(&git.LogOptions{From: ref.Hash()})
+	if err != nil {
+		return 0, 0, err
+	}
+	commits := 0
+	err = iter.ForEach(func(*object.Commit) error {
+		commits++
+		return nil
+	})
+	return size, commits, err
+}
+
+// Accounts lists the repositories whose owner is recorded. Repositories
+// created before account state was kept appear once their owner signs in.
+func (GitProvider) Accounts(ctx context.Context) ([]*Account, error) {
+	entries, err := os.ReadDir(Config.LocalGitPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var accounts []*Account
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		b, err := os.ReadFile(filepath.Join(Config.LocalGitPath, e.Name(), ".git", accountStateFile))
+		if err != nil {
+			continue
+		}
+		var st gitAccount
+		if err := json.Unmarshal(b, &st); err != nil || st.User == "" {
+			continue
+		}
+		if filepath.Base(userDir(st.User)) != e.Name() {
+			continue
+		}
+		a := &Account{User: st.User, Created: st.Created, LastLogin: st.LastLogin, Disabled: st.Disabled}
+		if _, err := os.Stat(passwordPath(st.User)); err == nil {
+			a.HasPassword = true
+		}
+		if a.BookmarkBytes, a.Commits, err = gitRepoStats(st.User); err != nil {
+			return nil, fmt.Errorf("%s: %w", st.User, err)
+		}
+		accounts = append(accounts, a)
+	}
+	return accounts, nil
+}
+
+func (GitProvider) AccountDisabled(ctx context.Context, user string) (bool, error) {
+	a, err := loadGitAccount(user)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return a.Disabled, nil
+}
+
+func (GitProvider) SetAccountDisabled(ctx context.Context, user string, disabled bool) error {
+	return updateGitAccount(user, func(a *gitAccount) { a.Disabled = disabled })
+}
+
+func (GitProvider) RecordLogin(ctx context.Context, user string, at time.Time) error {
+	return updateGitAccount(user, func(a *gitAccount) { a.LastLogin = at.UTC() })
+}
+
+// firstCommitTime returns the time of the oldest commit on main.
+func firstCommitTime(user string) (time.Time, error) {
+	r, err := openRepo(user)
+	if err != nil {
+		return time.Time{}, err
+	}
+	ref, err := r.Reference(plumbing.NewBranchReferenceName("main"), true)
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return time.Time{}, nil
+	} else if err != nil {
+		return time.Time{}, err
+	}
+	iter, err := r.Log(&git.LogOptions{From: ref.Hash()})
+	if err != nil {
+		return time.Time{}, err
+	}
+	var first time.Time
+	err = iter.ForEach(func(c *object.Commit) error {
+		first = c.Committer.When
+		return nil
+	})
+	return first.UTC(), err
+}
+
+// RegisterLogins records the owner of every repository named by events that
+// has no account state yet, so accounts created before the state was kept
+// can be listed. The newest login time of each user becomes its last login
+// and the first commit its creation time. It returns the users registered.
+func (GitProvider) RegisterLogins(ctx context.Context, events []LoginEvent) ([]string, error) {
+	last := map[string]time.Time{}
+	var users []string
+	for _, e := range events {
+		if e.User == "" || (e.Provider != "" && e.Provider != "git") {
+			continue
+		}
+		t, seen := last[e.User]
+		if !seen {
+			users = append(users, e.User)
+		}
+		if e.Time.After(t) {
+			t = e.Time
+		}
+		last[e.User] = t
+	}
+	var registered []string
+	for _, user := range users {
+		if _, err := loadGitAccount(user); err == nil {
+			continue
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return registered, fmt.Errorf("%s: %w", user, err)
+		}
+		if exists, err := (GitProvider{}).RepoExists(ctx, user, nil, ""); err != nil {
+			return registered, err
+		} else if !exists {
+			continue
+		}
+		created, err := firstCommitTime(user)
+		if err != nil {
+			return registered, fmt.Errorf("%s: %w", user, err)
+		}
+		err = updateGitAccount(user, func(a *gitAccount) {
+			if !created.IsZero() {
+				a.Created = created
+			}
+			if t := last[user]; !t.IsZero() {
+				a.LastLogin = t.UTC()
+			}
+		})
+		if err != nil {
+			return registered, fmt.Errorf("%s: %w", user, err)
+		}
+		registered = append(registered, user)
+	}
+	return registered, nil
+}
+
+// RenameAccount moves user's repository, password and two-factor files to
+// newUser. It returns ErrUserExists when newUser already has a repository.
+func (GitProvider) RenameAccount(ctx context.Context, user, newUser string) error {
+	if newUser == "" || newUser == user {
+		return ErrUserExists
+	}
+	if _, err := os.Stat(userDir(user)); err != nil {
+		if os.IsNotExist(err) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+	if _, err := os.Stat(userDir(newUser)); err == nil {
+		return ErrUserExists
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Rename(userDir(user), userDir(newUser)); err != nil {
+		return err
+	}
+	if exists, err := (GitProvider{}).RepoExists(ctx, newUser, nil, ""); err != nil || !exists {
+		return err
+	}
+	return updateGitAccount(newUser, func(*gitAccount) {})
+}
+
+// DeleteAccount removes the user's repository together with the password and
+// two-factor files kept in it.
+func (GitProvider) DeleteAccount(ctx context.Context, user string) error {
+	if exists, err := (GitProvider{}).RepoExists(ctx, user, nil, ""); err != nil {
+		return err
+	} else if !exists {
+		if _, err := os.Stat(passwordPath(user)); err != nil {
+			return ErrUserNotFound
+		}
+	}
+	return os.RemoveAll(userDir(user))
+}
@@ -0,0 +1,117 @@
+package gobookmarks
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func setupGitAccountsTest(t *testing.T, users ...string) {
+	t.Helper()
+	old := Config
+	t.Cleanup(func() { Config = old })
+	Config.LocalGitPath = t.TempDir()
+	ctx := context.Background()
+	for _, u := range users {
+		if err := (GitProvider{}).CreateUser(ctx, u, "password"); err != nil {
+			t.Fatalf("CreateUser: %v", err)
+		}
+		if err := ensureRepo(ctx, GitProvider{}, u, nil); err != nil {
+			t.Fatalf("ensureRepo: %v", err)
+		}
+	}
+}
+
+func TestParseLoginLog(t *testing.T) {
+	log := strings.Join([]string{
+		`2024/05/01 10:00:00 audit: event=login user="alice" provider=git ip=127.0.0.1 detail=""`,
+		`2024/05/02 11:00:00 audit: event=login_lockout user="bob \"b\"" provider=git ip=127.0.0.1 detail=""`,
+		`2024/05/03 12:00:00 git login failed for carol: invalid password`,
+		`2024/05/03 12:00:01 sql login for dave refused: account disabled`,
+		`2024/05/03 12:00:02 two-factor login for "erin": invalid code`,
+		`2024/05/03 12:00:03 unrelated line`,
+	}, "\n")
+	events, err := ParseLoginLog(strings.NewReader(log))
+	if err != nil {
+		t.Fatalf("ParseLoginLog: %v", err)
+	}
+	want := []LoginEvent{
+		{User: "alice", Provider: "git", Time: time.Date(2024, 5, 1, 10, 0, 0, 0, time.Local)},
+		{User: `bob "b"`, Provider: "git"},
+		{User: "carol", Provider: "git"},
+		{User: "dave", Provider: "sql"},
+		{User: "erin"},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("events = %+v", events)
+	}
+	for i, e := range events {
+		if e.User != want[i].User || e.Provider != want[i].Provider || !e.Time.Equal(want[i].Time) {
+			t.Fatalf("event %d = %+v, want %+v", i, e, want[i])
+		}
+	}
+}
+
+func TestGitRegisterLogins(t *testing.T) {
+	setupGitAccountsTest(t, "alice", "bob")
+	ctx := context.Background()
+	// simulate repositories created before account state was kept
+	for _, u := range []string{"alice", "bob"} {
+		if err := os.Remove(accountStatePath(u)); err != nil {
+			t.Fatalf("remove state: %v", err)
+		}
+	}
+	if accounts, _ := (GitProvider{}).Accounts(ctx); len(accounts) != 0 {
+		t.Fatalf("unregistered accounts listed: %v", accounts)
+	}
+
+	login := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	registered, err := GitProvider{}.RegisterLogins(ctx, []LoginEvent{
+		{User: "alice", Provider: "git", Time: login},
+		{User: "alice", Provider: "git"},
+		{User: "bob", Provider: "sql", Time: login},
+		{User: "nobody", Provider: "git"},
+	})
+	if err != nil || len(registered) != 1 || registered[0] != "alice" {
+		t.Fatalf("RegisterLogins = %v, %v", registered, err)
+	}
+	accounts, err := GitProvider{}.Accounts(ctx)
+	if err != nil || len(accounts) != 1 {
+		t.Fatalf("Accounts = %v, %v", accounts, err)
+	}
+	if a := accounts[0]; a.User != "alice" || !a.LastLogin.Equal(login) || a.Created.IsZero() {
+		t.Fatalf("account = %+v", a)
+	}
+	if registered, _ := (GitProvider{}).RegisterLogins(ctx, []LoginEvent{{User: "alice"}}); len(registered) != 0 {
+		t.Fatalf("registered twice: %v", registered)
+	}
+}
+
+func TestGitRenameAccount(t *testing.T) {
+	setupGitAccountsTest(t, "alice", "bob")
+	ctx := context.Background()
+	if err := (GitProvider{}).RenameAccount(ctx, "alice", "bob"); !errors.Is(err, ErrUserExists) {
+		t.Fatalf("rename onto existing user: %v", err)
+	}
+	if err := (GitProvider{}).RenameAccount(ctx, "nobody", "carol"); !errors.Is(err, ErrUserNotFound) {
+		t.Fatalf("rename unknown user: %v", err)
+	}
+	if err := (GitProvider{}).RenameAccount(ctx, "alice", "carol"); err != nil {
+		t.Fatalf("RenameAccount: %v", err)
+	}
+	if ok, _ := (GitProvider{}).CheckPassword(ctx, "carol", "password"); !ok {
+		t.Fatalf("password not moved")
+	}
+	if text, _, err := (GitProvider{}).GetBookmarks(ctx, "carol", "", nil); err != nil || text != defaultBookmarks {
+		t.Fatalf("bookmarks not moved: %q %v", text, err)
+	}
+	if a, err := loadGitAccount("carol"); err != nil || a.User != "carol" {
+		t.Fatalf("account state = %+v, %v", a, err)
+	}
+	if exists, _ := (GitProvider{}).RepoExists(ctx, "alice", nil, ""); exists {
+		t.Fatalf("old repository kept")
+	}
+}
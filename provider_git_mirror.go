@@ -0,0 +1,735 @@
+//go:build !excludegitprovider
+
+package gobookmarks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/client"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	mirrorRemoteName    = "mirror"
+	mirrorStateFile     = "gobookmarks-mirror.json"
+	mirrorAuthFile      = "gobookmarks-mirror-auth.json"
+	mirrorPushAttempts  = 3
+	mirrorRemotePrefix  = "refs/remotes/" + mirrorRemoteName + "/"
+	mirrorPushHeads     = "refs/heads/*:refs/heads/*"
+	mirrorPushTags      = "refs/tags/*:refs/tags/*"
+	mirrorFetchHeads    = "+refs/heads/*:" + mirrorRemotePrefix + "*"
+	mirrorFetchTags     = "refs/tags/*:refs/tags/*"
+	mirrorResultCreated = "created"
+	mirrorResultFF      = "fast-forward"
+	mirrorResultMerged  = "merged"
+	mirrorResultAhead   = "ahead"
+)
+
+// ErrMirrorNotConfigured is returned when pulling without a mirror remote.
+var ErrMirrorNotConfigured = errors.New("no mirror configured")
+
+// ErrMirrorURL indicates a mirror URL that is malformed or not permitted.
+var ErrMirrorURL = errors.New("unsupported mirror url")
+
+// ErrMirrorConflict is returned when local and mirrored changes touch the same
+// file and cannot be merged automatically.
+var ErrMirrorConflict = errors.New("mirror has conflicting changes")
+
+// mirrorState is persisted inside the repository's .git directory so it is
+// never committed or pushed.
+type mirrorState struct {
+	LastPush   time.Time `json:"last_push"`
+	LastPull   time.Time `json:"last_pull"`
+	LastError  string    `json:"last_error"`
+	LastResult string    `json:"last_result"`
+}
+
+func mirrorStatePath(user string) string {
+	return filepath.Join(userDir(user), ".git", mirrorStateFile)
+}
+
+func loadMirrorState(user string) mirrorState {
+	var st mirrorState
+	b, err := os.ReadFile(mirrorStatePath(user))
+	if err == nil {
+		_ = json.Unmarshal(b, &st)
+	}
+	return st
+}
+
+func saveMirrorState(user string, st mirrorState) {
+	b, err := json.Marshal(st)
+	if err == nil {
+		err = os.WriteFile(mirrorStatePath(user), b, 0600)
+	}
+	if err != nil {
+		slog.Error("git mirror state save failed", "provider", "git", "user", user, "err", err)
+	}
+}
+
+// mirrorPushRetryDelay is the wait before the first retry of a failed push.
+// Each further retry waits one more delay.
+var mirrorPushRetryDelay = 10 * time.Second
+
+// mirrorAuth holds the HTTPS credentials of a mirror. It is kept inside .git
+// next to the state and never shown back to the user.
+type mirrorAuth struct {
+	URL      string `json:"url"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+func mirrorAuthPath(user string) string {
+	return filepath.Join(userDir(user), ".git", mirrorAuthFile)
+}
+
+func loadMirrorAuth(user string) mirrorAuth {
+	var a mirrorAuth
+	b, err := os.ReadFile(mirrorAuthPath(user))
+	if err == nil {
+		_ = json.Unmarshal(b, &a)
+	}
+	return a
+}
+
+func saveMirrorAuth(user string, a mirrorAuth) error {
+	if a.Username == "" && a.Password == "" {
+		if err := os.Remove(mirrorAuthPath(user)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	b, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(mirrorAuthPath(user), b, 0600)
+}
+
+func isHTTPMirror(u string) bool {
+	return strings.HasPrefix(u, "https://") || strings.HasPrefix(u, "http://")
+}
+
+func isSSHMirror(u string) bool {
+	if strings.HasPrefix(u, "ssh://") {
+		return true
+	}
+	i := strings.Index(u, ":")
+	return i > 0 && strings.Contains(u[:i], "@") && !strings.Contains(u[:i], "/")
+}
+
+// mirrorIPAllowed reports whether a mirror may connect to ip. Loopback,
+// private, link-local, multicast and unspecified addresses are refused
+// unless Config.GitMirrorAllowLocal is set.
+func mirrorIPAllowed(ip net.IP) bool {
+	if Config.GitMirrorAllowLocal {
+		return true
+	}
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() && !ip.IsMulticast() && !ip.IsUnspecified()
+}
+
+// mirrorDialControl refuses connections to addresses mirrorIPAllowed
+// rejects. It checks the resolved address, so host names and redirects
+// cannot reach internal services either.
+func mirrorDialControl(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !mirrorIPAllowed(ip) {
+		return fmt.Errorf("%w: %s is not a public address", ErrMirrorURL, host)
+	}
+	return nil
+}
+
+func init() {
+	c := githttp.NewClient(&http.Client{
+		Transport: &http.Transport{
+			DialContext:         (&net.Dialer{Timeout: 30 * time.Second, Control: mirrorDialControl}).DialContext,
+			TLSHandshakeTimeout: 10 * time.Second,
+		},
+	})
+	client.InstallProtocol("https", c)
+	client.InstallProtocol("http", c)
+}
+
+// mirrorSSHKeys loads Config.GitMirrorSSHKey for the ssh user login. It
+// returns nil when no key is configured.
+func mirrorSSHKeys(login string) (*gitssh.PublicKeys, error) {
+	if Config.GitMirrorSSHKey == "" {
+		return nil, nil
+	}
+	keys, err := gitssh.NewPublicKeysFromFile(login, Config.GitMirrorSSHKey, "")
+	if err != nil {
+		return nil, fmt.Errorf("git_mirror_ssh_key: %w", err)
+	}
+	if Config.GitMirrorKnownHosts != "" {
+		cb, err := gitssh.NewKnownHostsCallback(Config.GitMirrorKnownHosts)
+		if err != nil {
+			return nil, fmt.Errorf("git_mirror_known_hosts: %w", err)
+		}
+		keys.HostKeyCallback = cb
+	}
+	return keys, nil
+}
+
+// sshMirrorEndpoint parses an ssh mirror URL and checks it against
+// Config.GitMirrorSSHHosts. Entries are "[user@]host[/path]": the login
+// defaults to git and a path limits the mirror to repositories below it.
+// Without Config.GitMirrorSSHKey no ssh mirror is allowed, as the server's
+// key is shared by every user.
+func sshMirrorEndpoint(u string) (*transport.Endpoint, error) {
+	if Config.GitMirrorSSHKey == "" {
+		return nil, fmt.Errorf("%w: ssh mirrors are disabled", ErrMirrorURL)
+	}
+	ep, err := transport.NewEndpoint(u)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMirrorURL, err)
+	}
+	login := ep.User
+	if login == "" {
+		login = "git"
+	}
+	repoPath := path.Clean("/" + ep.Path)
+	for _, entry := range Config.GitMirrorSSHHosts {
+		entryLogin, host := "git", entry
+		if l, h, ok := strings.Cut(entry, "@"); ok {
+			entryLogin, host = l, h
+		}
+		host, prefix, _ := strings.Cut(host, "/")
+		if entryLogin != login || !strings.EqualFold(host, ep.Host) {
+			continue
+		}
+		prefix = strings.TrimSuffix("/"+prefix, "/")
+		if prefix == "" || repoPath == prefix || strings.HasPrefix(repoPath, prefix+"/") {
+			ep.User = login
+			return ep, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s@%s%s is not an allowed ssh mirror", ErrMirrorURL, login, ep.Host, repoPath)
+}
+
+// mirrorTransportAuth checks url again, as the configuration may have
+// changed since it was set, and returns the credentials used to reach it:
+// the stored username and password for HTTPS and the configured key for ssh.
+func mirrorTransportAuth(user, url string) (transport.AuthMethod, error) {
+	if err := validateMirrorURL(url); err != nil {
+		return nil, err
+	}
+	switch {
+	case isHTTPMirror(url):
+		a := loadMirrorAuth(user)
+		if a.URL != url || (a.Username == "" && a.Password == "") {
+			return nil, nil
+		}
+		return &githttp.BasicAuth{Username: a.Username, Password: a.Password}, nil
+	case isSSHMirror(url):
+		ep, err := sshMirrorEndpoint(url)
+		if err != nil {
+			return nil, err
+		}
+		keys, err := mirrorSSHKeys(ep.User)
+		if err != nil {
+			return nil, err
+		}
+		return keys, nil
+	}
+	return nil, nil
+}
+
+// validateMirrorURL accepts https URLs of public hosts and ssh or scp style
+// URLs allowed by sshMirrorEndpoint. Plain http, private addresses, local
+// paths and file:// URLs are only accepted when Config.GitMirrorAllowLocal
+// is set.
+func validateMirrorURL(u string) error {
+	switch {
+	case isHTTPMirror(u):
+		parsed, err := neturl.Parse(u)
+		if err != nil || parsed.Hostname() == "" {
+			return ErrMirrorURL
+		}
+		if Config.GitMirrorAllowLocal {
+			return nil
+		}
+		if parsed.Scheme != "https" {
+			return fmt.Errorf("%w: use https", ErrMirrorURL)
+		}
+		host := strings.ToLower(parsed.Hostname())
+		ip := net.ParseIP(host)
+		if ip != nil && !mirrorIPAllowed(ip) || host == "localhost" || strings.HasSuffix(host, ".localhost") {
+			return fmt.Errorf("%w: %s is not a public host", ErrMirrorURL, host)
+		}
+		return nil
+	case isSSHMirror(u):
+		_, err := sshMirrorEndpoint(u)
+		return err
+	case strings.HasPrefix(u, "file://"), filepath.IsAbs(u):
+		if Config.GitMirrorAllowLocal {
+			return nil
+		}
+		return fmt.Errorf("%w: local mirrors are disabled", ErrMirrorURL)
+	}
+	return ErrMirrorURL
+}
+
+func (GitProvider) MirrorStatus(ctx context.Context, user string) (*MirrorStatus, error) {
+	r, err := openRepo(user)
+	if err != nil {
+		return nil, err
+	}
+	res := &MirrorStatus{}
+	rem, err := r.Remote(mirrorRemoteName)
+	switch {
+	case err == nil:
+		if urls := rem.Config().URLs; len(urls) > 0 {
+			res.URL = urls[0]
+		}
+	case !errors.Is(err, git.ErrRemoteNotFound):
+		return nil, err
+	}
+	if isHTTPMirror(res.URL) {
+		if a := loadMirrorAuth(user); a.URL == res.URL {
+			res.Username = a.Username
+			res.HasPassword = a.Password != ""
+		}
+	}
+	if keys, err := mirrorSSHKeys("git"); err != nil {
+		slog.WarnContext(ctx, "git mirror ssh key unusable", "provider", "git", "err", err)
+	} else if keys != nil {
+		res.SSHPublicKey = strings.TrimSpace(string(ssh.MarshalAuthorizedKey(keys.Signer.PublicKey())))
+	}
+	st := loadMirrorState(user)
+	res.LastPush = st.LastPush
+	res.LastPull = st.LastPull
+	res.LastError = st.LastError
+	res.LastResult = st.LastResult
+	return res, nil
+}
+
+// SetMirror replaces the mirror remote and starts a push of the repository
+// to it. An empty URL removes the mirror and its credentials.
+func (GitProvider) SetMirror(ctx context.Context, user string, remote MirrorRemote) error {
+	url := strings.TrimSpace(remote.URL)
+	if url != "" {
+		if err := validateMirrorURL(url); err != nil {
+			return err
+		}
+	}
+	r, err := openRepo(user)
+	if err != nil {
+		return err
+	}
+	if err := r.DeleteRemote(mirrorRemoteName); err != nil && !errors.Is(err, git.ErrRemoteNotFound) {
+		return err
+	}
+	_ = os.Remove(mirrorStatePath(user))
+	auth := mirrorAuth{URL: url, Username: strings.TrimSpace(remote.Username), Password: remote.Password}
+	if !isHTTPMirror(url) {
+		auth = mirrorAuth{}
+	} else if auth.Password == "" {
+		if old := loadMirrorAuth(user); old.URL == url && old.Username == auth.Username {
+			auth.Password = old.Password
+		}
+	}
+	if err := saveMirrorAuth(user, auth); err != nil {
+		return err
+	}
+	if url == "" {
+		return nil
+	}
+	if _, err := r.CreateRemote(&config.RemoteConfig{Name: mirrorRemoteName, URLs: []string{url}}); err != nil {
+		return err
+	}
+	schedulePushMirror(ctx, user, r)
+	return nil
+}
+
+// mirrorPushes holds the users whose repository is being pushed in the
+// background. A true value asks for another push once the current one ends.
+var (
+	mirrorPushMu sync.Mutex
+	mirrorPushes = map[string]bool{}
+)
+
+// schedulePushMirror pushes r, the user's repository, to its mirror in the
+// background so a slow remote does not hold up the save. Saves made while a
+// push runs are sent by a single follow-up push. Failed pushes are logged
+// and retried; the outcome is recorded in the mirror status.
+func schedulePushMirror(ctx context.Context, user string, r *git.Repository) {
+	if _, err := r.Remote(mirrorRemoteName); err != nil {
+		return
+	}
+	mirrorPushMu.Lock()
+	if _, running := mirrorPushes[user]; running {
+		mirrorPushes[user] = true
+		mirrorPushMu.Unlock()
+		return
+	}
+	mirrorPushes[user] = false
+	mirrorPushMu.Unlock()
+	done := trackWork()
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		defer done()
+		for {
+			pushMirrorWithRetry(ctx, user)
+			mirrorPushMu.Lock()
+			again := mirrorPushes[user]
+			if !again {
+				delete(mirrorPushes, user)
+				mirrorPushMu.Unlock()
+				return
+			}
+			mirrorPushes[user] = false
+			mirrorPushMu.Unlock()
+		}
+	}()
+}
+
+// pushMirrorWithRetry pushes up to mirrorPushAttempts times, waiting longer
+// after each failure. Rejected and unauthorised pushes are not retried as
+// they fail the same way until the user acts.
+func pushMirrorWithRetry(ctx context.Context, user string) {
+	for attempt := 1; ; attempt++ {
+		r, err := openRepo(user)
+		if err == nil {
+			err = pushMirror(ctx, user, r)
+		}
+		if err == nil {
+			return
+		}
+		final := attempt == mirrorPushAttempts || !retryableMirrorError(err)
+		slog.ErrorContext(ctx, "git mirror push failed", "provider", "git", "user", user, "attempt", attempt, "retry", !final, "err", err)
+		if final {
+			return
+		}
+		time.Sleep(time.Duration(attempt) * mirrorPushRetryDelay)
+	}
+}
+
+func retryableMirrorError(err error) bool {
+	// go-git reports a rejected non-fast-forward push as a formatted string.
+	if strings.Contains(err.Error(), "non-fast-forward") {
+		return false
+	}
+	for _, e := range []error{
+		git.ErrForceNeeded,
+		transport.ErrAuthenticationRequired,
+		transport.ErrAuthorizationFailed,
+		transport.ErrRepositoryNotFound,
+		transport.ErrInvalidAuthMethod,
+		ErrRepoNotFound,
+	} {
+		if errors.Is(err, e) {
+			return false
+		}
+	}
+	return true
+}
+
+// pushMirror pushes all branches and tags to the mirror remote when one is
+// configured and records the outcome.
+func pushMirror(ctx context.Context, user string, r *git.Repository) error {
+	rem, err := r.Remote(mirrorRemoteName)
+	if err != nil {
+		if errors.Is(err, git.ErrRemoteNotFound) {
+			return nil
+		}
+		return err
+	}
+	var auth transport.AuthMethod
+	if urls := rem.Config().URLs; len(urls) > 0 {
+		auth, err = mirrorTransportAuth(user, urls[0])
+	}
+	if err == nil {
+		err = r.PushContext(ctx, &git.PushOptions{
+			RemoteName: mirrorRemoteName,
+			RefSpecs:   []config.RefSpec{mirrorPushHeads, mirrorPushTags},
+			Auth:       auth,
+		})
+	}
+	if errors.Is(err, git.NoErrAlreadyUpToDate) {
+		err = nil
+	}
+	st := loadMirrorState(user)
+	if err != nil {
+		st.LastError = "push: " + err.Error()
+	} else {
+		st.LastPush = time.Now()
+		st.LastError = ""
+	}
+	saveMirrorState(user, st)
+	return err
+}
+
+// PullMirror fetches the mirror and brings every mirrored branch up to date.
+// Branches are fast-forwarded when possible, otherwise the changes are merged
+// file by file. Conflicting edits to the same file abort the merge of that
+// branch and are reported in the mirror status.
+func (GitProvider) PullMirror(ctx context.Context, user string) error {
+	r, err := openRepo(user)
+	if err != nil {
+		return err
+	}
+	rem, err := r.Remote(mirrorRemoteName)
+	if err != nil {
+		if errors.Is(err, git.ErrRemoteNotFound) {
+			return ErrMirrorNotConfigured
+		}
+		return err
+	}
+	st := loadMirrorState(user)
+	var auth transport.AuthMethod
+	if urls := rem.Config().URLs; len(urls) > 0 {
+		auth, err = mirrorTransportAuth(user, urls[0])
+	}
+	if err == nil {
+		err = r.FetchContext(ctx, &git.FetchOptions{
+			RemoteName: mirrorRemoteName,
+			RefSpecs:   []config.RefSpec{mirrorFetchHeads, mirrorFetchTags},
+			Auth:       auth,
+		})
+	}
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		st.LastError = "fetch: " + err.Error()
+		saveMirrorState(user, st)
+		return err
+	}
+
+	head, _ := r.Head()
+	refs, err := r.References()
+	if err != nil {
+		return err
+	}
+	remotes := map[string]plumbing.Hash{}
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		if name := ref.Name().String(); strings.HasPrefix(name, mirrorRemotePrefix) && ref.Type() == plumbing.HashReference {
+			remotes[strings.TrimPrefix(name, mirrorRemotePrefix)] = ref.Hash()
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(remotes))
+	for n := range remotes {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	var results []string
+	var pullErr error
+	changed := false
+	for _, n := range names {
+		res, err := syncMirrorBranch(ctx, r, plumbing.NewBranchReferenceName(n), remotes[n])
+		if err != nil {
+			pullErr = errors.Join(pullErr, fmt.Errorf("%s: %w", n, err))
+			continue
+		}
+		if res != "" {
+			results = append(results, n+": "+res)
+			changed = changed || res != mirrorResultAhead
+		}
+	}
+
+	if changed && head != nil && head.Name().IsBranch() {
+		if wt, err := r.Worktree(); err == nil {
+			if err := wt.Checkout(&git.CheckoutOptions{Branch: head.Name(), Force: true}); err != nil {
+				pullErr = errors.Join(pullErr, err)
+			}
+		}
+	}
+
+	st = loadMirrorState(user)
+	st.LastPull = time.Now()
+	st.LastResult = strings.Join(results, ", ")
+	if st.LastResult == "" {
+		st.LastResult = "up to date"
+	}
+	if pullErr != nil {
+		st.LastError = "pull: " + pullErr.Error()
+	} else {
+		st.LastError = ""
+	}
+	saveMirrorState(user, st)
+	if pullErr != nil {
+		return pullErr
+	}
+	invalidateBookmarkCache(user)
+	return pushMirror(ctx, user, r)
+}
+
+// syncMirrorBranch updates branch to include the mirrored commit and returns
+// a short description of what happened, or "" when nothing changed.
+func syncMirrorBranch(ctx context.Context, r *git.Repository, branch plumbing.ReferenceName, remote plumbing.Hash) (string, error) {
+	local, err := r.Reference(branch, true)
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return mirrorResultCreated, r.Storer.SetReference(plumbing.NewHashReference(branch, remote))
+	}
+	if err != nil {
+		return "", err
+	}
+	if local.Hash() == remote {
+		return "", nil
+	}
+	lc, err := r.CommitObject(local.Hash())
+	if err != nil {
+		return "", err
+	}
+	rc, err := r.CommitObject(remote)
+	if err != nil {
+		return "", err
+	}
+	if ok, err := rc.IsAncestor(lc); err != nil {
+		return "", err
+	} else if ok {
+		return mirrorResultAhead, nil
+	}
+	if ok, err := lc.IsAncestor(rc); err != nil {
+		return "", err
+	} else if ok {
+		return mirrorResultFF, r.Storer.SetReference(plumbing.NewHashReference(branch, remote))
+	}
+	if err := mergeMirrorBranch(ctx, r, branch, lc, rc); err != nil {
+		return "", err
+	}
+	return mirrorResultMerged, nil
+}
+
+func commitFiles(c *object.Commit) (map[string]plumbing.Hash, error) {
+	files := map[string]plumbing.Hash{}
+	if c == nil {
+		return files, nil
+	}
+	tree, err := c.Tree()
+	if err != nil {
+		return nil, err
+	}
+	err = tree.Files().ForEach(func(f *object.File) error {
+		files[f.Name] = f.Hash
+		return nil
+	})
+	return files, err
+}
+
+// mergeMirrorBranch records a merge commit on branch joining the local and
+// mirrored histories. Files changed on only one side are taken from that side.
+func mergeMirrorBranch(ctx context.Context, r *git.Repository, branch plumbing.ReferenceName, lc, rc *object.Commit) error {
+	var base *object.Commit
+	if bases, err := lc.MergeBase(rc); err != nil {
+		return err
+	} else if len(bases) > 0 {
+		base = bases[0]
+	}
+	baseFiles, err := commitFiles(base)
+	if err != nil {
+		return err
+	}
+	localFiles, err := commitFiles(lc)
+	if err != nil {
+		return err
+	}
+	remoteFiles, err := commitFiles(rc)
+	if err != nil {
+		return err
+	}
+
+	paths := map[string]bool{}
+	for p := range localFiles {
+		paths[p] = true
+	}
+	for p := range remoteFiles {
+		paths[p] = true
+	}
+	take := map[string]plumbing.Hash{}
+	var removed, conflicts []string
+	for p := range paths {
+		l, lok := localFiles[p]
+		rh, rok := remoteFiles[p]
+		b, bok := baseFiles[p]
+		switch {
+		case lok == rok && l == rh:
+		case lok == bok && l == b:
+			if rok {
+				take[p] = rh
+			} else {
+				removed = append(removed, p)
+			}
+		case rok == bok && rh == b:
+		default:
+			conflicts = append(conflicts, p)
+		}
+	}
+	if len(conflicts) > 0 {
+		sort.Strings(conflicts)
+		return fmt.Errorf("%w: %s", ErrMirrorConflict, strings.Join(conflicts, ", "))
+	}
+
+	wt, err := r.Worktree()
+	if err != nil {
+		return err
+	}
+	if err := wt.Checkout(&git.CheckoutOptions{Branch: branch, Force: true}); err != nil {
+		return err
+	}
+	for p, h := range take {
+		blob, err := r.BlobObject(h)
+		if err != nil {
+			return err
+		}
+		rd, err := blob.Reader()
+		if err != nil {
+			return err
+		}
+		data, err := io.ReadAll(rd)
+		_ = rd.Close()
+		if err != nil {
+			return err
+		}
+		full := filepath.Join(wt.Filesystem.Root(), filepath.FromSlash(p))
+		if err := os.MkdirAll(filepath.Dir(full), 0700); err != nil {
+			return err
+		}
+		if err := os.WriteFile(full, data, 0600); err != nil {
+			return err
+		}
+		if _, err := wt.Add(p); err != nil {
+			return err
+		}
+	}
+	for _, p := range removed {
+		if _, err := wt.Remove(p); err != nil {
+			return err
+		}
+	}
+	_, err = wt.Commit("Merge mirror into "+branch.Short(), &git.CommitOptions{
+		Author:            commitSignature(ctx),
+		Parents:           []plumbing.Hash{lc.Hash, rc.Hash},
+		AllowEmptyCommits: true,
+	})
+	return err
+}
@@ -0,0 +1,289 @@
+package gobookmarks
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func setupMirrorTest(t *testing.T) (GitProvider, string, string) {
+	t.Helper()
+	Config.LocalGitPath = t.TempDir()
+	Config.GitMirrorAllowLocal = true
+	t.Cleanup(func() { Config.GitMirrorAllowLocal = false })
+	bare := t.TempDir()
+	if _, err := git.PlainInit(bare, true); err != nil {
+		t.Fatalf("PlainInit bare: %v", err)
+	}
+	p := GitProvider{}
+	user := "alice"
+	if err := p.CreateRepo(context.Background(), user, nil, Config.GetRepoName()); err != nil {
+		t.Fatalf("CreateRepo: %v", err)
+	}
+	if err := p.CreateBookmarks(context.Background(), user, nil, "main", "Category: A\n"); err != nil {
+		t.Fatalf("CreateBookmarks: %v", err)
+	}
+	if err := p.SetMirror(context.Background(), user, MirrorRemote{URL: bare}); err != nil {
+		t.Fatalf("SetMirror: %v", err)
+	}
+	waitMirrorPush(t)
+	return p, user, bare
+}
+
+// waitMirrorPush waits for background mirror pushes to finish.
+func waitMirrorPush(t *testing.T) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		mirrorPushMu.Lock()
+		n := len(mirrorPushes)
+		mirrorPushMu.Unlock()
+		if n == 0 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("mirror push still running")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// commitToMirror clones the mirror, writes file and pushes the change back.
+func commitToMirror(t *testing.T, bare, file, text string) {
+	t.Helper()
+	dir := t.TempDir()
+	r, err := git.PlainClone(dir, false, &git.CloneOptions{URL: bare, ReferenceName: plumbing.NewBranchReferenceName("main")})
+	if err != nil {
+		t.Fatalf("PlainClone: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, file), []byte(text), 0600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	wt, _ := r.Worktree()
+	if _, err := wt.Add(file); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if _, err := wt.Commit("remote edit", &git.CommitOptions{Author: &object.Signature{Name: "elsewhere", When: time.Now()}}); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	if err := r.Push(&git.PushOptions{}); err != nil {
+		t.Fatalf("Push: %v", err)
+	}
+}
+
+func mirrorHead(t *testing.T, bare string) string {
+	t.Helper()
+	r, err := git.PlainOpen(bare)
+	if err != nil {
+		t.Fatalf("PlainOpen: %v", err)
+	}
+	ref, err := r.Reference(plumbing.NewBranchReferenceName("main"), true)
+	if err != nil {
+		t.Fatalf("Reference: %v", err)
+	}
+	return ref.Hash().String()
+}
+
+func TestGitMirrorPushAfterCommit(t *testing.T) {
+	p, user, bare := setupMirrorTest(t)
+	ctx := context.Background()
+	if err := p.UpdateBookmarks(ctx, user, nil, "refs/heads/main", "main", "Category: B\n", ""); err != nil {
+		t.Fatalf("UpdateBookmarks: %v", err)
+	}
+	waitMirrorPush(t)
+	_, sha, _ := p.GetBookmarks(ctx, user, "refs/heads/main", nil)
+	if got := mirrorHead(t, bare); got != sha {
+		t.Fatalf("mirror at %s want %s", got, sha)
+	}
+	st, err := p.MirrorStatus(ctx, user)
+	if err != nil {
+		t.Fatalf("MirrorStatus: %v", err)
+	}
+	if st.URL != bare || st.LastPush.IsZero() || st.LastError != "" {
+		t.Fatalf("unexpected status %+v", st)
+	}
+}
+
+func TestGitMirrorPullFastForward(t *testing.T) {
+	p, user, bare := setupMirrorTest(t)
+	ctx := context.Background()
+	commitToMirror(t, bare, "bookmarks.txt", "Category: Remote\n")
+	if err := p.PullMirror(ctx, user); err != nil {
+		t.Fatalf("PullMirror: %v", err)
+	}
+	got, _, _ := p.GetBookmarks(ctx, user, "refs/heads/main", nil)
+	if got != "Category: Remote\n" {
+		t.Fatalf("expected remote bookmarks, got %q", got)
+	}
+	st, _ := p.MirrorStatus(ctx, user)
+	if st.LastResult != "main: fast-forward" {
+		t.Fatalf("unexpected result %q", st.LastResult)
+	}
+	// Later edits build on the pulled commit.
+	if err := p.UpdateBookmarks(ctx, user, nil, "refs/heads/main", "main", "Category: Local\n", ""); err != nil {
+		t.Fatalf("UpdateBookmarks: %v", err)
+	}
+	waitMirrorPush(t)
+	if st, _ := p.MirrorStatus(ctx, user); st.LastError != "" {
+		t.Fatalf("push after pull failed: %s", st.LastError)
+	}
+}
+
+func TestGitMirrorPullMerge(t *testing.T) {
+	p, user, bare := setupMirrorTest(t)
+	ctx := context.Background()
+	commitToMirror(t, bare, "work.txt", "Category: Work\n")
+	if err := p.UpdateBookmarks(ctx, user, nil, "refs/heads/main", "main", "Category: Local\n", ""); err != nil {
+		t.Fatalf("UpdateBookmarks: %v", err)
+	}
+	waitMirrorPush(t)
+	if st, _ := p.MirrorStatus(ctx, user); st.LastError == "" {
+		t.Fatalf("expected diverged push to be reported")
+	}
+	if err := p.PullMirror(ctx, user); err != nil {
+		t.Fatalf("PullMirror: %v", err)
+	}
+	local, sha, _ := p.GetBookmarks(ctx, user, "refs/heads/main", nil)
+	work, _, _ := p.GetBookmarks(withBookmarkFile(ctx, "work.txt"), user, "refs/heads/main", nil)
+	if local != "Category: Local\n" || work != "Category: Work\n" {
+		t.Fatalf("merge lost changes: %q %q", local, work)
+	}
+	if got := mirrorHead(t, bare); got != sha {
+		t.Fatalf("merge not pushed: mirror at %s want %s", got, sha)
+	}
+}
+
+func TestGitMirrorPullConflict(t *testing.T) {
+	p, user, bare := setupMirrorTest(t)
+	ctx := context.Background()
+	commitToMirror(t, bare, "bookmarks.txt", "Category: Remote\n")
+	if err := p.UpdateBookmarks(ctx, user, nil, "refs/heads/main", "main", "Category: Local\n", ""); err != nil {
+		t.Fatalf("UpdateBookmarks: %v", err)
+	}
+	if err := p.PullMirror(ctx, user); !errors.Is(err, ErrMirrorConflict) {
+		t.Fatalf("expected conflict, got %v", err)
+	}
+	got, _, _ := p.GetBookmarks(ctx, user, "refs/heads/main", nil)
+	if got != "Category: Local\n" {
+		t.Fatalf("conflict changed local bookmarks: %q", got)
+	}
+}
+
+func TestGitMirrorRejectsLocalPaths(t *testing.T) {
+	p, user, _ := setupMirrorTest(t)
+	Config.GitMirrorAllowLocal = false
+	if err := p.SetMirror(context.Background(), user, MirrorRemote{URL: "/tmp/elsewhere"}); !errors.Is(err, ErrMirrorURL) {
+		t.Fatalf("expected url error, got %v", err)
+	}
+}
+
+func TestGitMirrorHTTPSCredentials(t *testing.T) {
+	p, user, _ := setupMirrorTest(t)
+	var mu sync.Mutex
+	var got []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		u, pw, _ := r.BasicAuth()
+		mu.Lock()
+		got = append(got, u+":"+pw)
+		mu.Unlock()
+		http.Error(w, "forbidden", http.StatusForbidden)
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+	url := srv.URL + "/alice/bookmarks.git"
+	if err := p.SetMirror(ctx, user, MirrorRemote{URL: url, Username: "alice", Password: "token"}); err != nil {
+		t.Fatalf("SetMirror: %v", err)
+	}
+	waitMirrorPush(t)
+	// A blank password keeps the stored one.
+	if err := p.SetMirror(ctx, user, MirrorRemote{URL: url, Username: "alice"}); err != nil {
+		t.Fatalf("SetMirror: %v", err)
+	}
+	waitMirrorPush(t)
+	mu.Lock()
+	defer mu.Unlock()
+	if len(got) != 2 || got[0] != "alice:token" || got[1] != "alice:token" {
+		t.Fatalf("unexpected credentials %q", got)
+	}
+	st, err := p.MirrorStatus(ctx, user)
+	if err != nil {
+		t.Fatalf("MirrorStatus: %v", err)
+	}
+	if st.Username != "alice" || !st.HasPassword || st.LastError == "" {
+		t.Fatalf("unexpected status %+v", st)
+	}
+}
+
+func TestGitMirrorPushRetries(t *testing.T) {
+	p, user, _ := setupMirrorTest(t)
+	delay := mirrorPushRetryDelay
+	mirrorPushRetryDelay = time.Millisecond
+	t.Cleanup(func() { mirrorPushRetryDelay = delay })
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+	if err := p.SetMirror(context.Background(), user, MirrorRemote{URL: srv.URL + "/alice/bookmarks.git"}); err != nil {
+		t.Fatalf("SetMirror: %v", err)
+	}
+	waitMirrorPush(t)
+	if n := calls.Load(); n != mirrorPushAttempts {
+		t.Fatalf("pushed %d times want %d", n, mirrorPushAttempts)
+	}
+}
+
+func TestGitMirrorURLRules(t *testing.T) {
+	old := Config
+	t.Cleanup(func() { Config = old })
+	Config.GitMirrorAllowLocal = false
+	Config.GitMirrorSSHKey = ""
+	Config.GitMirrorSSHHosts = []string{"github.com/team/", "deploy@git.example.com"}
+	for url, ok := range map[string]bool{
+		"https://example.com/alice/bookmarks.git": true,
+		"http://example.com/alice/bookmarks.git":  false,
+		"https://127.0.0.1/bookmarks.git":         false,
+		"https://10.1.2.3/bookmarks.git":          false,
+		"https://169.254.169.254/latest":          false,
+		"https://[::1]/bookmarks.git":             false,
+		"https://localhost/bookmarks.git":         false,
+		"git@github.com:team/bookmarks.git":       false,
+	} {
+		if err := validateMirrorURL(url); (err == nil) != ok {
+			t.Errorf("without a key %s: %v", url, err)
+		}
+	}
+	Config.GitMirrorSSHKey = "/etc/gobookmarks/mirror_key"
+	for url, ok := range map[string]bool{
+		"git@github.com:team/bookmarks.git":                true,
+		"ssh://git@github.com/team/bookmarks.git":          true,
+		"git@github.com:other/bookmarks.git":               false,
+		"ssh://git@github.com/team/../other/bookmarks.git": false,
+		"root@github.com:team/bookmarks.git":               false,
+		"git@gitlab.com:team/bookmarks.git":                false,
+		"deploy@git.example.com:anything/bookmarks.git":    true,
+		"git@git.example.com:anything/bookmarks.git":       false,
+	} {
+		if err := validateMirrorURL(url); (err == nil) != ok {
+			t.Errorf("with a key %s: %v", url, err)
+		}
+	}
+	if err := mirrorDialControl("tcp", "10.0.0.1:443", nil); !errors.Is(err, ErrMirrorURL) {
+		t.Errorf("dial to a private address: %v", err)
+	}
+	if err := mirrorDialControl("tcp", "93.184.216.34:443", nil); err != nil {
+		t.Errorf("dial to a public address: %v", err)
+	}
+}
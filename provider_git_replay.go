@@ -0,0 +1,161 @@
+//go:build !excludegitprovider
+
+package gobookmarks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"golang.org/x/oauth2"
+)
+
+// ReplayCommit writes a commit with the given metadata directly to the object
+// store. The bookmark file is replaced in the parent's tree; other files are
+// carried over unchanged.
+func (GitProvider) ReplayCommit(ctx context.Context, user string, _ *oauth2.Token, c ReplayCommit) (string, error) {
+	r, err := openRepo(user)
+	if err != nil {
+		return "", err
+	}
+	var base *object.Tree
+	var parents []plumbing.Hash
+	if c.Parent != "" {
+		pc, err := r.CommitObject(plumbing.NewHash(c.Parent))
+		if err != nil {
+			return "", fmt.Errorf("parent %s: %w", c.Parent, err)
+		}
+		if base, err = pc.Tree(); err != nil {
+			return "", err
+		}
+		parents = []plumbing.Hash{pc.Hash}
+	}
+
+	blob := r.Storer.NewEncodedObject()
+	blob.SetType(plumbing.BlobObject)
+	w, err := blob.Writer()
+	if err != nil {
+		return "", err
+	}
+	if _, err := w.Write([]byte(c.Text)); err != nil {
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+	blobHash, err := r.Storer.SetEncodedObject(blob)
+	if err != nil {
+		return "", err
+	}
+	treeHash, err := replaceTreeFile(r, base, strings.Split(bookmarkFileFromContext(ctx), "/"), blobHash)
+	if err != nil {
+		return "", err
+	}
+
+	sig := commitSignature(ctx)
+	if c.AuthorName != "" {
+		sig.Name = c.AuthorName
+	}
+	if c.AuthorEmail != "" {
+		sig.Email = c.AuthorEmail
+	}
+	if !c.Date.IsZero() {
+		sig.When = c.Date
+	}
+	commit := &object.Commit{
+		Author:       *sig,
+		Committer:    *sig,
+		Message:      c.Message,
+		TreeHash:     treeHash,
+		ParentHashes: parents,
+	}
+	obj := r.Storer.NewEncodedObject()
+	if err := commit.Encode(obj); err != nil {
+		return "", err
+	}
+	h, err := r.Storer.SetEncodedObject(obj)
+	if err != nil {
+		return "", err
+	}
+	return h.String(), nil
+}
+
+// replaceTreeFile returns the hash of a copy of base with the file at path
+// set to blob, creating intermediate trees as required.
+func replaceTreeFile(r *git.Repository, base *object.Tree, path []string, blob plumbing.Hash) (plumbing.Hash, error) {
+	var entries []object.TreeEntry
+	if base != nil {
+		entries = append(entries, base.Entries...)
+	}
+	entry := object.TreeEntry{Name: path[0], Mode: filemode.Regular, Hash: blob}
+	if len(path) > 1 {
+		var sub *object.Tree
+		if base != nil {
+			if t, err := base.Tree(path[0]); err == nil {
+				sub = t
+			} else if !errors.Is(err, object.ErrDirectoryNotFound) {
+				return plumbing.ZeroHash, err
+			}
+		}
+		h, err := replaceTreeFile(r, sub, path[1:], blob)
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+		entry = object.TreeEntry{Name: path[0], Mode: filemode.Dir, Hash: h}
+	}
+	replaced := false
+	for i := range entries {
+		if entries[i].Name == entry.Name {
+			entries[i] = entry
+			replaced = true
+		}
+	}
+	if !replaced {
+		entries = append(entries, entry)
+	}
+	// git orders tree entries as if directory names end in "/"
+	sortKey := func(e object.TreeEntry) string {
+		if e.Mode == filemode.Dir {
+			return e.Name + "/"
+		}
+		return e.Name
+	}
+	sort.Slice(entries, func(i, j int) bool { return sortKey(entries[i]) < sortKey(entries[j]) })
+
+	tree := &object.Tree{Entries: entries}
+	obj := r.Storer.NewEncodedObject()
+	if err := tree.Encode(obj); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	return r.Storer.SetEncodedObject(obj)
+}
+
+func (GitProvider) SetRef(ctx context.Context, user string, _ *oauth2.Token, ref, sha string) error {
+	if !strings.HasPrefix(ref, "refs/heads/") && !strings.HasPrefix(ref, "refs/tags/") {
+		return fmt.Errorf("unsupported ref %s", ref)
+	}
+	r, err := openRepo(user)
+	if err != nil {
+		return err
+	}
+	name := plumbing.ReferenceName(ref)
+	hash := plumbing.NewHash(sha)
+	if err := r.Storer.SetReference(plumbing.NewHashReference(name, hash)); err != nil {
+		return err
+	}
+	// keep the worktree in step when the checked out branch moved
+	if head, err := r.Head(); err == nil && head.Name() == name {
+		wt, err := r.Worktree()
+		if err != nil {
+			return err
+		}
+		return wt.Reset(&git.ResetOptions{Commit: hash, Mode: git.HardReset})
+	}
+	return nil
+}
@@ -40,6 +40,44 @@ func TestGitProviderCreateAndGet(t *testing.T) {
 	}
 }
 
+func TestGitSaveUpdatesOldGitignore(t *testing.T) {
+	Config.LocalGitPath = t.TempDir()
+	p := GitProvider{}
+	user := "dave"
+	ctx := context.Background()
+	if err := p.CreateRepo(ctx, user, nil, Config.GetRepoName()); err != nil {
+		t.Fatalf("CreateRepo: %v", err)
+	}
+	// Repositories created before .totp existed ignore only the other files.
+	old := ".password\n.email\n.reset"
+	if err := os.WriteFile(filepath.Join(userDir(user), ".gitignore"), []byte(old), 0600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := p.CreateBookmarks(ctx, user, nil, "main", "Category: A\n"); err != nil {
+		t.Fatalf("CreateBookmarks: %v", err)
+	}
+	r, err := openRepo(user)
+	if err != nil {
+		t.Fatalf("openRepo: %v", err)
+	}
+	head, err := r.Head()
+	if err != nil {
+		t.Fatalf("Head: %v", err)
+	}
+	c, err := r.CommitObject(head.Hash())
+	if err != nil {
+		t.Fatalf("CommitObject: %v", err)
+	}
+	f, err := c.File(".gitignore")
+	if err != nil {
+		t.Fatalf("gitignore not committed: %v", err)
+	}
+	got, _ := f.Contents()
+	if got != old+"\n.totp\n" {
+		t.Fatalf("gitignore = %q", got)
+	}
+}
+
 func TestGitRepoExists(t *testing.T) {
 	tmp := t.TempDir()
 	Config.LocalGitPath = tmp
@@ -7,7 +7,7 @@ import (
 	"encoding/base64"
 	"encoding/gob"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"strings"
 
@@ -60,7 +60,7 @@ func (GitHubProvider) client(ctx context.Context, token *oauth2.Token) *github.C
 func (p GitHubProvider) CurrentUser(ctx context.Context, token *oauth2.Token) (*User, error) {
 	u, _, err := p.client(ctx, token).Users.Get(ctx, "")
 	if err != nil {
-		log.Printf("github CurrentUser: %v", err)
+		slog.ErrorContext(ctx, "github CurrentUser failed", "provider", "github", "err", err)
 		return nil, err
 	}
 	user := &User{}
@@ -71,9 +71,9 @@ func (p GitHubProvider) CurrentUser(ctx context.Context, token *oauth2.Token) (*
 }
 
 func (p GitHubProvider) GetTags(ctx context.Context, user string, token *oauth2.Token) ([]*Tag, error) {
-	tags, _, err := p.client(ctx, token).Repositories.ListTags(ctx, user, Config.GetRepoName(), &github.ListOptions{})
+	tags, _, err := p.client(ctx, token).Repositories.ListTags(ctx, user, repoNameFromContext(ctx), &github.ListOptions{})
 	if err != nil {
-		log.Printf("github GetTags: %v", err)
+		slog.ErrorContext(ctx, "github GetTags failed", "provider", "github", "err", err)
 		return nil, fmt.Errorf("ListTags: %w", err)
 	}
 	res := make([]*Tag, 0, len(tags))
@@ -84,9 +84,9 @@ func (p GitHubProvider) GetTags(ctx context.Context, user string, token *oauth2.
 }
 
 func (p GitHubProvider) GetBranches(ctx context.Context, user string, token *oauth2.Token) ([]*Branch, error) {
-	bs, _, err := p.client(ctx, token).Repositories.ListBranches(ctx, user, Config.GetRepoName(), &github.BranchListOptions{})
+	bs, _, err := p.client(ctx, token).Repositories.ListBranches(ctx, user, repoNameFromContext(ctx), &github.BranchListOptions{})
 	if err != nil {
-		log.Printf("github GetBranches: %v", err)
+		slog.ErrorContext(ctx, "github GetBranches failed", "provider", "github", "err", err)
 		return nil, fmt.Errorf("ListBranches: %w", err)
 	}
 	res := make([]*Branch, 0, len(bs))
@@ -97,10 +97,10 @@ func (p GitHubProvider) GetBranches(ctx context.Context, user string, token *oau
 }
 
 func (p GitHubProvider) GetCommits(ctx context.Context, user string, token *oauth2.Token, ref string, page, perPage int) ([]*Commit, error) {
-	opts := &github.CommitsListOptions{SHA: ref, ListOptions: github.ListOptions{Page: page, PerPage: perPage}}
-	cs, _, err := p.client(ctx, token).Repositories.ListCommits(ctx, user, Config.GetRepoName(), opts)
+	opts := &github.CommitsListOptions{SHA: ref, Path: bookmarkFileFromContext(ctx), ListOptions: github.ListOptions{Page: page, PerPage: perPage}}
+	cs, _, err := p.client(ctx, token).Repositories.ListCommits(ctx, user, repoNameFromContext(ctx), opts)
 	if err != nil {
-		log.Printf("github GetCommits: %v", err)
+		slog.ErrorContext(ctx, "github GetCommits failed", "provider", "github", "err", err)
 		return nil, fmt.Errorf("ListCommits: %w", err)
 	}
 	res := make([]*Commit, 0, len(cs))
@@ -115,6 +115,11 @@ func (p GitHubProvider) GetCommits(ctx context.Context, user string, token *oaut
 				com.CommitterEmail = comm.GetEmail()
 				com.CommitterDate = comm.GetDate().Time
 			}
+			if a := cm.Author; a != nil {
+				com.AuthorName = a.GetName()
+				com.AuthorEmail = a.GetEmail()
+				com.AuthorDate = a.GetDate().Time
+			}
 		}
 		res = append(res, com)
 	}
@@ -122,7 +127,7 @@ func (p GitHubProvider) GetCommits(ctx context.Context, user string, token *oaut
 }
 
 func (p GitHubProvider) GetBookmarks(ctx context.Context, user, ref string, token *oauth2.Token) (string, string, error) {
-	contents, _, resp, err := p.client(ctx, token).Repositories.GetContents(ctx, user, Config.GetRepoName(), "bookmarks.txt", &github.RepositoryContentGetOptions{Ref: ref})
+	contents, _, resp, err := p.client(ctx, token).Repositories.GetContents(ctx, user, repoNameFromContext(ctx), bookmarkFileFromContext(ctx), &github.RepositoryContentGetOptions{Ref: ref})
 	if resp != nil && resp.StatusCode == http.StatusUnauthorized {
 		return "", "", ErrSignedOut
 	}
@@ -130,7 +135,7 @@ func (p GitHubProvider) GetBookmarks(ctx context.Context, user, ref string, toke
 		return "", "", nil
 	}
 	if err != nil {
-		log.Printf("github GetBookmarks: %v", err)
+		slog.ErrorContext(ctx, "github GetBookmarks failed", "provider", "github", "err", err)
 		return "", "", fmt.Errorf("GetBookmarks: %w", err)
 	}
 	if contents.Content == nil {
@@ -138,7 +143,7 @@ func (p GitHubProvider) GetBookmarks(ctx context.Context, user, ref string, toke
 	}
 	b, err := base64.StdEncoding.DecodeString(*contents.Content)
 	if err != nil {
-		log.Printf("github GetBookmarks decode: %v", err)
+		slog.ErrorContext(ctx, "github GetBookmarks decode failed", "provider", "github", "err", err)
 		return "", "", fmt.Errorf("GetBookmarks: %w", err)
 	}
 	sha := ""
@@ -150,14 +155,47 @@ func (p GitHubProvider) GetBookmarks(ctx context.Context, user, ref string, toke
 
 var commitAuthor = &github.CommitAuthor{Name: SP("Gobookmarks"), Email: SP("Gobookmarks@arran.net.au")}
 
+// githubCommitAuthor returns the author for commits made on behalf of the
+// request, naming the editor when one was recorded.
+func githubCommitAuthor(ctx context.Context) *github.CommitAuthor {
+	if name := commitAuthorName(ctx); name != "" {
+		return &github.CommitAuthor{Name: SP(name), Email: commitAuthor.Email}
+	}
+	return commitAuthor
+}
+
+func (p GitHubProvider) ListBookmarkFiles(ctx context.Context, user string, token *oauth2.Token, ref string) ([]string, error) {
+	if ref == "" {
+		ref = "HEAD"
+	}
+	tree, resp, err := p.client(ctx, token).Git.GetTree(ctx, user, repoNameFromContext(ctx), ref, true)
+	if resp != nil && resp.StatusCode == http.StatusUnauthorized {
+		return nil, ErrSignedOut
+	}
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "github ListBookmarkFiles failed", "provider", "github", "err", err)
+		return nil, fmt.Errorf("GetTree: %w", err)
+	}
+	var files []string
+	for _, e := range tree.Entries {
+		if e.GetType() == "blob" && isBookmarkFileName(e.GetPath()) {
+			files = append(files, e.GetPath())
+		}
+	}
+	return files, nil
+}
+
 func (p GitHubProvider) getDefaultBranch(ctx context.Context, user string, client *github.Client, _ string) (string, error) {
 	var branch string
-	rep, resp, err := client.Repositories.Get(ctx, user, Config.GetRepoName())
+	rep, resp, err := client.Repositories.Get(ctx, user, repoNameFromContext(ctx))
 	if resp != nil && resp.StatusCode == 404 {
 		return "", ErrRepoNotFound
 	}
 	if err != nil {
-		log.Printf("github getDefaultBranch: %v", err)
+		slog.ErrorContext(ctx, "github getDefaultBranch failed", "provider", "github", "err", err)
 		return "", fmt.Errorf("Repositories.Get: %w", err)
 	}
 	if rep.DefaultBranch != nil {
@@ -174,7 +212,7 @@ func (p GitHubProvider) CreateRepo(ctx context.Context, user string, token *oaut
 	rep, _, err := client.Repositories.Create(ctx, "", rep)
 	if err != nil {
 		if e, ok := err.(*github.ErrorResponse); !ok || e.Response == nil || e.Response.StatusCode != http.StatusUnprocessableEntity {
-			log.Printf("github createRepo: %v", err)
+			slog.ErrorContext(ctx, "github createRepo failed", "provider", "github", "err", err)
 			return fmt.Errorf("Repositories.Create: %w", err)
 		}
 	}
@@ -186,7 +224,7 @@ See . https://github.com/arran4/gobookmarks `),
 		Author: commitAuthor, Committer: commitAuthor,
 	})
 	if err != nil {
-		log.Printf("github createRepo readme: %v", err)
+		slog.ErrorContext(ctx, "github createRepo readme failed", "provider", "github", "err", err)
 		return fmt.Errorf("CreateReadme: %w", err)
 	}
 	_ = rep
@@ -209,17 +247,17 @@ func (p GitHubProvider) RepoExists(ctx context.Context, user string, token *oaut
 }
 
 func (p GitHubProvider) createRef(ctx context.Context, user string, client *github.Client, sourceRef, branchRef string) error {
-	gsref, resp, err := client.Git.GetRef(ctx, user, Config.GetRepoName(), sourceRef)
+	gsref, resp, err := client.Git.GetRef(ctx, user, repoNameFromContext(ctx), sourceRef)
 	if resp != nil && resp.StatusCode == 404 {
 		err = nil
 	}
 	if err != nil {
-		log.Printf("github createRef getRef: %v", err)
+		slog.ErrorContext(ctx, "github createRef getRef failed", "provider", "github", "err", err)
 		return fmt.Errorf("GetRef: %w", err)
 	}
-	_, _, err = client.Git.CreateRef(ctx, user, Config.GetRepoName(), &github.Reference{Ref: &branchRef, Object: gsref.Object})
+	_, _, err = client.Git.CreateRef(ctx, user, repoNameFromContext(ctx), &github.Reference{Ref: &branchRef, Object: gsref.Object})
 	if err != nil {
-		log.Printf("github createRef create: %v", err)
+		slog.ErrorContext(ctx, "github createRef create failed", "provider", "github", "err", err)
 		return fmt.Errorf("CreateRef: %w", err)
 	}
 	return nil
@@ -238,23 +276,23 @@ func (p GitHubProvider) UpdateBookmarks(ctx context.Context, user string, token
 	if sourceRef == "" {
 		sourceRef = branchRef
 	}
-	_, grefResp, err := client.Git.GetRef(ctx, user, Config.GetRepoName(), branchRef)
+	_, grefResp, err := client.Git.GetRef(ctx, user, repoNameFromContext(ctx), branchRef)
 	if err != nil && grefResp.StatusCode != 404 {
-		log.Printf("github UpdateBookmarks getRef: %v", err)
+		slog.ErrorContext(ctx, "github UpdateBookmarks getRef failed", "provider", "github", "err", err)
 		return fmt.Errorf("GetRef: %w", err)
 	}
 	if grefResp.StatusCode == 404 {
 		if err := p.createRef(ctx, user, client, sourceRef, branchRef); err != nil {
-			log.Printf("github UpdateBookmarks create ref: %v", err)
+			slog.ErrorContext(ctx, "github UpdateBookmarks create ref failed", "provider", "github", "err", err)
 			return fmt.Errorf("create ref: %w", err)
 		}
 	}
-	contents, _, resp, err := client.Repositories.GetContents(ctx, user, Config.GetRepoName(), "bookmarks.txt", &github.RepositoryContentGetOptions{Ref: branchRef})
+	contents, _, resp, err := client.Repositories.GetContents(ctx, user, repoNameFromContext(ctx), bookmarkFileFromContext(ctx), &github.RepositoryContentGetOptions{Ref: branchRef})
 	if resp != nil && resp.StatusCode == 404 {
 		return ErrRepoNotFound
 	}
 	if err != nil {
-		log.Printf("github UpdateBookmarks get contents: %v", err)
+		slog.ErrorContext(ctx, "github UpdateBookmarks get contents failed", "provider", "github", "err", err)
 		return fmt.Errorf("GetContents: %w", err)
 	}
 	if contents == nil || contents.Content == nil {
@@ -263,16 +301,16 @@ func (p GitHubProvider) UpdateBookmarks(ctx context.Context, user string, token
 	if expectSHA != "" && contents.SHA != nil && *contents.SHA != expectSHA {
 		return fmt.Errorf("bookmarks modified concurrently")
 	}
-	_, _, err = client.Repositories.UpdateFile(ctx, user, Config.GetRepoName(), "bookmarks.txt", &github.RepositoryContentFileOptions{
+	_, _, err = client.Repositories.UpdateFile(ctx, user, repoNameFromContext(ctx), bookmarkFileFromContext(ctx), &github.RepositoryContentFileOptions{
 		Message:   SP("Auto change from web"),
 		Content:   []byte(text),
 		Branch:    &branch,
 		SHA:       contents.SHA,
-		Author:    commitAuthor,
+		Author:    githubCommitAuthor(ctx),
 		Committer: commitAuthor,
 	})
 	if err != nil {
-		log.Printf("github UpdateBookmarks update: %v", err)
+		slog.ErrorContext(ctx, "github UpdateBookmarks update failed", "provider", "github", "err", err)
 		return fmt.Errorf("UpdateBookmarks: %w", err)
 	}
 	return nil
@@ -284,22 +322,22 @@ func (p GitHubProvider) CreateBookmarks(ctx context.Context, user string, token
 		var err error
 		branch, err = p.getDefaultBranch(ctx, user, client, branch)
 		if err != nil {
-			log.Printf("github CreateBookmarks default branch: %v", err)
+			slog.ErrorContext(ctx, "github CreateBookmarks default branch failed", "provider", "github", "err", err)
 			return err
 		}
 	}
-	_, resp, err := client.Repositories.CreateFile(ctx, user, Config.GetRepoName(), "bookmarks.txt", &github.RepositoryContentFileOptions{
+	_, resp, err := client.Repositories.CreateFile(ctx, user, repoNameFromContext(ctx), bookmarkFileFromContext(ctx), &github.RepositoryContentFileOptions{
 		Message:   SP("Auto create from web"),
 		Content:   []byte(text),
 		Branch:    &branch,
-		Author:    commitAuthor,
+		Author:    githubCommitAuthor(ctx),
 		Committer: commitAuthor,
 	})
 	if resp != nil && resp.StatusCode == http.StatusNotFound {
 		return ErrRepoNotFound
 	}
 	if err != nil {
-		log.Printf("github CreateBookmarks: %v", err)
+		slog.ErrorContext(ctx, "github CreateBookmarks failed", "provider", "github", "err", err)
 		return fmt.Errorf("CreateBookmarks: %w", err)
 	}
 	return nil
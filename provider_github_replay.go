@@ -0,0 +1,100 @@
+//go:build !nogithub
+
+package gobookmarks
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-github/v69/github"
+	"golang.org/x/oauth2"
+)
+
+// ReplayCommit creates a commit with the given metadata through the git data
+// API. The bookmark file is replaced in the parent's tree; other files are
+// carried over unchanged.
+func (p GitHubProvider) ReplayCommit(ctx context.Context, user string, token *oauth2.Token, c ReplayCommit) (string, error) {
+	client := p.client(ctx, token)
+	repo := repoNameFromContext(ctx)
+	baseTree := ""
+	var parents []*github.Commit
+	if c.Parent != "" {
+		pc, resp, err := client.Git.GetCommit(ctx, user, repo, c.Parent)
+		if resp != nil && resp.StatusCode == http.StatusUnauthorized {
+			return "", ErrSignedOut
+		}
+		if err != nil {
+			slog.ErrorContext(ctx, "github ReplayCommit get parent failed", "provider", "github", "err", err)
+			return "", fmt.Errorf("parent %s: %w", c.Parent, err)
+		}
+		baseTree = pc.GetTree().GetSHA()
+		parents = []*github.Commit{{SHA: SP(c.Parent)}}
+	}
+	tree, resp, err := client.Git.CreateTree(ctx, user, repo, baseTree, []*github.TreeEntry{{
+		Path:    SP(bookmarkFileFromContext(ctx)),
+		Mode:    SP("100644"),
+		Type:    SP("blob"),
+		Content: SP(c.Text),
+	}})
+	if resp != nil && resp.StatusCode == http.StatusUnauthorized {
+		return "", ErrSignedOut
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "github ReplayCommit create tree failed", "provider", "github", "err", err)
+		return "", fmt.Errorf("CreateTree: %w", err)
+	}
+
+	author := *githubCommitAuthor(ctx)
+	if c.AuthorName != "" {
+		author.Name = SP(c.AuthorName)
+	}
+	if c.AuthorEmail != "" {
+		author.Email = SP(c.AuthorEmail)
+	}
+	if !c.Date.IsZero() {
+		author.Date = &github.Timestamp{Time: c.Date}
+	}
+	message := c.Message
+	if message == "" {
+		message = "Migrated from another provider"
+	}
+	commit, _, err := client.Git.CreateCommit(ctx, user, repo, &github.Commit{
+		Message:   SP(message),
+		Tree:      &github.Tree{SHA: tree.SHA},
+		Parents:   parents,
+		Author:    &author,
+		Committer: &author,
+	}, nil)
+	if err != nil {
+		slog.ErrorContext(ctx, "github ReplayCommit create commit failed", "provider", "github", "err", err)
+		return "", fmt.Errorf("CreateCommit: %w", err)
+	}
+	return commit.GetSHA(), nil
+}
+
+// SetRef creates or force-updates a branch or tag to point at sha.
+func (p GitHubProvider) SetRef(ctx context.Context, user string, token *oauth2.Token, ref, sha string) error {
+	if !strings.HasPrefix(ref, "refs/heads/") && !strings.HasPrefix(ref, "refs/tags/") {
+		return fmt.Errorf("unsupported ref %s", ref)
+	}
+	client := p.client(ctx, token)
+	repo := repoNameFromContext(ctx)
+	target := &github.Reference{Ref: SP(ref), Object: &github.GitObject{SHA: SP(sha)}}
+	_, resp, err := client.Git.GetRef(ctx, user, repo, ref)
+	switch {
+	case resp != nil && resp.StatusCode == http.StatusUnauthorized:
+		return ErrSignedOut
+	case resp != nil && resp.StatusCode == http.StatusNotFound:
+		_, _, err = client.Git.CreateRef(ctx, user, repo, target)
+	case err == nil:
+		_, _, err = client.Git.UpdateRef(ctx, user, repo, target, true)
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "github SetRef failed", "provider", "github", "ref", ref, "err", err)
+		return fmt.Errorf("SetRef %s: %w", ref, err)
+	}
+	return nil
+}
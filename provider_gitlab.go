@@ -8,7 +8,7 @@ import (
 	"encoding/gob"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"strings"
 
@@ -16,6 +16,15 @@ import (
 	"golang.org/x/oauth2"
 )
 
+// gitlabAuthorName returns the author name for commits made on behalf of the
+// request.
+func gitlabAuthorName(ctx context.Context) string {
+	if name := commitAuthorName(ctx); name != "" {
+		return name
+	}
+	return "Gobookmarks"
+}
+
 // GitLabProvider implements Provider for GitLab.
 //
 // The GitLab server URL can be overridden using the GitlabServer variable
@@ -64,12 +73,12 @@ func (GitLabProvider) client(token *oauth2.Token) (*gitlab.Client, error) {
 func (GitLabProvider) CurrentUser(ctx context.Context, token *oauth2.Token) (*User, error) {
 	c, err := GitLabProvider{}.client(token)
 	if err != nil {
-		log.Printf("gitlab CurrentUser client: %v", err)
+		slog.ErrorContext(ctx, "gitlab CurrentUser client failed", "provider", "gitlab", "err", err)
 		return nil, err
 	}
 	u, _, err := c.Users.CurrentUser()
 	if err != nil {
-		log.Printf("gitlab CurrentUser lookup: %v", err)
+		slog.ErrorContext(ctx, "gitlab CurrentUser lookup failed", "provider", "gitlab", "err", err)
 		return nil, err
 	}
 	return &User{Login: u.Username}, nil
@@ -78,15 +87,15 @@ func (GitLabProvider) CurrentUser(ctx context.Context, token *oauth2.Token) (*Us
 func (GitLabProvider) GetTags(ctx context.Context, user string, token *oauth2.Token) ([]*Tag, error) {
 	c, err := GitLabProvider{}.client(token)
 	if err != nil {
-		log.Printf("gitlab GetTags client: %v", err)
+		slog.ErrorContext(ctx, "gitlab GetTags client failed", "provider", "gitlab", "err", err)
 		return nil, err
 	}
-	tags, _, err := c.Tags.ListTags(user+"/"+Config.GetRepoName(), &gitlab.ListTagsOptions{})
+	tags, _, err := c.Tags.ListTags(user+"/"+repoNameFromContext(ctx), &gitlab.ListTagsOptions{})
 	if err != nil {
 		if gitlabUnauthorized(err) {
 			return nil, ErrSignedOut
 		}
-		log.Printf("gitlab GetTags: %v", err)
+		slog.ErrorContext(ctx, "gitlab GetTags failed", "provider", "gitlab", "err", err)
 		return nil, fmt.Errorf("ListTags: %w", err)
 	}
 	res := make([]*Tag, 0, len(tags))
@@ -99,15 +108,15 @@ func (GitLabProvider) GetTags(ctx context.Context, user string, token *oauth2.To
 func (GitLabProvider) GetBranches(ctx context.Context, user string, token *oauth2.Token) ([]*Branch, error) {
 	c, err := GitLabProvider{}.client(token)
 	if err != nil {
-		log.Printf("gitlab GetBranches client: %v", err)
+		slog.ErrorContext(ctx, "gitlab GetBranches client failed", "provider", "gitlab", "err", err)
 		return nil, err
 	}
-	bs, _, err := c.Branches.ListBranches(user+"/"+Config.GetRepoName(), &gitlab.ListBranchesOptions{})
+	bs, _, err := c.Branches.ListBranches(user+"/"+repoNameFromContext(ctx), &gitlab.ListBranchesOptions{})
 	if err != nil {
 		if gitlabUnauthorized(err) {
 			return nil, ErrSignedOut
 		}
-		log.Printf("gitlab GetBranches: %v", err)
+		slog.ErrorContext(ctx, "gitlab GetBranches failed", "provider", "gitlab", "err", err)
 		return nil, fmt.Errorf("ListBranches: %w", err)
 	}
 	res := make([]*Branch, 0, len(bs))
@@ -120,15 +129,15 @@ func (GitLabProvider) GetBranches(ctx context.Context, user string, token *oauth
 func (GitLabProvider) GetCommits(ctx context.Context, user string, token *oauth2.Token, ref string, page, perPage int) ([]*Commit, error) {
 	c, err := GitLabProvider{}.client(token)
 	if err != nil {
-		log.Printf("gitlab GetCommits client: %v", err)
+		slog.ErrorContext(ctx, "gitlab GetCommits client failed", "provider", "gitlab", "err", err)
 		return nil, err
 	}
-	cs, _, err := c.Commits.ListCommits(user+"/"+Config.GetRepoName(), &gitlab.ListCommitsOptions{RefName: &ref, ListOptions: gitlab.ListOptions{Page: int64(page), PerPage: int64(perPage)}})
+	cs, _, err := c.Commits.ListCommits(user+"/"+repoNameFromContext(ctx), &gitlab.ListCommitsOptions{RefName: &ref, Path: gitlab.Ptr(bookmarkFileFromContext(ctx)), ListOptions: gitlab.ListOptions{Page: int64(page), PerPage: int64(perPage)}})
 	if err != nil {
 		if gitlabUnauthorized(err) {
 			return nil, ErrSignedOut
 		}
-		log.Printf("gitlab GetCommits: %v", err)
+		slog.ErrorContext(ctx, "gitlab GetCommits failed", "provider", "gitlab", "err", err)
 		return nil, fmt.Errorf("ListCommits: %w", err)
 	}
 	res := make([]*Commit, 0, len(cs))
@@ -139,6 +148,9 @@ func (GitLabProvider) GetCommits(ctx context.Context, user string, token *oauth2
 			CommitterName:  commit.CommitterName,
 			CommitterEmail: commit.CommitterEmail,
 			CommitterDate:  *commit.CommittedDate,
+			AuthorName:     commit.AuthorName,
+			AuthorEmail:    commit.AuthorEmail,
+			AuthorDate:     *commit.AuthoredDate,
 		})
 	}
 	return res, nil
@@ -147,13 +159,13 @@ func (GitLabProvider) GetCommits(ctx context.Context, user string, token *oauth2
 func (GitLabProvider) GetBookmarks(ctx context.Context, user, ref string, token *oauth2.Token) (string, string, error) {
 	c, err := GitLabProvider{}.client(token)
 	if err != nil {
-		log.Printf("gitlab GetBookmarks client: %v", err)
+		slog.ErrorContext(ctx, "gitlab GetBookmarks client failed", "provider", "gitlab", "err", err)
 		return "", "", err
 	}
 	if ref == "" {
 		ref = "HEAD"
 	}
-	f, _, err := c.RepositoryFiles.GetFile(user+"/"+Config.GetRepoName(), "bookmarks.txt", &gitlab.GetFileOptions{Ref: gitlab.Ptr(ref)})
+	f, _, err := c.RepositoryFiles.GetFile(user+"/"+repoNameFromContext(ctx), bookmarkFileFromContext(ctx), &gitlab.GetFileOptions{Ref: gitlab.Ptr(ref)})
 	if err != nil {
 		if errors.Is(err, gitlab.ErrNotFound) {
 			return "", "", nil
@@ -165,26 +177,62 @@ func (GitLabProvider) GetBookmarks(ctx context.Context, user, ref string, token
 			if gitlabUnauthorized(err) {
 				return "", "", ErrSignedOut
 			}
-			log.Printf("gitlab GetBookmarks get file: %v", err)
+			slog.ErrorContext(ctx, "gitlab GetBookmarks get file failed", "provider", "gitlab", "err", err)
 			return "", "", nil
 		}
 		if gitlabUnauthorized(err) {
 			return "", "", ErrSignedOut
 		}
-		log.Printf("gitlab GetBookmarks: %v", err)
+		slog.ErrorContext(ctx, "gitlab GetBookmarks failed", "provider", "gitlab", "err", err)
 		return "", "", err
 	}
 	data, err := base64.StdEncoding.DecodeString(f.Content)
 	if err != nil {
-		log.Printf("gitlab GetBookmarks decode: %v", err)
+		slog.ErrorContext(ctx, "gitlab GetBookmarks decode failed", "provider", "gitlab", "err", err)
 		return "", "", err
 	}
 	return string(data), f.LastCommitID, nil
 }
 
+func (GitLabProvider) ListBookmarkFiles(ctx context.Context, user string, token *oauth2.Token, ref string) ([]string, error) {
+	c, err := GitLabProvider{}.client(token)
+	if err != nil {
+		slog.ErrorContext(ctx, "gitlab ListBookmarkFiles client failed", "provider", "gitlab", "err", err)
+		return nil, err
+	}
+	opt := &gitlab.ListTreeOptions{Recursive: gitlab.Ptr(true), ListOptions: gitlab.ListOptions{PerPage: 100}}
+	if ref != "" {
+		opt.Ref = gitlab.Ptr(ref)
+	}
+	var files []string
+	for {
+		nodes, resp, err := c.Repositories.ListTree(user+"/"+repoNameFromContext(ctx), opt)
+		if err != nil {
+			if errors.Is(err, gitlab.ErrNotFound) {
+				return nil, nil
+			}
+			if gitlabUnauthorized(err) {
+				return nil, ErrSignedOut
+			}
+			slog.ErrorContext(ctx, "gitlab ListBookmarkFiles failed", "provider", "gitlab", "err", err)
+			return nil, fmt.Errorf("ListTree: %w", err)
+		}
+		for _, n := range nodes {
+			if n.Type == "blob" && isBookmarkFileName(n.Path) {
+				files = append(files, n.Path)
+			}
+		}
+		if resp == nil || resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+	return files, nil
+}
+
 func (GitLabProvider) getDefaultBranch(ctx context.Context, user string, client *gitlab.Client, _ string) (string, error) {
 	var branch string
-	p, _, err := client.Projects.GetProject(user+"/"+Config.GetRepoName(), nil)
+	p, _, err := client.Projects.GetProject(user+"/"+repoNameFromContext(ctx), nil)
 	if err != nil {
 		if respErr, ok := err.(*gitlab.ErrorResponse); ok {
 			if respErr.Response != nil && respErr.Response.StatusCode == http.StatusNotFound {
@@ -197,7 +245,7 @@ func (GitLabProvider) getDefaultBranch(ctx context.Context, user string, client
 		if gitlabUnauthorized(err) {
 			return "", ErrSignedOut
 		}
-		log.Printf("gitlab getDefaultBranch: %v", err)
+		slog.ErrorContext(ctx, "gitlab getDefaultBranch failed", "provider", "gitlab", "err", err)
 		return "", err
 	}
 	if p.DefaultBranch != "" {
@@ -210,13 +258,13 @@ func (GitLabProvider) getDefaultBranch(ctx context.Context, user string, client
 func (GitLabProvider) UpdateBookmarks(ctx context.Context, user string, token *oauth2.Token, sourceRef, branch, text, expectSHA string) error {
 	c, err := GitLabProvider{}.client(token)
 	if err != nil {
-		log.Printf("gitlab UpdateBookmarks client: %v", err)
+		slog.ErrorContext(ctx, "gitlab UpdateBookmarks client failed", "provider", "gitlab", "err", err)
 		return err
 	}
 	if branch == "" {
 		branch, err = GitLabProvider{}.getDefaultBranch(ctx, user, c, branch)
 		if err != nil {
-			log.Printf("gitlab UpdateBookmarks default branch: %v", err)
+			slog.ErrorContext(ctx, "gitlab UpdateBookmarks default branch failed", "provider", "gitlab", "err", err)
 			return err
 		}
 	}
@@ -224,11 +272,11 @@ func (GitLabProvider) UpdateBookmarks(ctx context.Context, user string, token *o
 		Branch:        gitlab.Ptr(branch),
 		Content:       gitlab.Ptr(text),
 		AuthorEmail:   gitlab.Ptr("Gobookmarks@arran.net.au"),
-		AuthorName:    gitlab.Ptr("Gobookmarks"),
+		AuthorName:    gitlab.Ptr(gitlabAuthorName(ctx)),
 		LastCommitID:  gitlab.Ptr(expectSHA),
 		CommitMessage: gitlab.Ptr("Auto change from web"),
 	}
-	_, _, err = c.RepositoryFiles.UpdateFile(user+"/"+Config.GetRepoName(), "bookmarks.txt", opt)
+	_, _, err = c.RepositoryFiles.UpdateFile(user+"/"+repoNameFromContext(ctx), bookmarkFileFromContext(ctx), opt)
 	if err != nil {
 		var respErr *gitlab.ErrorResponse
 		if errors.As(err, &respErr) {
@@ -238,7 +286,7 @@ func (GitLabProvider) UpdateBookmarks(ctx context.Context, user string, token *o
 			if gitlabUnauthorized(err) {
 				return ErrSignedOut
 			}
-			log.Printf("gitlab UpdateBookmarks update file: %v", err)
+			slog.ErrorContext(ctx, "gitlab UpdateBookmarks update file failed", "provider", "gitlab", "err", err)
 			return err
 		}
 		if gitlabUnauthorized(err) {
@@ -247,7 +295,7 @@ func (GitLabProvider) UpdateBookmarks(ctx context.Context, user string, token *o
 		if err.Error() == "404 Not Found" {
 			return ErrRepoNotFound
 		}
-		log.Printf("gitlab UpdateBookmarks: %v", err)
+		slog.ErrorContext(ctx, "gitlab UpdateBookmarks failed", "provider", "gitlab", "err", err)
 		return err
 	}
 	return nil
@@ -256,13 +304,13 @@ func (GitLabProvider) UpdateBookmarks(ctx context.Context, user string, token *o
 func (GitLabProvider) CreateBookmarks(ctx context.Context, user string, token *oauth2.Token, branch, text string) error {
 	c, err := GitLabProvider{}.client(token)
 	if err != nil {
-		log.Printf("gitlab CreateBookmarks client: %v", err)
+		slog.ErrorContext(ctx, "gitlab CreateBookmarks client failed", "provider", "gitlab", "err", err)
 		return err
 	}
 	if branch == "" {
 		branch, err = GitLabProvider{}.getDefaultBranch(ctx, user, c, branch)
 		if err != nil {
-			log.Printf("gitlab CreateBookmarks default branch: %v", err)
+			slog.ErrorContext(ctx, "gitlab CreateBookmarks default branch failed", "provider", "gitlab", "err", err)
 			return err
 		}
 	}
@@ -270,10 +318,10 @@ func (GitLabProvider) CreateBookmarks(ctx context.Context, user string, token *o
 		Branch:        gitlab.Ptr(branch),
 		Content:       gitlab.Ptr(text),
 		AuthorEmail:   gitlab.Ptr("Gobookmarks@arran.net.au"),
-		AuthorName:    gitlab.Ptr("Gobookmarks"),
+		AuthorName:    gitlab.Ptr(gitlabAuthorName(ctx)),
 		CommitMessage: gitlab.Ptr("Auto create from web"),
 	}
-	_, _, err = c.RepositoryFiles.CreateFile(user+"/"+Config.GetRepoName(), "bookmarks.txt", opt)
+	_, _, err = c.RepositoryFiles.CreateFile(user+"/"+repoNameFromContext(ctx), bookmarkFileFromContext(ctx), opt)
 	if err != nil {
 		if respErr, ok := err.(*gitlab.ErrorResponse); ok {
 			if respErr.Response != nil && respErr.Response.StatusCode == http.StatusNotFound {
@@ -282,13 +330,13 @@ func (GitLabProvider) CreateBookmarks(ctx context.Context, user string, token *o
 			if gitlabUnauthorized(err) {
 				return ErrSignedOut
 			}
-			log.Printf("gitlab CreateBookmarks create file: %v", err)
+			slog.ErrorContext(ctx, "gitlab CreateBookmarks create file failed", "provider", "gitlab", "err", err)
 			return err
 		}
 		if gitlabUnauthorized(err) {
 			return ErrSignedOut
 		}
-		log.Printf("gitlab CreateBookmarks: %v", err)
+		slog.ErrorContext(ctx, "gitlab CreateBookmarks failed", "provider", "gitlab", "err", err)
 		return err
 	}
 	return nil
@@ -6,6 +6,7 @@ import (
 	"database/sql"
 	"embed"
 	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"strings"
@@ -18,34 +19,58 @@ import (
 	"golang.org/x/oauth2"
 )
 
-type SQLProvider struct {
-	db *sql.DB
-	mu sync.Mutex
-}
+type SQLProvider struct{}
 
-const sqlSchemaVersion = 1
+const sqlSchemaVersion = 8
 
-//go:embed sql/schema*.sql
+//go:embed sql/schema*.sql sql/migrate*.sql
 var sqlSchemas embed.FS
 
 func init() {
 	RegisterProvider(&SQLProvider{})
 }
 
-func (p *SQLProvider) getDB() (*sql.DB, error) {
-	p.mu.Lock()
-	defer p.mu.Unlock()
+// sqlDB is the database handle shared by the SQL provider and every SQL
+// backed store, so they use one connection pool and the schema is brought up
+// to date once. conn records the configuration it was opened for.
+var sqlDB struct {
+	mu   sync.Mutex
+	db   *sql.DB
+	conn string
+}
 
-	if p.db != nil {
-		return p.db, nil
+// sharedDB returns the shared database handle, opening it on first use or
+// when the configured database has changed.
+func sharedDB() (*sql.DB, error) {
+	conn := Config.DBConnectionProvider + "\x00" + Config.DBConnectionString
+	sqlDB.mu.Lock()
+	defer sqlDB.mu.Unlock()
+	if sqlDB.db != nil && sqlDB.conn == conn {
+		return sqlDB.db, nil
+	}
+	if sqlDB.db != nil {
+		_ = sqlDB.db.Close()
+		sqlDB.db = nil
 	}
-
 	db, err := OpenDB()
 	if err != nil {
 		return nil, err
 	}
-	p.db = db
-	return p.db, nil
+	sqlDB.db, sqlDB.conn = db, conn
+	return db, nil
+}
+
+// closeSharedDB closes the shared handle, if one is open. A later call to
+// sharedDB opens a new one.
+func closeSharedDB() error {
+	sqlDB.mu.Lock()
+	defer sqlDB.mu.Unlock()
+	if sqlDB.db == nil {
+		return nil
+	}
+	err := sqlDB.db.Close()
+	sqlDB.db = nil
+	return err
 }
 
 func (p *SQLProvider) Name() string                                                     { return "sql" }
@@ -56,7 +81,7 @@ func (p *SQLProvider) CurrentUser(ctx context.Context, token *oauth2.Token) (*Us
 }
 
 func (p *SQLProvider) GetTags(ctx context.Context, user string, token *oauth2.Token) ([]*Tag, error) {
-	db, err := p.getDB()
+	db, err := sharedDB()
 	if err != nil {
 		return nil, err
 	}
@@ -79,7 +104,7 @@ func (p *SQLProvider) GetTags(ctx context.Context, user string, token *oauth2.To
 }
 
 func (p *SQLProvider) GetBranches(ctx context.Context, user string, token *oauth2.Token) ([]*Branch, error) {
-	db, err := p.getDB()
+	db, err := sharedDB()
 	if err != nil {
 		return nil, err
 	}
@@ -105,12 +130,12 @@ func (p *SQLProvider) GetBranches(ctx context.Context, user string, token *oauth
 }
 
 func (p *SQLProvider) GetCommits(ctx context.Context, user string, token *oauth2.Token, ref string, page, perPage int) ([]*Commit, error) {
-	db, err := p.getDB()
+	db, err := sharedDB()
 	if err != nil {
 		return nil, err
 	}
 
-	query := "SELECT sha, message, date FROM history WHERE user=? ORDER BY id DESC"
+	query := "SELECT sha, message, date, COALESCE(author, '') FROM history WHERE user=? ORDER BY id DESC"
 	args := []any{user}
 	if perPage > 0 {
 		query += " LIMIT ? OFFSET ?"
@@ -124,24 +149,30 @@ func (p *SQLProvider) GetCommits(ctx context.Context, user string, token *oauth2
 
 	var commits []*Commit
 	for rows.Next() {
-		var sha, msg string
+		var sha, msg, author string
 		var t time.Time
-		if err := rows.Scan(&sha, &msg, &t); err != nil {
+		if err := rows.Scan(&sha, &msg, &t, &author); err != nil {
 			return nil, err
 		}
+		if author == "" {
+			author = "gobookmarks"
+		}
 		commits = append(commits, &Commit{
 			SHA:            sha,
 			Message:        msg,
-			CommitterName:  "gobookmarks",
+			CommitterName:  author,
 			CommitterEmail: "gobookmarks@arran.net.au",
 			CommitterDate:  t,
+			AuthorName:     author,
+			AuthorEmail:    "gobookmarks@arran.net.au",
+			AuthorDate:     t,
 		})
 	}
 	return commits, rows.Err()
 }
 
 func (p *SQLProvider) AdjacentCommits(ctx context.Context, user string, token *oauth2.Token, ref, sha string) (string, string, error) {
-	db, err := p.getDB()
+	db, err := sharedDB()
 	if err != nil {
 		return "", "", err
 	}
@@ -165,7 +196,10 @@ func (p *SQLProvider) AdjacentCommits(ctx context.Context, user string, token *o
 }
 
 func (p *SQLProvider) GetBookmarks(ctx context.Context, user, ref string, token *oauth2.Token) (string, string, error) {
-	db, err := p.getDB()
+	if bookmarkFileFromContext(ctx) != DefaultBookmarkFile {
+		return "", "", ErrSingleBookmarkFile
+	}
+	db, err := sharedDB()
 	if err != nil {
 		return "", "", err
 	}
@@ -181,8 +215,27 @@ func (p *SQLProvider) GetBookmarks(ctx context.Context, user, ref string, token
 
 	var sha, text string
 	switch {
+	case strings.HasPrefix(ref, "refs/tags/"):
+		err = db.QueryRowContext(ctx, "SELECT sha FROM tags WHERE user=? AND name=?", user, strings.TrimPrefix(ref, "refs/tags/")).Scan(&sha)
+		if err == sql.ErrNoRows {
+			return "", "", nil
+		} else if err != nil {
+			return "", "", err
+		}
 	case ref == "main" || !strings.Contains(ref, "/"):
 		err = db.QueryRowContext(ctx, "SELECT sha FROM branches WHERE user=? AND name=?", user, ref).Scan(&sha)
+		if err == sql.ErrNoRows && ref != "main" {
+			// not a branch, try it as a commit sha
+			var n int
+			if err = db.QueryRowContext(ctx, "SELECT COUNT(1) FROM history WHERE user=? AND sha=?", user, ref).Scan(&n); err != nil {
+				return "", "", err
+			}
+			if n > 0 {
+				sha = ref
+			} else {
+				err = sql.ErrNoRows
+			}
+		}
 		if err == sql.ErrNoRows {
 			err = db.QueryRowContext(ctx, "SELECT list FROM bookmarks WHERE user=?", user).Scan(&text)
 			if err == sql.ErrNoRows {
@@ -210,10 +263,13 @@ func (p *SQLProvider) GetBookmarks(ctx context.Context, user, ref string, token
 }
 
 func (p *SQLProvider) UpdateBookmarks(ctx context.Context, user string, token *oauth2.Token, sourceRef, branch, text, expectSHA string) error {
+	if bookmarkFileFromContext(ctx) != DefaultBookmarkFile {
+		return ErrSingleBookmarkFile
+	}
 	if branch == "" {
 		branch = "main"
 	}
-	db, err := p.getDB()
+	db, err := sharedDB()
 	if err != nil {
 		return err
 	}
@@ -238,8 +294,8 @@ func (p *SQLProvider) UpdateBookmarks(ctx context.Context, user string, token *o
 	newSha := hex.EncodeToString(sum[:])
 
 	if _, err := tx.ExecContext(ctx,
-		"INSERT INTO history(user, sha, message, text, date) VALUES(?,?,?,?,?)",
-		user, newSha, "update", text, time.Now(),
+		"INSERT INTO history(user, sha, message, text, date, author) VALUES(?,?,?,?,?,?)",
+		user, newSha, "update", text, time.Now(), commitAuthorName(ctx),
 	); err != nil {
 		_ = tx.Rollback()
 		return err
@@ -281,10 +337,13 @@ func (p *SQLProvider) UpdateBookmarks(ctx context.Context, user string, token *o
 }
 
 func (p *SQLProvider) CreateBookmarks(ctx context.Context, user string, token *oauth2.Token, branch, text string) error {
+	if bookmarkFileFromContext(ctx) != DefaultBookmarkFile {
+		return ErrSingleBookmarkFile
+	}
 	if branch == "" {
 		branch = "main"
 	}
-	db, err := p.getDB()
+	db, err := sharedDB()
 	if err != nil {
 		return err
 	}
@@ -321,8 +380,8 @@ func (p *SQLProvider) CreateBookmarks(ctx context.Context, user string, token *o
 	newSha := hex.EncodeToString(sum[:])
 
 	if _, err := tx.ExecContext(ctx,
-		"INSERT INTO history(user, sha, message, text, date) VALUES(?,?,?,?,?)",
-		user, newSha, "create", text, time.Now(),
+		"INSERT INTO history(user, sha, message, text, date, author) VALUES(?,?,?,?,?,?)",
+		user, newSha, "create", text, time.Now(), commitAuthorName(ctx),
 	); err != nil {
 		_ = tx.Rollback()
 		return err
@@ -363,8 +422,92 @@ func (p *SQLProvider) CreateBookmarks(ctx context.Context, user string, token *o
 	return tx.Commit()
 }
 
+// ReplayCommit stores a history entry carrying the original message, author
+// and date. Branches are moved separately with SetRef.
+func (p *SQLProvider) ReplayCommit(ctx context.Context, user string, _ *oauth2.Token, c ReplayCommit) (string, error) {
+	if bookmarkFileFromContext(ctx) != DefaultBookmarkFile {
+		return "", ErrSingleBookmarkFile
+	}
+	db, err := sharedDB()
+	if err != nil {
+		return "", err
+	}
+	date := c.Date
+	if date.IsZero() {
+		date = time.Now()
+	}
+	author := c.AuthorName
+	if author == "" {
+		author = commitAuthorName(ctx)
+	}
+	sum := sha1.Sum([]byte(c.Parent + date.String() + c.Message + c.Text))
+	newSha := hex.EncodeToString(sum[:])
+	if _, err := db.ExecContext(ctx,
+		"INSERT INTO history(user, sha, message, text, date, author) VALUES(?,?,?,?,?,?)",
+		user, newSha, c.Message, c.Text, date, author,
+	); err != nil {
+		return "", err
+	}
+	return newSha, nil
+}
+
+// SetRef points a branch or tag at an existing history entry. Moving main
+// also updates the current bookmark list.
+func (p *SQLProvider) SetRef(ctx context.Context, user string, _ *oauth2.Token, ref, sha string) error {
+	var table, name string
+	switch {
+	case strings.HasPrefix(ref, "refs/heads/"):
+		table, name = "branches", strings.TrimPrefix(ref, "refs/heads/")
+	case strings.HasPrefix(ref, "refs/tags/"):
+		table, name = "tags", strings.TrimPrefix(ref, "refs/tags/")
+	default:
+		return fmt.Errorf("unsupported ref %s", ref)
+	}
+	db, err := sharedDB()
+	if err != nil {
+		return err
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	var text string
+	if err := tx.QueryRowContext(ctx, "SELECT text FROM history WHERE user=? AND sha=?", user, sha).Scan(&text); err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("commit %s: %w", sha, err)
+	}
+
+	var upsert, ensure string
+	switch strings.ToLower(Config.DBConnectionProvider) {
+	case "mysql":
+		upsert = "INSERT INTO " + table + "(user, name, sha) VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE sha=VALUES(sha)"
+		ensure = "INSERT INTO bookmarks(user, list) VALUES(?, '') ON DUPLICATE KEY UPDATE list=list"
+	case "sqlite3":
+		upsert = "INSERT INTO " + table + "(user, name, sha) VALUES (?, ?, ?) ON CONFLICT(user, name) DO UPDATE SET sha = excluded.sha"
+		ensure = "INSERT OR IGNORE INTO bookmarks(user, list) VALUES(?, '')"
+	default:
+		_ = tx.Rollback()
+		return errors.New("unsupported connection provider")
+	}
+	if _, err := tx.ExecContext(ctx, upsert, user, name, sha); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if table == "branches" && name == "main" {
+		if _, err := tx.ExecContext(ctx, ensure, user); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "UPDATE bookmarks SET list=? WHERE user=?", text, user); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
 func (p *SQLProvider) CreateRepo(ctx context.Context, user string, token *oauth2.Token, name string) error {
-	db, err := p.getDB()
+	db, err := sharedDB()
 	if err != nil {
 		return err
 	}
@@ -416,7 +559,7 @@ func (p *SQLProvider) CreateRepo(ctx context.Context, user string, token *oauth2
 }
 
 func (p *SQLProvider) RepoExists(ctx context.Context, user string, token *oauth2.Token, name string) (bool, error) {
-	db, err := p.getDB()
+	db, err := sharedDB()
 	if err != nil {
 		return false, err
 	}
@@ -427,7 +570,7 @@ func (p *SQLProvider) RepoExists(ctx context.Context, user string, token *oauth2
 }
 
 func (p *SQLProvider) CreateUser(ctx context.Context, user, password string) error {
-	db, err := p.getDB()
+	db, err := sharedDB()
 	if err != nil {
 		return err
 	}
@@ -449,7 +592,7 @@ func (p *SQLProvider) CreateUser(ctx context.Context, user, password string) err
 }
 
 func (p *SQLProvider) SetPassword(ctx context.Context, user, password string) error {
-	db, err := p.getDB()
+	db, err := sharedDB()
 	if err != nil {
 		return err
 	}
@@ -470,7 +613,7 @@ func (p *SQLProvider) SetPassword(ctx context.Context, user, password string) er
 }
 
 func (p *SQLProvider) CheckPassword(ctx context.Context, user, password string) (bool, error) {
-	db, err := p.getDB()
+	db, err := sharedDB()
 	if err != nil {
 		return false, err
 	}
@@ -485,3 +628,147 @@ func (p *SQLProvider) CheckPassword(ctx context.Context, user, password string)
 	}
 	return bcrypt.CompareHashAndPassword(hash, []byte(password)) == nil, nil
 }
+
+// HasPassword reports whether the user has a row in passwords.
+func (p *SQLProvider) HasPassword(ctx context.Context, user string) (bool, error) {
+	db, err := sharedDB()
+	if err != nil {
+		return false, err
+	}
+
+	var count int
+	err = db.QueryRowContext(ctx, "SELECT COUNT(1) FROM passwords WHERE user=?", user).Scan(&count)
+	return count > 0, err
+}
+
+// TwoFactor reads the TOTP enrollment stored with the user's password.
+func (p *SQLProvider) TwoFactor(ctx context.Context, user string) (*TwoFactor, error) {
+	db, err := sharedDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var data sql.NullString
+	err = db.QueryRowContext(ctx, "SELECT totp FROM passwords WHERE user=?", user).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !data.Valid || data.String == "" {
+		return nil, nil
+	}
+	var tf TwoFactor
+	if err := json.Unmarshal([]byte(data.String), &tf); err != nil {
+		return nil, fmt.Errorf("decode two-factor data: %w", err)
+	}
+	return &tf, nil
+}
+
+// SetTwoFactor stores or, when tf is nil, clears the user's TOTP enrollment.
+func (p *SQLProvider) SetTwoFactor(ctx context.Context, user string, tf *TwoFactor) error {
+	db, err := sharedDB()
+	if err != nil {
+		return err
+	}
+
+	var data sql.NullString
+	if tf != nil {
+		b, err := json.Marshal(tf)
+		if err != nil {
+			return err
+		}
+		data = sql.NullString{String: string(b), Valid: true}
+	}
+	res, err := db.ExecContext(ctx, "UPDATE passwords SET totp=? WHERE user=?", data, user)
+	if err != nil {
+		return err
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// Email reads the address stored with the user's password.
+func (p *SQLProvider) Email(ctx context.Context, user string) (string, error) {
+	db, err := sharedDB()
+	if err != nil {
+		return "", err
+	}
+
+	var email sql.NullString
+	err = db.QueryRowContext(ctx, "SELECT email FROM passwords WHERE user=?", user).Scan(&email)
+	if err == sql.ErrNoRows {
+		return "", ErrUserNotFound
+	}
+	return email.String, err
+}
+
+// SetEmail stores or, when email is empty, clears the user's address.
+func (p *SQLProvider) SetEmail(ctx context.Context, user, email string) error {
+	db, err := sharedDB()
+	if err != nil {
+		return err
+	}
+
+	res, err := db.ExecContext(ctx, "UPDATE passwords SET email=? WHERE user=?", sql.NullString{String: email, Valid: email != ""}, user)
+	if err != nil {
+		return err
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// PasswordReset reads the pending reset stored with the user's password.
+func (p *SQLProvider) PasswordReset(ctx context.Context, user string) (*PasswordReset, error) {
+	db, err := sharedDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var data sql.NullString
+	err = db.QueryRowContext(ctx, "SELECT reset FROM passwords WHERE user=?", user).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !data.Valid || data.String == "" {
+		return nil, nil
+	}
+	var reset PasswordReset
+	if err := json.Unmarshal([]byte(data.String), &reset); err != nil {
+		return nil, fmt.Errorf("decode password reset: %w", err)
+	}
+	return &reset, nil
+}
+
+// SetPasswordReset stores or, when reset is nil, clears the pending reset.
+func (p *SQLProvider) SetPasswordReset(ctx context.Context, user string, reset *PasswordReset) error {
+	db, err := sharedDB()
+	if err != nil {
+		return err
+	}
+
+	var data sql.NullString
+	if reset != nil {
+		b, err := json.Marshal(reset)
+		if err != nil {
+			return err
+		}
+		data = sql.NullString{String: string(b), Valid: true}
+	}
+	res, err := db.ExecContext(ctx, "UPDATE passwords SET reset=? WHERE user=?", data, user)
+	if err != nil {
+		return err
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
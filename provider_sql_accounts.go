@@ -0,0 +1,137 @@
+package gobookmarks
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+// Accounts lists every user with bookmarks or a password.
+func (p *SQLProvider) Accounts(ctx context.Context) ([]*Account, error) {
+	db, err := sharedDB()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT u.user, a.last_login, COALESCE(a.disabled, 0),
+			EXISTS(SELECT 1 FROM passwords p WHERE p.user=u.user),
+			COALESCE((SELECT LENGTH(b.list) FROM bookmarks b WHERE b.user=u.user), 0),
+			(SELECT COUNT(1) FROM history h WHERE h.user=u.user)
+		FROM (SELECT user FROM bookmarks UNION SELECT user FROM passwords) u
+		LEFT JOIN accounts a ON a.user=u.user
+		ORDER BY u.user`)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var accounts []*Account
+	for rows.Next() {
+		var a Account
+		var last sql.NullTime
+		if err := rows.Scan(&a.User, &last, &a.Disabled, &a.HasPassword, &a.BookmarkBytes, &a.Commits); err != nil {
+			return nil, err
+		}
+		if last.Valid {
+			a.LastLogin = last.Time
+		}
+		accounts = append(accounts, &a)
+	}
+	return accounts, rows.Err()
+}
+
+func (p *SQLProvider) accountExists(ctx context.Context, db *sql.DB, user string) error {
+	var n int
+	if err := db.QueryRowContext(ctx,
+		"SELECT (SELECT COUNT(1) FROM bookmarks WHERE user=?) + (SELECT COUNT(1) FROM passwords WHERE user=?)",
+		user, user,
+	).Scan(&n); err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// AccountDisabled reports whether an administrator disabled the account.
+func (p *SQLProvider) AccountDisabled(ctx context.Context, user string) (bool, error) {
+	db, err := sharedDB()
+	if err != nil {
+		return false, err
+	}
+
+	var disabled bool
+	err = db.QueryRowContext(ctx, "SELECT disabled FROM accounts WHERE user=?", user).Scan(&disabled)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return disabled, err
+}
+
+func (p *SQLProvider) SetAccountDisabled(ctx context.Context, user string, disabled bool) error {
+	db, err := sharedDB()
+	if err != nil {
+		return err
+	}
+	if err := p.accountExists(ctx, db, user); err != nil {
+		return err
+	}
+
+	var query string
+	switch strings.ToLower(Config.DBConnectionProvider) {
+	case "mysql":
+		query = "INSERT INTO accounts(user, disabled) VALUES(?, ?) ON DUPLICATE KEY UPDATE disabled=VALUES(disabled)"
+	case "sqlite3":
+		query = "INSERT INTO accounts(user, disabled) VALUES(?, ?) ON CONFLICT(user) DO UPDATE SET disabled=excluded.disabled"
+	default:
+		return errors.New("unsupported connection provider")
+	}
+	_, err = db.ExecContext(ctx, query, user, disabled)
+	return err
+}
+
+func (p *SQLProvider) RecordLogin(ctx context.Context, user string, at time.Time) error {
+	db, err := sharedDB()
+	if err != nil {
+		return err
+	}
+
+	var query string
+	switch strings.ToLower(Config.DBConnectionProvider) {
+	case "mysql":
+		query = "INSERT INTO accounts(user, last_login) VALUES(?, ?) ON DUPLICATE KEY UPDATE last_login=VALUES(last_login)"
+	case "sqlite3":
+		query = "INSERT INTO accounts(user, last_login) VALUES(?, ?) ON CONFLICT(user) DO UPDATE SET last_login=excluded.last_login"
+	default:
+		return errors.New("unsupported connection provider")
+	}
+	_, err = db.ExecContext(ctx, query, user, at.UTC())
+	return err
+}
+
+// DeleteAccount removes the user's password, bookmarks and history.
+func (p *SQLProvider) DeleteAccount(ctx context.Context, user string) error {
+	db, err := sharedDB()
+	if err != nil {
+		return err
+	}
+	if err := p.accountExists(ctx, db, user); err != nil {
+		return err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	for _, table := range []string{"bookmarks", "passwords", "history", "branches", "tags", "accounts"} {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM "+table+" WHERE user=?", user); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
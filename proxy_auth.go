@@ -0,0 +1,157 @@
+package gobookmarks
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/sessions"
+)
+
+// ParseTrustedProxies parses addresses and CIDRs from Config.TrustedProxies.
+func ParseTrustedProxies(list []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(list))
+	for _, entry := range list {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q", entry)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", entry, err)
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+// trustedProxyNets holds the proxies set by SetTrustedProxies.
+var trustedProxyNets []*net.IPNet
+
+// SetTrustedProxies parses list, normally Config.TrustedProxies, and makes
+// it the set of proxies whose forwarding headers are believed. It is called
+// once when the configuration is loaded so requests do not parse it again.
+func SetTrustedProxies(list []string) error {
+	nets, err := ParseTrustedProxies(list)
+	if err != nil {
+		return err
+	}
+	trustedProxyNets = nets
+	return nil
+}
+
+// trustedProxy reports whether host is one of the configured proxies.
+func trustedProxy(host string) bool {
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, n := range trustedProxyNets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// ProxyAuthMiddleware signs in the user named by Config.ProxyAuthHeader when
+// the request comes from a trusted proxy. The header is removed from requests
+// sent by anyone else. It must run after UserAdderMiddleware.
+func ProxyAuthMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := Config.ProxyAuthHeader
+		if header == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !trustedProxy(remoteHost(r)) {
+			if r.Header.Get(header) != "" {
+				slog.WarnContext(r.Context(), "ignoring proxy auth header from untrusted address", "header", header, "ip", remoteHost(r))
+			}
+			r.Header.Del(header)
+			next.ServeHTTP(w, r)
+			return
+		}
+		session, ok := r.Context().Value(ContextValues("session")).(*sessions.Session)
+		if !ok || session == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		user := strings.TrimSpace(r.Header.Get(header))
+		if err := applyProxyUser(w, r, session, user); err != nil {
+			slog.WarnContext(r.Context(), "proxy auth failed", "user", user, "err", err)
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		if user != "" && (r.URL.Path == "/login" || strings.HasPrefix(r.URL.Path, "/login/") || strings.HasPrefix(r.URL.Path, "/signup/")) {
+			http.Redirect(w, r, "/", http.StatusSeeOther)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// applyProxyUser makes session match the user asserted by the proxy,
+// provisioning the account on first visit. An empty user signs out sessions
+// that were created by the proxy.
+func applyProxyUser(w http.ResponseWriter, r *http.Request, session *sessions.Session, user string) error {
+	storage := Config.GetProxyAuthStorage()
+	current, _ := session.Values["GithubUser"].(*User)
+	fromProxy, _ := session.Values["ProxyAuth"].(bool)
+	if user == "" {
+		if current == nil || !fromProxy {
+			return nil
+		}
+		renewSession(r, session)
+		for _, k := range []string{"GithubUser", "Token", "Provider", "ProxyAuth"} {
+			delete(session.Values, k)
+		}
+		return session.Save(r, w)
+	}
+	if current != nil && current.Login == user && session.Values["Provider"] == storage {
+		return nil
+	}
+	if IsReservedUsername(user) {
+		return fmt.Errorf("reserved username")
+	}
+	p := GetProvider(storage)
+	if p == nil || providerCreds(storage) == nil {
+		return fmt.Errorf("storage provider %q is not available", storage)
+	}
+	if err := ensureRepo(r.Context(), p, user, nil); err != nil {
+		return fmt.Errorf("repository setup failed: %w", err)
+	}
+	if accountDisabled(r.Context(), p, user) {
+		return ErrAccountDisabled
+	}
+	recordAccountLogin(r, p, user)
+	renewSession(r, session)
+	session.Values["Provider"] = storage
+	session.Values["GithubUser"] = &User{Login: user}
+	session.Values["Token"] = nil
+	session.Values["ProxyAuth"] = true
+	session.Values["version"] = version
+	return session.Save(r, w)
+}
@@ -0,0 +1,129 @@
+package gobookmarks
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/sessions"
+)
+
+func setupProxyAuthTest(t *testing.T) http.Handler {
+	t.Helper()
+	old := Config
+	t.Cleanup(func() { Config = old })
+	Config.ProxyAuthHeader = "X-Forwarded-User"
+	Config.TrustedProxies = []string{"10.0.0.0/8", "192.0.2.7"}
+	if err := SetTrustedProxies(Config.TrustedProxies); err != nil {
+		t.Fatalf("SetTrustedProxies: %v", err)
+	}
+	t.Cleanup(func() { trustedProxyNets = nil })
+	Config.ProxyAuthStorage = "git"
+	Config.LocalGitPath = t.TempDir()
+	Config.SessionName = "gobookmarks"
+	SessionStore = sessions.NewCookieStore([]byte("secret-key"))
+	return UserAdderMiddleware(ProxyAuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s := r.Context().Value(ContextValues("session")).(*sessions.Session)
+		if u, ok := s.Values["GithubUser"].(*User); ok {
+			w.Header().Set("X-Test-User", u.Login)
+		}
+		w.Header().Set("X-Test-Header", r.Header.Get("X-Forwarded-User"))
+	})))
+}
+
+func proxyRequest(h http.Handler, remote, path, user string, prev *httptest.ResponseRecorder) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", path, nil)
+	req.RemoteAddr = remote
+	if user != "" {
+		req.Header.Set("X-Forwarded-User", user)
+	}
+	if prev != nil {
+		addLastCookies(req, prev)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+func TestProxyAuthTrusted(t *testing.T) {
+	h := setupProxyAuthTest(t)
+	w := proxyRequest(h, "10.1.2.3:5000", "/", "alice", nil)
+	if got := w.Header().Get("X-Test-User"); got != "alice" {
+		t.Fatalf("user = %q", got)
+	}
+	if exists, _ := (GitProvider{}).RepoExists(context.Background(), "alice", nil, Config.GetRepoName()); !exists {
+		t.Fatalf("account not provisioned")
+	}
+	if got := proxyRequest(h, "192.0.2.7:5000", "/", "alice", w).Header().Get("X-Test-User"); got != "alice" {
+		t.Fatalf("session not kept: %q", got)
+	}
+	w = proxyRequest(h, "10.1.2.3:5000", "/", "bob", w)
+	if got := w.Header().Get("X-Test-User"); got != "bob" {
+		t.Fatalf("user change ignored: %q", got)
+	}
+	w = proxyRequest(h, "10.1.2.3:5000", "/", "", w)
+	if got := w.Header().Get("X-Test-User"); got != "" {
+		t.Fatalf("still signed in as %q after proxy dropped the header", got)
+	}
+}
+
+func TestProxyAuthBypassesLogin(t *testing.T) {
+	h := setupProxyAuthTest(t)
+	w := proxyRequest(h, "10.1.2.3:5000", "/login/sql", "alice", nil)
+	if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/" {
+		t.Fatalf("login form not bypassed: %d %q", w.Code, w.Header().Get("Location"))
+	}
+}
+
+func TestProxyAuthUntrusted(t *testing.T) {
+	h := setupProxyAuthTest(t)
+	w := proxyRequest(h, "203.0.113.9:5000", "/", "alice", nil)
+	if got := w.Header().Get("X-Test-User"); got != "" {
+		t.Fatalf("untrusted header signed in %q", got)
+	}
+	if got := w.Header().Get("X-Test-Header"); got != "" {
+		t.Fatalf("untrusted header passed on: %q", got)
+	}
+	if exists, _ := (GitProvider{}).RepoExists(context.Background(), "alice", nil, Config.GetRepoName()); exists {
+		t.Fatalf("account provisioned for untrusted request")
+	}
+}
+
+func TestProxyAuthReservedName(t *testing.T) {
+	h := setupProxyAuthTest(t)
+	if w := proxyRequest(h, "10.1.2.3:5000", "/", collectionStoragePrefix+"team", nil); w.Code != http.StatusForbidden {
+		t.Fatalf("reserved name accepted: %d", w.Code)
+	}
+}
+
+func TestClientIPTrustedProxy(t *testing.T) {
+	setupProxyAuthTest(t)
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "10.1.2.3:5000"
+	req.Header.Set("X-Forwarded-For", "198.51.100.4, 10.9.9.9")
+	if got := clientIP(req); got != "198.51.100.4" {
+		t.Fatalf("clientIP = %q", got)
+	}
+	req.RemoteAddr = "203.0.113.9:5000"
+	if got := clientIP(req); got != "203.0.113.9" {
+		t.Fatalf("clientIP trusted an untrusted peer: %q", got)
+	}
+}
+
+func TestProxyAccountBlocksLocalSignup(t *testing.T) {
+	h := setupProxyAuthTest(t)
+	proxyRequest(h, "10.1.2.3:5000", "/", "alice", nil)
+	form := url.Values{"username": {"alice"}, "password": {"pw"}}
+	req := httptest.NewRequest("POST", "/signup/git", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	if err := GitSignupAction(w, req); err != nil {
+		t.Fatalf("GitSignupAction: %v", err)
+	}
+	if loc := w.Header().Get("Location"); loc != "/login/git?error=exists" {
+		t.Fatalf("signup for proxy account allowed: %q", loc)
+	}
+}
@@ -0,0 +1,299 @@
+package gobookmarks
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestQRReedSolomon(t *testing.T) {
+	// "HELLO WORLD" at 1-M from the worked example at thonky.com.
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	want := []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}
+	if got := rsRemainder(data, rsDivisor(10)); !bytes.Equal(got, want) {
+		t.Fatalf("ecc = %v, want %v", got, want)
+	}
+}
+
+func TestQRFormatAndVersionBits(t *testing.T) {
+	for mask, want := range []int{0x5412, 0x5125, 0x5E7C, 0x5B4B, 0x45F9, 0x40CE, 0x4F97, 0x4AA0} {
+		if got := qrFormatBits(mask); got != want {
+			t.Errorf("format bits for mask %d = %015b, want %015b", mask, got, want)
+		}
+	}
+	q := newQRCode(7)
+	q.drawFunctionPatterns(7, qrVersionsM[7])
+	var got int
+	for i := 17; i >= 0; i-- {
+		got = got<<1 | boolInt(q.modules[i/3][q.size-11+i%3])
+	}
+	if got != 0x07C94 {
+		t.Fatalf("version 7 info = %018b", got)
+	}
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// qrSpecM holds the level M layout of versions 1-10 as listed in ISO/IEC
+// 18004 table 9 and annex E, kept apart from the encoder's own table.
+var qrSpecM = []struct {
+	total, data, ecPerBlock int
+	blocks                  []int
+	align                   []int
+}{
+	1:  {26, 16, 10, []int{16}, nil},
+	2:  {44, 28, 16, []int{28}, []int{6, 18}},
+	3:  {70, 44, 26, []int{44}, []int{6, 22}},
+	4:  {100, 64, 18, []int{32, 32}, []int{6, 26}},
+	5:  {134, 86, 24, []int{43, 43}, []int{6, 30}},
+	6:  {172, 108, 16, []int{27, 27, 27, 27}, []int{6, 34}},
+	7:  {196, 124, 18, []int{31, 31, 31, 31}, []int{6, 22, 38}},
+	8:  {242, 154, 22, []int{38, 38, 39, 39}, []int{6, 24, 42}},
+	9:  {292, 182, 22, []int{36, 36, 36, 37, 37}, []int{6, 26, 46}},
+	10: {346, 216, 26, []int{43, 43, 43, 43, 44}, []int{6, 28, 50}},
+}
+
+// qrFormatM maps the level M format bits to their mask.
+var qrFormatM = map[int]int{0x5412: 0, 0x5125: 1, 0x5E7C: 2, 0x5B4B: 3, 0x45F9: 4, 0x40CE: 5, 0x4F97: 6, 0x4AA0: 7}
+
+func gf256Mul(a, b byte) byte {
+	var p byte
+	for ; b > 0; b >>= 1 {
+		if b&1 != 0 {
+			p ^= a
+		}
+		hi := a & 0x80
+		a <<= 1
+		if hi != 0 {
+			a ^= 0x1d
+		}
+	}
+	return p
+}
+
+// decodeQR reads a level M byte mode symbol using only its modules and the
+// specification, checking the format copies and every block's error
+// correction on the way.
+func decodeQR(t *testing.T, q *qrCode) string {
+	t.Helper()
+	size := len(q.modules)
+	ver := (size - 17) / 4
+	if ver < 1 || ver >= len(qrSpecM) || size != 17+4*ver {
+		t.Fatalf("unexpected size %d", size)
+	}
+	spec := qrSpecM[ver]
+
+	function := make([][]bool, size)
+	for y := range function {
+		function[y] = make([]bool, size)
+	}
+	mark := func(y0, x0, h, w int) {
+		for y := y0; y < y0+h; y++ {
+			for x := x0; x < x0+w; x++ {
+				function[y][x] = true
+			}
+		}
+	}
+	mark(0, 0, 9, 9)
+	mark(0, size-8, 9, 8)
+	mark(size-8, 0, 8, 9)
+	mark(6, 0, 1, size)
+	mark(0, 6, size, 1)
+	last := len(spec.align) - 1
+	for i, cy := range spec.align {
+		for j, cx := range spec.align {
+			if i == 0 && j == 0 || i == 0 && j == last || i == last && j == 0 {
+				continue
+			}
+			mark(cy-2, cx-2, 5, 5)
+		}
+	}
+	if ver >= 7 {
+		mark(0, size-11, 6, 3)
+		mark(size-11, 0, 3, 6)
+	}
+
+	var first, second int
+	for i := 14; i >= 0; i-- {
+		var a, b bool
+		switch {
+		case i <= 5:
+			a = q.modules[i][8]
+		case i == 6:
+			a = q.modules[7][8]
+		case i == 7:
+			a = q.modules[8][8]
+		case i == 8:
+			a = q.modules[8][7]
+		default:
+			a = q.modules[8][14-i]
+		}
+		if i < 8 {
+			b = q.modules[8][size-1-i]
+		} else {
+			b = q.modules[size-15+i][8]
+		}
+		first, second = first<<1|boolInt(a), second<<1|boolInt(b)
+	}
+	if first != second {
+		t.Fatalf("format copies differ: %015b %015b", first, second)
+	}
+	mask, ok := qrFormatM[first]
+	if !ok {
+		t.Fatalf("format %015b is not level M", first)
+	}
+	masked := func(y, x int) bool {
+		switch mask {
+		case 0:
+			return (y+x)%2 == 0
+		case 1:
+			return y%2 == 0
+		case 2:
+			return x%3 == 0
+		case 3:
+			return (y+x)%3 == 0
+		case 4:
+			return (y/2+x/3)%2 == 0
+		case 5:
+			return y*x%2+y*x%3 == 0
+		case 6:
+			return (y*x%2+y*x%3)%2 == 0
+		default:
+			return ((y+x)%2+y*x%3)%2 == 0
+		}
+	}
+
+	var raw []byte
+	var cur byte
+	n := 0
+	for right := size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := 0; vert < size; vert++ {
+			for j := 0; j < 2; j++ {
+				x, y := right-j, vert
+				if (right+1)&2 == 0 {
+					y = size - 1 - vert
+				}
+				if function[y][x] {
+					continue
+				}
+				cur = cur<<1 | byte(boolInt(q.modules[y][x] != masked(y, x)))
+				if n++; n%8 == 0 {
+					raw = append(raw, cur)
+				}
+			}
+		}
+	}
+	if len(raw) != spec.total {
+		t.Fatalf("version %d holds %d codewords, want %d", ver, len(raw), spec.total)
+	}
+
+	blocks := make([][]byte, len(spec.blocks))
+	i := 0
+	for col := 0; col < spec.blocks[len(spec.blocks)-1]; col++ {
+		for b, length := range spec.blocks {
+			if col < length {
+				blocks[b] = append(blocks[b], raw[i])
+				i++
+			}
+		}
+	}
+	var stream []byte
+	for b := range blocks {
+		stream = append(stream, blocks[b]...)
+	}
+	for col := 0; col < spec.ecPerBlock; col++ {
+		for b := range blocks {
+			blocks[b] = append(blocks[b], raw[i])
+			i++
+		}
+	}
+	// A valid block is divisible by the generator, so it evaluates to
+	// zero at each of the generator's roots 2^0 .. 2^(ec-1).
+	root := byte(1)
+	for k := 0; k < spec.ecPerBlock; k++ {
+		for b, block := range blocks {
+			var s byte
+			for _, c := range block {
+				s = gf256Mul(s, root) ^ c
+			}
+			if s != 0 {
+				t.Fatalf("version %d block %d fails syndrome %d", ver, b, k)
+			}
+		}
+		root = gf256Mul(root, 2)
+	}
+
+	pos := 0
+	read := func(bits int) int {
+		v := 0
+		for ; bits > 0; bits-- {
+			v = v<<1 | int(stream[pos/8]>>(7-pos%8)&1)
+			pos++
+		}
+		return v
+	}
+	if mode := read(4); mode != 0x4 {
+		t.Fatalf("mode = %x", mode)
+	}
+	countBits := 8
+	if ver >= 10 {
+		countBits = 16
+	}
+	payload := make([]byte, read(countBits))
+	for k := range payload {
+		payload[k] = byte(read(8))
+	}
+	return string(payload)
+}
+
+// TestQRRoundTrip encodes texts filling each supported version and reads
+// them back with decodeQR.
+func TestQRRoundTrip(t *testing.T) {
+	for ver := 1; ver < len(qrSpecM); ver++ {
+		info := qrVersionsM[ver]
+		got := []int{info.dataCodewords(), info.ecPerBlock}
+		want := []int{qrSpecM[ver].data, qrSpecM[ver].ecPerBlock}
+		if got[0] != want[0] || got[1] != want[1] || info.blocks1+info.blocks2 != len(qrSpecM[ver].blocks) {
+			t.Fatalf("version %d layout %+v differs from the specification", ver, info)
+		}
+		countBits := 8
+		if ver >= 10 {
+			countBits = 16
+		}
+		capacity := (qrSpecM[ver].data*8 - 4 - countBits) / 8
+		text := strings.Repeat("otpauth://totp/x?secret=ABCDEFGHIJKLMNOP", 6)[:capacity]
+		q, err := encodeQR(text)
+		if err != nil {
+			t.Fatalf("encodeQR %d bytes: %v", capacity, err)
+		}
+		if q.size != 17+4*ver {
+			t.Fatalf("%d bytes used size %d, want version %d", capacity, q.size, ver)
+		}
+		if got := decodeQR(t, q); got != text {
+			t.Fatalf("version %d decoded %q", ver, got)
+		}
+	}
+
+	text := totpURI("alice@example.com", strings.Repeat("A", 32))
+	q, err := encodeQR(text)
+	if err != nil {
+		t.Fatalf("encodeQR: %v", err)
+	}
+	if got := decodeQR(t, q); got != text {
+		t.Fatalf("decoded %q", got)
+	}
+	if !strings.HasPrefix(q.SVG(4), "<svg") {
+		t.Fatalf("svg not rendered")
+	}
+	if _, err := encodeQR(strings.Repeat("x", 214)); err != ErrQRTooLong {
+		t.Fatalf("long text: %v", err)
+	}
+}
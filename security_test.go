@@ -0,0 +1,116 @@
+package gobookmarks
+
+import (
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestSecurityHeadersMiddleware(t *testing.T) {
+	old := Config
+	t.Cleanup(func() { Config = old })
+	Config.TrustedProxies = []string{"10.0.0.0/8"}
+	if err := SetTrustedProxies(Config.TrustedProxies); err != nil {
+		t.Fatalf("SetTrustedProxies: %v", err)
+	}
+	t.Cleanup(func() { trustedProxyNets = nil })
+	h := SecurityHeadersMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	serve := func(req *http.Request) http.Header {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w.Header()
+	}
+
+	got := serve(httptest.NewRequest("GET", "/", nil))
+	if csp := got.Get("Content-Security-Policy"); !strings.Contains(csp, "frame-ancestors 'none'") {
+		t.Errorf("Content-Security-Policy = %q", csp)
+	}
+	if got.Get("Referrer-Policy") != "same-origin" || got.Get("X-Content-Type-Options") != "nosniff" || got.Get("X-Frame-Options") != "DENY" {
+		t.Errorf("headers = %v", got)
+	}
+	if got.Get("Strict-Transport-Security") != "" {
+		t.Errorf("HSTS sent over plain HTTP")
+	}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.TLS = &tls.ConnectionState{}
+	if serve(req).Get("Strict-Transport-Security") == "" {
+		t.Errorf("HSTS missing over HTTPS")
+	}
+
+	req = httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-Forwarded-Proto", "https")
+	if serve(req).Get("Strict-Transport-Security") != "" {
+		t.Errorf("X-Forwarded-Proto trusted from an unknown client")
+	}
+	req.RemoteAddr = "10.1.2.3:1234"
+	if serve(req).Get("Strict-Transport-Security") == "" {
+		t.Errorf("X-Forwarded-Proto ignored from a trusted proxy")
+	}
+
+	Config.ContentSecurityPolicy = "none"
+	if csp := serve(httptest.NewRequest("GET", "/", nil)).Get("Content-Security-Policy"); csp != "" {
+		t.Errorf("Content-Security-Policy = %q, want none", csp)
+	}
+}
+
+// TestTemplatesHaveNoInlineScripts keeps the templates working under the
+// default policy, which does not allow inline scripts or event handlers.
+func TestTemplatesHaveNoInlineScripts(t *testing.T) {
+	if strings.Contains(defaultContentSecurityPolicy, "script-src 'self' 'unsafe-inline'") {
+		t.Fatalf("default policy allows inline scripts")
+	}
+	inline := regexp.MustCompile(`<script(\s[^>]*)?>\s*[^<\s]|\son[a-z]+\s*=|javascript:`)
+	files, err := filepath.Glob("templates/*.gohtml")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("templates: %v", err)
+	}
+	partials, _ := filepath.Glob("templates/_partials/*")
+	for _, f := range append(files, partials...) {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatalf("read %s: %v", f, err)
+		}
+		if m := inline.Find(b); m != nil {
+			t.Errorf("%s: inline script %q", f, m)
+		}
+	}
+}
+
+func TestMaxBodyMiddleware(t *testing.T) {
+	old := Config
+	t.Cleanup(func() { Config = old })
+	Config.MaxBodyBytes = 8
+	h := MaxBodyMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.ReadAll(r.Body); err != nil {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		}
+	}))
+
+	for _, tc := range []struct {
+		body    string
+		chunked bool
+		want    int
+	}{
+		{"12345678", false, http.StatusOK},
+		{"123456789", false, http.StatusRequestEntityTooLarge},
+		{"123456789", true, http.StatusRequestEntityTooLarge},
+	} {
+		req := httptest.NewRequest("POST", "/", strings.NewReader(tc.body))
+		if tc.chunked {
+			req.ContentLength = -1
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code != tc.want {
+			t.Errorf("body %q chunked=%v: status %d, want %d", tc.body, tc.chunked, w.Code, tc.want)
+		}
+	}
+}
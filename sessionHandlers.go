@@ -0,0 +1,92 @@
+package gobookmarks
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/sessions"
+)
+
+// ErrSessionsUnsupported indicates sessions are kept in cookies and cannot be
+// listed or revoked.
+var ErrSessionsUnsupported = errors.New("server-side sessions are not enabled")
+
+// SessionInfo describes one of the signed-in user's sessions.
+type SessionInfo struct {
+	ID       string
+	Device   string
+	IP       string
+	Created  time.Time
+	LastSeen time.Time
+	Current  bool
+}
+
+// requestSessionOwner returns the server session store with the provider and
+// login of the signed-in user.
+func requestSessionOwner(r *http.Request) (*ServerSessionStore, *sessions.Session, string, string, error) {
+	store := serverSessionStore()
+	if store == nil {
+		return nil, nil, "", "", ErrSessionsUnsupported
+	}
+	session := r.Context().Value(ContextValues("session")).(*sessions.Session)
+	githubUser, _ := session.Values["GithubUser"].(*User)
+	if githubUser == nil {
+		return nil, nil, "", "", ErrSignedOut
+	}
+	provider, _ := session.Values["Provider"].(string)
+	return store, session, provider, githubUser.Login, nil
+}
+
+// userSessions lists the active sessions of the signed-in user.
+func userSessions(r *http.Request) ([]SessionInfo, error) {
+	store, session, provider, login, err := requestSessionOwner(r)
+	if err != nil {
+		return nil, nil
+	}
+	recs, err := store.Sessions(r.Context(), provider, login)
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]SessionInfo, 0, len(recs))
+	for _, rec := range recs {
+		infos = append(infos, SessionInfo{
+			ID:       rec.ID,
+			Device:   rec.Device,
+			IP:       rec.IP,
+			Created:  rec.Created,
+			LastSeen: rec.LastSeen,
+			Current:  rec.ID == session.ID,
+		})
+	}
+	return infos, nil
+}
+
+// SessionRevokeAction signs out one of the user's sessions.
+func SessionRevokeAction(w http.ResponseWriter, r *http.Request) error {
+	store, _, provider, login, err := requestSessionOwner(r)
+	if err != nil {
+		return err
+	}
+	if err := store.Revoke(r.Context(), provider, login, r.PostFormValue("id")); err != nil {
+		if errors.Is(err, ErrSessionNotFound) {
+			return NewUserError("Session not found", err)
+		}
+		return fmt.Errorf("revoke session: %w", err)
+	}
+	return nil
+}
+
+// SessionRevokeOthersAction signs out every session of the user except the
+// current one.
+func SessionRevokeOthersAction(w http.ResponseWriter, r *http.Request) error {
+	store, session, provider, login, err := requestSessionOwner(r)
+	if err != nil {
+		return err
+	}
+	if _, err := store.RevokeAll(r.Context(), provider, login, session.ID); err != nil {
+		return fmt.Errorf("revoke sessions: %w", err)
+	}
+	return nil
+}
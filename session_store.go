@@ -0,0 +1,359 @@
+package gobookmarks
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+)
+
+const (
+	// DefaultSessionMaxAge is how long an idle session stays valid.
+	DefaultSessionMaxAge = 30 * 24 * time.Hour
+	// sessionTouchInterval limits how often last-seen is written back.
+	sessionTouchInterval = time.Minute
+	maxSessionDeviceLen  = 255
+)
+
+// ErrSessionNotFound is returned by session backends for unknown or expired
+// session IDs.
+var ErrSessionNotFound = errors.New("session not found")
+
+// SessionRecord is a server-side session. Data holds the encoded session
+// values; the other fields describe the session for the "your sessions" page.
+type SessionRecord struct {
+	ID       string    `json:"id"`
+	User     string    `json:"user"`
+	Provider string    `json:"provider"`
+	Data     []byte    `json:"data"`
+	Device   string    `json:"device"`
+	IP       string    `json:"ip"`
+	Created  time.Time `json:"created"`
+	LastSeen time.Time `json:"last_seen"`
+	Expires  time.Time `json:"expires"`
+}
+
+// SessionBackend persists session records for ServerSessionStore. Save keeps
+// the Created time of an existing record.
+type SessionBackend interface {
+	Load(ctx context.Context, id string) (*SessionRecord, error)
+	Save(ctx context.Context, rec *SessionRecord) error
+	Delete(ctx context.Context, id string) error
+	ListUser(ctx context.Context, provider, user string) ([]*SessionRecord, error)
+	DeleteExpired(ctx context.Context, now time.Time) (int, error)
+	// CountActive returns the number of sessions that have not expired.
+	CountActive(ctx context.Context, now time.Time) (int, error)
+}
+
+// ServerSessionStore is a sessions.Store that keeps session values on the
+// server. The cookie only carries a signed session ID, so deleting the record
+// signs the session out wherever the cookie is used.
+type ServerSessionStore struct {
+	Codecs  []securecookie.Codec
+	Options *sessions.Options
+	Backend SessionBackend
+}
+
+// NewServerSessionStore returns a store using backend. Sessions expire after
+// maxAge without activity.
+func NewServerSessionStore(backend SessionBackend, maxAge time.Duration, keyPairs ...[]byte) *ServerSessionStore {
+	if maxAge <= 0 {
+		maxAge = DefaultSessionMaxAge
+	}
+	s := &ServerSessionStore{
+		Codecs: securecookie.CodecsFromPairs(keyPairs...),
+		Options: &sessions.Options{
+			Path:     "/",
+			MaxAge:   int(maxAge / time.Second),
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		},
+		Backend: backend,
+	}
+	for _, c := range s.Codecs {
+		if sc, ok := c.(*securecookie.SecureCookie); ok {
+			sc.MaxAge(s.Options.MaxAge)
+		}
+	}
+	return s
+}
+
+func (s *ServerSessionStore) Get(r *http.Request, name string) (*sessions.Session, error) {
+	return sessions.GetRegistry(r).Get(s, name)
+}
+
+func (s *ServerSessionStore) New(r *http.Request, name string) (*sessions.Session, error) {
+	session := sessions.NewSession(s, name)
+	opts := *s.Options
+	session.Options = &opts
+	session.IsNew = true
+
+	c, err := r.Cookie(name)
+	if err != nil {
+		return session, nil
+	}
+	var id string
+	if err := securecookie.DecodeMulti(name, c.Value, &id, s.Codecs...); err != nil {
+		return session, err
+	}
+	rec, err := s.Backend.Load(r.Context(), id)
+	if errors.Is(err, ErrSessionNotFound) {
+		return session, nil
+	} else if err != nil {
+		return session, err
+	}
+	now := time.Now()
+	if !rec.Expires.After(now) {
+		if err := s.Backend.Delete(r.Context(), id); err != nil {
+			slog.ErrorContext(r.Context(), "session delete failed", "err", err)
+		}
+		return session, nil
+	}
+	if err := (securecookie.GobEncoder{}).Deserialize(rec.Data, &session.Values); err != nil {
+		return session, err
+	}
+	session.ID = id
+	session.IsNew = false
+
+	if now.Sub(rec.LastSeen) > sessionTouchInterval {
+		rec.LastSeen = now
+		rec.IP = clientIP(r)
+		rec.Expires = now.Add(time.Duration(s.Options.MaxAge) * time.Second)
+		if err := s.Backend.Save(r.Context(), rec); err != nil {
+			slog.ErrorContext(r.Context(), "session touch failed", "err", err)
+		}
+	}
+	return session, nil
+}
+
+func (s *ServerSessionStore) Save(r *http.Request, w http.ResponseWriter, session *sessions.Session) error {
+	if session.Options.MaxAge < 0 {
+		if session.ID != "" {
+			if err := s.Backend.Delete(r.Context(), session.ID); err != nil {
+				return err
+			}
+		}
+		http.SetCookie(w, sessions.NewCookie(session.Name(), "", session.Options))
+		return nil
+	}
+	if session.ID == "" {
+		id, err := newSessionID()
+		if err != nil {
+			return err
+		}
+		session.ID = id
+	}
+	data, err := (securecookie.GobEncoder{}).Serialize(session.Values)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	rec := &SessionRecord{
+		ID:       session.ID,
+		Data:     data,
+		Device:   truncate(r.UserAgent(), maxSessionDeviceLen),
+		IP:       clientIP(r),
+		Created:  now,
+		LastSeen: now,
+		Expires:  now.Add(time.Duration(session.Options.MaxAge) * time.Second),
+	}
+	if u, ok := session.Values["GithubUser"].(*User); ok && u != nil {
+		rec.User = u.Login
+		rec.Provider, _ = session.Values["Provider"].(string)
+	}
+	if err := s.Backend.Save(r.Context(), rec); err != nil {
+		return err
+	}
+	encoded, err := securecookie.EncodeMulti(session.Name(), session.ID, s.Codecs...)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, sessions.NewCookie(session.Name(), encoded, session.Options))
+	return nil
+}
+
+// Sessions lists the active sessions of a user, most recently used first.
+func (s *ServerSessionStore) Sessions(ctx context.Context, provider, user string) ([]*SessionRecord, error) {
+	recs, err := s.Backend.ListUser(ctx, provider, user)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	active := recs[:0]
+	for _, rec := range recs {
+		if rec.Expires.After(now) {
+			active = append(active, rec)
+		}
+	}
+	sort.Slice(active, func(i, j int) bool { return active[i].LastSeen.After(active[j].LastSeen) })
+	return active, nil
+}
+
+// Revoke deletes one of the user's sessions.
+func (s *ServerSessionStore) Revoke(ctx context.Context, provider, user, id string) error {
+	recs, err := s.Backend.ListUser(ctx, provider, user)
+	if err != nil {
+		return err
+	}
+	for _, rec := range recs {
+		if rec.ID == id {
+			return s.Backend.Delete(ctx, id)
+		}
+	}
+	return ErrSessionNotFound
+}
+
+// RevokeAll deletes every session of the user except the one with ID except.
+func (s *ServerSessionStore) RevokeAll(ctx context.Context, provider, user, except string) (int, error) {
+	recs, err := s.Backend.ListUser(ctx, provider, user)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, rec := range recs {
+		if rec.ID == except {
+			continue
+		}
+		if err := s.Backend.Delete(ctx, rec.ID); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// Cleanup removes expired sessions every interval until ctx is done.
+func (s *ServerSessionStore) Cleanup(ctx context.Context, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		if n, err := s.Backend.DeleteExpired(ctx, time.Now()); err != nil {
+			slog.ErrorContext(ctx, "session cleanup failed", "err", err)
+		} else if n > 0 {
+			slog.InfoContext(ctx, "session cleanup", "removed", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// NewConfiguredSessionStore returns the session store selected by
+// Config.SessionStore: "cookie" (the default), "sql" or "file". Its cookies
+// are marked Secure when Config.SecureCookies says the site is served over
+// HTTPS.
+func NewConfiguredSessionStore(key []byte) (sessions.Store, error) {
+	maxAge := time.Duration(Config.SessionMaxAge) * time.Second
+	switch strings.ToLower(Config.SessionStore) {
+	case "", "cookie":
+		store := sessions.NewCookieStore(key)
+		if maxAge > 0 {
+			store.MaxAge(int(maxAge / time.Second))
+		}
+		store.Options.Secure = Config.SecureCookies()
+		return store, nil
+	case "sql":
+		store := NewServerSessionStore(&SQLSessionBackend{}, maxAge, key)
+		store.Options.Secure = Config.SecureCookies()
+		return store, nil
+	case "file":
+		dir := Config.SessionDir
+		if dir == "" {
+			dir = DefaultSessionDir()
+		}
+		backend, err := NewFileSessionBackend(dir)
+		if err != nil {
+			return nil, err
+		}
+		store := NewServerSessionStore(backend, maxAge, key)
+		store.Options.Secure = Config.SecureCookies()
+		return store, nil
+	default:
+		return nil, fmt.Errorf("unknown session store %q, expected cookie, sql or file", Config.SessionStore)
+	}
+}
+
+// serverSessionStore returns the active ServerSessionStore, or nil when
+// sessions are kept in cookies.
+func serverSessionStore() *ServerSessionStore {
+	s, _ := SessionStore.(*ServerSessionStore)
+	return s
+}
+
+// renewSession discards the server-side record behind session so the next
+// save issues a new ID, and drops the CSRF token. It is called when the
+// signed-in user changes.
+func renewSession(r *http.Request, session *sessions.Session) {
+	delete(session.Values, csrfSessionKey)
+	s := serverSessionStore()
+	if s == nil || session.ID == "" {
+		return
+	}
+	if err := s.Backend.Delete(r.Context(), session.ID); err != nil {
+		slog.ErrorContext(r.Context(), "session renew failed", "err", err)
+	}
+	session.ID = ""
+}
+
+var sessionIDEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+func newSessionID() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return strings.ToLower(sessionIDEncoding.EncodeToString(b)), nil
+}
+
+// validSessionID reports whether id looks like an ID from newSessionID.
+func validSessionID(id string) bool {
+	if len(id) == 0 || len(id) > 64 {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= '2' && c <= '7') {
+			return false
+		}
+	}
+	return true
+}
+
+// clientIP returns the address of the client, following X-Forwarded-For
+// through trusted proxies.
+func clientIP(r *http.Request) string {
+	host := remoteHost(r)
+	if !trustedProxy(host) {
+		return host
+	}
+	hops := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if net.ParseIP(hop) == nil {
+			break
+		}
+		host = hop
+		if !trustedProxy(hop) {
+			break
+		}
+	}
+	return host
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
@@ -0,0 +1,159 @@
+package gobookmarks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileSessionBackend stores each session as a JSON file in Dir.
+type FileSessionBackend struct {
+	Dir string
+	mu  sync.Mutex
+}
+
+// NewFileSessionBackend creates dir if needed and returns a backend using it.
+func NewFileSessionBackend(dir string) (*FileSessionBackend, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("session dir: %w", err)
+	}
+	return &FileSessionBackend{Dir: dir}, nil
+}
+
+func (b *FileSessionBackend) path(id string) (string, error) {
+	if !validSessionID(id) {
+		return "", ErrSessionNotFound
+	}
+	return filepath.Join(b.Dir, id+".json"), nil
+}
+
+func (b *FileSessionBackend) read(path string) (*SessionRecord, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrSessionNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	var rec SessionRecord
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	return &rec, nil
+}
+
+func (b *FileSessionBackend) Load(ctx context.Context, id string) (*SessionRecord, error) {
+	path, err := b.path(id)
+	if err != nil {
+		return nil, err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.read(path)
+}
+
+func (b *FileSessionBackend) Save(ctx context.Context, rec *SessionRecord) error {
+	path, err := b.path(rec.ID)
+	if err != nil {
+		return err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if old, err := b.read(path); err == nil && !old.Created.IsZero() {
+		rec.Created = old.Created
+	}
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func (b *FileSessionBackend) Delete(ctx context.Context, id string) error {
+	path, err := b.path(id)
+	if err != nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// each calls fn for every stored session.
+func (b *FileSessionBackend) each(fn func(path string, rec *SessionRecord) error) error {
+	entries, err := os.ReadDir(b.Dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		path := filepath.Join(b.Dir, e.Name())
+		rec, err := b.read(path)
+		if errors.Is(err, ErrSessionNotFound) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		if err := fn(path, rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *FileSessionBackend) ListUser(ctx context.Context, provider, user string) ([]*SessionRecord, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var recs []*SessionRecord
+	err := b.each(func(_ string, rec *SessionRecord) error {
+		if rec.User == user && rec.Provider == provider {
+			recs = append(recs, rec)
+		}
+		return nil
+	})
+	return recs, err
+}
+
+func (b *FileSessionBackend) DeleteExpired(ctx context.Context, now time.Time) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	n := 0
+	err := b.each(func(path string, rec *SessionRecord) error {
+		if rec.Expires.After(now) {
+			return nil
+		}
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		n++
+		return nil
+	})
+	return n, err
+}
+
+func (b *FileSessionBackend) CountActive(ctx context.Context, now time.Time) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	n := 0
+	err := b.each(func(_ string, rec *SessionRecord) error {
+		if rec.Expires.After(now) {
+			n++
+		}
+		return nil
+	})
+	return n, err
+}
@@ -0,0 +1,116 @@
+package gobookmarks
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+// SQLSessionBackend stores sessions in the sessions table of the configured
+// database.
+type SQLSessionBackend struct{}
+
+const sqlSessionColumns = "id, user, provider, data, device, ip, created, last_seen, expires"
+
+func scanSessionRecord(row interface{ Scan(...any) error }) (*SessionRecord, error) {
+	var rec SessionRecord
+	var user, provider, device, ip sql.NullString
+	if err := row.Scan(&rec.ID, &user, &provider, &rec.Data, &device, &ip, &rec.Created, &rec.LastSeen, &rec.Expires); err != nil {
+		return nil, err
+	}
+	rec.User, rec.Provider, rec.Device, rec.IP = user.String, provider.String, device.String, ip.String
+	return &rec, nil
+}
+
+func (b *SQLSessionBackend) Load(ctx context.Context, id string) (*SessionRecord, error) {
+	db, err := sharedDB()
+	if err != nil {
+		return nil, err
+	}
+	rec, err := scanSessionRecord(db.QueryRowContext(ctx, "SELECT "+sqlSessionColumns+" FROM sessions WHERE id=?", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrSessionNotFound
+	}
+	return rec, err
+}
+
+func (b *SQLSessionBackend) Save(ctx context.Context, rec *SessionRecord) error {
+	db, err := sharedDB()
+	if err != nil {
+		return err
+	}
+	var query string
+	switch strings.ToLower(Config.DBConnectionProvider) {
+	case "mysql":
+		query = `
+			INSERT INTO sessions(id, user, provider, data, device, ip, created, last_seen, expires)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+			ON DUPLICATE KEY UPDATE user=VALUES(user), provider=VALUES(provider), data=VALUES(data),
+				device=VALUES(device), ip=VALUES(ip), last_seen=VALUES(last_seen), expires=VALUES(expires)`
+	case "sqlite3":
+		query = `
+			INSERT INTO sessions(id, user, provider, data, device, ip, created, last_seen, expires)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT(id) DO UPDATE SET user=excluded.user, provider=excluded.provider, data=excluded.data,
+				device=excluded.device, ip=excluded.ip, last_seen=excluded.last_seen, expires=excluded.expires`
+	default:
+		return errors.New("unsupported connection provider")
+	}
+	_, err = db.ExecContext(ctx, query, rec.ID, rec.User, rec.Provider, rec.Data, rec.Device, rec.IP, rec.Created.UTC(), rec.LastSeen.UTC(), rec.Expires.UTC())
+	return err
+}
+
+func (b *SQLSessionBackend) Delete(ctx context.Context, id string) error {
+	db, err := sharedDB()
+	if err != nil {
+		return err
+	}
+	_, err = db.ExecContext(ctx, "DELETE FROM sessions WHERE id=?", id)
+	return err
+}
+
+func (b *SQLSessionBackend) ListUser(ctx context.Context, provider, user string) ([]*SessionRecord, error) {
+	db, err := sharedDB()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx, "SELECT "+sqlSessionColumns+" FROM sessions WHERE user=? AND provider=?", user, provider)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	var recs []*SessionRecord
+	for rows.Next() {
+		rec, err := scanSessionRecord(rows)
+		if err != nil {
+			return nil, err
+		}
+		recs = append(recs, rec)
+	}
+	return recs, rows.Err()
+}
+
+func (b *SQLSessionBackend) DeleteExpired(ctx context.Context, now time.Time) (int, error) {
+	db, err := sharedDB()
+	if err != nil {
+		return 0, err
+	}
+	res, err := db.ExecContext(ctx, "DELETE FROM sessions WHERE expires <= ?", now.UTC())
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+func (b *SQLSessionBackend) CountActive(ctx context.Context, now time.Time) (int, error) {
+	db, err := sharedDB()
+	if err != nil {
+		return 0, err
+	}
+	var n int
+	err = db.QueryRowContext(ctx, "SELECT COUNT(1) FROM sessions WHERE expires > ?", now.UTC()).Scan(&n)
+	return n, err
+}
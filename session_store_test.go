@@ -0,0 +1,148 @@
+package gobookmarks
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func sessionBackends(t *testing.T) map[string]SessionBackend {
+	t.Helper()
+	fb, err := NewFileSessionBackend(filepath.Join(t.TempDir(), "sessions"))
+	if err != nil {
+		t.Fatalf("NewFileSessionBackend: %v", err)
+	}
+	Config.DBConnectionProvider = "sqlite3"
+	Config.DBConnectionString = filepath.Join(t.TempDir(), "sessions.db")
+	t.Cleanup(func() {
+		Config.DBConnectionProvider = ""
+		Config.DBConnectionString = ""
+	})
+	sb := &SQLSessionBackend{}
+	t.Cleanup(func() { _ = closeSharedDB() })
+	return map[string]SessionBackend{"file": fb, "sql": sb}
+}
+
+// signIn saves a session for user and returns its cookie.
+func signIn(t *testing.T, store *ServerSessionStore, user, agent string) *http.Cookie {
+	t.Helper()
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("User-Agent", agent)
+	s, err := store.New(req, "gobookmarks")
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	s.Values["GithubUser"] = &User{Login: user}
+	s.Values["Provider"] = "git"
+	w := httptest.NewRecorder()
+	if err := s.Save(req, w); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	return w.Result().Cookies()[0]
+}
+
+func loadUser(t *testing.T, store *ServerSessionStore, c *http.Cookie) string {
+	t.Helper()
+	req := httptest.NewRequest("GET", "/", nil)
+	req.AddCookie(c)
+	s, err := store.New(req, "gobookmarks")
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if u, ok := s.Values["GithubUser"].(*User); ok {
+		return u.Login
+	}
+	return ""
+}
+
+func TestServerSessionStore(t *testing.T) {
+	for name, backend := range sessionBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			store := NewServerSessionStore(backend, time.Hour, []byte("secret-key"))
+			ctx := context.Background()
+			laptop := signIn(t, store, "alice", "Laptop")
+			phone := signIn(t, store, "alice", "Phone")
+			signIn(t, store, "bob", "Desktop")
+
+			if got := loadUser(t, store, laptop); got != "alice" {
+				t.Fatalf("loaded user %q", got)
+			}
+			recs, err := store.Sessions(ctx, "git", "alice")
+			if err != nil || len(recs) != 2 {
+				t.Fatalf("Sessions = %d, %v", len(recs), err)
+			}
+			var phoneID string
+			for _, rec := range recs {
+				if rec.Device == "Phone" {
+					phoneID = rec.ID
+				}
+				if rec.IP != "192.0.2.1" || rec.Created.IsZero() {
+					t.Errorf("unexpected record %+v", rec)
+				}
+			}
+
+			if err := store.Revoke(ctx, "git", "bob", phoneID); err != ErrSessionNotFound {
+				t.Fatalf("revoking another user's session: %v", err)
+			}
+			if err := store.Revoke(ctx, "git", "alice", phoneID); err != nil {
+				t.Fatalf("Revoke: %v", err)
+			}
+			if got := loadUser(t, store, phone); got != "" {
+				t.Fatalf("revoked cookie still signed in as %q", got)
+			}
+			if got := loadUser(t, store, laptop); got != "alice" {
+				t.Fatalf("other session lost: %q", got)
+			}
+		})
+	}
+}
+
+func TestServerSessionStoreExpiry(t *testing.T) {
+	for name, backend := range sessionBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			store := NewServerSessionStore(backend, time.Hour, []byte("secret-key"))
+			ctx := context.Background()
+			c := signIn(t, store, "alice", "Laptop")
+			if n, err := backend.CountActive(ctx, time.Now()); err != nil || n != 1 {
+				t.Fatalf("CountActive = %d, %v", n, err)
+			}
+			if n, err := backend.CountActive(ctx, time.Now().Add(2*time.Hour)); err != nil || n != 0 {
+				t.Fatalf("CountActive after expiry = %d, %v", n, err)
+			}
+			n, err := backend.DeleteExpired(ctx, time.Now().Add(2*time.Hour))
+			if err != nil || n != 1 {
+				t.Fatalf("DeleteExpired = %d, %v", n, err)
+			}
+			if got := loadUser(t, store, c); got != "" {
+				t.Fatalf("expired session still signed in as %q", got)
+			}
+		})
+	}
+}
+
+func TestUserLogoutRenewsServerSession(t *testing.T) {
+	backend, err := NewFileSessionBackend(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileSessionBackend: %v", err)
+	}
+	store := NewServerSessionStore(backend, time.Hour, []byte("secret-key"))
+	old := SessionStore
+	SessionStore = store
+	t.Cleanup(func() { SessionStore = old })
+
+	c := signIn(t, store, "alice", "Laptop")
+	req := httptest.NewRequest("GET", "/logout", nil)
+	req.AddCookie(c)
+	s, _ := store.New(req, "gobookmarks")
+	ctx := context.WithValue(req.Context(), ContextValues("session"), s)
+	ctx = context.WithValue(ctx, ContextValues("coreData"), &CoreData{})
+	if err := UserLogoutAction(httptest.NewRecorder(), req.WithContext(ctx)); err != nil {
+		t.Fatalf("UserLogoutAction: %v", err)
+	}
+	if got := loadUser(t, store, c); got != "" {
+		t.Fatalf("cookie from before logout still signed in as %q", got)
+	}
+}
@@ -4,7 +4,7 @@ import (
 	"errors"
 	"github.com/gorilla/securecookie"
 	"github.com/gorilla/sessions"
-	"log"
+	"log/slog"
 	"net/http"
 )
 
@@ -16,11 +16,11 @@ func sanitizeSession(w http.ResponseWriter, r *http.Request, session *sessions.S
 	}
 	scErr := new(securecookie.MultiError)
 	if (errors.As(err, scErr) && scErr.IsDecode() && !scErr.IsInternal() && !scErr.IsUsage()) || errors.Is(err, securecookie.ErrMacInvalid) {
-		log.Printf("session error: %v", err)
+		slog.WarnContext(r.Context(), "invalid session cookie", "err", err)
 		if session != nil {
 			session.Options.MaxAge = -1
 			if saveErr := session.Save(r, w); saveErr != nil {
-				log.Printf("session clear error: %v", saveErr)
+				slog.ErrorContext(r.Context(), "session clear failed", "err", saveErr)
 			}
 		}
 		session, _ = SessionStore.New(r, Config.GetSessionName())
@@ -0,0 +1,266 @@
+package gobookmarks
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/sessions"
+	"golang.org/x/oauth2"
+)
+
+// SettingsPageData is rendered by settings.gohtml.
+type SettingsPageData struct {
+	*CoreData
+	Error    string
+	User     string
+	Provider string
+	// HasPassword is false for accounts that sign in through another service.
+	HasPassword bool
+	// CanDelete is false when the bookmarks are kept by GitHub or GitLab.
+	CanDelete bool
+	// EmailAvailable is set for password accounts that can store an email
+	// address for password resets.
+	EmailAvailable bool
+	Email          string
+}
+
+// settingsAccount is the signed-in user's own account. Collections and
+// impersonation do not apply to it.
+type settingsAccount struct {
+	session  *sessions.Session
+	provider Provider
+	user     string
+	token    *oauth2.Token
+}
+
+func loadSettingsAccount(r *http.Request) (settingsAccount, error) {
+	session := r.Context().Value(ContextValues("session")).(*sessions.Session)
+	githubUser, _ := session.Values["GithubUser"].(*User)
+	if githubUser == nil {
+		return settingsAccount{}, ErrSignedOut
+	}
+	providerName, _ := session.Values["Provider"].(string)
+	p := GetProvider(providerName)
+	if p == nil {
+		return settingsAccount{}, ErrSignedOut
+	}
+	token, _ := session.Values["Token"].(*oauth2.Token)
+	return settingsAccount{session: session, provider: p, user: githubUser.Login, token: token}, nil
+}
+
+// passwordHandler returns the account's password store when the account has
+// a password.
+func (a settingsAccount) passwordHandler(r *http.Request) (PasswordHandler, error) {
+	ph, ok := a.provider.(PasswordHandler)
+	if !ok {
+		return nil, nil
+	}
+	has, err := ph.HasPassword(r.Context(), a.user)
+	if err != nil || !has {
+		return nil, err
+	}
+	return ph, nil
+}
+
+// checkPassword verifies the password typed to confirm a change. Failures
+// count towards the login limits.
+func (a settingsAccount) checkPassword(r *http.Request, ph PasswordHandler, password string) error {
+	name := a.provider.Name()
+	if wait := loginThrottled(r, name, a.user); wait > 0 {
+		return NewUserError(fmt.Sprintf("Too many failed attempts, try again in %s", wait.Round(time.Second)), nil)
+	}
+	ok, err := ph.CheckPassword(r.Context(), a.user, password)
+	if err != nil {
+		return fmt.Errorf("check password: %w", err)
+	}
+	if !ok {
+		slog.InfoContext(r.Context(), "password confirmation failed", "provider", name, "user", a.user)
+		recordLoginFailure(r, name, a.user)
+		return NewUserError("Current password is incorrect", nil)
+	}
+	return nil
+}
+
+func (a settingsAccount) audit(r *http.Request, event string) {
+	Audit(r.Context(), AuditEntry{
+		Event:    event,
+		User:     a.user,
+		Provider: a.provider.Name(),
+		IP:       clientIP(r),
+	})
+}
+
+// SettingsPage shows the signed-in user's account settings.
+func SettingsPage(w http.ResponseWriter, r *http.Request) error {
+	a, err := loadSettingsAccount(r)
+	if err != nil {
+		return err
+	}
+	ph, err := a.passwordHandler(r)
+	if err != nil {
+		return fmt.Errorf("password lookup: %w", err)
+	}
+	_, canDelete := a.provider.(AccountManager)
+	data := SettingsPageData{
+		CoreData:    r.Context().Value(ContextValues("coreData")).(*CoreData),
+		Error:       r.URL.Query().Get("error"),
+		User:        a.user,
+		Provider:    a.provider.Name(),
+		HasPassword: ph != nil,
+		CanDelete:   canDelete,
+	}
+	if eh, ok := a.provider.(EmailHandler); ok && ph != nil {
+		data.EmailAvailable = true
+		if data.Email, err = eh.Email(r.Context(), a.user); err != nil {
+			return fmt.Errorf("email lookup: %w", err)
+		}
+	}
+	if err := GetCompiledTemplates(NewFuncs(r)).ExecuteTemplate(w, "settings.gohtml", data); err != nil {
+		return fmt.Errorf("template: %w", err)
+	}
+	return nil
+}
+
+// SettingsPasswordAction changes the password after checking the current one
+// and signs out the user's other sessions.
+func SettingsPasswordAction(w http.ResponseWriter, r *http.Request) error {
+	a, err := loadSettingsAccount(r)
+	if err != nil {
+		return err
+	}
+	ph, err := a.passwordHandler(r)
+	if err != nil {
+		return fmt.Errorf("password lookup: %w", err)
+	}
+	if ph == nil {
+		return NewUserError("This account signs in without a password", ErrUserNotFound)
+	}
+	password := r.PostFormValue("password")
+	if password == "" {
+		return NewUserError("Enter a new password", nil)
+	}
+	if password != r.PostFormValue("confirm") {
+		return NewUserError("The new passwords do not match", nil)
+	}
+	if err := a.checkPassword(r, ph, r.PostFormValue("current")); err != nil {
+		return err
+	}
+	if err := ph.SetPassword(r.Context(), a.user, password); err != nil {
+		return fmt.Errorf("set password: %w", err)
+	}
+	if store := serverSessionStore(); store != nil {
+		if _, err := store.RevokeAll(r.Context(), a.provider.Name(), a.user, a.session.ID); err != nil {
+			slog.ErrorContext(r.Context(), "revoke sessions failed", "action", "password change", "err", err)
+		}
+	}
+	a.audit(r, "password_change")
+	return nil
+}
+
+// SettingsEmailAction sets or clears the address password reset links are
+// sent to after checking the password.
+func SettingsEmailAction(w http.ResponseWriter, r *http.Request) error {
+	a, err := loadSettingsAccount(r)
+	if err != nil {
+		return err
+	}
+	ph, err := a.passwordHandler(r)
+	if err != nil {
+		return fmt.Errorf("password lookup: %w", err)
+	}
+	eh, ok := a.provider.(EmailHandler)
+	if !ok || ph == nil {
+		return NewUserError("This account signs in without a password", ErrUserNotFound)
+	}
+	email := strings.TrimSpace(r.PostFormValue("email"))
+	if email != "" && !validEmail(email) {
+		return NewUserError("Enter a valid email address", nil)
+	}
+	if err := a.checkPassword(r, ph, r.PostFormValue("current")); err != nil {
+		return err
+	}
+	if err := eh.SetEmail(r.Context(), a.user, email); err != nil {
+		return fmt.Errorf("set email: %w", err)
+	}
+	a.audit(r, "email_change")
+	return nil
+}
+
+// exportFilename returns the download name of user's export.
+func exportFilename(user string, now time.Time) string {
+	safe := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '.' {
+			return r
+		}
+		return '_'
+	}, user)
+	return fmt.Sprintf("gobookmarks-%s-%s.zip", safe, now.Format("2006-01-02"))
+}
+
+// SettingsExportAction downloads every bookmark file with its full history
+// as a zip archive.
+func SettingsExportAction(w http.ResponseWriter, r *http.Request) error {
+	a, err := loadSettingsAccount(r)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := writeExport(r.Context(), &buf, a.provider, a.user, a.token); err != nil {
+		return fmt.Errorf("export: %w", err)
+	}
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", exportFilename(a.user, time.Now())))
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		slog.WarnContext(r.Context(), "export write failed", "err", err)
+	}
+	return nil
+}
+
+// SettingsDeleteAction removes the signed-in user's account with all of its
+// bookmarks and history, then signs them out. The username must be typed to
+// confirm, as must the password when the account has one.
+func SettingsDeleteAction(w http.ResponseWriter, r *http.Request) error {
+	a, err := loadSettingsAccount(r)
+	if err != nil {
+		return err
+	}
+	am, ok := a.provider.(AccountManager)
+	if !ok {
+		return NewUserError("Bookmarks kept on "+a.provider.Name()+" must be deleted there", nil)
+	}
+	if r.PostFormValue("confirm") != a.user {
+		return NewUserError("Type your username to confirm the deletion", nil)
+	}
+	ph, err := a.passwordHandler(r)
+	if err != nil {
+		return fmt.Errorf("password lookup: %w", err)
+	}
+	if ph != nil {
+		if err := a.checkPassword(r, ph, r.PostFormValue("password")); err != nil {
+			return err
+		}
+	}
+	if err := am.DeleteAccount(r.Context(), a.user); err != nil {
+		return fmt.Errorf("delete account: %w", err)
+	}
+	invalidateBookmarkCache(a.user)
+	if store := serverSessionStore(); store != nil {
+		if _, err := store.RevokeAll(r.Context(), a.provider.Name(), a.user, a.session.ID); err != nil {
+			slog.ErrorContext(r.Context(), "revoke sessions failed", "action", "account delete", "err", err)
+		}
+	}
+	a.audit(r, "account_delete")
+
+	renewSession(r, a.session)
+	for _, k := range []string{"GithubUser", "Token", "Provider", "ProxyAuth", "ImpersonateProvider", "ImpersonateUser"} {
+		delete(a.session.Values, k)
+	}
+	if err := a.session.Save(r, w); err != nil {
+		return fmt.Errorf("session save: %w", err)
+	}
+	return nil
+}
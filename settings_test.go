@@ -0,0 +1,148 @@
+package gobookmarks
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/sessions"
+)
+
+// setupSettingsTest signs "alice" in to the git provider and returns the
+// handler with the settings routes.
+func setupSettingsTest(t *testing.T) (http.Handler, *httptest.ResponseRecorder) {
+	t.Helper()
+	useLoginAttempts(t, nil)
+	old := Config
+	t.Cleanup(func() { Config = old })
+	Config.LocalGitPath = t.TempDir()
+	Config.SessionName = "gobookmarks"
+	SessionStore = sessions.NewCookieStore([]byte("secret-key"))
+	ctx := context.Background()
+	if err := (GitProvider{}).CreateUser(ctx, "alice", "password"); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	if err := ensureRepo(ctx, GitProvider{}, "alice", nil); err != nil {
+		t.Fatalf("ensureRepo: %v", err)
+	}
+	mux := http.NewServeMux()
+	handle := func(path string, h func(http.ResponseWriter, *http.Request) error) {
+		mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			if err := h(w, r); err != nil && !errors.Is(err, ErrHandled) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			}
+		})
+	}
+	handle("/settings", SettingsPage)
+	handle("/settings/password", SettingsPasswordAction)
+	handle("/settings/export", SettingsExportAction)
+	handle("/settings/delete", SettingsDeleteAction)
+	handle("/login/git", GitLoginAction)
+	h := UserAdderMiddleware(CoreAdderMiddleware(mux))
+	return h, adminRequest(h, "POST", "/login/git", url.Values{"username": {"alice"}, "password": {"password"}}, nil)
+}
+
+func TestSettingsPasswordChange(t *testing.T) {
+	h, alice := setupSettingsTest(t)
+	ctx := context.Background()
+	if w := adminRequest(h, "GET", "/settings", nil, alice); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "/settings/password") {
+		t.Fatalf("settings page: %d %s", w.Code, w.Body.String())
+	}
+
+	w := adminRequest(h, "POST", "/settings/password", url.Values{"current": {"wrong"}, "password": {"new"}, "confirm": {"new"}}, alice)
+	if w.Code == http.StatusOK {
+		t.Fatalf("wrong current password accepted")
+	}
+	w = adminRequest(h, "POST", "/settings/password", url.Values{"current": {"password"}, "password": {"new"}, "confirm": {"other"}}, alice)
+	if w.Code == http.StatusOK {
+		t.Fatalf("mismatched confirmation accepted")
+	}
+	if ok, _ := (GitProvider{}).CheckPassword(ctx, "alice", "password"); !ok {
+		t.Fatalf("password changed by rejected requests")
+	}
+
+	w = adminRequest(h, "POST", "/settings/password", url.Values{"current": {"password"}, "password": {"new"}, "confirm": {"new"}}, alice)
+	if w.Code != http.StatusOK {
+		t.Fatalf("change password: %d %s", w.Code, w.Body.String())
+	}
+	if ok, _ := (GitProvider{}).CheckPassword(ctx, "alice", "new"); !ok {
+		t.Fatalf("new password not set")
+	}
+}
+
+func TestSettingsExport(t *testing.T) {
+	h, alice := setupSettingsTest(t)
+	if err := (GitProvider{}).UpdateBookmarks(context.Background(), "alice", nil, "", "main", "Category: Exported\n", ""); err != nil {
+		t.Fatalf("UpdateBookmarks: %v", err)
+	}
+
+	w := adminRequest(h, "GET", "/settings/export", nil, alice)
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/zip" {
+		t.Fatalf("export: %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+	zr, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	if err != nil {
+		t.Fatalf("zip: %v", err)
+	}
+	read := func(name string) string {
+		f, err := zr.Open(name)
+		if err != nil {
+			t.Fatalf("open %s: %v", name, err)
+		}
+		defer f.Close()
+		b, _ := io.ReadAll(f)
+		return string(b)
+	}
+	if got := read(DefaultBookmarkFile); got != "Category: Exported\n" {
+		t.Fatalf("current file = %q", got)
+	}
+	var commits []exportCommit
+	if err := json.Unmarshal([]byte(read("history/bookmarks.txt/commits.json")), &commits); err != nil {
+		t.Fatalf("commits.json: %v", err)
+	}
+	if len(commits) != 2 {
+		t.Fatalf("commits = %+v", commits)
+	}
+	if got := read("history/bookmarks.txt/" + commits[0].File); got != defaultBookmarks {
+		t.Fatalf("first commit = %q", got)
+	}
+	if got := read("history/bookmarks.txt/" + commits[1].File); got != "Category: Exported\n" {
+		t.Fatalf("last commit = %q", got)
+	}
+}
+
+func TestSettingsDelete(t *testing.T) {
+	h, alice := setupSettingsTest(t)
+
+	w := adminRequest(h, "POST", "/settings/delete", url.Values{"confirm": {"bob"}, "password": {"password"}}, alice)
+	if w.Code == http.StatusOK {
+		t.Fatalf("delete without confirmation accepted")
+	}
+	w = adminRequest(h, "POST", "/settings/delete", url.Values{"confirm": {"alice"}, "password": {"wrong"}}, alice)
+	if w.Code == http.StatusOK {
+		t.Fatalf("delete with wrong password accepted")
+	}
+	if _, err := os.Stat(userDir("alice")); err != nil {
+		t.Fatalf("repository removed by rejected request: %v", err)
+	}
+
+	w = adminRequest(h, "POST", "/settings/delete", url.Values{"confirm": {"alice"}, "password": {"password"}}, alice)
+	if w.Code != http.StatusOK {
+		t.Fatalf("delete: %d %s", w.Code, w.Body.String())
+	}
+	if _, err := os.Stat(userDir("alice")); !os.IsNotExist(err) {
+		t.Fatalf("repository kept: %v", err)
+	}
+	if w := adminRequest(h, "GET", "/settings", nil, w); w.Code == http.StatusOK {
+		t.Fatalf("still signed in after delete")
+	}
+}
@@ -0,0 +1,50 @@
+package gobookmarks
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+)
+
+// pendingWork counts bookmark saves and favicon cache writes that are still
+// running so Shutdown can wait for them.
+var pendingWork sync.WaitGroup
+
+// trackWork marks the start of work Shutdown must wait for. Call the
+// returned function when it is done.
+func trackWork() func() {
+	pendingWork.Add(1)
+	return pendingWork.Done
+}
+
+// waitPendingWork waits for tracked work to finish or ctx to be done.
+func waitPendingWork(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		pendingWork.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Shutdown waits for in-flight bookmark saves and favicon cache writes to
+// finish, giving up when ctx is done, and then closes the database handle
+// shared by the SQL provider and stores. The HTTP
+// servers should be shut down first so no new work arrives.
+func Shutdown(ctx context.Context) error {
+	var errs []error
+	if err := waitPendingWork(ctx); err != nil {
+		slog.WarnContext(ctx, "shutdown before pending saves finished", "err", err)
+		errs = append(errs, err)
+	}
+	if err := closeSharedDB(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
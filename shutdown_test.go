@@ -0,0 +1,46 @@
+package gobookmarks
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestShutdownWaitsForPendingWork(t *testing.T) {
+	Config.DBConnectionProvider = "sqlite3"
+	Config.DBConnectionString = filepath.Join(t.TempDir(), "shutdown.db")
+	t.Cleanup(func() {
+		Config.DBConnectionProvider = ""
+		Config.DBConnectionString = ""
+	})
+	if _, err := sharedDB(); err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+
+	done := trackWork()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Shutdown with a pending save = %v", err)
+	}
+
+	if _, err := sharedDB(); err != nil {
+		t.Fatalf("reopen db: %v", err)
+	}
+	finished := make(chan error)
+	go func() { finished <- Shutdown(context.Background()) }()
+	select {
+	case err := <-finished:
+		t.Fatalf("Shutdown returned %v before the save finished", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	done()
+	if err := <-finished; err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	if sqlDB.db != nil {
+		t.Fatalf("database left open")
+	}
+}
@@ -0,0 +1,2 @@
+-- Records which user made each change.
+ALTER TABLE history ADD COLUMN author TEXT;
@@ -0,0 +1,13 @@
+-- Server-side sessions.
+CREATE TABLE IF NOT EXISTS sessions (
+    id VARCHAR(64) PRIMARY KEY,
+    user TEXT,
+    provider TEXT,
+    data BLOB,
+    device TEXT,
+    ip TEXT,
+    created DATETIME,
+    last_seen DATETIME,
+    expires DATETIME,
+    INDEX sessions_user (user(191), provider(32))
+);
@@ -0,0 +1,13 @@
+-- Server-side sessions.
+CREATE TABLE IF NOT EXISTS sessions (
+    id TEXT PRIMARY KEY,
+    user TEXT,
+    provider TEXT,
+    data BLOB,
+    device TEXT,
+    ip TEXT,
+    created TIMESTAMP,
+    last_seen TIMESTAMP,
+    expires TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS sessions_user ON sessions(user, provider);
@@ -0,0 +1,2 @@
+-- TOTP two-factor enrollment stored with the password.
+ALTER TABLE passwords ADD COLUMN totp TEXT;
@@ -0,0 +1,15 @@
+-- Failed logins for rate limiting and the audit log.
+CREATE TABLE IF NOT EXISTS login_failures (
+    name VARCHAR(255),
+    at DATETIME,
+    INDEX login_failures_name (name, at)
+);
+CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    at DATETIME,
+    event TEXT,
+    user TEXT,
+    provider TEXT,
+    ip TEXT,
+    detail TEXT
+);
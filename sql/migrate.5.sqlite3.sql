@@ -0,0 +1,15 @@
+-- Failed logins for rate limiting and the audit log.
+CREATE TABLE IF NOT EXISTS login_failures (
+    name TEXT,
+    at TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS login_failures_name ON login_failures(name, at);
+CREATE TABLE IF NOT EXISTS audit_log (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    at TIMESTAMP,
+    event TEXT,
+    user TEXT,
+    provider TEXT,
+    ip TEXT,
+    detail TEXT
+);
@@ -0,0 +1,7 @@
+-- Account status for administrators.
+CREATE TABLE IF NOT EXISTS accounts (
+    user TEXT,
+    last_login DATETIME NULL,
+    disabled TINYINT NOT NULL DEFAULT 0,
+    PRIMARY KEY(user(191))
+);
@@ -0,0 +1,6 @@
+-- Account status for administrators.
+CREATE TABLE IF NOT EXISTS accounts (
+    user TEXT PRIMARY KEY,
+    last_login TIMESTAMP,
+    disabled INTEGER NOT NULL DEFAULT 0
+);
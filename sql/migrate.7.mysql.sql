@@ -0,0 +1,3 @@
+-- Optional email address and pending password reset stored with the password.
+ALTER TABLE passwords ADD COLUMN email TEXT;
+ALTER TABLE passwords ADD COLUMN reset TEXT;
@@ -0,0 +1,6 @@
+-- Repository and bookmark file chosen by each account.
+CREATE TABLE IF NOT EXISTS account_settings (
+    name VARCHAR(255) PRIMARY KEY,
+    repo_name TEXT,
+    bookmark_file TEXT
+);
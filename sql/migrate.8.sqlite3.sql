@@ -0,0 +1,6 @@
+-- Repository and bookmark file chosen by each account.
+CREATE TABLE IF NOT EXISTS account_settings (
+    name TEXT PRIMARY KEY,
+    repo_name TEXT,
+    bookmark_file TEXT
+);
@@ -7,6 +7,9 @@ CREATE TABLE IF NOT EXISTS bookmarks (
 CREATE TABLE IF NOT EXISTS passwords (
     user TEXT,
     hash BLOB,
+    totp TEXT,
+    email TEXT,
+    reset TEXT,
     PRIMARY KEY(user(191))
 );
 
@@ -16,7 +19,8 @@ CREATE TABLE IF NOT EXISTS history (
     sha TEXT,
     message TEXT,
     text BLOB,
-    date TIMESTAMP
+    date TIMESTAMP,
+    author TEXT
 );
 
 CREATE TABLE IF NOT EXISTS branches (
@@ -33,6 +37,46 @@ CREATE TABLE IF NOT EXISTS tags (
     PRIMARY KEY(user(191), name(191))
 );
 
+CREATE TABLE IF NOT EXISTS sessions (
+    id VARCHAR(64) PRIMARY KEY,
+    user TEXT,
+    provider TEXT,
+    data BLOB,
+    device TEXT,
+    ip TEXT,
+    created DATETIME,
+    last_seen DATETIME,
+    expires DATETIME,
+    INDEX sessions_user (user(191), provider(32))
+);
+
+CREATE TABLE IF NOT EXISTS login_failures (
+    name VARCHAR(255),
+    at DATETIME,
+    INDEX login_failures_name (name, at)
+);
+CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    at DATETIME,
+    event TEXT,
+    user TEXT,
+    provider TEXT,
+    ip TEXT,
+    detail TEXT
+);
+CREATE TABLE IF NOT EXISTS accounts (
+    user TEXT,
+    last_login DATETIME NULL,
+    disabled TINYINT NOT NULL DEFAULT 0,
+    PRIMARY KEY(user(191))
+);
+
+CREATE TABLE IF NOT EXISTS account_settings (
+    name VARCHAR(255) PRIMARY KEY,
+    repo_name TEXT,
+    bookmark_file TEXT
+);
+
 CREATE TABLE IF NOT EXISTS meta (
     version INTEGER
 );
@@ -4,7 +4,10 @@ CREATE TABLE IF NOT EXISTS bookmarks (
 );
 CREATE TABLE IF NOT EXISTS passwords (
     user TEXT PRIMARY KEY,
-    hash BLOB
+    hash BLOB,
+    totp TEXT,
+    email TEXT,
+    reset TEXT
 );
 CREATE TABLE IF NOT EXISTS history (
     id INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -12,7 +15,8 @@ CREATE TABLE IF NOT EXISTS history (
     sha TEXT,
     message TEXT,
     text BLOB,
-    date TIMESTAMP
+    date TIMESTAMP,
+    author TEXT
 );
 CREATE TABLE IF NOT EXISTS branches (
     user TEXT,
@@ -26,6 +30,42 @@ CREATE TABLE IF NOT EXISTS tags (
     sha TEXT,
     PRIMARY KEY(user, name)
 );
+CREATE TABLE IF NOT EXISTS sessions (
+    id TEXT PRIMARY KEY,
+    user TEXT,
+    provider TEXT,
+    data BLOB,
+    device TEXT,
+    ip TEXT,
+    created TIMESTAMP,
+    last_seen TIMESTAMP,
+    expires TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS sessions_user ON sessions(user, provider);
+CREATE TABLE IF NOT EXISTS login_failures (
+    name TEXT,
+    at TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS login_failures_name ON login_failures(name, at);
+CREATE TABLE IF NOT EXISTS audit_log (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    at TIMESTAMP,
+    event TEXT,
+    user TEXT,
+    provider TEXT,
+    ip TEXT,
+    detail TEXT
+);
+CREATE TABLE IF NOT EXISTS accounts (
+    user TEXT PRIMARY KEY,
+    last_login TIMESTAMP,
+    disabled INTEGER NOT NULL DEFAULT 0
+);
+CREATE TABLE IF NOT EXISTS account_settings (
+    name TEXT PRIMARY KEY,
+    repo_name TEXT,
+    bookmark_file TEXT
+);
 CREATE TABLE IF NOT EXISTS meta (
     version INTEGER
 );
@@ -3,6 +3,8 @@ package gobookmarks
 import (
 	"html/template"
 	"io/fs"
+	"log/slog"
+	"net/http"
 	"path/filepath"
 	"strings"
 )
@@ -37,3 +39,18 @@ func ParseFSRecursive(t *template.Template, fsys fs.FS, baseDir, ext string) (*t
 	})
 	return t, err
 }
+
+// renderErrorPage responds with status and error.gohtml showing msg, for
+// middleware that rejects a request before any handler runs.
+func renderErrorPage(w http.ResponseWriter, r *http.Request, status int, msg string) {
+	data := struct {
+		*CoreData
+		Error string
+	}{Error: msg}
+	data.CoreData, _ = r.Context().Value(ContextValues("coreData")).(*CoreData)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if err := GetCompiledTemplates(NewFuncs(r)).ExecuteTemplate(w, "error.gohtml", data); err != nil {
+		slog.ErrorContext(r.Context(), "error page failed", "err", err)
+	}
+}
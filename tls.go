@@ -0,0 +1,142 @@
+package gobookmarks
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CertReloader serves a certificate loaded from PEM files and replaces it
+// when the files change, so renewed certificates are picked up without a
+// restart.
+type CertReloader struct {
+	certFile, keyFile string
+
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	certMod time.Time
+	keyMod  time.Time
+}
+
+// NewCertReloader loads the certificate in certFile and its key in keyFile.
+func NewCertReloader(certFile, keyFile string) (*CertReloader, error) {
+	c := &CertReloader{certFile: certFile, keyFile: keyFile}
+	if err := c.Reload(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func modTime(path string) (time.Time, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return fi.ModTime(), nil
+}
+
+// Reload reads the files again. On error the previous certificate is kept.
+func (c *CertReloader) Reload() error {
+	certMod, err := modTime(c.certFile)
+	if err != nil {
+		return err
+	}
+	keyMod, err := modTime(c.keyFile)
+	if err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return fmt.Errorf("load certificate: %w", err)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cert = &cert
+	c.certMod, c.keyMod = certMod, keyMod
+	return nil
+}
+
+// changed reports whether either file was modified since the last load.
+func (c *CertReloader) changed() bool {
+	certMod, err := modTime(c.certFile)
+	if err != nil {
+		return false
+	}
+	keyMod, err := modTime(c.keyFile)
+	if err != nil {
+		return false
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return !certMod.Equal(c.certMod) || !keyMod.Equal(c.keyMod)
+}
+
+// GetCertificate is used as tls.Config.GetCertificate.
+func (c *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.cert, nil
+}
+
+// TLSConfig returns a server TLS configuration using the reloaded
+// certificate.
+func (c *CertReloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: c.GetCertificate,
+	}
+}
+
+// Watch checks the files every interval and reloads them when they change,
+// until ctx is done. A certificate and key replaced one after the other may
+// not match for a moment; the failed load is logged and retried on the next
+// check.
+func (c *CertReloader) Watch(ctx context.Context, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		if !c.changed() {
+			continue
+		}
+		if err := c.Reload(); err != nil {
+			slog.ErrorContext(ctx, "certificate reload failed", "err", err)
+			continue
+		}
+		slog.InfoContext(ctx, "certificate reloaded", "file", c.certFile)
+	}
+}
+
+// HTTPSRedirectHandler sends every request to the same path over HTTPS. The
+// host is taken from ExternalURL when it is set and otherwise from the
+// request, with the port of the HTTPS listener.
+func HTTPSRedirectHandler(w http.ResponseWriter, r *http.Request) {
+	base := Config.GetExternalURL()
+	if !strings.HasPrefix(base, "https://") {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if _, port, err := net.SplitHostPort(Config.GetHTTPSListen()); err == nil && port != "" && port != "443" {
+			host = net.JoinHostPort(host, port)
+		} else if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+		base = "https://" + host
+	}
+	if i := strings.Index(base[len("https://"):], "/"); i >= 0 {
+		base = base[:len("https://")+i]
+	}
+	http.Redirect(w, r, base+r.URL.RequestURI(), http.StatusMovedPermanently)
+}
@@ -0,0 +1,160 @@
+package gobookmarks
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeTestCert writes a self-signed certificate for name to certFile and
+// keyFile.
+func writeTestCert(t *testing.T, certFile, keyFile, name string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &tmpl, &tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kb, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: kb}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func servedName(t *testing.T, c *CertReloader) string {
+	t.Helper()
+	cert, err := c.GetCertificate(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return leaf.Subject.CommonName
+}
+
+func TestCertReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	writeTestCert(t, certFile, keyFile, "first")
+	c, err := NewCertReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("NewCertReloader: %v", err)
+	}
+	if n := servedName(t, c); n != "first" {
+		t.Fatalf("served %q", n)
+	}
+
+	if err := os.WriteFile(certFile, []byte("garbage"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Reload(); err == nil {
+		t.Fatalf("broken certificate loaded")
+	}
+	if n := servedName(t, c); n != "first" {
+		t.Fatalf("failed reload replaced the certificate: %q", n)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.Watch(ctx, 10*time.Millisecond)
+	writeTestCert(t, certFile, keyFile, "second")
+	later := time.Now().Add(time.Second)
+	_ = os.Chtimes(certFile, later, later)
+	_ = os.Chtimes(keyFile, later, later)
+	deadline := time.Now().Add(2 * time.Second)
+	for servedName(t, c) != "second" {
+		if time.Now().After(deadline) {
+			t.Fatalf("changed files not reloaded")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestTLSExternalURL(t *testing.T) {
+	old := Config
+	t.Cleanup(func() { Config = old })
+	Config = Configuration{ExternalURL: "http://box.example:8080/"}
+	if got := Config.GetOauthRedirectURL(); got != "http://box.example:8080/oauth2Callback" {
+		t.Errorf("without TLS: %q", got)
+	}
+	if Config.SecureCookies() {
+		t.Errorf("secure cookies without TLS")
+	}
+
+	Config.TLSCertFile, Config.TLSKeyFile = "cert.pem", "key.pem"
+	if got := Config.GetOauthRedirectURL(); got != "https://box.example:8443/oauth2Callback" {
+		t.Errorf("with TLS: %q", got)
+	}
+	Config.HTTPListen, Config.HTTPSListen = ":80", ":443"
+	Config.ExternalURL = "http://box.example:80"
+	if got := Config.GetOIDCRedirectURL(); got != "https://box.example/oidcCallback" {
+		t.Errorf("standard ports: %q", got)
+	}
+	if !Config.SecureCookies() {
+		t.Errorf("cookies not secure with TLS")
+	}
+	store, err := NewConfiguredSessionStore([]byte("0123456789abcdef0123456789abcdef"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/", nil)
+	session, _ := store.New(req, "s")
+	session.Values["x"] = "y"
+	if err := session.Save(req, w); err != nil {
+		t.Fatal(err)
+	}
+	if c := w.Result().Cookies(); len(c) != 1 || !c[0].Secure {
+		t.Errorf("session cookie = %+v", c)
+	}
+}
+
+func TestHTTPSRedirectHandler(t *testing.T) {
+	old := Config
+	t.Cleanup(func() { Config = old })
+	Config = Configuration{TLSCertFile: "cert.pem", TLSKeyFile: "key.pem"}
+
+	for _, tc := range []struct {
+		external, host, want string
+	}{
+		{"", "box.example:8080", "https://box.example:8443/tab/1?ref=x"},
+		{"https://bookmarks.example/", "box.example", "https://bookmarks.example/tab/1?ref=x"},
+		{"http://bookmarks.example:8080", "box.example", "https://bookmarks.example:8443/tab/1?ref=x"},
+	} {
+		Config.ExternalURL = tc.external
+		req := httptest.NewRequest("GET", "http://"+tc.host+"/tab/1?ref=x", nil)
+		w := httptest.NewRecorder()
+		HTTPSRedirectHandler(w, req)
+		if w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != tc.want {
+			t.Errorf("external %q: %d %q, want %q", tc.external, w.Code, w.Header().Get("Location"), tc.want)
+		}
+	}
+}
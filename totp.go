@@ -0,0 +1,142 @@
+package gobookmarks
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	totpPeriod        = 30
+	totpDigits        = 6
+	recoveryCodeCount = 10
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// TwoFactor holds a user's TOTP enrollment. RecoveryCodes are bcrypt hashes
+// of unused one-time codes and LastStep is the last accepted time step, which
+// stops a code from being used twice.
+type TwoFactor struct {
+	Secret        string   `json:"secret"`
+	Enabled       bool     `json:"enabled"`
+	RecoveryCodes []string `json:"recovery_codes,omitempty"`
+	LastStep      int64    `json:"last_step,omitempty"`
+}
+
+// newTOTPSecret returns a random 160 bit base32 secret.
+func newTOTPSecret() (string, error) {
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return totpEncoding.EncodeToString(b), nil
+}
+
+// totpCode computes the RFC 6238 code for secret at time step.
+func totpCode(secret string, step int64) (string, error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", fmt.Errorf("totp secret: %w", err)
+	}
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+	off := sum[len(sum)-1] & 0x0f
+	v := binary.BigEndian.Uint32(sum[off:]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, v%1000000), nil
+}
+
+// totpURI returns the otpauth URI authenticator apps use to enroll secret.
+func totpURI(user, secret string) string {
+	issuer := strings.ReplaceAll(Config.Title, ":", "")
+	if issuer == "" {
+		issuer = "gobookmarks"
+	}
+	v := url.Values{}
+	v.Set("secret", secret)
+	v.Set("issuer", issuer)
+	v.Set("algorithm", "SHA1")
+	v.Set("digits", fmt.Sprint(totpDigits))
+	v.Set("period", fmt.Sprint(totpPeriod))
+	label := url.PathEscape(issuer) + ":" + url.PathEscape(user)
+	return "otpauth://totp/" + label + "?" + v.Encode()
+}
+
+// checkTOTP returns the step matching code within one step of now, or zero.
+func checkTOTP(secret, code string, now time.Time) int64 {
+	if len(code) != totpDigits {
+		return 0
+	}
+	step := now.Unix() / totpPeriod
+	for _, s := range []int64{step, step - 1, step + 1} {
+		want, err := totpCode(secret, s)
+		if err == nil && hmac.Equal([]byte(want), []byte(code)) {
+			return s
+		}
+	}
+	return 0
+}
+
+// Verify checks an authenticator or recovery code. Accepted codes are
+// consumed, so the caller must store tf afterwards.
+func (tf *TwoFactor) Verify(code string, now time.Time) bool {
+	code = strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+	if step := checkTOTP(tf.Secret, code, now); step != 0 {
+		if step <= tf.LastStep {
+			return false
+		}
+		tf.LastStep = step
+		return true
+	}
+	code = normalizeRecoveryCode(code)
+	if code == "" {
+		return false
+	}
+	for i, hash := range tf.RecoveryCodes {
+		if bcrypt.CompareHashAndPassword([]byte(hash), []byte(code)) == nil {
+			tf.RecoveryCodes = append(tf.RecoveryCodes[:i:i], tf.RecoveryCodes[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// NewRecoveryCodes replaces the recovery codes and returns the new ones.
+func (tf *TwoFactor) NewRecoveryCodes() ([]string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		code := strings.ToLower(totpEncoding.EncodeToString(b))
+		hash, err := bcrypt.GenerateFromPassword([]byte(code), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code[:4]+"-"+code[4:])
+		hashes = append(hashes, string(hash))
+	}
+	tf.RecoveryCodes = hashes
+	return codes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.ReplaceAll(code, "-", ""))
+	if len(code) != 8 {
+		return ""
+	}
+	return code
+}
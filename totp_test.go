@@ -0,0 +1,210 @@
+package gobookmarks
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base32"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/sessions"
+)
+
+func TestTOTPCodeRFC6238(t *testing.T) {
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+	for unix, want := range map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1234567890: "005924",
+		2000000000: "279037",
+	} {
+		got, err := totpCode(secret, unix/totpPeriod)
+		if err != nil || got != want {
+			t.Errorf("totpCode at %d = %q, %v; want %q", unix, got, err, want)
+		}
+	}
+}
+
+func TestTwoFactorVerify(t *testing.T) {
+	secret, err := newTOTPSecret()
+	if err != nil {
+		t.Fatalf("newTOTPSecret: %v", err)
+	}
+	tf := &TwoFactor{Secret: secret, Enabled: true}
+	now := time.Now()
+	code, _ := totpCode(secret, now.Unix()/totpPeriod)
+	if !tf.Verify(code, now) {
+		t.Fatalf("current code rejected")
+	}
+	if tf.Verify(code, now) {
+		t.Fatalf("code accepted twice")
+	}
+	old, _ := totpCode(secret, now.Unix()/totpPeriod-3)
+	if tf.Verify(old, now) {
+		t.Fatalf("stale code accepted")
+	}
+
+	codes, err := tf.NewRecoveryCodes()
+	if err != nil || len(codes) != recoveryCodeCount {
+		t.Fatalf("NewRecoveryCodes = %d, %v", len(codes), err)
+	}
+	if !tf.Verify(strings.ToUpper(codes[3]), now) {
+		t.Fatalf("recovery code rejected")
+	}
+	if tf.Verify(codes[3], now) || len(tf.RecoveryCodes) != recoveryCodeCount-1 {
+		t.Fatalf("recovery code not consumed")
+	}
+}
+
+func TestSQLTwoFactorMigration(t *testing.T) {
+	Config.DBConnectionProvider = "sqlite3"
+	Config.DBConnectionString = filepath.Join(t.TempDir(), "bookmarks.db")
+	t.Cleanup(func() {
+		Config.DBConnectionProvider = ""
+		Config.DBConnectionString = ""
+	})
+	// A version 3 database predates the totp column.
+	db, err := sql.Open("sqlite3", Config.DBConnectionString)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if _, err := db.Exec("CREATE TABLE passwords (user TEXT PRIMARY KEY, hash BLOB); CREATE TABLE meta (version INTEGER); INSERT INTO meta(version) VALUES(3)"); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+	_ = db.Close()
+
+	p := &SQLProvider{}
+	t.Cleanup(func() { _ = closeSharedDB() })
+	ctx := context.Background()
+	if _, err := p.TwoFactor(ctx, "alice"); !errors.Is(err, ErrUserNotFound) {
+		t.Fatalf("TwoFactor for unknown user: %v", err)
+	}
+	if err := p.CreateUser(ctx, "alice", "password"); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	if tf, err := p.TwoFactor(ctx, "alice"); tf != nil || err != nil {
+		t.Fatalf("TwoFactor before enrollment = %v, %v", tf, err)
+	}
+	if err := p.SetTwoFactor(ctx, "alice", &TwoFactor{Secret: "ABC", Enabled: true}); err != nil {
+		t.Fatalf("SetTwoFactor: %v", err)
+	}
+	if tf, err := p.TwoFactor(ctx, "alice"); err != nil || tf == nil || tf.Secret != "ABC" || !tf.Enabled {
+		t.Fatalf("TwoFactor = %+v, %v", tf, err)
+	}
+	if err := p.SetTwoFactor(ctx, "alice", nil); err != nil {
+		t.Fatalf("reset: %v", err)
+	}
+	if tf, _ := p.TwoFactor(ctx, "alice"); tf != nil {
+		t.Fatalf("enrollment not removed: %+v", tf)
+	}
+	if ok, _ := p.CheckPassword(ctx, "alice", "password"); !ok {
+		t.Fatalf("password lost")
+	}
+}
+
+// twoFactorPost posts form to handler, carrying cookies from prev.
+func twoFactorPost(t *testing.T, handler func(w http.ResponseWriter, r *http.Request) error, path string, form url.Values, prev *httptest.ResponseRecorder) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest("POST", path, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if prev != nil {
+		addLastCookies(req, prev)
+	}
+	w := httptest.NewRecorder()
+	if err := handler(w, req); err != nil && err != ErrHandled {
+		t.Fatalf("%s: %v", path, err)
+	}
+	return w
+}
+
+func TestGitTwoFactorLogin(t *testing.T) {
+	useLoginAttempts(t, NewMemoryLoginAttemptStore())
+	old := Config
+	t.Cleanup(func() { Config = old })
+	Config.LocalGitPath = t.TempDir()
+	Config.SessionName = "gobookmarks"
+	SessionStore = sessions.NewCookieStore([]byte("secret-key"))
+	ctx := context.Background()
+	p := GitProvider{}
+	if err := p.CreateUser(ctx, "alice", "password"); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	tf := &TwoFactor{Enabled: true}
+	tf.Secret, _ = newTOTPSecret()
+	codes, _ := tf.NewRecoveryCodes()
+	if err := p.SetTwoFactor(ctx, "alice", tf); err != nil {
+		t.Fatalf("SetTwoFactor: %v", err)
+	}
+
+	signedIn := func(w *httptest.ResponseRecorder) string {
+		req := httptest.NewRequest("GET", "/", nil)
+		addLastCookies(req, w)
+		s, _ := SessionStore.New(req, Config.GetSessionName())
+		if u, ok := s.Values["GithubUser"].(*User); ok {
+			return u.Login
+		}
+		return ""
+	}
+
+	login := twoFactorPost(t, GitLoginAction, "/login/git", url.Values{"username": {"alice"}, "password": {"password"}}, nil)
+	if loc := login.Header().Get("Location"); loc != "/login/2fa" {
+		t.Fatalf("password alone redirected to %q", loc)
+	}
+	if got := signedIn(login); got != "" {
+		t.Fatalf("signed in as %q before second step", got)
+	}
+
+	bad := twoFactorPost(t, TwoFactorLoginAction, "/login/2fa", url.Values{"code": {"000000"}}, login)
+	if loc := bad.Header().Get("Location"); loc != "/login/2fa?error=code" || signedIn(bad) != "" {
+		t.Fatalf("bad code: %q", loc)
+	}
+
+	ok := twoFactorPost(t, TwoFactorLoginAction, "/login/2fa", url.Values{"code": {codes[0]}}, bad)
+	if got := signedIn(ok); got != "alice" {
+		t.Fatalf("recovery code login = %q (%s)", got, ok.Header().Get("Location"))
+	}
+	if stored, _ := p.TwoFactor(ctx, "alice"); len(stored.RecoveryCodes) != recoveryCodeCount-1 {
+		t.Fatalf("used recovery code kept")
+	}
+	if _, err := os.Stat(twoFactorPath("alice")); err != nil {
+		t.Fatalf("two-factor file: %v", err)
+	}
+	if _, err := CleanBookmarkPath(".totp"); err == nil {
+		t.Fatalf(".totp accepted as a bookmark file")
+	}
+}
+
+func TestTwoFactorLoginLockout(t *testing.T) {
+	// Only the per-login attempt limit is under test here.
+	useLoginAttempts(t, nil)
+	old := Config
+	t.Cleanup(func() { Config = old })
+	Config.LocalGitPath = t.TempDir()
+	Config.SessionName = "gobookmarks"
+	SessionStore = sessions.NewCookieStore([]byte("secret-key"))
+	ctx := context.Background()
+	p := GitProvider{}
+	_ = p.CreateUser(ctx, "alice", "password")
+	secret, _ := newTOTPSecret()
+	_ = p.SetTwoFactor(ctx, "alice", &TwoFactor{Secret: secret, Enabled: true})
+
+	w := twoFactorPost(t, GitLoginAction, "/login/git", url.Values{"username": {"alice"}, "password": {"password"}}, nil)
+	for i := 0; i < twoFactorLoginAttempts; i++ {
+		w = twoFactorPost(t, TwoFactorLoginAction, "/login/2fa", url.Values{"code": {"000000"}}, w)
+	}
+	if loc := w.Header().Get("Location"); loc != "/login/git?error=2fa" {
+		t.Fatalf("pending login kept after %d failures: %q", twoFactorLoginAttempts, loc)
+	}
+	code, _ := totpCode(secret, time.Now().Unix()/totpPeriod)
+	w = twoFactorPost(t, TwoFactorLoginAction, "/login/2fa", url.Values{"code": {code}}, w)
+	if loc := w.Header().Get("Location"); loc != "/login?error=2fa" {
+		t.Fatalf("valid code accepted after lockout: %q", loc)
+	}
+}
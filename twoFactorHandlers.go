@@ -0,0 +1,298 @@
+package gobookmarks
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/sessions"
+)
+
+const (
+	twoFactorLoginTimeout  = 5 * time.Minute
+	twoFactorLoginAttempts = 5
+)
+
+// beginTwoFactor starts the second login step when user has enrolled in
+// TOTP. It reports whether the caller should stop; the session then records
+// the pending login and the browser is sent to /login/2fa.
+func beginTwoFactor(w http.ResponseWriter, r *http.Request, session *sessions.Session, p Provider, user string) (bool, error) {
+	th, ok := p.(TwoFactorHandler)
+	if !ok {
+		return false, nil
+	}
+	tf, err := th.TwoFactor(r.Context(), user)
+	if err != nil {
+		return false, fmt.Errorf("two-factor lookup: %w", err)
+	}
+	if tf == nil || !tf.Enabled {
+		return false, nil
+	}
+	session.Values["TwoFactorUser"] = user
+	session.Values["TwoFactorProvider"] = p.Name()
+	session.Values["TwoFactorExpires"] = time.Now().Add(twoFactorLoginTimeout).Unix()
+	session.Values["TwoFactorAttempts"] = 0
+	session.Values["version"] = version
+	if err := session.Save(r, w); err != nil {
+		return false, fmt.Errorf("session save: %w", err)
+	}
+	http.Redirect(w, r, "/login/2fa", http.StatusSeeOther)
+	return true, nil
+}
+
+func clearTwoFactorLogin(session *sessions.Session) {
+	delete(session.Values, "TwoFactorUser")
+	delete(session.Values, "TwoFactorProvider")
+	delete(session.Values, "TwoFactorExpires")
+	delete(session.Values, "TwoFactorAttempts")
+}
+
+// TwoFactorLoginAction completes a password login with an authenticator or
+// recovery code.
+func TwoFactorLoginAction(w http.ResponseWriter, r *http.Request) error {
+	session, err := getSession(w, r)
+	if session, err = sanitizeSession(w, r, session, err); err != nil {
+		return fmt.Errorf("session error: %w", err)
+	}
+	user, _ := session.Values["TwoFactorUser"].(string)
+	providerName, _ := session.Values["TwoFactorProvider"].(string)
+	expires, _ := session.Values["TwoFactorExpires"].(int64)
+	attempts, _ := session.Values["TwoFactorAttempts"].(int)
+
+	restart := func(reason string) error {
+		slog.InfoContext(r.Context(), "two-factor login failed", "provider", providerName, "user", user, "reason", reason)
+		clearTwoFactorLogin(session)
+		if err := session.Save(r, w); err != nil {
+			return fmt.Errorf("session save: %w", err)
+		}
+		target := "/login?error=2fa"
+		if providerName != "" {
+			target = "/login/" + providerName + "?error=2fa"
+		}
+		http.Redirect(w, r, target, http.StatusSeeOther)
+		return ErrHandled
+	}
+	if user == "" || time.Now().Unix() > expires {
+		return restart("no pending login")
+	}
+	th, ok := GetProvider(providerName).(TwoFactorHandler)
+	if !ok {
+		return restart("provider does not support two-factor")
+	}
+	if wait := loginThrottled(r, providerName, user); wait > 0 {
+		rejectThrottled(w, r, "/login/2fa?error=throttled", wait)
+		return ErrHandled
+	}
+	tf, err := th.TwoFactor(r.Context(), user)
+	if err != nil {
+		return fmt.Errorf("two-factor lookup: %w", err)
+	}
+	if tf == nil || !tf.Enabled {
+		return restart("enrollment removed")
+	}
+	if !tf.Verify(r.PostFormValue("code"), time.Now()) {
+		recordLoginFailure(r, providerName, user)
+		attempts++
+		if attempts >= twoFactorLoginAttempts {
+			return restart("too many attempts")
+		}
+		slog.InfoContext(r.Context(), "two-factor login failed", "provider", providerName, "user", user, "reason", "invalid code")
+		session.Values["TwoFactorAttempts"] = attempts
+		if err := session.Save(r, w); err != nil {
+			return fmt.Errorf("session save: %w", err)
+		}
+		http.Redirect(w, r, "/login/2fa?error=code", http.StatusSeeOther)
+		return ErrHandled
+	}
+	if err := th.SetTwoFactor(r.Context(), user, tf); err != nil {
+		return fmt.Errorf("two-factor save: %w", err)
+	}
+
+	recordLoginSuccess(r, providerName, user)
+	recordAccountLogin(r, GetProvider(providerName), user)
+	clearTwoFactorLogin(session)
+	renewSession(r, session)
+	session.Values["Provider"] = providerName
+	session.Values["GithubUser"] = &User{Login: user}
+	session.Values["Token"] = nil
+	session.Values["version"] = version
+	if err := session.Save(r, w); err != nil {
+		return fmt.Errorf("session save: %w", err)
+	}
+	return nil
+}
+
+// twoFactorAccount returns the signed-in user's two-factor store.
+func twoFactorAccount(r *http.Request) (TwoFactorHandler, string, error) {
+	session := r.Context().Value(ContextValues("session")).(*sessions.Session)
+	githubUser, _ := session.Values["GithubUser"].(*User)
+	if githubUser == nil {
+		return nil, "", ErrSignedOut
+	}
+	providerName, _ := session.Values["Provider"].(string)
+	th, ok := GetProvider(providerName).(TwoFactorHandler)
+	if !ok {
+		return nil, "", NewUserError("Two-factor authentication is only available for password accounts", ErrUserNotFound)
+	}
+	return th, githubUser.Login, nil
+}
+
+// TwoFactorPageData is rendered by twoFactor.gohtml.
+type TwoFactorPageData struct {
+	*CoreData
+	Error string
+	// Available is false for accounts without a password.
+	Available bool
+	Enabled   bool
+	// Secret and URI are set while enrollment awaits confirmation.
+	Secret  string
+	URI     string
+	URILink template.URL
+	// RecoveryCodes are only shown right after they are generated.
+	RecoveryCodes     []string
+	RecoveryRemaining int
+}
+
+func renderTwoFactorPage(w http.ResponseWriter, r *http.Request, codes []string) error {
+	data := TwoFactorPageData{
+		CoreData:      r.Context().Value(ContextValues("coreData")).(*CoreData),
+		Error:         r.URL.Query().Get("error"),
+		RecoveryCodes: codes,
+	}
+	th, user, err := twoFactorAccount(r)
+	if err == nil {
+		var tf *TwoFactor
+		tf, err = th.TwoFactor(r.Context(), user)
+		switch {
+		case err == nil:
+			data.Available = true
+			if tf != nil {
+				data.Enabled = tf.Enabled
+				data.RecoveryRemaining = len(tf.RecoveryCodes)
+				if !tf.Enabled {
+					data.Secret = tf.Secret
+					data.URI = totpURI(user, tf.Secret)
+					data.URILink = template.URL(data.URI)
+				}
+			}
+		case errors.Is(err, ErrUserNotFound):
+		default:
+			return fmt.Errorf("two-factor lookup: %w", err)
+		}
+	} else if errors.Is(err, ErrSignedOut) {
+		return err
+	}
+	if err := GetCompiledTemplates(NewFuncs(r)).ExecuteTemplate(w, "twoFactor.gohtml", data); err != nil {
+		return fmt.Errorf("template: %w", err)
+	}
+	return nil
+}
+
+// TwoFactorPage shows the enrollment state of the signed-in user.
+func TwoFactorPage(w http.ResponseWriter, r *http.Request) error {
+	return renderTwoFactorPage(w, r, nil)
+}
+
+// loadTwoFactor returns the signed-in user's store, name and enrollment,
+// turning a missing password account into a user error.
+func loadTwoFactor(r *http.Request) (TwoFactorHandler, string, *TwoFactor, error) {
+	th, user, err := twoFactorAccount(r)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	tf, err := th.TwoFactor(r.Context(), user)
+	if errors.Is(err, ErrUserNotFound) {
+		return nil, "", nil, NewUserError("Two-factor authentication is only available for password accounts", err)
+	}
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("two-factor lookup: %w", err)
+	}
+	return th, user, tf, nil
+}
+
+// TwoFactorSetupAction creates a new secret awaiting confirmation.
+func TwoFactorSetupAction(w http.ResponseWriter, r *http.Request) error {
+	th, user, tf, err := loadTwoFactor(r)
+	if err != nil {
+		return err
+	}
+	if tf != nil && tf.Enabled {
+		return NewUserError("Two-factor authentication is already enabled", nil)
+	}
+	secret, err := newTOTPSecret()
+	if err != nil {
+		return err
+	}
+	if err := th.SetTwoFactor(r.Context(), user, &TwoFactor{Secret: secret}); err != nil {
+		return fmt.Errorf("two-factor save: %w", err)
+	}
+	return nil
+}
+
+// TwoFactorConfirmAction enables two-factor authentication once the user
+// proves their authenticator works, then shows the recovery codes.
+func TwoFactorConfirmAction(w http.ResponseWriter, r *http.Request) error {
+	th, user, tf, err := loadTwoFactor(r)
+	if err != nil {
+		return err
+	}
+	if tf == nil || tf.Enabled {
+		return NewUserError("Start the two-factor setup first", nil)
+	}
+	if !tf.Verify(r.PostFormValue("code"), time.Now()) {
+		return NewUserError("Invalid authentication code", nil)
+	}
+	codes, err := tf.NewRecoveryCodes()
+	if err != nil {
+		return err
+	}
+	tf.Enabled = true
+	if err := th.SetTwoFactor(r.Context(), user, tf); err != nil {
+		return fmt.Errorf("two-factor save: %w", err)
+	}
+	return renderTwoFactorPage(w, r, codes)
+}
+
+// TwoFactorRecoveryAction replaces the recovery codes after checking a code.
+func TwoFactorRecoveryAction(w http.ResponseWriter, r *http.Request) error {
+	th, user, tf, err := loadTwoFactor(r)
+	if err != nil {
+		return err
+	}
+	if tf == nil || !tf.Enabled {
+		return NewUserError("Two-factor authentication is not enabled", nil)
+	}
+	if !tf.Verify(r.PostFormValue("code"), time.Now()) {
+		return NewUserError("Invalid authentication code", nil)
+	}
+	codes, err := tf.NewRecoveryCodes()
+	if err != nil {
+		return err
+	}
+	if err := th.SetTwoFactor(r.Context(), user, tf); err != nil {
+		return fmt.Errorf("two-factor save: %w", err)
+	}
+	return renderTwoFactorPage(w, r, codes)
+}
+
+// TwoFactorDisableAction removes the enrollment. A pending setup can be
+// cancelled without a code.
+func TwoFactorDisableAction(w http.ResponseWriter, r *http.Request) error {
+	th, user, tf, err := loadTwoFactor(r)
+	if err != nil {
+		return err
+	}
+	if tf == nil {
+		return nil
+	}
+	if tf.Enabled && !tf.Verify(r.PostFormValue("code"), time.Now()) {
+		return NewUserError("Invalid authentication code", nil)
+	}
+	if err := th.SetTwoFactor(r.Context(), user, nil); err != nil {
+		return fmt.Errorf("two-factor save: %w", err)
+	}
+	return nil
+}